@@ -1,22 +1,105 @@
 package auth
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"github.com/dgrijalva/jwt-go"
 	"time"
 	"wegugin/config"
 )
 
-func GenerateJWTToken(id, role string) (string, error) {
-	conf := config.Load()
+// TokenExpiry is when an access token issued now, and the session behind
+// it, expires.
+func TokenExpiry() time.Time {
+	return time.Now().AddDate(0, 6, 0)
+}
+
+// Tokens signs the service's tokens with TOKEN_KEY and checks them with the
+// key named by their kid header, TOKEN_KEY or one of TOKEN_OLD_KEYS.
+type Tokens struct {
+	kid string
+	key []byte
+	// keys are by kid, the signing key first in order
+	keys  map[string][]byte
+	order [][]byte
+}
+
+func New(conf config.TokensConfig) *Tokens {
+	t := &Tokens{kid: KeyId(conf.TOKEN_KEY), key: []byte(conf.TOKEN_KEY), keys: map[string][]byte{}}
+	for _, key := range append([]string{conf.TOKEN_KEY}, conf.OldKeys()...) {
+		if _, ok := t.keys[KeyId(key)]; !ok {
+			t.keys[KeyId(key)] = []byte(key)
+			t.order = append(t.order, []byte(key))
+		}
+	}
+	return t
+}
+
+// KeyId is the kid of the tokens a key signs, a hash so the header does not
+// give the key away.
+func KeyId(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+// sign signs token with the current key.
+func (t *Tokens) sign(token *jwt.Token) (string, error) {
+	token.Header["kid"] = t.kid
+	return token.SignedString(t.key)
+}
+
+// parse checks a token with the key its kid names. Tokens from before keys
+// had ids carry none, every key is tried for them.
+func (t *Tokens) parse(tokenStr string) (*jwt.Token, error) {
+	var (
+		token *jwt.Token
+		err   error
+	)
+	for _, legacy := range t.order {
+		token, err = jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+			}
+			kid, ok := token.Header["kid"].(string)
+			if !ok {
+				return legacy, nil
+			}
+			key, ok := t.keys[kid]
+			if !ok {
+				return nil, errors.New("token is signed with an unknown key")
+			}
+			return key, nil
+		})
+		var verr *jwt.ValidationError
+		if err == nil || token == nil || token.Header["kid"] != nil ||
+			!errors.As(err, &verr) || verr.Errors&jwt.ValidationErrorSignatureInvalid == 0 {
+			break
+		}
+	}
+	return token, err
+}
+
+func (t *Tokens) GenerateJWTToken(id, role string) (string, error) {
+	return t.GenerateSessionToken(id, role, "", TokenExpiry())
+}
+
+// GenerateSessionToken issues an access token bound to a user_sessions row
+// through the sid claim, so the session can be revoked before exp.
+func (t *Tokens) GenerateSessionToken(id, role, sessionId string, exp time.Time) (string, error) {
 	token := *jwt.New(jwt.SigningMethodHS256)
 	//payload
 	claims := token.Claims.(jwt.MapClaims)
 	claims["user_id"] = id
 	claims["role"] = role
+	if sessionId != "" {
+		claims["sid"] = sessionId
+	}
 	claims["iat"] = time.Now().Unix()
-	claims["exp"] = time.Now().AddDate(0, 6, 0).Unix()
+	claims["exp"] = exp.Unix()
 
-	newToken, err := token.SignedString([]byte(conf.Token.TOKEN_KEY))
+	newToken, err := t.sign(&token)
 	if err != nil {
 		return "", err
 	}
@@ -24,19 +107,27 @@ func GenerateJWTToken(id, role string) (string, error) {
 	return newToken, nil
 }
 
-func ValidateToken(tokenStr string) (bool, error) {
-	_, err := ExtractClaim(tokenStr)
+// GetSessionId returns the sid claim of an access token, empty for tokens
+// issued before sessions were tracked.
+func (t *Tokens) GetSessionId(tokenStr string) string {
+	claims, err := t.ExtractClaim(tokenStr)
+	if err != nil || claims == nil {
+		return ""
+	}
+	sid, _ := (*claims)["sid"].(string)
+	return sid
+}
+
+func (t *Tokens) ValidateToken(tokenStr string) (bool, error) {
+	_, err := t.ExtractClaim(tokenStr)
 	if err != nil {
 		return false, err
 	}
 	return true, nil
 }
 
-func ExtractClaim(tokenStr string) (*jwt.MapClaims, error) {
-	conf := config.Load()
-	token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-		return []byte(conf.Token.TOKEN_KEY), nil
-	})
+func (t *Tokens) ExtractClaim(tokenStr string) (*jwt.MapClaims, error) {
+	token, err := t.parse(tokenStr)
 
 	if err != nil {
 		return nil, err
@@ -50,9 +141,8 @@ func ExtractClaim(tokenStr string) (*jwt.MapClaims, error) {
 	return &claims, nil
 }
 
-func GetUserIdFromToken(req string) (Id string, Role string, err error) {
-	conf := config.Load()
-	Token, err := jwt.Parse(req, func(token *jwt.Token) (interface{}, error) { return []byte(conf.Token.TOKEN_KEY), nil })
+func (t *Tokens) GetUserIdFromToken(req string) (Id string, Role string, err error) {
+	Token, err := t.parse(req)
 	if err != nil || !Token.Valid {
 		return "", "", err
 	}
@@ -60,8 +150,47 @@ func GetUserIdFromToken(req string) (Id string, Role string, err error) {
 	if !ok {
 		return "", "", err
 	}
-	Id = claims["user_id"].(string)
-	Role = claims["role"].(string)
+	// Link tokens are only good for the flow they were issued for
+	if _, ok := claims["purpose"]; ok {
+		return "", "", errors.New("token is not an access token")
+	}
+	Id, _ = claims["user_id"].(string)
+	Role, _ = claims["role"].(string)
+	if Id == "" {
+		return "", "", errors.New("token has no user id")
+	}
 
 	return Id, Role, nil
 }
+
+// GeneratePurposeToken signs a short lived token for links sent by email.
+// The purpose claim keeps it from being accepted as an access token or by
+// another flow.
+func (t *Tokens) GeneratePurposeToken(purpose, id string, extra map[string]interface{}, exp time.Time) (string, error) {
+	token := jwt.New(jwt.SigningMethodHS256)
+	claims := token.Claims.(jwt.MapClaims)
+	for k, v := range extra {
+		claims[k] = v
+	}
+	claims["purpose"] = purpose
+	claims["user_id"] = id
+	claims["iat"] = time.Now().Unix()
+	claims["exp"] = exp.Unix()
+
+	return t.sign(token)
+}
+
+// ParsePurposeToken validates a token made by GeneratePurposeToken for the given purpose.
+func (t *Tokens) ParsePurposeToken(purpose, tokenStr string) (jwt.MapClaims, error) {
+	claims, err := t.ExtractClaim(tokenStr)
+	if err != nil {
+		return nil, err
+	}
+	if claims == nil || (*claims)["purpose"] != purpose {
+		return nil, errors.New("invalid token purpose")
+	}
+	if id, _ := (*claims)["user_id"].(string); id == "" {
+		return nil, errors.New("token has no user id")
+	}
+	return *claims, nil
+}
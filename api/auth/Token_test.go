@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"testing"
+	"time"
+	"wegugin/config"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	oldKey = "old-key-old-key-old-key-old-key-old-key"
+	newKey = "new-key-new-key-new-key-new-key-new-key"
+)
+
+// TestRotation signs a token, rotates the key and checks that the token is
+// still good while the old key is kept, and no longer once it is dropped.
+func TestRotation(t *testing.T) {
+	before := New(config.TokensConfig{TOKEN_KEY: oldKey})
+	token, err := before.GenerateJWTToken("user", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A token from before keys had ids
+	legacy := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": "legacy", "role": "user", "exp": time.Now().Add(time.Hour).Unix(),
+	})
+	legacyToken, err := legacy.SignedString([]byte(oldKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := New(config.TokensConfig{TOKEN_KEY: newKey, TOKEN_OLD_KEYS: oldKey})
+	if id, _, err := rotated.GetUserIdFromToken(token); err != nil || id != "user" {
+		t.Errorf("token of the old key after rotating: %q, %v", id, err)
+	}
+	if id, _, err := rotated.GetUserIdFromToken(legacyToken); err != nil || id != "legacy" {
+		t.Errorf("token without a kid after rotating: %q, %v", id, err)
+	}
+	fresh, err := rotated.GenerateJWTToken("fresh", "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, _, err := new(jwt.Parser).ParseUnverified(fresh, jwt.MapClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kid := parsed.Header["kid"]; kid != KeyId(newKey) {
+		t.Errorf("kid %v, want the one of the new key", kid)
+	}
+	if _, _, err := before.GetUserIdFromToken(fresh); err == nil {
+		t.Error("a token of the new key passed with the old key only")
+	}
+
+	dropped := New(config.TokensConfig{TOKEN_KEY: newKey})
+	for _, token := range []string{token, legacyToken} {
+		if _, _, err := dropped.GetUserIdFromToken(token); err == nil {
+			t.Error("a token of a dropped key still passes")
+		}
+	}
+}
+
+// TestExpiredLegacyToken checks that an expired token without a kid is
+// reported as expired, not as signed with the wrong key.
+func TestExpiredLegacyToken(t *testing.T) {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": "legacy", "exp": time.Now().Add(-time.Hour).Unix(),
+	}).SignedString([]byte(oldKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens := New(config.TokensConfig{TOKEN_KEY: newKey, TOKEN_OLD_KEYS: oldKey})
+	_, err = tokens.ExtractClaim(token)
+	verr, ok := err.(*jwt.ValidationError)
+	if !ok || verr.Errors&jwt.ValidationErrorExpired == 0 {
+		t.Errorf("got %v, want an expired token", err)
+	}
+}
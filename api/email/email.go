@@ -2,10 +2,11 @@ package email
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"html/template"
-	"log"
 	"math/rand"
+	"net"
 	"net/smtp"
 	"regexp"
 	"strconv"
@@ -13,7 +14,17 @@ import (
 	"wegugin/config"
 )
 
-func EmailCode(email string) (string, error) {
+// Sender sends the service's emails from the configured account.
+type Sender struct {
+	From     string
+	Password string
+}
+
+func NewSender(conf config.EmailConfig) *Sender {
+	return &Sender{From: conf.SENDER_EMAIL, Password: conf.APP_PASSWORD}
+}
+
+func (s *Sender) EmailCode(email string) (string, error) {
 
 	// Seed the random number generator with a cryptographically secure value
 	source := rand.NewSource(time.Now().UnixNano())
@@ -23,7 +34,7 @@ func EmailCode(email string) (string, error) {
 	randomNumber := myRand.Intn(900000) + 100000
 	code := strconv.Itoa(randomNumber)
 
-	err := SendEmail(email, code)
+	err := s.SendEmail(email, code)
 
 	if err != nil {
 		return "", err
@@ -32,39 +43,76 @@ func EmailCode(email string) (string, error) {
 	return code, nil
 }
 
-func SendEmail(email string, code string) error {
-	conf := config.Load()
+func (s *Sender) SendEmail(email string, code string) error {
+	return s.send(email, "Your verification code", "api/email/template.html", struct {
+		Passwd string
+	}{
+		Passwd: code,
+	})
+}
+
+// LinkMessage is an email with a single call to action link.
+type LinkMessage struct {
+	Subject string
+	Title   string
+	Text    string
+	Link    string
+	Button  string
+}
+
+func (s *Sender) SendLink(email string, message LinkMessage) error {
+	return s.send(email, message.Subject, "api/email/link_template.html", message)
+}
+
+// The SMTP server the emails are sent through
+const (
+	smtpHost = "smtp.gmail.com"
+	smtpPort = "587"
+)
+
+// Health checks the SMTP server answers, without logging in.
+func Health(ctx context.Context) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", smtpHost+":"+smtpPort)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	client, err := smtp.NewClient(conn, smtpHost)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	return client.Quit()
+}
+
+func (s *Sender) send(email, subject, templateFile string, data interface{}) error {
 	// sender data
-	from := conf.Email.SENDER_EMAIL
-	password := conf.Email.APP_PASSWORD
+	from := s.From
+	password := s.Password
 
 	// Receiver email address
 	to := []string{
 		email,
 	}
 
-	// smtp server configuration.
-	smtpHost := "smtp.gmail.com"
-	smtpPort := "587"
-
 	// Authentication.
 	auth := smtp.PlainAuth("", from, password, smtpHost)
 
-	t, err := template.ParseFiles("api/email/template.html")
+	t, err := template.ParseFiles(templateFile)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	var body bytes.Buffer
 
 	mimeHeaders := "MIME-version: 1.0;\nContent-Type: text/html; charset=\"UTF-8\";\n\n"
-	body.Write([]byte(fmt.Sprintf("Subject: Your verification code \n%s\n\n", mimeHeaders)))
-	t.Execute(&body, struct {
-		Passwd string
-	}{
-
-		Passwd: code,
-	})
+	body.Write([]byte(fmt.Sprintf("Subject: %s \n%s\n\n", subject, mimeHeaders)))
+	if err := t.Execute(&body, data); err != nil {
+		return err
+	}
 
 	// Sending email.
 	err = smtp.SendMail(smtpHost+":"+smtpPort, auth, from, to, body.Bytes())
@@ -0,0 +1,279 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	pb "wegugin/genproto/user"
+	"wegugin/model"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/metadata"
+)
+
+// withAuth forwards the caller's token to the user service, which checks the
+// admin role again before running any Admin* method.
+func withAuth(c *gin.Context) context.Context {
+	return metadata.AppendToOutgoingContext(c, "authorization", c.GetHeader("Authorization"))
+}
+
+// AdminListUsers godoc
+// @Security ApiKeyAuth
+// @Summary List Users
+// @Description Search users. Dates are YYYY-MM-DD, deleted is active (default), deleted or all
+// @Tags admin
+// @Param email query string false "Email contains"
+// @Param phone_number query string false "Phone number contains"
+// @Param name query string false "Name or surname contains"
+// @Param role query string false "Role"
+// @Param created_from query string false "Created on or after"
+// @Param created_to query string false "Created on or before"
+// @Param deleted query string false "active, deleted or all"
+// @Param page query int false "Page, starting from 1"
+// @Param limit query int false "Page size, at most 100"
+// @Success 200 {object} user.AdminUserList
+// @Failure 400 {object} string "Invalid data"
+// @Failure 403 {object} string "Admin role is required"
+// @Failure 500 {object} string "error while reading from server"
+// @Router /admin/users [get]
+func (h *Handler) AdminListUsers(c *gin.Context) {
+	h.Log.Info("AdminListUsers is working")
+	var req model.AdminListUsers
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	res, err := h.User.AdminListUsers(withAuth(c), &pb.AdminListUsersReq{
+		Email:       req.Email,
+		PhoneNumber: req.PhoneNumber,
+		Name:        req.Name,
+		Role:        req.Role,
+		CreatedFrom: req.CreatedFrom,
+		CreatedTo:   req.CreatedTo,
+		Deleted:     req.Deleted,
+		Page:        req.Page,
+		Limit:       req.Limit,
+	})
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.Log.Info("AdminListUsers finished successfully")
+	c.JSON(http.StatusOK, res)
+}
+
+// AdminGetUser godoc
+// @Security ApiKeyAuth
+// @Summary Get User
+// @Description Get any user, including soft-deleted ones
+// @Tags admin
+// @Param id path string true "USER ID"
+// @Success 200 {object} user.AdminUser
+// @Failure 403 {object} string "Admin role is required"
+// @Failure 404 {object} string "User not found"
+// @Router /admin/users/{id} [get]
+func (h *Handler) AdminGetUser(c *gin.Context) {
+	h.Log.Info("AdminGetUser is working")
+	res, err := h.User.AdminGetUser(withAuth(c), &pb.UserId{Id: c.Param("id")})
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	h.Log.Info("AdminGetUser finished successfully")
+	c.JSON(http.StatusOK, res)
+}
+
+// AdminUpdateRole godoc
+// @Security ApiKeyAuth
+// @Summary Change User Role
+// @Description Change the role of a user to admin or user
+// @Tags admin
+// @Param id path string true "USER ID"
+// @Param role body model.UpdateRole true "Role"
+// @Success 200 {object} string "Role updated successfully"
+// @Failure 400 {object} string "Invalid data"
+// @Failure 403 {object} string "Admin role is required"
+// @Failure 500 {object} string "error while reading from server"
+// @Router /admin/users/{id}/role [put]
+func (h *Handler) AdminUpdateRole(c *gin.Context) {
+	h.Log.Info("AdminUpdateRole is working")
+	var req model.UpdateRole
+	if err := c.BindJSON(&req); err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	_, err := h.User.AdminUpdateRole(withAuth(c), &pb.UpdateRoleReq{Id: c.Param("id"), Role: req.Role})
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.Log.Info("AdminUpdateRole finished successfully")
+	c.JSON(http.StatusOK, gin.H{"message": "Role updated successfully"})
+}
+
+// AdminForcePasswordReset godoc
+// @Security ApiKeyAuth
+// @Summary Force Password Reset
+// @Description Block login with the current password and email the user a reset code
+// @Tags admin
+// @Param id path string true "USER ID"
+// @Success 200 {object} string "Password reset required"
+// @Failure 403 {object} string "Admin role is required"
+// @Failure 500 {object} string "error while reading from server"
+// @Router /admin/users/{id}/force-password-reset [post]
+func (h *Handler) AdminForcePasswordReset(c *gin.Context) {
+	h.Log.Info("AdminForcePasswordReset is working")
+	_, err := h.User.AdminForcePasswordReset(withAuth(c), &pb.UserId{Id: c.Param("id")})
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error forcing password reset"})
+		return
+	}
+	h.Log.Info("AdminForcePasswordReset finished successfully")
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset required, code sent to user's email"})
+}
+
+// AdminRestoreUser godoc
+// @Security ApiKeyAuth
+// @Summary Restore User
+// @Description Restore a soft-deleted user
+// @Tags admin
+// @Param id path string true "USER ID"
+// @Success 200 {object} string "User restored successfully"
+// @Failure 403 {object} string "Admin role is required"
+// @Failure 500 {object} string "error while reading from server"
+// @Router /admin/users/{id}/restore [post]
+func (h *Handler) AdminRestoreUser(c *gin.Context) {
+	h.Log.Info("AdminRestoreUser is working")
+	_, err := h.User.AdminRestoreUser(withAuth(c), &pb.UserId{Id: c.Param("id")})
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error restoring user"})
+		return
+	}
+	h.Log.Info("AdminRestoreUser finished successfully")
+	c.JSON(http.StatusOK, gin.H{"message": "User restored successfully"})
+}
+
+// AdminHardDeleteUser godoc
+// @Security ApiKeyAuth
+// @Summary Hard Delete User
+// @Description Permanently delete a user, their photo, car images, export archives and everything that references them
+// @Tags admin
+// @Param id path string true "USER ID"
+// @Success 200 {object} string "User deleted permanently"
+// @Failure 403 {object} string "Admin role is required"
+// @Failure 500 {object} string "error while reading from server"
+// @Router /admin/users/{id} [delete]
+func (h *Handler) AdminHardDeleteUser(c *gin.Context) {
+	h.Log.Info("AdminHardDeleteUser is working")
+	ctx := withAuth(c)
+	user, err := h.User.AdminGetUser(ctx, &pb.UserId{Id: c.Param("id")})
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	_, err = h.User.AdminHardDeleteUser(ctx, &pb.UserId{Id: user.Id})
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting user"})
+		return
+	}
+	h.Log.Info("AdminHardDeleteUser finished successfully")
+	c.JSON(http.StatusOK, gin.H{"message": "User deleted permanently"})
+}
+
+// AdminSuspendUser godoc
+// @Security ApiKeyAuth
+// @Summary Suspend User
+// @Description Suspend a user until expires_at (RFC3339). Without expires_at the user is banned. Their cars are hidden while suspended
+// @Tags admin
+// @Param id path string true "USER ID"
+// @Param suspension body model.SuspendUser true "Suspension"
+// @Success 200 {object} user.Suspension
+// @Failure 400 {object} string "Invalid data"
+// @Failure 403 {object} string "Admin role is required"
+// @Failure 500 {object} string "error while reading from server"
+// @Router /admin/users/{id}/suspend [post]
+func (h *Handler) AdminSuspendUser(c *gin.Context) {
+	h.Log.Info("AdminSuspendUser is working")
+	var req model.SuspendUser
+	if err := c.BindJSON(&req); err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	res, err := h.User.AdminSuspendUser(withAuth(c), &pb.SuspendUserReq{
+		UserId:    c.Param("id"),
+		Reason:    req.Reason,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.Log.Info("AdminSuspendUser finished successfully")
+	c.JSON(http.StatusOK, res)
+}
+
+// AdminLiftSuspension godoc
+// @Security ApiKeyAuth
+// @Summary Lift Suspension
+// @Description Lift the active suspension of a user
+// @Tags admin
+// @Param id path string true "USER ID"
+// @Param lift body model.LiftSuspension false "Reason"
+// @Success 200 {object} string "Suspension lifted successfully"
+// @Failure 403 {object} string "Admin role is required"
+// @Failure 500 {object} string "error while reading from server"
+// @Router /admin/users/{id}/unsuspend [post]
+func (h *Handler) AdminLiftSuspension(c *gin.Context) {
+	h.Log.Info("AdminLiftSuspension is working")
+	var req model.LiftSuspension
+	if c.Request.ContentLength > 0 {
+		if err := c.BindJSON(&req); err != nil {
+			h.Log.Error(err.Error())
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	_, err := h.User.AdminLiftSuspension(withAuth(c), &pb.LiftSuspensionReq{
+		UserId: c.Param("id"),
+		Reason: req.Reason,
+	})
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.Log.Info("AdminLiftSuspension finished successfully")
+	c.JSON(http.StatusOK, gin.H{"message": "Suspension lifted successfully"})
+}
+
+// AdminListSuspensions godoc
+// @Security ApiKeyAuth
+// @Summary Suspension History
+// @Description All suspensions of a user, newest first
+// @Tags admin
+// @Param id path string true "USER ID"
+// @Success 200 {object} user.SuspensionList
+// @Failure 403 {object} string "Admin role is required"
+// @Failure 500 {object} string "error while reading from server"
+// @Router /admin/users/{id}/suspensions [get]
+func (h *Handler) AdminListSuspensions(c *gin.Context) {
+	h.Log.Info("AdminListSuspensions is working")
+	res, err := h.User.AdminListSuspensions(withAuth(c), &pb.UserId{Id: c.Param("id")})
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error listing suspensions"})
+		return
+	}
+	h.Log.Info("AdminListSuspensions finished successfully")
+	c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"net/http"
+	pb "wegugin/genproto/user"
+	"wegugin/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminListAuditEvents godoc
+// @Security ApiKeyAuth
+// @Summary List Audit Events
+// @Description Search the audit log. Dates are YYYY-MM-DD
+// @Tags admin
+// @Param actor_id query string false "Actor user ID"
+// @Param target_id query string false "Target user ID"
+// @Param action query string false "Action, e.g. login.failure or profile.update"
+// @Param from query string false "On or after"
+// @Param to query string false "On or before"
+// @Param page query int false "Page, starting from 1"
+// @Param limit query int false "Page size, at most 100"
+// @Success 200 {object} user.AuditEventList
+// @Failure 400 {object} string "Invalid data"
+// @Failure 403 {object} string "Admin role is required"
+// @Failure 500 {object} string "error while reading from server"
+// @Router /admin/audit-events [get]
+func (h *Handler) AdminListAuditEvents(c *gin.Context) {
+	h.Log.Info("AdminListAuditEvents is working")
+	var req model.AuditEventFilter
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	res, err := h.User.AdminListAuditEvents(withAuth(c), &pb.AuditEventFilter{
+		ActorId:  req.ActorId,
+		TargetId: req.TargetId,
+		Action:   req.Action,
+		From:     req.From,
+		To:       req.To,
+		Page:     req.Page,
+		Limit:    req.Limit,
+	})
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.Log.Info("AdminListAuditEvents finished successfully")
+	c.JSON(http.StatusOK, res)
+}
+
+// ListSecurityActivity godoc
+// @Security ApiKeyAuth
+// @Summary Security Activity
+// @Description Logins, password changes, profile changes and other events on the current user's account. Dates are YYYY-MM-DD
+// @Tags user
+// @Param action query string false "Action, e.g. login.failure or profile.update"
+// @Param from query string false "On or after"
+// @Param to query string false "On or before"
+// @Param page query int false "Page, starting from 1"
+// @Param limit query int false "Page size, at most 100"
+// @Success 200 {object} user.AuditEventList
+// @Failure 400 {object} string "Invalid data"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 500 {object} string "error while reading from server"
+// @Router /user/security-activity [get]
+func (h *Handler) ListSecurityActivity(c *gin.Context) {
+	h.Log.Info("ListSecurityActivity is working")
+	token := c.GetHeader("Authorization")
+	id, _, err := h.Tokens.GetUserIdFromToken(token)
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var req model.AuditEventFilter
+	if err := c.ShouldBindQuery(&req); err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	res, err := h.User.ListSecurityActivity(c, &pb.AuditEventFilter{
+		TargetId: id,
+		Action:   req.Action,
+		From:     req.From,
+		To:       req.To,
+		Page:     req.Page,
+		Limit:    req.Limit,
+	})
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error listing security activity"})
+		return
+	}
+	h.Log.Info("ListSecurityActivity finished successfully")
+	c.JSON(http.StatusOK, res)
+}
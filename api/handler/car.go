@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"net/http"
+	pb "wegugin/genproto/user"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetCarPriceHistory godoc
+// @Summary Get Car Price History
+// @Description Every price the car had, oldest first
+// @Tags cars
+// @Param id path string true "CAR ID"
+// @Success 200 {object} user.PriceHistory
+// @Failure 400 {object} string "Invalid data"
+// @Failure 404 {object} string "Car not found"
+// @Router /cars/{id}/price-history [get]
+func (h *Handler) GetCarPriceHistory(c *gin.Context) {
+	h.Log.Info("GetCarPriceHistory is working")
+	res, err := h.User.GetCarPriceHistory(c, &pb.CarId{Id: c.Param("id")})
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusNotFound, gin.H{"error": "Car not found"})
+		return
+	}
+	h.Log.Info("GetCarPriceHistory finished successfully")
+	c.JSON(http.StatusOK, res)
+}
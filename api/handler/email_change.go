@@ -0,0 +1,249 @@
+package handler
+
+import (
+	"html/template"
+	"net/http"
+	"wegugin/api/email"
+	"wegugin/api/middleware"
+	"wegugin/api/throttle"
+	pb "wegugin/genproto/user"
+	"wegugin/model"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RequestEmailChange godoc
+// @Security ApiKeyAuth
+// @Summary Change Email
+// @Description Sends a confirmation link to the new email and a notice with a link undoing the change to the current one. The email changes once the link is confirmed, which logs out the other sessions
+// @Tags user
+// @Param info body model.ChangeEmail true "New email and current password"
+// @Success 200 {object} string "message"
+// @Failure 400 {object} string "Invalid data"
+// @Failure 401 {object} string "INVALID_CREDENTIALS"
+// @Failure 409 {object} string "EMAIL_TAKEN"
+// @Failure 429 {object} string "TOO_MANY_ATTEMPTS or ACCOUNT_LOCKED, see Retry-After"
+// @Failure 500 {object} string "error while reading from server"
+// @Router /user/email [post]
+func (h *Handler) RequestEmailChange(c *gin.Context) {
+	h.Log.Info("RequestEmailChange is working")
+	token := c.GetHeader("Authorization")
+	id, _, err := h.Tokens.GetUserIdFromToken(token)
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var req model.ChangeEmail
+	if err := c.BindJSON(&req); err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !email.IsValidEmail(req.NewEmail) {
+		h.Log.Error("Invalid email")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email"})
+		return
+	}
+	// The password is checked like at login, a stolen token must not be
+	// enough to guess it
+	guard := throttle.Login(h.Config.Throttle, h.Redis)
+	if !h.allowAttempt(c, guard, id) {
+		return
+	}
+
+	_, err = h.User.RequestEmailChange(c, &pb.EmailChangeReq{
+		UserId:    id,
+		NewEmail:  req.NewEmail,
+		Password:  req.Password,
+		SessionId: h.Tokens.GetSessionId(token),
+	})
+	switch status.Code(err) {
+	case codes.OK:
+	case codes.InvalidArgument:
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": status.Convert(err).Message()})
+		return
+	case codes.Unauthenticated:
+		h.Log.Error(err.Error())
+		if _, err := guard.Fail(c, id, c.ClientIP()); err != nil {
+			h.Log.Error(err.Error())
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Password is incorrect", "code": middleware.InvalidCredentials})
+		return
+	case codes.AlreadyExists:
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusConflict, gin.H{"error": "Email is already used", "code": middleware.EmailTaken})
+		return
+	default:
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error changing email"})
+		return
+	}
+	if err := guard.Reset(c, id); err != nil {
+		h.Log.Error(err.Error())
+	}
+	h.Log.Info("RequestEmailChange finished successfully")
+	c.JSON(http.StatusOK, gin.H{"message": "Confirm the change with the link sent to the new email"})
+}
+
+// emailChangePage asks for a click before the link in the email does
+// anything, so mail scanners and link previews opening it change nothing.
+var emailChangePage = template.Must(template.New("email-change").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+</head>
+<body style="font-family: sans-serif; max-width: 32rem; margin: 4rem auto; padding: 0 1rem;">
+<h1>{{.Title}}</h1>
+<p>{{.Text}}</p>
+<form method="post" action="{{.Action}}">
+<input type="hidden" name="token" value="{{.Token}}">
+<button type="submit">{{.Button}}</button>
+</form>
+</body>
+</html>
+`))
+
+type emailChangeAction struct {
+	Title, Text, Button, Action, Token string
+}
+
+// showEmailChangePage answers the GET of an emailed link with a form
+// posting its token back.
+func (h *Handler) showEmailChangePage(c *gin.Context, page emailChangeAction) {
+	page.Token = c.Query("token")
+	if page.Token == "" {
+		h.Log.Error("token is required")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+		return
+	}
+	// The token is in the url: keep it out of caches and Referer headers,
+	// and the page out of frames that could trick a click
+	c.Header("Cache-Control", "no-store")
+	c.Header("Referrer-Policy", "no-referrer")
+	c.Header("X-Frame-Options", "DENY")
+	c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; form-action 'self'; frame-ancestors 'none'")
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusOK)
+	if err := emailChangePage.Execute(c.Writer, page); err != nil {
+		h.Log.Error(err.Error())
+	}
+}
+
+// ConfirmEmailChangePage godoc
+// @Summary Confirm Email Change Page
+// @Description The link sent to the new email opens this page. It changes nothing, its button posts the token to /auth/email-change/confirm
+// @Tags auth
+// @Produce html
+// @Param token query string true "Confirmation token"
+// @Success 200 {object} string "Confirmation page"
+// @Failure 400 {object} string "Invalid data"
+// @Router /auth/email-change/confirm [get]
+func (h *Handler) ConfirmEmailChangePage(c *gin.Context) {
+	h.Log.Info("ConfirmEmailChangePage is working")
+	h.showEmailChangePage(c, emailChangeAction{
+		Title:  "Confirm your new email",
+		Text:   "Confirm to use this address for your account from now on. Your other sessions will be logged out.",
+		Button: "Confirm email",
+		Action: "/auth/email-change/confirm",
+	})
+}
+
+// ConfirmEmailChange godoc
+// @Summary Confirm Email Change
+// @Description Confirm a new email with the token of the link sent to it. Other sessions of the user are logged out
+// @Tags auth
+// @Accept x-www-form-urlencoded
+// @Param token formData string true "Confirmation token"
+// @Success 200 {object} string "message"
+// @Failure 400 {object} string "Invalid data"
+// @Failure 401 {object} string "INVALID_LINK"
+// @Failure 409 {object} string "EMAIL_TAKEN"
+// @Failure 500 {object} string "error while reading from server"
+// @Router /auth/email-change/confirm [post]
+func (h *Handler) ConfirmEmailChange(c *gin.Context) {
+	h.Log.Info("ConfirmEmailChange is working")
+	token := c.PostForm("token")
+	if token == "" {
+		h.Log.Error("token is required")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+		return
+	}
+	_, err := h.User.ConfirmEmailChange(c, &pb.EmailChangeTokenReq{Token: token})
+	if emailChangeError(c, err) {
+		h.Log.Error(err.Error())
+		return
+	}
+	h.Log.Info("ConfirmEmailChange succeeded")
+	c.JSON(http.StatusOK, gin.H{"message": "Your email is changed"})
+}
+
+// RevertEmailChangePage godoc
+// @Summary Revert Email Change Page
+// @Description The link sent to the old email opens this page. It changes nothing, its button posts the token to /auth/email-change/revert
+// @Tags auth
+// @Produce html
+// @Param token query string true "Revert token"
+// @Success 200 {object} string "Confirmation page"
+// @Failure 400 {object} string "Invalid data"
+// @Router /auth/email-change/revert [get]
+func (h *Handler) RevertEmailChangePage(c *gin.Context) {
+	h.Log.Info("RevertEmailChangePage is working")
+	h.showEmailChangePage(c, emailChangeAction{
+		Title:  "Undo the email change",
+		Text:   "Keep this address for your account. Every session will be logged out and you will need a new password.",
+		Button: "Undo the change",
+		Action: "/auth/email-change/revert",
+	})
+}
+
+// RevertEmailChange godoc
+// @Summary Revert Email Change
+// @Description Undo an email change with the token of the link sent to the old email. It keeps or restores the old email, logs out every session and requires a new password through /auth/forgot-password
+// @Tags auth
+// @Accept x-www-form-urlencoded
+// @Param token formData string true "Revert token"
+// @Success 200 {object} string "message"
+// @Failure 400 {object} string "Invalid data"
+// @Failure 401 {object} string "INVALID_LINK"
+// @Failure 409 {object} string "EMAIL_TAKEN"
+// @Failure 500 {object} string "error while reading from server"
+// @Router /auth/email-change/revert [post]
+func (h *Handler) RevertEmailChange(c *gin.Context) {
+	h.Log.Info("RevertEmailChange is working")
+	token := c.PostForm("token")
+	if token == "" {
+		h.Log.Error("token is required")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+		return
+	}
+	_, err := h.User.RevertEmailChange(c, &pb.EmailChangeTokenReq{Token: token})
+	if emailChangeError(c, err) {
+		h.Log.Error(err.Error())
+		return
+	}
+	h.Log.Info("RevertEmailChange succeeded")
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Your email is kept and you were logged out everywhere. Set a new password with the forgot password flow",
+	})
+}
+
+// emailChangeError answers for a failed confirm or revert link.
+func emailChangeError(c *gin.Context, err error) bool {
+	switch status.Code(err) {
+	case codes.OK:
+		return false
+	case codes.Unauthenticated:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired link", "code": middleware.InvalidLink})
+	case codes.AlreadyExists:
+		c.JSON(http.StatusConflict, gin.H{"error": "Email is already used by another account", "code": middleware.EmailTaken})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error changing email"})
+	}
+	return true
+}
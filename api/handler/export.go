@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"net/http"
+	pb "wegugin/genproto/user"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RequestDataExport godoc
+// @Security ApiKeyAuth
+// @Summary Request Data Export
+// @Description Queue an archive with all personal data of the current user. The user gets an email with a download link once it is built
+// @Tags user
+// @Success 202 {object} user.DataExport
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 429 {object} string "Export in progress or requested too recently"
+// @Failure 500 {object} string "error while reading from server"
+// @Router /user/export [post]
+func (h *Handler) RequestDataExport(c *gin.Context) {
+	h.Log.Info("RequestDataExport is working")
+	token := c.GetHeader("Authorization")
+	id, _, err := h.Tokens.GetUserIdFromToken(token)
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	res, err := h.User.RequestDataExport(c, &pb.UserId{Id: id})
+	if status.Code(err) == codes.ResourceExhausted {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": status.Convert(err).Message()})
+		return
+	}
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error requesting data export"})
+		return
+	}
+	h.Log.Info("RequestDataExport finished successfully")
+	c.JSON(http.StatusAccepted, res)
+}
+
+// GetDataExport godoc
+// @Security ApiKeyAuth
+// @Summary Get Data Export
+// @Description Status of the latest data export of the current user. A ready export carries a short-lived download_url
+// @Tags user
+// @Success 200 {object} user.DataExport
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 404 {object} string "No export requested"
+// @Failure 500 {object} string "error while reading from server"
+// @Router /user/export [get]
+func (h *Handler) GetDataExport(c *gin.Context) {
+	h.Log.Info("GetDataExport is working")
+	token := c.GetHeader("Authorization")
+	id, _, err := h.Tokens.GetUserIdFromToken(token)
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	res, err := h.User.GetDataExport(c, &pb.UserId{Id: id})
+	if status.Code(err) == codes.NotFound {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusNotFound, gin.H{"error": "No export requested"})
+		return
+	}
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting data export"})
+		return
+	}
+	h.Log.Info("GetDataExport finished successfully")
+	c.JSON(http.StatusOK, res)
+}
@@ -2,10 +2,24 @@ package handler
 
 import (
 	"log/slog"
+	"wegugin/api/auth"
+	"wegugin/api/email"
+	"wegugin/api/middleware"
+	"wegugin/config"
 	"wegugin/genproto/user"
+	"wegugin/health"
+	minioStorage "wegugin/storage/minio"
+	"wegugin/storage/redis"
 )
 
 type Handler struct {
-	User user.UserClient
-	Log  *slog.Logger
+	User   user.UserClient
+	Tokens *auth.Tokens
+	Redis  *redis.Client
+	Minio  *minioStorage.Storage
+	Email  *email.Sender
+	Auth   *middleware.Auth
+	Config *config.Config
+	Log    *slog.Logger
+	Health *health.Checker
 }
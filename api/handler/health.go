@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Healthz godoc
+// @Summary Liveness
+// @Description Answers as long as the process serves HTTP, without checking any dependency
+// @Tags health
+// @Success 200 {object} string "ok"
+// @Router /healthz [get]
+func (h *Handler) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz godoc
+// @Summary Readiness
+// @Description Whether Postgres, the migrations, Redis, MinIO and optionally SMTP are ok or failing, as of the last check every HEALTH_INTERVAL
+// @Tags health
+// @Success 200 {object} health.Summary
+// @Failure 503 {object} health.Summary "A dependency is failing or the service is shutting down"
+// @Router /readyz [get]
+func (h *Handler) Readyz(c *gin.Context) {
+	summary := h.Health.Last()
+	if !summary.Ready {
+		c.JSON(http.StatusServiceUnavailable, summary)
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
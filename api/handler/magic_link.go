@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"wegugin/api/email"
+	"wegugin/api/middleware"
+	"wegugin/api/throttle"
+	pb "wegugin/genproto/user"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	magicLinkCookie = "magic_link_nonce"
+	magicLinkPath   = "/auth/magic-link"
+)
+
+// setMagicLinkCookie sets the device nonce cookie, maxAge -1 removes it.
+func (h *Handler) setMagicLinkCookie(c *gin.Context, nonce string, maxAge int) {
+	secure := strings.HasPrefix(h.Config.Server.PUBLIC_URL, "https://")
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(magicLinkCookie, nonce, maxAge, magicLinkPath, "", secure, true)
+}
+
+// RequestMagicLink godoc
+// @Summary Request Login Link
+// @Description Emails a single use login link. It only works in the browser or app that requested it, which gets a nonce cookie. The answer is the same whether the email has an account or not
+// @Tags auth
+// @Param email body user.GetUSerByEmailReq true "Email"
+// @Success 200 {object} string "message"
+// @Failure 400 {object} string "Invalid data"
+// @Failure 429 {object} string "TOO_MANY_ATTEMPTS or ACCOUNT_LOCKED, see Retry-After"
+// @Failure 500 {object} string "error while reading from server"
+// @Router /auth/magic-link [post]
+func (h *Handler) RequestMagicLink(c *gin.Context) {
+	h.Log.Info("RequestMagicLink is working")
+	var req pb.GetUSerByEmailReq
+	if err := c.BindJSON(&req); err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !email.IsValidEmail(req.Email) {
+		h.Log.Error("Invalid email")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email"})
+		return
+	}
+	// Every link sent counts as an attempt, so nobody can flood a mailbox
+	guard := throttle.MagicLink(h.Config.Throttle, h.Redis)
+	if !h.allowAttempt(c, guard, req.Email) {
+		return
+	}
+	if _, err := guard.Fail(c, req.Email, c.ClientIP()); err != nil {
+		h.Log.Error(err.Error())
+	}
+
+	// Asking again from the same device keeps the earlier links working
+	nonce, err := c.Cookie(magicLinkCookie)
+	if err != nil || nonce == "" {
+		nonce = uuid.NewString()
+	}
+	_, err = h.User.RequestMagicLink(c, &pb.MagicLinkReq{Email: req.Email, Nonce: nonce})
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error sending login link"})
+		return
+	}
+	h.setMagicLinkCookie(c, nonce, int(h.Config.Account.MAGIC_LINK_TTL.Seconds()))
+	h.Log.Info("RequestMagicLink succeeded")
+	c.JSON(http.StatusOK, gin.H{"message": "If the email has an account, a login link was sent to it"})
+}
+
+// ConsumeMagicLink godoc
+// @Summary Log In With Link
+// @Description Log in with the emailed link, in the browser or app that requested it. It returns a token like /auth/login
+// @Tags auth
+// @Param token query string true "Login link token"
+// @Success 200 {object} string "Token"
+// @Failure 400 {object} string "Invalid data"
+// @Failure 401 {object} string "INVALID_LINK"
+// @Failure 403 {object} string "WRONG_DEVICE or ACCOUNT_SUSPENDED"
+// @Failure 500 {object} string "error while reading from server"
+// @Router /auth/magic-link/consume [get]
+func (h *Handler) ConsumeMagicLink(c *gin.Context) {
+	h.Log.Info("ConsumeMagicLink is working")
+	token := c.Query("token")
+	if token == "" {
+		h.Log.Error("token is required")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+		return
+	}
+	nonce, _ := c.Cookie(magicLinkCookie)
+	res, err := h.User.ConsumeMagicLink(c, &pb.ConsumeMagicLinkReq{Token: token, Nonce: nonce})
+	switch status.Code(err) {
+	case codes.OK:
+	case codes.Unauthenticated:
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid, expired or used login link", "code": middleware.InvalidLink})
+		return
+	case codes.FailedPrecondition:
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Open the login link on the device you requested it from",
+			"code":  middleware.WrongDevice,
+		})
+		return
+	case codes.PermissionDenied:
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is suspended", "code": middleware.AccountSuspended})
+		return
+	default:
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error logging in"})
+		return
+	}
+	h.setMagicLinkCookie(c, "", -1)
+	h.Log.Info("ConsumeMagicLink succeeded")
+	c.JSON(http.StatusOK, gin.H{
+		"Token": res.Token,
+	})
+}
@@ -0,0 +1,241 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"wegugin/api/middleware"
+	"wegugin/api/oidc"
+	pb "wegugin/genproto/user"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	oidcCookie = "oidc_nonce"
+	oidcPath   = "/auth/oidc"
+)
+
+// oidcNonce returns the nonce cookie binding sign ins to this browser. A
+// browser without one gets it here; one that has it keeps it, so sign ins
+// started side by side keep working.
+func (h *Handler) oidcNonce(c *gin.Context, provider string) string {
+	nonce, err := c.Cookie(oidcCookie)
+	if err != nil || nonce == "" {
+		nonce = uuid.NewString()
+	}
+	h.setOIDCCookie(c, provider, nonce, int(h.Config.OIDC.STATE_TTL.Seconds()))
+	return nonce
+}
+
+// setOIDCCookie sets the nonce cookie, maxAge -1 removes it. It is SameSite
+// Lax, but None for the providers posting the callback from their site,
+// which browsers only allow on https.
+func (h *Handler) setOIDCCookie(c *gin.Context, provider, nonce string, maxAge int) {
+	secure := strings.HasPrefix(h.Config.Server.PUBLIC_URL, "https://")
+	sameSite := http.SameSiteLaxMode
+	if oidc.PostsCallback(provider) {
+		sameSite, secure = http.SameSiteNoneMode, true
+	}
+	c.SetSameSite(sameSite)
+	c.SetCookie(oidcCookie, nonce, maxAge, oidcPath, "", secure, true)
+}
+
+// StartOIDC godoc
+// @Summary Social Login
+// @Description Redirects to the sign in page of google, apple or kakao, which comes back to /auth/oidc/{provider}/callback. The browser gets a nonce cookie, the callback only works in it
+// @Tags auth
+// @Param provider path string true "google, apple or kakao"
+// @Success 302 {object} string "Redirect to the provider"
+// @Failure 400 {object} string "Unknown provider"
+// @Failure 500 {object} string "error while reading from server"
+// @Router /auth/oidc/{provider} [get]
+func (h *Handler) StartOIDC(c *gin.Context) {
+	h.Log.Info("StartOIDC is working")
+	provider := c.Param("provider")
+	res, err := h.User.StartOIDC(c, &pb.OIDCStartReq{Provider: provider, Nonce: h.oidcNonce(c, provider)})
+	if err != nil {
+		h.Log.Error(err.Error())
+		oidcError(c, err)
+		return
+	}
+	h.Log.Info("StartOIDC finished successfully")
+	c.Redirect(http.StatusFound, res.Url)
+}
+
+// OIDCCallback godoc
+// @Summary Social Login Callback
+// @Description The provider sends the user back here. It logs the user in like /auth/login, or links the provider account when the sign in was started from /user/identities/{provider}. A provider account that is not linked does not log in, even with the email of an account
+// @Tags auth
+// @Param provider path string true "google, apple or kakao"
+// @Param code query string true "Authorization code"
+// @Param state query string true "State"
+// @Success 200 {object} string "Token"
+// @Failure 400 {object} string "Unknown provider, invalid or expired sign in"
+// @Failure 401 {object} string "Sign in with the provider failed"
+// @Failure 403 {object} string "WRONG_DEVICE or ACCOUNT_SUSPENDED"
+// @Failure 404 {object} string "ACCOUNT_NOT_FOUND"
+// @Failure 409 {object} string "IDENTITY_TAKEN, or LINK_REQUIRED when an account has the email"
+// @Failure 500 {object} string "error while reading from server"
+// @Router /auth/oidc/{provider}/callback [get]
+func (h *Handler) OIDCCallback(c *gin.Context) {
+	h.Log.Info("OIDCCallback is working")
+	// Apple posts the callback as a form, the others use the query
+	if reason := c.Request.FormValue("error"); reason != "" {
+		h.Log.Error("provider returned " + reason)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Sign in was cancelled or refused: " + reason})
+		return
+	}
+	provider := c.Param("provider")
+	nonce, _ := c.Cookie(oidcCookie)
+	res, err := h.User.FinishOIDC(c, &pb.OIDCFinishReq{
+		Provider: provider,
+		Code:     c.Request.FormValue("code"),
+		State:    c.Request.FormValue("state"),
+		Nonce:    nonce,
+	})
+	if err != nil {
+		h.Log.Error(err.Error())
+		oidcError(c, err)
+		return
+	}
+	h.setOIDCCookie(c, provider, "", -1)
+	h.Log.Info("OIDCCallback finished successfully")
+	if res.Linked {
+		c.JSON(http.StatusOK, gin.H{"message": "Account linked"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"Token": res.Token,
+	})
+}
+
+// LinkIdentity godoc
+// @Security ApiKeyAuth
+// @Summary Link Social Account
+// @Description Returns the sign in url of the provider. Signing in there, in the browser that made this request, links the provider account to the current user
+// @Tags user
+// @Param provider path string true "google, apple or kakao"
+// @Success 200 {object} user.OIDCStartRes
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 400 {object} string "Unknown provider"
+// @Failure 500 {object} string "error while reading from server"
+// @Router /user/identities/{provider} [post]
+func (h *Handler) LinkIdentity(c *gin.Context) {
+	h.Log.Info("LinkIdentity is working")
+	id, _, err := h.Tokens.GetUserIdFromToken(c.GetHeader("Authorization"))
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	provider := c.Param("provider")
+	res, err := h.User.StartOIDC(c, &pb.OIDCStartReq{Provider: provider, UserId: id, Nonce: h.oidcNonce(c, provider)})
+	if err != nil {
+		h.Log.Error(err.Error())
+		oidcError(c, err)
+		return
+	}
+	h.Log.Info("LinkIdentity finished successfully")
+	c.JSON(http.StatusOK, res)
+}
+
+// ListIdentities godoc
+// @Security ApiKeyAuth
+// @Summary List Social Accounts
+// @Description Provider accounts linked to the current user
+// @Tags user
+// @Success 200 {object} user.IdentityList
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 500 {object} string "error while reading from server"
+// @Router /user/identities [get]
+func (h *Handler) ListIdentities(c *gin.Context) {
+	h.Log.Info("ListIdentities is working")
+	id, _, err := h.Tokens.GetUserIdFromToken(c.GetHeader("Authorization"))
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	res, err := h.User.ListIdentities(c, &pb.UserId{Id: id})
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error listing linked accounts"})
+		return
+	}
+	h.Log.Info("ListIdentities finished successfully")
+	c.JSON(http.StatusOK, res)
+}
+
+// UnlinkIdentity godoc
+// @Security ApiKeyAuth
+// @Summary Unlink Social Account
+// @Description Remove a linked provider account. Logging in with the password keeps working
+// @Tags user
+// @Param provider path string true "google, apple or kakao"
+// @Success 200 {object} string "Account unlinked"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 404 {object} string "Provider is not linked"
+// @Failure 500 {object} string "error while reading from server"
+// @Router /user/identities/{provider} [delete]
+func (h *Handler) UnlinkIdentity(c *gin.Context) {
+	h.Log.Info("UnlinkIdentity is working")
+	id, _, err := h.Tokens.GetUserIdFromToken(c.GetHeader("Authorization"))
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	_, err = h.User.UnlinkIdentity(c, &pb.IdentityReq{UserId: id, Provider: c.Param("provider")})
+	if status.Code(err) == codes.NotFound {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusNotFound, gin.H{"error": "Provider is not linked"})
+		return
+	}
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error unlinking account"})
+		return
+	}
+	h.Log.Info("UnlinkIdentity finished successfully")
+	c.JSON(http.StatusOK, gin.H{"message": "Account unlinked"})
+}
+
+func oidcError(c *gin.Context, err error) {
+	message := status.Convert(err).Message()
+	switch status.Code(err) {
+	case codes.InvalidArgument:
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+	case codes.Unauthenticated:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": message})
+	case codes.FailedPrecondition:
+		if hasReason(err, middleware.LinkRequired) {
+			c.JSON(http.StatusConflict, gin.H{"error": message, "code": middleware.LinkRequired})
+			return
+		}
+		c.JSON(http.StatusForbidden, gin.H{"error": "Finish the sign in in the browser you started it in", "code": middleware.WrongDevice})
+	case codes.PermissionDenied:
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is suspended", "code": middleware.AccountSuspended})
+	case codes.NotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": message, "code": middleware.AccountNotFound})
+	case codes.AlreadyExists:
+		c.JSON(http.StatusConflict, gin.H{"error": message, "code": middleware.IdentityTaken})
+	case codes.Unavailable:
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "The provider can not be reached, please try again"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error signing in"})
+	}
+}
+
+// hasReason reports whether the status of err carries an ErrorInfo with reason.
+func hasReason(err error, reason string) bool {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.Reason == reason {
+			return true
+		}
+	}
+	return false
+}
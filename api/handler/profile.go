@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"net/http"
+	pb "wegugin/genproto/user"
+	"wegugin/model"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GetUserById godoc
+// @Summary Get Public Profile
+// @Description Public seller card: name, photo, member since, listing count and rating. The phone number and email are only included when the user's privacy settings show them to the caller, who may send a token to be seen as logged in
+// @Tags auth
+// @Param id path string true "USER ID"
+// @Success 200 {object} user.PublicProfile
+// @Failure 404 {object} string "User not found"
+// @Failure 500 {object} string "error while reading from server"
+// @Router /auth/user/{id} [get]
+func (h Handler) GetUserById(c *gin.Context) {
+	h.Log.Info("GetUserById is working")
+	res, err := h.User.GetPublicProfile(c, &pb.PublicProfileReq{
+		Id:       c.Param("id"),
+		ViewerId: h.Auth.ViewerId(c),
+	})
+	if status.Code(err) == codes.NotFound {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting user"})
+		return
+	}
+	h.Log.Info("GetUserById succeeded")
+	c.JSON(http.StatusOK, res)
+}
+
+// GetPrivacySettings godoc
+// @Security ApiKeyAuth
+// @Summary Get Privacy Settings
+// @Description Who sees the phone number and email on the public profile: nobody, logged_in or everyone
+// @Tags user
+// @Success 200 {object} model.PrivacySettings
+// @Failure 401 {object} string "unauthorized"
+// @Failure 500 {object} string "error while reading from server"
+// @Router /user/privacy [get]
+func (h Handler) GetPrivacySettings(c *gin.Context) {
+	h.Log.Info("GetPrivacySettings is working")
+	id, _, err := h.Tokens.GetUserIdFromToken(c.GetHeader("Authorization"))
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	res, err := h.User.GetPrivacySettings(c, &pb.UserId{Id: id})
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting privacy settings"})
+		return
+	}
+	h.Log.Info("GetPrivacySettings succeeded")
+	c.JSON(http.StatusOK, model.PrivacySettings{PhoneNumber: res.PhoneNumber, Email: res.Email})
+}
+
+// UpdatePrivacySettings godoc
+// @Security ApiKeyAuth
+// @Summary Update Privacy Settings
+// @Description Choose who sees the phone number and email on the public profile: nobody, logged_in or everyone
+// @Tags user
+// @Param settings body model.PrivacySettings true "Visibility of each field"
+// @Success 200 {object} string "message"
+// @Failure 400 {object} string "Invalid data"
+// @Failure 401 {object} string "unauthorized"
+// @Failure 500 {object} string "error while reading from server"
+// @Router /user/privacy [put]
+func (h Handler) UpdatePrivacySettings(c *gin.Context) {
+	h.Log.Info("UpdatePrivacySettings is working")
+	id, _, err := h.Tokens.GetUserIdFromToken(c.GetHeader("Authorization"))
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var req model.PrivacySettings
+	if err := c.BindJSON(&req); err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	_, err = h.User.UpdatePrivacySettings(c, &pb.PrivacySettings{
+		UserId:      id,
+		PhoneNumber: req.PhoneNumber,
+		Email:       req.Email,
+	})
+	if status.Code(err) == codes.InvalidArgument {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": status.Convert(err).Message()})
+		return
+	}
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating privacy settings"})
+		return
+	}
+	h.Log.Info("UpdatePrivacySettings succeeded")
+	c.JSON(http.StatusOK, gin.H{"message": "Privacy settings updated"})
+}
@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"net/http"
+	pb "wegugin/genproto/user"
+	"wegugin/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateSavedSearch godoc
+// @Security ApiKeyAuth
+// @Summary Create Saved Search
+// @Description Save a car search filter and get notified about new matching cars. Frequency is instant or daily
+// @Tags saved-search
+// @Param search body model.SavedSearch true "Saved search"
+// @Success 200 {object} user.SavedSearch
+// @Failure 400 {object} string "Invalid data"
+// @Failure 500 {object} string "error while reading from server"
+// @Router /user/saved-searches [post]
+func (h *Handler) CreateSavedSearch(c *gin.Context) {
+	h.Log.Info("CreateSavedSearch is working")
+	token := c.GetHeader("Authorization")
+	id, _, err := h.Tokens.GetUserIdFromToken(token)
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var req model.SavedSearch
+	if err := c.BindJSON(&req); err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	res, err := h.User.CreateSavedSearch(c, &pb.CreateSavedSearchReq{
+		UserId:    id,
+		Name:      req.Name,
+		Filter:    req.Filter,
+		Frequency: req.Frequency,
+	})
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating saved search"})
+		return
+	}
+	h.Log.Info("CreateSavedSearch finished successfully")
+	c.JSON(http.StatusOK, res)
+}
+
+// ListSavedSearches godoc
+// @Security ApiKeyAuth
+// @Summary List Saved Searches
+// @Description List saved searches of the current user
+// @Tags saved-search
+// @Success 200 {object} user.SavedSearchList
+// @Failure 400 {object} string "Invalid data"
+// @Failure 500 {object} string "error while reading from server"
+// @Router /user/saved-searches [get]
+func (h *Handler) ListSavedSearches(c *gin.Context) {
+	h.Log.Info("ListSavedSearches is working")
+	token := c.GetHeader("Authorization")
+	id, _, err := h.Tokens.GetUserIdFromToken(token)
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	res, err := h.User.ListSavedSearches(c, &pb.UserId{Id: id})
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error listing saved searches"})
+		return
+	}
+	h.Log.Info("ListSavedSearches finished successfully")
+	c.JSON(http.StatusOK, res)
+}
+
+// GetSavedSearch godoc
+// @Security ApiKeyAuth
+// @Summary Get Saved Search
+// @Description Get one saved search of the current user
+// @Tags saved-search
+// @Param id path string true "SAVED SEARCH ID"
+// @Success 200 {object} user.SavedSearch
+// @Failure 400 {object} string "Invalid data"
+// @Failure 404 {object} string "Saved search not found"
+// @Router /user/saved-searches/{id} [get]
+func (h *Handler) GetSavedSearch(c *gin.Context) {
+	h.Log.Info("GetSavedSearch is working")
+	token := c.GetHeader("Authorization")
+	id, _, err := h.Tokens.GetUserIdFromToken(token)
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	res, err := h.User.GetSavedSearch(c, &pb.SavedSearchId{Id: c.Param("id"), UserId: id})
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusNotFound, gin.H{"error": "Saved search not found"})
+		return
+	}
+	h.Log.Info("GetSavedSearch finished successfully")
+	c.JSON(http.StatusOK, res)
+}
+
+// UpdateSavedSearch godoc
+// @Security ApiKeyAuth
+// @Summary Update Saved Search
+// @Description Update name, frequency or active flag of a saved search. A filter in the body replaces the old filter
+// @Tags saved-search
+// @Param id path string true "SAVED SEARCH ID"
+// @Param search body model.SavedSearch true "Saved search"
+// @Success 200 {object} string "Saved search updated successfully"
+// @Failure 400 {object} string "Invalid data"
+// @Failure 500 {object} string "error while reading from server"
+// @Router /user/saved-searches/{id} [put]
+func (h *Handler) UpdateSavedSearch(c *gin.Context) {
+	h.Log.Info("UpdateSavedSearch is working")
+	token := c.GetHeader("Authorization")
+	id, _, err := h.Tokens.GetUserIdFromToken(token)
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	var req model.SavedSearch
+	if err := c.BindJSON(&req); err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	_, err = h.User.UpdateSavedSearch(c, &pb.UpdateSavedSearchReq{
+		Id:        c.Param("id"),
+		UserId:    id,
+		Name:      req.Name,
+		Filter:    req.Filter,
+		Frequency: req.Frequency,
+		Active:    req.Active,
+	})
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating saved search"})
+		return
+	}
+	h.Log.Info("UpdateSavedSearch finished successfully")
+	c.JSON(http.StatusOK, gin.H{"message": "Saved search updated successfully"})
+}
+
+// DeleteSavedSearch godoc
+// @Security ApiKeyAuth
+// @Summary Delete Saved Search
+// @Description Delete a saved search of the current user
+// @Tags saved-search
+// @Param id path string true "SAVED SEARCH ID"
+// @Success 200 {object} string "Saved search deleted successfully"
+// @Failure 400 {object} string "Invalid data"
+// @Failure 500 {object} string "error while reading from server"
+// @Router /user/saved-searches/{id} [delete]
+func (h *Handler) DeleteSavedSearch(c *gin.Context) {
+	h.Log.Info("DeleteSavedSearch is working")
+	token := c.GetHeader("Authorization")
+	id, _, err := h.Tokens.GetUserIdFromToken(token)
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	_, err = h.User.DeleteSavedSearch(c, &pb.SavedSearchId{Id: c.Param("id"), UserId: id})
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting saved search"})
+		return
+	}
+	h.Log.Info("DeleteSavedSearch finished successfully")
+	c.JSON(http.StatusOK, gin.H{"message": "Saved search deleted successfully"})
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"net/http"
+	pb "wegugin/genproto/user"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ListSessions godoc
+// @Security ApiKeyAuth
+// @Summary List Sessions
+// @Description Devices the current user is logged in on. The session of the calling token has current set. Apps name the device with the X-Device-Name and X-Device-Platform headers when logging in
+// @Tags user
+// @Success 200 {object} user.SessionList
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 500 {object} string "error while reading from server"
+// @Router /user/sessions [get]
+func (h *Handler) ListSessions(c *gin.Context) {
+	h.Log.Info("ListSessions is working")
+	token := c.GetHeader("Authorization")
+	id, _, err := h.Tokens.GetUserIdFromToken(token)
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	res, err := h.User.ListSessions(c, &pb.SessionReq{UserId: id, SessionId: h.Tokens.GetSessionId(token)})
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error listing sessions"})
+		return
+	}
+	h.Log.Info("ListSessions finished successfully")
+	c.JSON(http.StatusOK, res)
+}
+
+// RevokeSession godoc
+// @Security ApiKeyAuth
+// @Summary Revoke Session
+// @Description Log out one of the current user's devices. Revoking the current session logs out the caller
+// @Tags user
+// @Param id path string true "SESSION ID"
+// @Success 200 {object} string "Session revoked"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 404 {object} string "Session not found"
+// @Failure 500 {object} string "error while reading from server"
+// @Router /user/sessions/{id} [delete]
+func (h *Handler) RevokeSession(c *gin.Context) {
+	h.Log.Info("RevokeSession is working")
+	token := c.GetHeader("Authorization")
+	id, _, err := h.Tokens.GetUserIdFromToken(token)
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	sessionId := c.Param("id")
+	if _, err := uuid.Parse(sessionId); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+	_, err = h.User.RevokeSession(c, &pb.SessionReq{UserId: id, SessionId: sessionId})
+	if status.Code(err) == codes.NotFound {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error revoking session"})
+		return
+	}
+	h.Log.Info("RevokeSession finished successfully")
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
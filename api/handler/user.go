@@ -3,19 +3,23 @@ package handler
 import (
 	"context"
 	"fmt"
+	"math"
 	"net/http"
 	"path/filepath"
-	"wegugin/api/auth"
+	"strconv"
 	"wegugin/api/email"
-	"wegugin/config"
+	"wegugin/api/middleware"
+	"wegugin/api/password"
+	"wegugin/api/throttle"
 	pb "wegugin/genproto/user"
 	"wegugin/model"
-	"wegugin/storage/redis"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // Register godoc
@@ -26,6 +30,7 @@ import (
 // @Success 200 {object} string "Token"
 // @Failure 400 {object} string "Invalid data"
 // @Failure 500 {object} string "Server error"
+// @Failure 422 {object} string "WEAK_PASSWORD with the broken rules in violations"
 // @Router /auth/register [post]
 func (h Handler) Register(c *gin.Context) {
 	h.Log.Info("Register is starting")
@@ -41,6 +46,10 @@ func (h Handler) Register(c *gin.Context) {
 		return
 	}
 	res, err := h.User.Register(c, &req)
+	if weakPassword(c, err) {
+		h.Log.Error(err.Error())
+		return
+	}
 	if err != nil {
 		h.Log.Error(err.Error())
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -59,6 +68,9 @@ func (h Handler) Register(c *gin.Context) {
 // @Param userinfo body user.LoginReq true "username and password"
 // @Success 200 {object} string "Token"
 // @Failure 400 {object} string "Invalid date"
+// @Failure 401 {object} string "INVALID_CREDENTIALS"
+// @Failure 403 {object} string "ACCOUNT_SUSPENDED, or PASSWORD_RESET_REQUIRED: reset it with /auth/forgot-password"
+// @Failure 429 {object} string "TOO_MANY_ATTEMPTS or ACCOUNT_LOCKED, see Retry-After"
 // @Failure 500 {object} string "error while reading from server"
 // @Router /auth/login [post]
 func (h Handler) Login(c *gin.Context) {
@@ -72,6 +84,32 @@ func (h Handler) Login(c *gin.Context) {
 	}
 
 	res, err := h.User.Login(c, &req)
+	if throttled, ok := throttle.FromStatus(err); ok {
+		h.Log.Error(err.Error())
+		tooManyAttempts(c, throttled)
+		return
+	}
+	if status.Code(err) == codes.Unauthenticated {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Invalid email, phone number or password",
+			"code":  middleware.InvalidCredentials,
+		})
+		return
+	}
+	if status.Code(err) == codes.PermissionDenied {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is suspended", "code": middleware.AccountSuspended})
+		return
+	}
+	if hasReason(err, middleware.PasswordResetRequired) {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "The password must be reset before logging in",
+			"code":  middleware.PasswordResetRequired,
+		})
+		return
+	}
 	if err != nil {
 		h.Log.Error(err.Error())
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -84,26 +122,59 @@ func (h Handler) Login(c *gin.Context) {
 	})
 }
 
-// GetUserById godoc
-// @Summary Get User By Id
-// @Description Get User By Id
+// RestoreAccount godoc
+// @Summary Restore Account
+// @Description Restore a deleted account with the link emailed on deletion. It logs the user in
 // @Tags auth
-// @Param id path string true "USER ID"
-// @Success 200 {object} user.GetUserResponse
-// @Failure 400 {object} string "Invalid date"
+// @Param token query string true "Restore token"
+// @Success 200 {object} string "Token"
+// @Failure 400 {object} string "Invalid data"
 // @Failure 500 {object} string "error while reading from server"
-// @Router /auth/user/{id} [get]
-func (h Handler) GetUserById(c *gin.Context) {
-	h.Log.Info("GetUserById is working")
-	id := c.Param("id")
-	res, err := h.User.GetUserById(c, &pb.UserId{Id: id})
+// @Router /auth/restore [get]
+func (h Handler) RestoreAccount(c *gin.Context) {
+	h.Log.Info("RestoreAccount is working")
+	token := c.Query("token")
+	if token == "" {
+		h.Log.Error("token is required")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+		return
+	}
+	res, err := h.User.RestoreAccount(c, &pb.RestoreAccountReq{Token: token})
 	if err != nil {
 		h.Log.Error(err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Account can not be restored"})
+		return
+	}
+	h.Log.Info("RestoreAccount succeeded")
+	c.JSON(http.StatusOK, gin.H{
+		"Token": res.Token,
+	})
+}
+
+// UnlockLogin godoc
+// @Summary Unlock Login
+// @Description Lift a login lockout with the link emailed when it started
+// @Tags auth
+// @Param token query string true "Unlock token"
+// @Success 200 {object} string "message"
+// @Failure 400 {object} string "Invalid data"
+// @Router /auth/unlock [get]
+func (h Handler) UnlockLogin(c *gin.Context) {
+	h.Log.Info("UnlockLogin is working")
+	token := c.Query("token")
+	if token == "" {
+		h.Log.Error("token is required")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token is required"})
+		return
+	}
+	_, err := h.User.UnlockLogin(c, &pb.UnlockLoginReq{Token: token})
+	if err != nil {
+		h.Log.Error(err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired unlock link"})
 		return
 	}
-	h.Log.Info("GetUserById succeeded")
-	c.JSON(http.StatusOK, res)
+	h.Log.Info("UnlockLogin succeeded")
+	c.JSON(http.StatusOK, gin.H{"message": "Your account is unlocked, you can log in again"})
 }
 
 // ForgotPassword godoc
@@ -113,6 +184,7 @@ func (h Handler) GetUserById(c *gin.Context) {
 // @Param token body user.GetUSerByEmailReq true "enough"
 // @Success 200 {object} string "message"
 // @Failure 400 {object} string "Invalid date"
+// @Failure 429 {object} string "TOO_MANY_ATTEMPTS or ACCOUNT_LOCKED, see Retry-After"
 // @Failure 500 {object} string "error while reading from server"
 // @Router /auth/forgot-password [post]
 func (h Handler) ForgotPassword(c *gin.Context) {
@@ -123,13 +195,21 @@ func (h Handler) ForgotPassword(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	res, err := email.EmailCode(req.Email)
+	// Every code sent counts as an attempt, so nobody can flood a mailbox
+	guard := throttle.PasswordResetSend(h.Config.Throttle, h.Redis)
+	if !h.allowAttempt(c, guard, req.Email) {
+		return
+	}
+	if _, err := guard.Fail(c, req.Email, c.ClientIP()); err != nil {
+		h.Log.Error(err.Error())
+	}
+	res, err := h.Email.EmailCode(req.Email)
 	if err != nil {
 		h.Log.Error(err.Error())
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error sending email"})
 		return
 	}
-	err = redis.StoreCodes(c, res, req.Email)
+	err = h.Redis.StoreCodes(c, res, req.Email)
 	if err != nil {
 		h.Log.Error(err.Error())
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error storing codes in Redis"})
@@ -147,7 +227,10 @@ func (h Handler) ForgotPassword(c *gin.Context) {
 // @Param token body user.ResetPassReq true "enough"
 // @Success 200 {object} string "message"
 // @Failure 400 {object} string "Invalid date"
+// @Failure 401 {object} string "INVALID_CODE"
+// @Failure 429 {object} string "TOO_MANY_ATTEMPTS or ACCOUNT_LOCKED, see Retry-After"
 // @Failure 500 {object} string "error while reading from server"
+// @Failure 422 {object} string "WEAK_PASSWORD with the broken rules in violations"
 // @Router /auth/reset-password [post]
 func (h *Handler) ResetPassword(c *gin.Context) {
 	h.Log.Info("ResetPassword is working")
@@ -157,15 +240,24 @@ func (h *Handler) ResetPassword(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	code, err := redis.GetCodes(c, req.Email)
-	if err != nil {
-		h.Log.Error(err.Error())
-		c.JSON(http.StatusNotFound, gin.H{"error": err})
+	guard := throttle.PasswordReset(h.Config.Throttle, h.Redis)
+	if !h.allowAttempt(c, guard, req.Email) {
 		return
 	}
-	if code != req.Code {
+	code, err := h.Redis.GetCodes(c, req.Email)
+	if err != nil || code != req.Code {
 		h.Log.Error("Invalid code")
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code"})
+		locked, err := guard.Fail(c, req.Email, c.ClientIP())
+		if err != nil {
+			h.Log.Error(err.Error())
+		}
+		if locked {
+			// A fresh code has to be requested after the lock
+			if err := h.Redis.DeleteCodes(c, req.Email); err != nil {
+				h.Log.Error(err.Error())
+			}
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired code", "code": middleware.InvalidCode})
 		return
 	}
 	res, err := h.User.GetUSerByEmail(c, &pb.GetUSerByEmailReq{Email: req.Email})
@@ -176,11 +268,21 @@ func (h *Handler) ResetPassword(c *gin.Context) {
 	}
 
 	_, err = h.User.UpdatePassword(c, &pb.UpdatePasswordReq{Id: res.Id, Password: req.Password})
+	if weakPassword(c, err) {
+		h.Log.Error(err.Error())
+		return
+	}
 	if err != nil {
 		h.Log.Error(err.Error())
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating password"})
 		return
 	}
+	if err := guard.Reset(c, req.Email); err != nil {
+		h.Log.Error(err.Error())
+	}
+	if err := h.Redis.DeleteCodes(c, req.Email); err != nil {
+		h.Log.Error(err.Error())
+	}
 	c.JSON(200, gin.H{"message": "Password reset successfully"})
 }
 
@@ -196,7 +298,7 @@ func (h *Handler) ResetPassword(c *gin.Context) {
 func (h Handler) GetUserProfile(c *gin.Context) {
 	h.Log.Info("GetUserProfile is working")
 	token := c.GetHeader("Authorization")
-	id, _, err := auth.GetUserIdFromToken(token)
+	id, _, err := h.Tokens.GetUserIdFromToken(token)
 	if err != nil {
 		h.Log.Error(err.Error())
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
@@ -225,7 +327,7 @@ func (h Handler) GetUserProfile(c *gin.Context) {
 func (h Handler) UpdateUserProfile(c *gin.Context) {
 	h.Log.Info("UpdateUserProfile is working")
 	token := c.GetHeader("Authorization")
-	id, _, err := auth.GetUserIdFromToken(token)
+	id, _, err := h.Tokens.GetUserIdFromToken(token)
 	if err != nil {
 		h.Log.Error(err.Error())
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
@@ -264,11 +366,12 @@ func (h Handler) UpdateUserProfile(c *gin.Context) {
 // @Success 200 {object} string "Password changed successfully"
 // @Failure 400 {object} string "Invalid date"
 // @Failure 500 {object} string "error while reading from server"
+// @Failure 422 {object} string "WEAK_PASSWORD with the broken rules in violations"
 // @Router /user/change-password [post]
 func (h Handler) ChangePassword(c *gin.Context) {
 	h.Log.Info("ChangePassword is working")
 	token := c.GetHeader("Authorization")
-	id, _, err := auth.GetUserIdFromToken(token)
+	id, _, err := h.Tokens.GetUserIdFromToken(token)
 	if err != nil {
 		h.Log.Error(err.Error())
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
@@ -285,6 +388,10 @@ func (h Handler) ChangePassword(c *gin.Context) {
 		Newpassword: user.NewPassword,
 		Oldpassword: user.OldPassword,
 	})
+	if weakPassword(c, err) {
+		h.Log.Error(err.Error())
+		return
+	}
 	if err != nil {
 		h.Log.Error(err.Error())
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error resetting password"})
@@ -314,7 +421,7 @@ func (h *Handler) UploadMediaUser(c *gin.Context) {
 	defer file.Close()
 
 	// minio start
-	cfg := config.Load()
+	cfg := h.Config
 
 	fileExt := filepath.Ext(header.Filename)
 
@@ -364,7 +471,7 @@ func (h *Handler) UploadMediaUser(c *gin.Context) {
 
 	// minio end
 	token := c.GetHeader("Authorization")
-	id, _, err := auth.GetUserIdFromToken(token)
+	id, _, err := h.Tokens.GetUserIdFromToken(token)
 	if err != nil {
 		h.Log.Error(err.Error())
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
@@ -415,7 +522,7 @@ func (h *Handler) DeleteMediaUser(c *gin.Context) {
 
 	// Tokenni olish va foydalanuvchi ID sini olish
 	token := c.GetHeader("Authorization")
-	id, _, err := auth.GetUserIdFromToken(token)
+	id, _, err := h.Tokens.GetUserIdFromToken(token)
 	if err != nil {
 		h.Log.Error(err.Error())
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
@@ -447,24 +554,11 @@ func (h *Handler) deletePhoto(id string, ctx context.Context) error {
 		return nil
 	}
 
-	// MinIO clientni sozlash
-	cfg := config.Load()
-	minioClient, err := minio.New(cfg.Minio.MINIO_ENDPOINT, &minio.Options{
-		Creds:  credentials.NewStaticV4(cfg.Minio.MINIO_ACCESS_KEY_ID, cfg.Minio.MINIO_SECRET_ACCESS_KEY, ""),
-		Secure: false,
-	})
-	if err != nil {
-		return fmt.Errorf("error initializing MinIO client: %v", err)
-	}
-
-	// Rasm nomini olish
-	fileName := filepath.Base(user.Photo)
-
 	// MinIO'dan faylni o‘chirish
-	err = minioClient.RemoveObject(context.Background(), "photos", fileName, minio.RemoveObjectOptions{})
+	err = h.Minio.RemovePhoto(context.Background(), user.Photo)
 	if err != nil {
 		h.Log.Error(err.Error())
-		return fmt.Errorf("error deleting photo from MinIO: %v", err)
+		return err
 	}
 
 	// Foydalanuvchining photo maydonini bo‘sh qilish
@@ -490,7 +584,7 @@ func (h *Handler) DeleteUserProfile(c *gin.Context) {
 	h.Log.Info("DeleteUserProfile started")
 	// Tokenni olish va foydalanuvchi ID sini olish
 	token := c.GetHeader("Authorization")
-	id, _, err := auth.GetUserIdFromToken(token)
+	id, _, err := h.Tokens.GetUserIdFromToken(token)
 	if err != nil {
 		h.Log.Error(err.Error())
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
@@ -507,3 +601,43 @@ func (h *Handler) DeleteUserProfile(c *gin.Context) {
 	h.Log.Info("DeleteUserProfile finished successfully")
 	c.JSON(200, gin.H{"message": "User profile deleted successfully"})
 }
+
+// allowAttempt answers with 429 when guard wants the caller to wait. Redis
+// being down lets the attempt through.
+func (h *Handler) allowAttempt(c *gin.Context, guard *throttle.Guard, identifier string) bool {
+	err := guard.Check(c, identifier, c.ClientIP())
+	if throttled, ok := throttle.IsThrottled(err); ok {
+		h.Log.Error(err.Error())
+		tooManyAttempts(c, throttled)
+		return false
+	}
+	if err != nil {
+		h.Log.Error(err.Error())
+	}
+	return true
+}
+
+func tooManyAttempts(c *gin.Context, err *throttle.Error) {
+	retryAfter := int(math.Ceil(err.RetryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "Too many attempts, try again later",
+		"code":        err.Code(),
+		"retry_after": retryAfter,
+	})
+}
+
+// weakPassword answers 422 with the broken password rules when err is a
+// password policy violation.
+func weakPassword(c *gin.Context, err error) bool {
+	invalid, ok := password.FromStatus(err)
+	if !ok {
+		return false
+	}
+	c.JSON(http.StatusUnprocessableEntity, gin.H{
+		"error":      "Password does not meet the requirements",
+		"code":       password.WeakPassword,
+		"violations": invalid.Violations,
+	})
+	return true
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// clientIP runs TrustRequestMeta on a call from 203.0.113.7 carrying md and
+// returns the x-client-ip the handlers see.
+func clientIP(t *testing.T, g *Gateway, md metadata.MD) string {
+	t.Helper()
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 40000}})
+	ctx = metadata.NewIncomingContext(ctx, md)
+
+	var ip string
+	_, err := g.TrustRequestMeta(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if len(md.Get(GatewayKey)) > 0 {
+			t.Error("the gateway token reached the handler")
+		}
+		if ips := md.Get(ClientIPKey); len(ips) == 1 {
+			ip = ips[0]
+		}
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ip
+}
+
+// forwarded returns the metadata the gateway sends for a client at ip.
+func forwarded(t *testing.T, g *Gateway, ip string) metadata.MD {
+	t.Helper()
+	ctx := context.WithValue(context.Background(), ClientIPKey, ip)
+	var md metadata.MD
+	err := g.ForwardRequestMeta(ctx, "/user.User/Login", nil, nil, nil, func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return md
+}
+
+func TestTrustRequestMeta(t *testing.T) {
+	g, err := NewGateway()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewGateway()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ip := clientIP(t, g, forwarded(t, g, "198.51.100.1")); ip != "198.51.100.1" {
+		t.Errorf("gateway call: client ip %q, want the forwarded one", ip)
+	}
+	if ip := clientIP(t, g, metadata.Pairs(ClientIPKey, "198.51.100.1")); ip != "203.0.113.7" {
+		t.Errorf("forged x-client-ip: client ip %q, want the peer's", ip)
+	}
+	if ip := clientIP(t, g, forwarded(t, other, "198.51.100.1")); ip != "203.0.113.7" {
+		t.Errorf("wrong gateway token: client ip %q, want the peer's", ip)
+	}
+	if ip := clientIP(t, g, metadata.MD{}); ip != "203.0.113.7" {
+		t.Errorf("no metadata: client ip %q, want the peer's", ip)
+	}
+}
@@ -1,13 +1,77 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
+	"time"
 	"wegugin/api/auth"
+	"wegugin/storage"
+	"wegugin/storage/redis"
 
 	"github.com/gin-gonic/gin"
 )
 
-func Check(c *gin.Context) {
+// Error codes returned next to the message, for the apps to act on.
+const (
+	// AccountSuspended is returned to suspended users.
+	AccountSuspended = "ACCOUNT_SUSPENDED"
+	// InvalidCredentials is returned for an unknown login and a wrong
+	// password alike.
+	InvalidCredentials = "INVALID_CREDENTIALS"
+	// InvalidCode is returned for a wrong, expired or missing one-time code.
+	InvalidCode = "INVALID_CODE"
+	// AccountNotFound is returned for a social login no account is linked to.
+	AccountNotFound = "ACCOUNT_NOT_FOUND"
+	// IdentityTaken is returned when a provider account can not be linked
+	// because it, or another one of the same provider, is linked already.
+	IdentityTaken = "IDENTITY_TAKEN"
+	// InvalidLink is returned for a wrong, expired or used login link.
+	InvalidLink = "INVALID_LINK"
+	// WrongDevice is returned for a login link opened, or a social sign in
+	// finished, without the nonce cookie of the device that started it.
+	WrongDevice = "WRONG_DEVICE"
+	// EmailTaken is returned when another active account has the email.
+	EmailTaken = "EMAIL_TAKEN"
+	// LinkRequired is returned for a social login with the email of an
+	// account it is not linked to. The user logs in with the password and
+	// links the provider account from there.
+	LinkRequired = "LINK_REQUIRED"
+	// PasswordResetRequired is returned for a password login to an account
+	// whose password must be reset first, right password or not.
+	PasswordResetRequired = "PASSWORD_RESET_REQUIRED"
+)
+
+// Auth checks the access tokens of the gateway's requests, with the
+// suspension and revoked session markers kept in Redis. When Redis fails
+// the suspensions and sessions are looked up in Store instead, and a token
+// nobody can vouch for is refused.
+type Auth struct {
+	Tokens *auth.Tokens
+	Redis  *redis.Client
+	Store  storage.IStorage
+}
+
+// sessionRevoked reports whether the session was revoked, from the Redis
+// marker or, when Redis fails, from the database.
+func (a *Auth) sessionRevoked(ctx context.Context, sessionId string) (bool, error) {
+	revoked, err := a.Redis.IsSessionRevoked(ctx, sessionId)
+	if err == nil {
+		return revoked, nil
+	}
+	return a.Store.Session().IsSessionRevoked(ctx, sessionId)
+}
+
+// suspended reports whether the user is suspended, from the Redis marker
+// or, when Redis fails, from the database.
+func (a *Auth) suspended(ctx context.Context, userId string) (bool, error) {
+	suspended, err := a.Redis.IsSuspended(ctx, userId)
+	if err == nil {
+		return suspended, nil
+	}
+	return a.Store.Suspension().IsSuspended(ctx, userId)
+}
+
+func (a *Auth) Check(c *gin.Context) {
 	refreshToken := c.GetHeader("Authorization")
 
 	if refreshToken == "" {
@@ -17,7 +81,7 @@ func Check(c *gin.Context) {
 		return
 	}
 
-	_, err := auth.ValidateToken(refreshToken)
+	id, _, err := a.Tokens.GetUserIdFromToken(refreshToken)
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 			"error": "Invalid token provided",
@@ -25,5 +89,72 @@ func Check(c *gin.Context) {
 		return
 	}
 
+	suspended, err := a.suspended(c, id)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Account can not be checked, try again later",
+		})
+		return
+	}
+	if suspended {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error": "Account is suspended",
+			"code":  AccountSuspended,
+		})
+		return
+	}
+
+	if sid := a.Tokens.GetSessionId(refreshToken); sid != "" {
+		revoked, err := a.sessionRevoked(c, sid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"error": "Session can not be checked, try again later",
+			})
+			return
+		}
+		if revoked {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "Session has been revoked",
+			})
+			return
+		}
+		a.Redis.TouchSession(c, sid, time.Until(auth.TokenExpiry()))
+	}
+
 	c.Next()
 }
+
+// CheckAdmin must run after Check. It only lets tokens with the admin role through.
+func (a *Auth) CheckAdmin(c *gin.Context) {
+	_, role, err := a.Tokens.GetUserIdFromToken(c.GetHeader("Authorization"))
+	if err != nil || role != "admin" {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error": "Admin role is required",
+		})
+		return
+	}
+
+	c.Next()
+}
+
+// ViewerId returns the id of the user making the request on routes open to
+// everyone, or "" for anonymous requests and tokens Check would refuse.
+func (a *Auth) ViewerId(c *gin.Context) string {
+	token := c.GetHeader("Authorization")
+	if token == "" {
+		return ""
+	}
+	id, _, err := a.Tokens.GetUserIdFromToken(token)
+	if err != nil {
+		return ""
+	}
+	if suspended, err := a.suspended(c, id); err != nil || suspended {
+		return ""
+	}
+	if sid := a.Tokens.GetSessionId(token); sid != "" {
+		if revoked, err := a.sessionRevoked(c, sid); err != nil || revoked {
+			return ""
+		}
+	}
+	return id
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"wegugin/api/auth"
+	"wegugin/config"
+	pb "wegugin/genproto/user"
+	"wegugin/model"
+	"wegugin/storage/memory"
+	"wegugin/storage/redis"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// downRedis returns a client of a Redis that refuses connections.
+func downRedis(t *testing.T) *redis.Client {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	rdb, err := redis.New(config.RedisConfig{RDB_ADDRESS: addr, RDB_DIAL_TIMEOUT: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { rdb.Close() })
+	return rdb
+}
+
+// TestCheckWithoutRedis checks that a revoked session stays revoked, and a
+// suspended user suspended, while Redis is down, from the database.
+func TestCheckWithoutRedis(t *testing.T) {
+	conf, err := config.New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.Password.BCRYPT_COST = bcrypt.MinCost
+	store := memory.New(conf)
+	tokens := auth.New(conf.Token)
+	ctx := context.Background()
+
+	res, err := store.User().CreateUser(ctx, &pb.RegisterReq{
+		Email: "user@example.com", Name: "User", Password: "correct horse battery staple",
+		Phone: "+821012345678", BirthDate: "02-01-1990", Gender: "other",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	userId, _, err := tokens.GetUserIdFromToken(res.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := func(revoke bool) string {
+		sid, err := store.Session().CreateSession(ctx, &model.Session{UserId: userId, ExpiresAt: auth.TokenExpiry()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if revoke {
+			if _, err := store.Session().RevokeSession(ctx, &pb.SessionReq{UserId: userId, SessionId: sid}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		token, err := tokens.GenerateSessionToken(userId, "user", sid, auth.TokenExpiry())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	gin.SetMode(gin.TestMode)
+	a := &Auth{Tokens: tokens, Redis: downRedis(t), Store: store}
+	router := gin.New()
+	router.GET("/me", a.Check, func(c *gin.Context) { c.String(http.StatusOK, a.ViewerId(c)) })
+	get := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set("Authorization", token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := get(token(false)); rec.Code != http.StatusOK || rec.Body.String() != userId {
+		t.Errorf("active session: %d %q, want 200 and the viewer", rec.Code, rec.Body.String())
+	}
+	if rec := get(token(true)); rec.Code != http.StatusUnauthorized {
+		t.Errorf("revoked session: %d, want 401", rec.Code)
+	}
+
+	if _, err := store.Suspension().SuspendUser(ctx, &pb.SuspendUserReq{UserId: userId, Reason: "spam"}); err != nil {
+		t.Fatal(err)
+	}
+	if rec := get(token(false)); rec.Code != http.StatusForbidden {
+		t.Errorf("suspended user: %d, want 403", rec.Code)
+	}
+}
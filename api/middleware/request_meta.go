@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	RequestIdHeader  = "X-Request-ID"
+	DeviceNameHeader = "X-Device-Name"
+	PlatformHeader   = "X-Device-Platform"
+)
+
+// gRPC metadata keys carrying the HTTP caller to the user service.
+const (
+	RequestIdKey = "x-request-id"
+	ClientIPKey  = "x-client-ip"
+	UserAgentKey = "x-client-user-agent"
+	DeviceKey    = "x-device-name"
+	PlatformKey  = "x-device-platform"
+)
+
+// RequestMeta gives every request an id, taken from the X-Request-ID header
+// when the caller sent one, and echoes it back in the response. The client
+// address is the one gin settles on with the trusted proxies of the router. The apps
+// name the device they run on in X-Device-Name and X-Device-Platform.
+func RequestMeta(c *gin.Context) {
+	id := c.GetHeader(RequestIdHeader)
+	if id == "" || len(id) > 64 {
+		id = uuid.NewString()
+	}
+	c.Header(RequestIdHeader, id)
+
+	c.Set(RequestIdKey, id)
+	c.Set(ClientIPKey, c.ClientIP())
+	c.Set(UserAgentKey, c.Request.UserAgent())
+	c.Set(DeviceKey, c.GetHeader(DeviceNameHeader))
+	c.Set(PlatformKey, c.GetHeader(PlatformHeader))
+	c.Next()
+}
@@ -0,0 +1,302 @@
+// Package oidc signs users in with OpenID Connect providers using the
+// authorization code flow with PKCE.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// discoveryTTL is how long the discovery document and keys are cached. Keys
+// are fetched again earlier when a token names an unknown one.
+const discoveryTTL = 24 * time.Hour
+
+var ErrInvalidToken = errors.New("invalid id token")
+
+// Identity is what a provider tells about the signed in user.
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// Provider is one OpenID Connect provider. Its endpoints are discovered from
+// Issuer, so any provider, a local mock too, works by setting the issuer.
+type Provider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// SecretFunc makes the client secret per request when set, Apple wants
+	// a fresh signed JWT instead of a fixed secret
+	SecretFunc  func() (string, error)
+	RedirectURL string
+	Scopes      []string
+	// ResponseMode form_post makes the provider POST the callback, which
+	// Apple requires when asking for the email
+	ResponseMode string
+	Client       *http.Client
+
+	mu           sync.Mutex
+	discovery    *discovery
+	discoveredAt time.Time
+	keys         map[string]interface{}
+	keysAt       time.Time
+}
+
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// RandomToken returns a random url safe string, long enough for a state,
+// nonce or PKCE code verifier.
+func RandomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func codeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL returns where to send the user to sign in.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.ClientID},
+		"redirect_uri":          {p.RedirectURL},
+		"scope":                 {strings.Join(p.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {codeChallenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	if p.ResponseMode != "" {
+		params.Set("response_mode", p.ResponseMode)
+	}
+	sep := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return d.AuthorizationEndpoint + sep + params.Encode(), nil
+}
+
+// Exchange redeems the code from the callback and returns the identity in
+// the verified ID token. When the token leaves email_verified out, as Kakao
+// does, it is taken from the userinfo endpoint.
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*Identity, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := p.ClientSecret
+	if p.SecretFunc != nil {
+		if secret, err = p.SecretFunc(); err != nil {
+			return nil, fmt.Errorf("failed to make client secret: %w", err)
+		}
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.RedirectURL},
+		"client_id":     {p.ClientID},
+		"code_verifier": {verifier},
+	}
+	if secret != "" {
+		form.Set("client_secret", secret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var token struct {
+		AccessToken      string `json:"access_token"`
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := p.do(req, &token); err != nil && token.Error == "" {
+		return nil, fmt.Errorf("failed to redeem code: %w", err)
+	}
+	if token.Error != "" {
+		return nil, fmt.Errorf("failed to redeem code: %s %s", token.Error, token.ErrorDescription)
+	}
+	if token.IDToken == "" {
+		return nil, fmt.Errorf("failed to redeem code: no id_token in response")
+	}
+
+	claims, err := p.Verify(ctx, token.IDToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+	identity := claimsIdentity(claims)
+	if _, ok := claims["email_verified"]; !ok && d.UserinfoEndpoint != "" && token.AccessToken != "" {
+		info, err := p.userinfo(ctx, d.UserinfoEndpoint, token.AccessToken)
+		if err != nil {
+			return nil, err
+		}
+		// Userinfo claims only count for the user of the ID token
+		if info.Subject == identity.Subject {
+			if info.Email != "" {
+				identity.Email = info.Email
+			}
+			identity.EmailVerified = info.EmailVerified
+		}
+	}
+	return identity, nil
+}
+
+// Verify checks the signature, issuer, audience, expiry and nonce of an ID
+// token and returns its claims.
+func (p *Provider) Verify(ctx context.Context, rawToken, nonce string) (jwt.MapClaims, error) {
+	d, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &jwt.Parser{ValidMethods: []string{"RS256", "ES256"}}
+	claims := jwt.MapClaims{}
+	_, err = parser.ParseWithClaims(rawToken, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.key(ctx, d.JWKSURI, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	switch {
+	case !claims.VerifyIssuer(d.Issuer, true):
+		return nil, fmt.Errorf("%w: unexpected issuer %v", ErrInvalidToken, claims["iss"])
+	case !hasAudience(claims["aud"], p.ClientID):
+		return nil, fmt.Errorf("%w: unexpected audience %v", ErrInvalidToken, claims["aud"])
+	case !claims.VerifyExpiresAt(time.Now().Unix(), true):
+		return nil, fmt.Errorf("%w: token is expired", ErrInvalidToken)
+	case claims["nonce"] != nonce:
+		return nil, fmt.Errorf("%w: nonce does not match", ErrInvalidToken)
+	case claims["sub"] == nil || claims["sub"] == "":
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidToken)
+	}
+	return claims, nil
+}
+
+func hasAudience(aud interface{}, clientId string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == clientId
+	case []interface{}:
+		for _, a := range aud {
+			if a == clientId {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func claimsIdentity(claims map[string]interface{}) *Identity {
+	identity := &Identity{}
+	identity.Subject, _ = claims["sub"].(string)
+	identity.Email, _ = claims["email"].(string)
+	identity.Name, _ = claims["name"].(string)
+	// Apple sends email_verified as a string
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		identity.EmailVerified = verified == "true"
+	}
+	return identity
+}
+
+func (p *Provider) userinfo(ctx context.Context, endpoint, accessToken string) (*Identity, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+
+	claims := map[string]interface{}{}
+	if err := p.do(req, &claims); err != nil {
+		return nil, fmt.Errorf("failed to get userinfo: %w", err)
+	}
+	return claimsIdentity(claims), nil
+}
+
+func (p *Provider) discover(ctx context.Context) (*discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil && time.Since(p.discoveredAt) < discoveryTTL {
+		return p.discovery, nil
+	}
+
+	endpoint := strings.TrimSuffix(p.Issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	var d discovery
+	if err := p.do(req, &d); err != nil {
+		return nil, fmt.Errorf("failed to discover %s: %w", p.Name, err)
+	}
+	if d.Issuer != p.Issuer {
+		return nil, fmt.Errorf("failed to discover %s: issuer %q does not match %q", p.Name, d.Issuer, p.Issuer)
+	}
+	p.discovery, p.discoveredAt = &d, time.Now()
+	return p.discovery, nil
+}
+
+// do sends req and decodes the JSON response into v, also for error
+// statuses so their body can be read.
+func (p *Provider) do(req *http.Request, v interface{}) error {
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	decodeErr := json.Unmarshal(body, v)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", req.URL.Host, resp.Status)
+	}
+	return decodeErr
+}
@@ -0,0 +1,148 @@
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+	"wegugin/config"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	Google = "google"
+	Apple  = "apple"
+	Kakao  = "kakao"
+)
+
+// PostsCallback reports whether the provider POSTs the callback from its own
+// site, which browsers only send SameSite=None cookies along with.
+func PostsCallback(name string) bool {
+	return name == Apple
+}
+
+// Registry holds the enabled providers by name.
+type Registry struct {
+	providers map[string]*Provider
+}
+
+// NewRegistry sets up the providers that have a client id in the config. A
+// provider that can not be set up is left out and its error returned along
+// with the other providers.
+func NewRegistry(c config.OIDCConfig, publicURL string) (*Registry, error) {
+	var setupErr error
+	r := &Registry{providers: map[string]*Provider{}}
+	client := &http.Client{Timeout: 10 * time.Second}
+	redirect := func(name string) string {
+		return publicURL + "/auth/oidc/" + name + "/callback"
+	}
+
+	if c.GOOGLE_CLIENT_ID != "" {
+		r.Register(&Provider{
+			Name:         Google,
+			Issuer:       c.GOOGLE_ISSUER,
+			ClientID:     c.GOOGLE_CLIENT_ID,
+			ClientSecret: c.GOOGLE_CLIENT_SECRET,
+			RedirectURL:  redirect(Google),
+			Scopes:       []string{"openid", "email", "profile"},
+			Client:       client,
+		})
+	}
+
+	if c.APPLE_CLIENT_ID != "" {
+		apple := &Provider{
+			Name:         Apple,
+			Issuer:       c.APPLE_ISSUER,
+			ClientID:     c.APPLE_CLIENT_ID,
+			ClientSecret: c.APPLE_CLIENT_SECRET,
+			RedirectURL:  redirect(Apple),
+			Scopes:       []string{"openid", "email", "name"},
+			ResponseMode: "form_post",
+			Client:       client,
+		}
+		if c.APPLE_PRIVATE_KEY_FILE != "" {
+			key, err := readECKey(c.APPLE_PRIVATE_KEY_FILE)
+			if err != nil {
+				setupErr = fmt.Errorf("failed to read apple key: %w", err)
+			}
+			apple.SecretFunc = appleSecret(c.APPLE_ISSUER, c.APPLE_TEAM_ID, c.APPLE_KEY_ID, c.APPLE_CLIENT_ID, key)
+		}
+		if setupErr == nil {
+			r.Register(apple)
+		}
+	}
+
+	if c.KAKAO_CLIENT_ID != "" {
+		r.Register(&Provider{
+			Name:         Kakao,
+			Issuer:       c.KAKAO_ISSUER,
+			ClientID:     c.KAKAO_CLIENT_ID,
+			ClientSecret: c.KAKAO_CLIENT_SECRET,
+			RedirectURL:  redirect(Kakao),
+			Scopes:       []string{"openid", "account_email"},
+			Client:       client,
+		})
+	}
+	return r, setupErr
+}
+
+func (r *Registry) Register(p *Provider) {
+	r.providers[p.Name] = p
+}
+
+// Get returns the provider called name, or nil when it is not enabled.
+func (r *Registry) Get(name string) *Provider {
+	return r.providers[name]
+}
+
+// Names lists the enabled providers.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func readECKey(path string) (*ecdsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in %s", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an EC key", path)
+	}
+	return ecKey, nil
+}
+
+// appleSecret makes the client secret Apple wants, a short lived JWT signed
+// with the key from the developer account.
+func appleSecret(audience, teamId, keyId, clientId string, key *ecdsa.PrivateKey) func() (string, error) {
+	return func() (string, error) {
+		now := time.Now()
+		token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.StandardClaims{
+			Issuer:    teamId,
+			Subject:   clientId,
+			Audience:  audience,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(5 * time.Minute).Unix(),
+		})
+		token.Header["kid"] = keyId
+		return token.SignedString(key)
+	}
+}
@@ -0,0 +1,55 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BreachList looks passwords up in a local copy of a breached password
+// corpus in the k-anonymity range format: one file per first five hex
+// digits of the SHA-1, named PREFIX.txt, with SUFFIX:COUNT lines. This is
+// what the haveibeenpwned downloader writes when asked for single files.
+type BreachList struct {
+	dir string
+}
+
+// NewBreachList reads from dir, an empty dir disables the check.
+func NewBreachList(dir string) *BreachList {
+	return &BreachList{dir: dir}
+}
+
+func (b *BreachList) Contains(password string) (bool, error) {
+	if b == nil || b.dir == "" {
+		return false, nil
+	}
+
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	file, err := os.Open(filepath.Join(b.dir, prefix+".txt"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to open breach list: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if strings.EqualFold(line, suffix) {
+			return true, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("failed to read breach list: %w", err)
+	}
+	return false, nil
+}
@@ -0,0 +1,148 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"wegugin/config"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	Bcrypt   = "bcrypt"
+	Argon2id = "argon2id"
+)
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+var ErrUnknownHash = errors.New("unknown password hash format")
+
+// Hasher hashes new passwords with the configured algorithm and verifies
+// passwords against any hash it knows, bcrypt or encoded Argon2id.
+type Hasher struct {
+	Algorithm  string
+	BcryptCost int
+	// Argon2id parameters, Memory in KiB
+	Memory  uint32
+	Time    uint32
+	Threads uint8
+}
+
+func NewHasher(conf config.PasswordConfig) *Hasher {
+	return &Hasher{
+		Algorithm:  conf.HASH_ALGORITHM,
+		BcryptCost: conf.BCRYPT_COST,
+		Memory:     conf.ARGON2_MEMORY,
+		Time:       conf.ARGON2_TIME,
+		Threads:    conf.ARGON2_THREADS,
+	}
+}
+
+func (h *Hasher) Hash(password string) (string, error) {
+	switch h.Algorithm {
+	case Bcrypt:
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.BcryptCost)
+		if err != nil {
+			return "", fmt.Errorf("failed to hash password: %w", err)
+		}
+		return string(hash), nil
+	case Argon2id:
+		salt := make([]byte, argon2SaltLength)
+		if _, err := rand.Read(salt); err != nil {
+			return "", fmt.Errorf("failed to generate salt: %w", err)
+		}
+		key := argon2.IDKey([]byte(password), salt, h.Time, h.Memory, h.Threads, argon2KeyLength)
+		return encodeArgon2id(argon2Params{h.Memory, h.Time, h.Threads}, salt, key), nil
+	default:
+		return "", fmt.Errorf("unsupported password hash algorithm %q", h.Algorithm)
+	}
+}
+
+// Verify reports whether password matches hash.
+func (h *Hasher) Verify(hash, password string) (bool, error) {
+	switch {
+	case isBcrypt(hash):
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return false, nil
+		}
+		return err == nil, err
+	case strings.HasPrefix(hash, "$"+Argon2id+"$"):
+		params, salt, key, err := decodeArgon2id(hash)
+		if err != nil {
+			return false, err
+		}
+		other := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, uint32(len(key)))
+		return subtle.ConstantTimeCompare(key, other) == 1, nil
+	default:
+		return false, ErrUnknownHash
+	}
+}
+
+// NeedsRehash reports whether hash was made with another algorithm or other
+// parameters than h uses now.
+func (h *Hasher) NeedsRehash(hash string) bool {
+	switch h.Algorithm {
+	case Bcrypt:
+		if !isBcrypt(hash) {
+			return true
+		}
+		cost, err := bcrypt.Cost([]byte(hash))
+		return err != nil || cost != h.BcryptCost
+	case Argon2id:
+		params, _, key, err := decodeArgon2id(hash)
+		return err != nil || params != (argon2Params{h.Memory, h.Time, h.Threads}) || len(key) != argon2KeyLength
+	default:
+		return false
+	}
+}
+
+func isBcrypt(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+type argon2Params struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+}
+
+// encodeArgon2id writes the PHC string format also used by the reference
+// implementation: $argon2id$v=19$m=65536,t=3,p=2$salt$key
+func encodeArgon2id(p argon2Params, salt, key []byte) string {
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s", Argon2id, argon2.Version, p.memory, p.time, p.threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+}
+
+func decodeArgon2id(hash string) (argon2Params, []byte, []byte, error) {
+	var p argon2Params
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != Argon2id {
+		return p, nil, nil, ErrUnknownHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, fmt.Errorf("unsupported argon2 version %q", parts[2])
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil {
+		return p, nil, nil, fmt.Errorf("invalid argon2 parameters %q", parts[3])
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, fmt.Errorf("invalid argon2 salt: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return p, nil, nil, fmt.Errorf("invalid argon2 key: %w", err)
+	}
+	return p, salt, key, nil
+}
@@ -0,0 +1,202 @@
+// Package password checks new passwords against the configured policy and
+// a local list of breached passwords.
+package password
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"wegugin/config"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// WeakPassword is the error code of a password the policy rejects.
+const WeakPassword = "WEAK_PASSWORD"
+
+// Violation codes.
+const (
+	TooShort      = "too_short"
+	TooLong       = "too_long"
+	NoUpper       = "no_upper"
+	NoLower       = "no_lower"
+	NoDigit       = "no_digit"
+	NoSymbol      = "no_symbol"
+	ContainsEmail = "contains_email"
+	ContainsName  = "contains_name"
+	Reused        = "reused"
+	Breached      = "breached"
+)
+
+// bcrypt ignores everything after 72 bytes.
+const maxLength = 72
+
+type Violation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every rule a password breaks.
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.Message
+	}
+	return "password " + strings.Join(messages, ", ")
+}
+
+// Status turns e into an InvalidArgument status, the violations go into the
+// metadata of an ErrorInfo detail keyed by code. FromStatus reads it back.
+func (e *ValidationError) Status() error {
+	info := &errdetails.ErrorInfo{Reason: WeakPassword, Metadata: make(map[string]string)}
+	badRequest := &errdetails.BadRequest{}
+	for _, v := range e.Violations {
+		info.Metadata[v.Code] = v.Message
+		badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       "password",
+			Description: v.Message,
+		})
+	}
+	st, err := status.New(codes.InvalidArgument, e.Error()).WithDetails(info, badRequest)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, e.Error())
+	}
+	return st.Err()
+}
+
+func FromStatus(err error) (*ValidationError, bool) {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.InvalidArgument {
+		return nil, false
+	}
+	for _, detail := range st.Details() {
+		info, ok := detail.(*errdetails.ErrorInfo)
+		if !ok || info.Reason != WeakPassword {
+			continue
+		}
+		res := &ValidationError{}
+		for code, message := range info.Metadata {
+			res.Violations = append(res.Violations, Violation{Code: code, Message: message})
+		}
+		sort.Slice(res.Violations, func(i, j int) bool { return res.Violations[i].Code < res.Violations[j].Code })
+		return res, true
+	}
+	return nil, false
+}
+
+// Policy holds the rules a new password must follow.
+type Policy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// History is how many previous passwords can not be used again.
+	History  int
+	Breaches *BreachList
+}
+
+func NewPolicy(conf config.PasswordConfig) *Policy {
+	return &Policy{
+		MinLength:     conf.MIN_LENGTH,
+		RequireUpper:  conf.REQUIRE_UPPER,
+		RequireLower:  conf.REQUIRE_LOWER,
+		RequireDigit:  conf.REQUIRE_DIGIT,
+		RequireSymbol: conf.REQUIRE_SYMBOL,
+		History:       conf.HISTORY,
+		Breaches:      NewBreachList(conf.BREACHED_DIR),
+	}
+}
+
+// Owner is what a password must not contain.
+type Owner struct {
+	Email   string
+	Name    string
+	Surname string
+}
+
+// Validate checks everything but reuse, which needs the stored hashes and
+// is left to the caller. It returns a *ValidationError, or another error
+// when the breach list can not be read.
+func (p *Policy) Validate(password string, owner Owner) error {
+	var violations []Violation
+	add := func(code, format string, args ...interface{}) {
+		violations = append(violations, Violation{Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len([]rune(password)) < p.MinLength {
+		add(TooShort, "must be at least %d characters long", p.MinLength)
+	}
+	if len(password) > maxLength {
+		add(TooLong, "must be at most %d bytes long", maxLength)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if p.RequireUpper && !upper {
+		add(NoUpper, "must contain an uppercase letter")
+	}
+	if p.RequireLower && !lower {
+		add(NoLower, "must contain a lowercase letter")
+	}
+	if p.RequireDigit && !digit {
+		add(NoDigit, "must contain a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		add(NoSymbol, "must contain a symbol")
+	}
+
+	lowered := strings.ToLower(password)
+	email := strings.ToLower(owner.Email)
+	local, _, _ := strings.Cut(email, "@")
+	if contains(lowered, email) || contains(lowered, local) {
+		add(ContainsEmail, "must not contain your email")
+	}
+	if contains(lowered, strings.ToLower(owner.Name)) || contains(lowered, strings.ToLower(owner.Surname)) {
+		add(ContainsName, "must not contain your name")
+	}
+
+	breached, err := p.Breaches.Contains(password)
+	if err != nil {
+		return err
+	}
+	if breached {
+		add(Breached, "appears in a known data breach")
+	}
+
+	if len(violations) > 0 {
+		return &ValidationError{Violations: violations}
+	}
+	return nil
+}
+
+// ReuseViolation is the error for a password found in the history.
+func (p *Policy) ReuseViolation() *ValidationError {
+	return &ValidationError{Violations: []Violation{{
+		Code:    Reused,
+		Message: fmt.Sprintf("must differ from your last %d passwords", p.History),
+	}}}
+}
+
+// contains ignores parts too short to mean anything.
+func contains(password, part string) bool {
+	return len(part) >= 3 && strings.Contains(password, part)
+}
@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimited is the error code of a rejected request.
+const RateLimited = "RATE_LIMITED"
+
+const APIKeyHeader = "X-API-Key"
+
+// Gin limits the requests of the routes it is used on under p, telling the
+// callers apart with callers. It sets the
+// RateLimit-* headers on every response and answers 429 with Retry-After
+// once the limit is reached. Errors of the limiter let the request through.
+func Gin(limiter Limiter, callers *Callers, p Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !p.enabled() {
+			c.Next()
+			return
+		}
+
+		res, err := limiter.Allow(c, p, callers.ginKey(c, p.KeyBy))
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		for name, value := range headers(p, res) {
+			c.Header(name, value)
+		}
+		if !res.Allowed {
+			c.Header("Retry-After", strconv.Itoa(seconds(res.Reset)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error": "Too many requests, try again later",
+				"code":  RateLimited,
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// ginKey goes by c.ClientIP, which only believes X-Forwarded-For from the
+// trusted proxies of the router.
+func (callers *Callers) ginKey(c *gin.Context, keyBy KeyBy) string {
+	switch keyBy {
+	case ByUser:
+		if id, _, err := callers.tokens.GetUserIdFromToken(c.GetHeader("Authorization")); err == nil {
+			return "user:" + id
+		}
+	case ByAPIKey:
+		if key := c.GetHeader(APIKeyHeader); callers.apiKeys[key] {
+			return "key:" + key
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// headers are the RateLimit-* header fields of the IETF httpapi draft.
+func headers(p Policy, res Result) map[string]string {
+	return map[string]string{
+		"RateLimit-Limit":     strconv.Itoa(res.Limit),
+		"RateLimit-Remaining": strconv.Itoa(res.Remaining),
+		"RateLimit-Reset":     strconv.Itoa(seconds(res.Reset)),
+		"RateLimit-Policy":    strconv.Itoa(p.Limit) + ";w=" + strconv.Itoa(seconds(p.Window)),
+	}
+}
+
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
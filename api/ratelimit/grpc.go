@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"context"
+	"strings"
+	"wegugin/api/middleware"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// UnaryServerInterceptor limits rpc calls under the policy of their full
+// method name, or under fallback for methods without one. The RateLimit-*
+// values go out as response headers; a rejected call gets RESOURCE_EXHAUSTED
+// with a RetryInfo detail.
+func UnaryServerInterceptor(limiter Limiter, callers *Callers, policies map[string]Policy, fallback Policy) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		p, ok := policies[info.FullMethod]
+		if !ok {
+			p = fallback
+		}
+		if !p.enabled() {
+			return handler(ctx, req)
+		}
+
+		res, err := limiter.Allow(ctx, p, callers.grpcKey(ctx, p.KeyBy))
+		if err != nil {
+			return handler(ctx, req)
+		}
+
+		md := metadata.MD{}
+		for name, value := range headers(p, res) {
+			md.Set(strings.ToLower(name), value)
+		}
+		grpc.SetHeader(ctx, md)
+
+		if !res.Allowed {
+			st, err := status.New(codes.ResourceExhausted, "rate limit exceeded").WithDetails(
+				&errdetails.ErrorInfo{Reason: RateLimited},
+				&errdetails.RetryInfo{RetryDelay: durationpb.New(res.Reset)},
+			)
+			if err != nil {
+				return nil, status.Error(codes.ResourceExhausted, "rate limit exceeded")
+			}
+			return nil, st.Err()
+		}
+		return handler(ctx, req)
+	}
+}
+
+// grpcKey goes by x-client-ip, which middleware.Gateway's TrustRequestMeta
+// only leaves as sent for the calls of the gateway.
+func (callers *Callers) grpcKey(ctx context.Context, keyBy KeyBy) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	switch keyBy {
+	case ByUser:
+		if values := md.Get("authorization"); len(values) > 0 {
+			if id, _, err := callers.tokens.GetUserIdFromToken(values[0]); err == nil {
+				return "user:" + id
+			}
+		}
+	case ByAPIKey:
+		if keys := md.Get(strings.ToLower(APIKeyHeader)); len(keys) > 0 && callers.apiKeys[keys[0]] {
+			return "key:" + keys[0]
+		}
+	}
+
+	if ips := md.Get(middleware.ClientIPKey); len(ips) > 0 && ips[0] != "" {
+		return "ip:" + ips[0]
+	}
+	return "ip:" + middleware.PeerIP(ctx)
+}
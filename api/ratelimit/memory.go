@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often keys without recent requests are dropped.
+const sweepInterval = time.Minute
+
+type memoryLimiter struct {
+	mu        sync.Mutex
+	requests  map[string][]time.Time
+	lastSweep time.Time
+	maxWindow time.Duration
+}
+
+// NewMemory counts requests in this process only.
+func NewMemory() Limiter {
+	return &memoryLimiter{requests: make(map[string][]time.Time), lastSweep: time.Now()}
+}
+
+func (m *memoryLimiter) Allow(ctx context.Context, p Policy, key string) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if p.Window > m.maxWindow {
+		m.maxWindow = p.Window
+	}
+	if now.Sub(m.lastSweep) > sweepInterval {
+		m.sweep(now)
+	}
+
+	k := bucket(p, key)
+	times := prune(m.requests[k], now.Add(-p.Window))
+	res := Result{Limit: p.Limit}
+	if len(times) < p.Limit {
+		times = append(times, now)
+		res.Allowed = true
+	}
+	m.requests[k] = times
+
+	res.Remaining = p.Limit - len(times)
+	res.Reset = times[0].Add(p.Window).Sub(now)
+	return res, nil
+}
+
+func (m *memoryLimiter) sweep(now time.Time) {
+	for k, times := range m.requests {
+		if len(times) == 0 || now.Sub(times[len(times)-1]) > m.maxWindow {
+			delete(m.requests, k)
+		}
+	}
+	m.lastSweep = now
+}
+
+// prune drops the times before since, times is sorted.
+func prune(times []time.Time, since time.Time) []time.Time {
+	i := 0
+	for i < len(times) && !times[i].After(since) {
+		i++
+	}
+	return times[i:]
+}
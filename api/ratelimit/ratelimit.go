@@ -0,0 +1,124 @@
+// Package ratelimit limits requests per IP, user or API key over a sliding
+// window, as gin middleware and as a gRPC interceptor. Counters live in
+// Redis so every instance shares them; when Redis is unreachable each
+// instance falls back to counting in memory.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"wegugin/api/auth"
+	redisStorage "wegugin/storage/redis"
+)
+
+// KeyBy says whom a policy counts requests for.
+type KeyBy string
+
+const (
+	ByIP KeyBy = "ip"
+	// ByUser counts per user id from the access token, per IP without one.
+	ByUser KeyBy = "user"
+	// ByAPIKey counts per X-API-Key of the Callers, per IP for a missing or
+	// unknown key.
+	ByAPIKey KeyBy = "api_key"
+)
+
+// Callers knows the access tokens and API keys the policies count by.
+type Callers struct {
+	tokens  *auth.Tokens
+	apiKeys map[string]bool
+}
+
+// NewCallers takes the comma separated list of the API keys given out to
+// the other services.
+func NewCallers(tokens *auth.Tokens, apiKeys string) *Callers {
+	c := &Callers{tokens: tokens, apiKeys: map[string]bool{}}
+	for _, key := range strings.Split(apiKeys, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			c.apiKeys[key] = true
+		}
+	}
+	return c
+}
+
+// Policy allows Limit requests per Window for each key.
+type Policy struct {
+	Name   string
+	Limit  int
+	Window time.Duration
+	KeyBy  KeyBy
+}
+
+// ParsePolicy reads a "limit/window" spec such as "100/1m". An empty spec
+// gives a zero Policy, which allows everything.
+func ParsePolicy(name, spec string, keyBy KeyBy) (Policy, error) {
+	if spec == "" {
+		return Policy{}, nil
+	}
+	limit, window, ok := strings.Cut(spec, "/")
+	if !ok {
+		return Policy{}, fmt.Errorf("rate limit %s: %q is not limit/window", name, spec)
+	}
+	n, err := strconv.Atoi(limit)
+	if err != nil || n < 1 {
+		return Policy{}, fmt.Errorf("rate limit %s: invalid limit %q", name, limit)
+	}
+	d, err := time.ParseDuration(window)
+	if err != nil || d <= 0 {
+		return Policy{}, fmt.Errorf("rate limit %s: invalid window %q", name, window)
+	}
+	return Policy{Name: name, Limit: n, Window: d, KeyBy: keyBy}, nil
+}
+
+func (p Policy) enabled() bool {
+	return p.Limit > 0 && p.Window > 0
+}
+
+// Result describes the state of a key after a request was counted.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is when the oldest counted request leaves the window.
+	Reset time.Duration
+}
+
+type Limiter interface {
+	// Allow counts a request for key under p unless the limit is reached.
+	Allow(ctx context.Context, p Policy, key string) (Result, error)
+}
+
+type fallback struct {
+	primary, secondary Limiter
+	onError            func(error)
+}
+
+func (f *fallback) Allow(ctx context.Context, p Policy, key string) (Result, error) {
+	res, err := f.primary.Allow(ctx, p, key)
+	if err == nil {
+		return res, nil
+	}
+	if f.onError != nil {
+		f.onError(err)
+	}
+	return f.secondary.Allow(ctx, p, key)
+}
+
+// WithFallback uses secondary whenever primary fails, reporting the failure
+// to onError.
+func WithFallback(primary, secondary Limiter, onError func(error)) Limiter {
+	return &fallback{primary: primary, secondary: secondary, onError: onError}
+}
+
+func bucket(p Policy, key string) string {
+	return "ratelimit:" + p.Name + ":" + key
+}
+
+// New returns a limiter shared by the replicas through rdb, falling back to
+// counting in this process when Redis fails.
+func New(rdb *redisStorage.Client, onError func(error)) Limiter {
+	return WithFallback(NewRedis(rdb), NewMemory(), onError)
+}
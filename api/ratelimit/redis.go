@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+	redisStorage "wegugin/storage/redis"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// slidingLog keeps the timestamps of the requests in the window in a sorted
+// set and only adds one while there is room.
+var slidingLog = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+local count = redis.call('ZCARD', KEYS[1])
+local allowed = 0
+if count < limit then
+	redis.call('ZADD', KEYS[1], now, ARGV[4])
+	count = count + 1
+	allowed = 1
+end
+redis.call('PEXPIRE', KEYS[1], window)
+local reset = window
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+if oldest[2] then
+	reset = tonumber(oldest[2]) + window - now
+end
+return {allowed, count, reset}
+`)
+
+type redisLimiter struct {
+	rdb *redisStorage.Client
+}
+
+func NewRedis(rdb *redisStorage.Client) Limiter {
+	return &redisLimiter{rdb: rdb}
+}
+
+func (r *redisLimiter) Allow(ctx context.Context, p Policy, key string) (Result, error) {
+	now := time.Now().UnixMilli()
+	values, err := slidingLog.Run(ctx, r.rdb, []string{r.rdb.Keys.Key("ratelimit", p.Name, key)},
+		now, p.Window.Milliseconds(), p.Limit, uuid.NewString()).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to count request in Redis: %w", err)
+	}
+
+	return Result{
+		Allowed:   values[0] == 1,
+		Limit:     p.Limit,
+		Remaining: p.Limit - int(values[1]),
+		Reset:     time.Duration(values[2]) * time.Millisecond,
+	}, nil
+}
@@ -1,9 +1,14 @@
 package api
 
 import (
+	"expvar"
+	"fmt"
+	"log"
 	_ "wegugin/api/docs"
 	"wegugin/api/handler"
 	"wegugin/api/middleware"
+	"wegugin/api/ratelimit"
+	"wegugin/config"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
@@ -18,26 +23,108 @@ import (
 // @description API Gateway
 // BasePath: /
 func Router(hand *handler.Handler) *gin.Engine {
-	router := gin.Default()
+	limits := hand.Config.Limits
+	limiter := ratelimit.New(hand.Redis, func(err error) {
+		hand.Log.Error(fmt.Sprintf("rate limiter falls back to memory: %v", err))
+	})
+	callers := ratelimit.NewCallers(hand.Tokens, limits.API_KEYS)
+	authLimit := ratelimit.Gin(limiter, callers, policy("auth", limits.AUTH, ratelimit.ByIP))
+	publicLimit := ratelimit.Gin(limiter, callers, policy("public", limits.PUBLIC, ratelimit.ByIP))
+	userLimit := ratelimit.Gin(limiter, callers, policy("user", limits.USER, ratelimit.ByUser))
+
+	router, err := engine(hand.Config.Server)
+	if err != nil {
+		log.Fatal(err)
+	}
+	router.Use(middleware.RequestMeta)
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	// The probes are left out of the rate limits
+	router.GET("/healthz", hand.Healthz)
+	router.GET("/readyz", hand.Readyz)
 	auth := router.Group("/auth")
 	{
-		auth.POST("/register", hand.Register)
-		auth.POST("/login", hand.Login)
-		auth.POST("/forgot-password", hand.ForgotPassword)
-		auth.POST("/reset-password", hand.ResetPassword)
-		auth.GET("/user/:id", hand.GetUserById)
+		auth.POST("/register", authLimit, hand.Register)
+		auth.POST("/login", authLimit, hand.Login)
+		auth.POST("/forgot-password", authLimit, hand.ForgotPassword)
+		auth.POST("/reset-password", authLimit, hand.ResetPassword)
+		auth.GET("/user/:id", publicLimit, hand.GetUserById)
+		auth.GET("/restore", authLimit, hand.RestoreAccount)
+		auth.GET("/unlock", authLimit, hand.UnlockLogin)
+		auth.POST("/magic-link", authLimit, hand.RequestMagicLink)
+		auth.GET("/magic-link/consume", authLimit, hand.ConsumeMagicLink)
+		auth.GET("/email-change/confirm", authLimit, hand.ConfirmEmailChangePage)
+		auth.POST("/email-change/confirm", authLimit, hand.ConfirmEmailChange)
+		auth.GET("/email-change/revert", authLimit, hand.RevertEmailChangePage)
+		auth.POST("/email-change/revert", authLimit, hand.RevertEmailChange)
+		auth.GET("/oidc/:provider", authLimit, hand.StartOIDC)
+		auth.GET("/oidc/:provider/callback", authLimit, hand.OIDCCallback)
+		auth.POST("/oidc/:provider/callback", authLimit, hand.OIDCCallback)
+	}
+
+	cars := router.Group("/cars")
+	cars.Use(publicLimit)
+	{
+		cars.GET("/:id/price-history", hand.GetCarPriceHistory)
 	}
 
 	user := router.Group("/user")
-	user.Use(middleware.Check)
+	user.Use(hand.Auth.Check, userLimit)
 	{
 		user.GET("/profile", hand.GetUserProfile)
 		user.PUT("/profile", hand.UpdateUserProfile)
 		user.POST("/change-password", hand.ChangePassword)
+		user.POST("/email", hand.RequestEmailChange)
+		user.GET("/privacy", hand.GetPrivacySettings)
+		user.PUT("/privacy", hand.UpdatePrivacySettings)
 		user.POST("/photo", hand.UploadMediaUser)
 		user.DELETE("/photo", hand.DeleteMediaUser)
 		user.DELETE("/delete", hand.DeleteUserProfile)
+
+		user.POST("/saved-searches", hand.CreateSavedSearch)
+		user.GET("/saved-searches", hand.ListSavedSearches)
+		user.GET("/saved-searches/:id", hand.GetSavedSearch)
+		user.PUT("/saved-searches/:id", hand.UpdateSavedSearch)
+		user.DELETE("/saved-searches/:id", hand.DeleteSavedSearch)
+		user.POST("/export", hand.RequestDataExport)
+		user.GET("/export", hand.GetDataExport)
+		user.GET("/security-activity", hand.ListSecurityActivity)
+		user.GET("/sessions", hand.ListSessions)
+		user.DELETE("/sessions/:id", hand.RevokeSession)
+		user.GET("/identities", hand.ListIdentities)
+		user.POST("/identities/:provider", hand.LinkIdentity)
+		user.DELETE("/identities/:provider", hand.UnlinkIdentity)
+	}
+
+	admin := router.Group("/admin")
+	admin.Use(hand.Auth.Check, hand.Auth.CheckAdmin, userLimit)
+	{
+		admin.GET("/users", hand.AdminListUsers)
+		admin.GET("/users/:id", hand.AdminGetUser)
+		admin.PUT("/users/:id/role", hand.AdminUpdateRole)
+		admin.POST("/users/:id/force-password-reset", hand.AdminForcePasswordReset)
+		admin.POST("/users/:id/restore", hand.AdminRestoreUser)
+		admin.DELETE("/users/:id", hand.AdminHardDeleteUser)
+		admin.POST("/users/:id/suspend", hand.AdminSuspendUser)
+		admin.POST("/users/:id/unsuspend", hand.AdminLiftSuspension)
+		admin.GET("/users/:id/suspensions", hand.AdminListSuspensions)
+		admin.GET("/audit-events", hand.AdminListAuditEvents)
+		admin.GET("/metrics", gin.WrapH(expvar.Handler()))
 	}
 	return router
 }
+
+// engine is gin.Default with the TRUSTED_PROXIES of conf. c.ClientIP, which
+// the rate limits and throttles go by, only believes the forwarding headers
+// of those; none are trusted by default.
+func engine(conf config.ServerConfig) (*gin.Engine, error) {
+	router := gin.Default()
+	return router, router.SetTrustedProxies(conf.TrustedProxies())
+}
+
+func policy(name, spec string, keyBy ratelimit.KeyBy) ratelimit.Policy {
+	p, err := ratelimit.ParsePolicy(name, spec, keyBy)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return p
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+	"wegugin/api/throttle"
+	"wegugin/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// memoryStore is a throttle.Store without Redis.
+type memoryStore struct {
+	mu       sync.Mutex
+	failures map[string][]time.Time
+	locks    map[string]time.Time
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{failures: map[string][]time.Time{}, locks: map[string]time.Time{}}
+}
+
+func (m *memoryStore) AddFailure(ctx context.Context, key string, window time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failures[key] = append(m.failures[key], time.Now())
+	return int64(len(m.failures[key])), nil
+}
+
+func (m *memoryStore) Failures(ctx context.Context, key string, window time.Duration) (int64, time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	failures := m.failures[key]
+	if len(failures) == 0 {
+		return 0, time.Time{}, nil
+	}
+	return int64(len(failures)), failures[len(failures)-1], nil
+}
+
+func (m *memoryStore) Lock(ctx context.Context, key string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.locks[key] = time.Now().Add(ttl)
+	return nil
+}
+
+func (m *memoryStore) LockTTL(ctx context.Context, key string) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if ttl := time.Until(m.locks[key]); ttl > 0 {
+		return ttl, nil
+	}
+	return 0, nil
+}
+
+func (m *memoryStore) Delete(ctx context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range keys {
+		delete(m.failures, key)
+		delete(m.locks, key)
+	}
+	return nil
+}
+
+// TestForgedForwardedForKeepsIPCounter fails logins from one address, each
+// with another X-Forwarded-For, and expects the address to be locked out all
+// the same. Only the trusted proxy may name the client.
+func TestForgedForwardedForKeepsIPCounter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router, err := engine(config.ServerConfig{TRUSTED_PROXIES: "10.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	guard := &throttle.Guard{
+		Store:        newMemoryStore(),
+		Scope:        "login",
+		Window:       time.Hour,
+		FreeAttempts: 100,
+		LockAfter:    100,
+		IPLockAfter:  3,
+		LockDuration: time.Hour,
+	}
+	router.POST("/login", func(c *gin.Context) {
+		if err := guard.Check(c, "", c.ClientIP()); err != nil {
+			c.Status(http.StatusTooManyRequests)
+			return
+		}
+		if _, err := guard.Fail(c, "", c.ClientIP()); err != nil {
+			t.Error(err)
+		}
+		c.Status(http.StatusUnauthorized)
+	})
+
+	login := func(remoteAddr, forwardedFor string) int {
+		req := httptest.NewRequest(http.MethodPost, "/login", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		req.Header.Set("X-Real-IP", forwardedFor)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	forged := []string{"198.51.100.1", "198.51.100.2", "198.51.100.3", "198.51.100.4"}
+	for i, ip := range forged {
+		want := http.StatusUnauthorized
+		if int64(i) == guard.IPLockAfter {
+			want = http.StatusTooManyRequests
+		}
+		if got := login("203.0.113.7:40000", ip); got != want {
+			t.Fatalf("attempt %d forging %s: got %d, want %d", i+1, ip, got, want)
+		}
+	}
+
+	// Through the trusted proxy the forwarded address counts, and it has not
+	// failed yet,
+	if got := login("10.0.0.1:40000", "198.51.100.9"); got != http.StatusUnauthorized {
+		t.Fatalf("client behind the trusted proxy: got %d, want %d", got, http.StatusUnauthorized)
+	}
+	// and the locked address stays locked coming through the proxy
+	if got := login("10.0.0.1:40000", "203.0.113.7"); got != http.StatusTooManyRequests {
+		t.Fatalf("locked address behind the trusted proxy: got %d, want %d", got, http.StatusTooManyRequests)
+	}
+}
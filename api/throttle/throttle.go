@@ -0,0 +1,256 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"wegugin/config"
+	"wegugin/storage/redis"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Error codes returned to clients. They never tell whether an account exists.
+const (
+	TooManyAttempts = "TOO_MANY_ATTEMPTS"
+	AccountLocked   = "ACCOUNT_LOCKED"
+)
+
+// Error is returned by Guard.Check when the caller has to wait.
+type Error struct {
+	Locked     bool
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	if e.Locked {
+		return fmt.Sprintf("locked out, retry in %s", e.RetryAfter.Round(time.Second))
+	}
+	return fmt.Sprintf("too many attempts, retry in %s", e.RetryAfter.Round(time.Second))
+}
+
+// Code is TooManyAttempts or AccountLocked.
+func (e *Error) Code() string {
+	if e.Locked {
+		return AccountLocked
+	}
+	return TooManyAttempts
+}
+
+// Status turns e into a ResourceExhausted status carrying the code and the
+// retry delay, FromStatus reads it back on the client.
+func (e *Error) Status() error {
+	st, err := status.New(codes.ResourceExhausted, e.Error()).WithDetails(
+		&errdetails.ErrorInfo{Reason: e.Code()},
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(e.RetryAfter)},
+	)
+	if err != nil {
+		return status.Error(codes.ResourceExhausted, e.Error())
+	}
+	return st.Err()
+}
+
+func FromStatus(err error) (*Error, bool) {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.ResourceExhausted {
+		return nil, false
+	}
+	res := &Error{}
+	found := false
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.ErrorInfo:
+			res.Locked = d.Reason == AccountLocked
+			found = d.Reason == AccountLocked || d.Reason == TooManyAttempts
+		case *errdetails.RetryInfo:
+			res.RetryAfter = d.RetryDelay.AsDuration()
+		}
+	}
+	return res, found
+}
+
+// Store keeps the failure counters and the locks, *redis.Client does.
+type Store interface {
+	AddFailure(ctx context.Context, key string, window time.Duration) (int64, error)
+	Failures(ctx context.Context, key string, window time.Duration) (int64, time.Time, error)
+	Lock(ctx context.Context, key string, ttl time.Duration) error
+	LockTTL(ctx context.Context, key string) (time.Duration, error)
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// Guard slows down and then locks out repeated failures of one kind of
+// attempt, counted both per identifier (email or phone number) and per IP.
+// The IP must be one the caller can not choose: c.ClientIP of the router,
+// which only believes the TRUSTED_PROXIES, or the x-client-ip the gateway
+// forwards.
+type Guard struct {
+	Store        Store
+	Keys         redis.Keys
+	Scope        string
+	Window       time.Duration
+	FreeAttempts int64
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	LockAfter    int64
+	IPLockAfter  int64
+	LockDuration time.Duration
+}
+
+func newGuard(conf config.ThrottleConfig, rdb *redis.Client, scope string) *Guard {
+	return &Guard{
+		Store:        rdb,
+		Keys:         rdb.Keys,
+		Scope:        scope,
+		Window:       conf.WINDOW,
+		FreeAttempts: conf.FREE_ATTEMPTS,
+		BaseDelay:    conf.BASE_DELAY,
+		MaxDelay:     conf.MAX_DELAY,
+		LockAfter:    conf.LOCK_AFTER,
+		IPLockAfter:  conf.IP_LOCK_AFTER,
+		LockDuration: conf.LOCK_DURATION,
+	}
+}
+
+// Login guards password logins.
+func Login(conf config.ThrottleConfig, rdb *redis.Client) *Guard {
+	return newGuard(conf, rdb, "login")
+}
+
+// PasswordReset guards checking one-time codes.
+func PasswordReset(conf config.ThrottleConfig, rdb *redis.Client) *Guard {
+	return newGuard(conf, rdb, "reset")
+}
+
+// PasswordResetSend guards sending one-time codes. It counts apart from
+// PasswordReset, so requesting codes for someone can not lock them out of
+// using the one they got.
+func PasswordResetSend(conf config.ThrottleConfig, rdb *redis.Client) *Guard {
+	return newGuard(conf, rdb, "reset-send")
+}
+
+// MagicLink guards sending login links.
+func MagicLink(conf config.ThrottleConfig, rdb *redis.Client) *Guard {
+	return newGuard(conf, rdb, "magic-link")
+}
+
+func normalize(identifier string) string {
+	return strings.ToLower(strings.TrimSpace(identifier))
+}
+
+func (g *Guard) failuresKey(kind, value string) string {
+	return g.Keys.Key("throttle", g.Scope, kind, value)
+}
+
+func (g *Guard) lockKey(kind, value string) string {
+	return g.Keys.Key("lock", g.Scope, kind, value)
+}
+
+type subject struct {
+	kind, value string
+}
+
+func subjects(identifier, ip string) []subject {
+	var res []subject
+	if identifier = normalize(identifier); identifier != "" {
+		res = append(res, subject{"id", identifier})
+	}
+	if ip != "" {
+		res = append(res, subject{"ip", ip})
+	}
+	return res
+}
+
+// Check returns an *Error when the identifier or the IP is locked out or has
+// to wait after its last failure. Other errors mean Redis is unavailable,
+// callers let the attempt through then.
+func (g *Guard) Check(ctx context.Context, identifier, ip string) error {
+	for _, s := range subjects(identifier, ip) {
+		ttl, err := g.Store.LockTTL(ctx, g.lockKey(s.kind, s.value))
+		if err != nil {
+			return err
+		}
+		if ttl > 0 {
+			return &Error{Locked: true, RetryAfter: ttl}
+		}
+	}
+
+	for _, s := range subjects(identifier, ip) {
+		count, last, err := g.Store.Failures(ctx, g.failuresKey(s.kind, s.value), g.Window)
+		if err != nil {
+			return err
+		}
+		if wait := g.delay(count) - time.Since(last); count > 0 && wait > 0 {
+			return &Error{RetryAfter: wait}
+		}
+	}
+	return nil
+}
+
+// delay is the wait after count failures: nothing for the free attempts,
+// then BaseDelay doubling per failure up to MaxDelay.
+func (g *Guard) delay(count int64) time.Duration {
+	if count <= g.FreeAttempts {
+		return 0
+	}
+	delay := g.BaseDelay
+	for i := g.FreeAttempts + 1; i < count && delay < g.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > g.MaxDelay {
+		delay = g.MaxDelay
+	}
+	return delay
+}
+
+// Fail records a failed attempt. It reports whether this failure locked the
+// identifier out, so the caller can tell the account owner.
+func (g *Guard) Fail(ctx context.Context, identifier, ip string) (bool, error) {
+	locked := false
+	for _, s := range subjects(identifier, ip) {
+		count, err := g.Store.AddFailure(ctx, g.failuresKey(s.kind, s.value), g.Window)
+		if err != nil {
+			return false, err
+		}
+
+		limit := g.LockAfter
+		if s.kind == "ip" {
+			limit = g.IPLockAfter
+		}
+		if count < limit {
+			continue
+		}
+		if err := g.Store.Lock(ctx, g.lockKey(s.kind, s.value), g.LockDuration); err != nil {
+			return false, err
+		}
+		// Start over once the lock is gone
+		if err := g.Store.Delete(ctx, g.failuresKey(s.kind, s.value)); err != nil {
+			return false, err
+		}
+		locked = locked || s.kind == "id"
+	}
+	return locked, nil
+}
+
+// Reset clears the failures and the lock of an identifier, after a
+// successful attempt or through the unlock link. IP counters stay.
+func (g *Guard) Reset(ctx context.Context, identifier string) error {
+	identifier = normalize(identifier)
+	if identifier == "" {
+		return nil
+	}
+	return g.Store.Delete(ctx, g.failuresKey("id", identifier), g.lockKey("id", identifier))
+}
+
+// IsThrottled reports whether err came from Check.
+func IsThrottled(err error) (*Error, bool) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e, true
+	}
+	return nil, false
+}
@@ -0,0 +1,288 @@
+// Command admin runs operational tasks on the service's database, with the
+// same configuration as the service:
+//
+//	go run ./cmd/admin lookup ali@example.com
+//	echo "$PASSWORD" | go run ./cmd/admin set-password ali@example.com
+//	go run ./cmd/admin rotate-jwt-key > token.env
+//
+// Users are given by id, email or phone number. Every action is recorded in
+// the audit trail without an actor and with the operator's name in the user
+// agent, "admin-cli (name)", taken from -operator or $USER.
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"wegugin/api/auth"
+	"wegugin/api/middleware"
+	"wegugin/config"
+	pb "wegugin/genproto/user"
+	"wegugin/logs"
+	"wegugin/service"
+	"wegugin/storage/postgres"
+	"wegugin/storage/redis"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/metadata"
+)
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, s *service.UserService, args []string) error
+}
+
+var commands = map[string]command{
+	"create-admin": {
+		usage: "-email E -phone P -name N -surname S -birth-date DD-MM-YYYY -gender G, reads the password from stdin",
+		run:   createAdmin,
+	},
+	"set-role":        {usage: "USER admin|user", run: setRole},
+	"set-password":    {usage: "USER, reads the new password from stdin", run: setPassword},
+	"send-reset":      {usage: "USER, blocks the current password and mails a reset code", run: sendReset},
+	"lookup":          {usage: "EMAIL|PHONE", run: lookup},
+	"restore":         {usage: "USER, undoes the deletion of an account", run: restore},
+	"purge":           {usage: "[-anonymize] USER, purges a deleted account now", run: purge},
+	"revoke-sessions": {usage: "USER, ends every session of the user", run: revokeSessions},
+	"resend-email":    {usage: "restore USER | unlock EMAIL|PHONE, mails the restore or unlock link again", run: resendEmail},
+	"rotate-jwt-key":  {usage: "prints a new TOKEN_KEY and the TOKEN_OLD_KEYS to deploy with it", run: rotateJWTKey},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: admin [-operator name] <command> [arguments]\n\ncommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-16s %s\n", name, commands[name].usage)
+	}
+}
+
+func main() {
+	operator := flag.String("operator", os.Getenv("USER"), "name recorded in the audit trail")
+	flag.Usage = usage
+	flag.Parse()
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+	if *operator == "" {
+		log.Fatal("-operator is required when $USER is not set")
+	}
+
+	conf, err := config.New(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	db, err := postgres.ConnectionDb(conf.Postgres)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rdb, err := redis.New(conf.Redis)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer rdb.Close()
+
+	logger := logs.NewLogger()
+	s := service.NewUserService(postgres.NewPostgresStorage(db, conf, logger), rdb, conf, logger)
+	defer s.User.Close()
+
+	// audit takes the user agent from the metadata the gateway forwards
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		middleware.UserAgentKey, fmt.Sprintf("admin-cli (%s)", *operator)))
+	if err := cmd.run(ctx, s, flag.Args()[1:]); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// resolveUser turns an id, email or phone number into a user id.
+func resolveUser(ctx context.Context, s *service.UserService, user string) (string, error) {
+	if _, err := uuid.Parse(user); err == nil {
+		return user, nil
+	}
+	id, err := s.User.User().UserIdByLogin(ctx, user)
+	if err != nil {
+		return "", fmt.Errorf("user %s: %w", user, err)
+	}
+	return id, nil
+}
+
+// oneUser resolves the single USER argument of a command.
+func oneUser(ctx context.Context, s *service.UserService, args []string) (string, error) {
+	if len(args) != 1 {
+		return "", errors.New("expected one user")
+	}
+	return resolveUser(ctx, s, args[0])
+}
+
+// readPassword reads a line from stdin, so passwords stay out of the shell
+// history and process list.
+func readPassword() (string, error) {
+	fmt.Fprint(os.Stderr, "Password: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("reading the password: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func createAdmin(ctx context.Context, s *service.UserService, args []string) error {
+	flags := flag.NewFlagSet("create-admin", flag.ExitOnError)
+	req := &pb.RegisterReq{}
+	flags.StringVar(&req.Email, "email", "", "email")
+	flags.StringVar(&req.Phone, "phone", "", "phone number")
+	flags.StringVar(&req.Name, "name", "", "first name")
+	flags.StringVar(&req.Surname, "surname", "", "last name")
+	flags.StringVar(&req.BirthDate, "birth-date", "", "birth date, DD-MM-YYYY")
+	flags.StringVar(&req.Gender, "gender", "", "male, female or other")
+	flags.Parse(args)
+	if req.Email == "" || req.Phone == "" || req.Name == "" || req.Surname == "" {
+		return errors.New("-email, -phone, -name and -surname are required")
+	}
+
+	var err error
+	if req.Password, err = readPassword(); err != nil {
+		return err
+	}
+	id, err := s.CreateAdmin(ctx, req)
+	if err != nil {
+		return err
+	}
+	fmt.Println(id)
+	return nil
+}
+
+func setRole(ctx context.Context, s *service.UserService, args []string) error {
+	if len(args) != 2 {
+		return errors.New("expected a user and a role")
+	}
+	id, err := resolveUser(ctx, s, args[0])
+	if err != nil {
+		return err
+	}
+	_, err = s.AdminUpdateRole(ctx, &pb.UpdateRoleReq{Id: id, Role: args[1]})
+	return err
+}
+
+func setPassword(ctx context.Context, s *service.UserService, args []string) error {
+	id, err := oneUser(ctx, s, args)
+	if err != nil {
+		return err
+	}
+	password, err := readPassword()
+	if err != nil {
+		return err
+	}
+	_, err = s.UpdatePassword(ctx, &pb.UpdatePasswordReq{Id: id, Password: password})
+	return err
+}
+
+func sendReset(ctx context.Context, s *service.UserService, args []string) error {
+	id, err := oneUser(ctx, s, args)
+	if err != nil {
+		return err
+	}
+	_, err = s.AdminForcePasswordReset(ctx, &pb.UserId{Id: id})
+	return err
+}
+
+func lookup(ctx context.Context, s *service.UserService, args []string) error {
+	if len(args) != 1 {
+		return errors.New("expected an email or phone number")
+	}
+	user, err := s.LookupUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("id:       %s\nname:     %s %s\nemail:    %s\nphone:    %s\nrole:     %s\n",
+		user.Id, user.Name, user.Surname, user.Email, user.PhoneNumber, user.Role)
+	fmt.Printf("created:  %s\n", user.CreatedAt)
+	if user.DeletedAt != 0 {
+		fmt.Printf("deleted:  %d\n", user.DeletedAt)
+	}
+	return nil
+}
+
+func restore(ctx context.Context, s *service.UserService, args []string) error {
+	id, err := oneUser(ctx, s, args)
+	if err != nil {
+		return err
+	}
+	_, err = s.AdminRestoreUser(ctx, &pb.UserId{Id: id})
+	return err
+}
+
+func purge(ctx context.Context, s *service.UserService, args []string) error {
+	flags := flag.NewFlagSet("purge", flag.ExitOnError)
+	anonymize := flags.Bool("anonymize", s.Config.Account.PURGE_MODE == "anonymize",
+		"keep the row without personal data, defaults to PURGE_MODE")
+	flags.Parse(args)
+	id, err := oneUser(ctx, s, flags.Args())
+	if err != nil {
+		return err
+	}
+	return s.PurgeDeletedUser(ctx, id, *anonymize)
+}
+
+func revokeSessions(ctx context.Context, s *service.UserService, args []string) error {
+	id, err := oneUser(ctx, s, args)
+	if err != nil {
+		return err
+	}
+	return s.RevokeAllSessions(ctx, id)
+}
+
+func resendEmail(ctx context.Context, s *service.UserService, args []string) error {
+	if len(args) != 2 {
+		return errors.New("expected restore or unlock and a user")
+	}
+	switch args[0] {
+	case "restore":
+		id, err := resolveUser(ctx, s, args[1])
+		if err != nil {
+			return err
+		}
+		return s.ResendRestoreLink(ctx, id)
+	case "unlock":
+		// The lock is on the login the user typed, not on the account
+		return s.ResendUnlockLink(ctx, args[1])
+	default:
+		return fmt.Errorf("unknown email %q, expected restore or unlock", args[0])
+	}
+}
+
+// rotateJWTKey prints the settings of a new signing key. The current key
+// moves to TOKEN_OLD_KEYS, so the tokens it signed stay good until they
+// expire; the service keeps signing with the current key until it is
+// deployed with them.
+func rotateJWTKey(ctx context.Context, s *service.UserService, args []string) error {
+	if len(args) != 0 {
+		return errors.New("expected no arguments")
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	key := base64.StdEncoding.EncodeToString(b)
+	conf := s.Config.Token
+	old := append([]string{conf.TOKEN_KEY}, conf.OldKeys()...)
+
+	fmt.Printf("TOKEN_KEY=%s\nTOKEN_OLD_KEYS=%s\n", key, strings.Join(old, ","))
+	fmt.Fprintf(os.Stderr, "kid %s replaces %s; the old keys can go after %s, when the last token they sign expires\n",
+		auth.KeyId(key), auth.KeyId(conf.TOKEN_KEY), auth.TokenExpiry().Format("2006-01-02"))
+	s.RecordTokenKeyRotation(ctx, auth.KeyId(key))
+	return nil
+}
@@ -0,0 +1,59 @@
+// Command conformance runs the storage conformance suite against a backend,
+// so the in-memory storage keeps behaving like the postgres one. go test
+// runs it too, against postgres with TEST_DATABASE_URL; this command checks
+// the database of a deployment's own config:
+//
+//	go run ./cmd/conformance -backend memory -test.v
+//	go run ./cmd/conformance -backend postgres
+//
+// The postgres backend connects with the PDB_* settings. The suite only
+// touches rows it creates but leaves them behind: use a development
+// database, never production.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"regexp"
+	"testing"
+	"wegugin/config"
+	"wegugin/storage"
+	"wegugin/storage/memory"
+	"wegugin/storage/postgres"
+	"wegugin/storage/storagetest"
+)
+
+func main() {
+	testing.Init()
+	backend := flag.String("backend", "memory", "storage to check: memory or postgres")
+	flag.Parse()
+
+	var open func(t *testing.T, conf *config.Config) storage.IStorage
+	switch *backend {
+	case "memory":
+		open = func(t *testing.T, conf *config.Config) storage.IStorage { return memory.New(conf) }
+	case "postgres":
+		conf, err := config.New(os.Getenv("CONFIG_FILE"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		db, err := postgres.ConnectionDb(conf.Postgres)
+		if err != nil {
+			log.Fatal(err)
+		}
+		open = func(t *testing.T, conf *config.Config) storage.IStorage {
+			return postgres.NewPostgresStorage(db, conf, slog.Default())
+		}
+	default:
+		log.Fatal(fmt.Errorf("unknown storage backend %q", *backend))
+	}
+
+	tests := []testing.InternalTest{{
+		Name: "Storage",
+		F:    func(t *testing.T) { storagetest.Run(t, open) },
+	}}
+	testing.Main(regexp.MatchString, tests, nil, nil)
+}
@@ -1,68 +1,266 @@
 package main
 
 import (
-	"database/sql"
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"log/slog"
 	"net"
+	"net/http"
+	"os"
 	"wegugin/api"
+	"wegugin/api/auth"
+	"wegugin/api/email"
 	"wegugin/api/handler"
+	"wegugin/api/middleware"
+	"wegugin/api/ratelimit"
 	"wegugin/config"
 	pb "wegugin/genproto/user"
+	"wegugin/health"
+	"wegugin/lifecycle"
 	"wegugin/logs"
 	"wegugin/service"
+	"wegugin/storage"
+	"wegugin/storage/memory"
+	minioStorage "wegugin/storage/minio"
 	"wegugin/storage/postgres"
+	"wegugin/storage/redis"
+	"wegugin/worker"
 
+	"github.com/golang-migrate/migrate/v4"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
-var Db *sql.DB
-
 func main() {
-	listener, err := net.Listen("tcp", config.Load().Server.USER_SERVICE)
+	// The config is read and validated once, then handed to everything
+	conf, err := config.New(os.Getenv("CONFIG_FILE"))
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer listener.Close()
 
-	Db, err = postgres.ConnectionDb()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(conf, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	log.Printf("config:\n%s", conf.Redacted())
+
+	logger := logs.NewLogger()
+	app := lifecycle.New(logger)
+
+	store, checks, err := openStorage(conf, logger)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	logger := logs.NewLogger()
-	service1 := service.NewUserService(Db, logger)
+	rdb, err := redis.New(conf.Redis)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Redis may come up after the service, so a failed check only warns
+	if err := rdb.Health(context.Background()); err != nil {
+		logger.Error(err.Error())
+	}
+	service1 := service.NewUserService(store, rdb, conf, logger)
 
-	defer service1.User.Close()
+	checks = append(checks,
+		health.Check{Name: "redis", Run: rdb.Health},
+		health.Check{Name: "minio", Run: service1.Minio.Health},
+	)
+	if conf.Health.SMTP {
+		checks = append(checks, health.Check{Name: "smtp", Optional: true, Run: email.Health})
+	}
+	checker := health.NewChecker(conf.Health.CHECK_TIMEOUT, logger, checks...)
 
-	server := grpc.NewServer()
+	listener, err := net.Listen("tcp", conf.Server.USER_SERVICE)
+	if err != nil {
+		log.Fatal(err)
+	}
+	gateway, err := middleware.NewGateway()
+	if err != nil {
+		log.Fatal(err)
+	}
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		gateway.TrustRequestMeta,
+		rateLimitInterceptor(rdb, service1.Tokens, conf.Limits, logger),
+		service1.AdminInterceptor,
+	))
 	pb.RegisterUserServer(server, service1)
+	healthServer := grpchealth.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+	healthCtx, stopHealth := context.WithCancel(context.Background())
+	go checker.Serve(healthCtx, conf.Health.INTERVAL, healthServer, pb.User_ServiceDesc.ServiceName)
 
 	log.Printf("Server listening at %v", listener.Addr())
+	app.Go("grpc server", func() error { return server.Serve(listener) })
+
+	hand := NewHandler(conf, service1.User, rdb, gateway, checker)
+	httpServer := &http.Server{Addr: conf.Server.USER_ROUTER, Handler: api.Router(hand)}
+	app.Go("http server", func() error {
+		if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	})
+
+	workers := worker.Start(
+		worker.NewSavedSearchMatcher(service1.User, conf, logger),
+		worker.NewPriceDropNotifier(service1.User, conf, logger),
+		worker.NewSuspensionSync(service1.User, rdb, conf, logger),
+		worker.NewAccountPurger(service1.User, conf, logger),
+		worker.NewExportBuilder(service1.User, conf, logger),
+	)
+
+	// Stopped from the outside in: the probes report unready first, then
+	// the servers stop so nothing new comes in, then the workers, then the
+	// connections they all use
+	app.OnStop("health", conf.Shutdown.CLOSE_TIMEOUT, func(context.Context) error {
+		stopHealth()
+		checker.Drain()
+		healthServer.Shutdown()
+		return nil
+	})
+	stopServers(app, conf.Shutdown, httpServer, server)
+	app.OnStop("workers", conf.Shutdown.WORKER_TIMEOUT, func(ctx context.Context) error {
+		// The mails the RPCs send after answering need the connections too
+		return errors.Join(workers.Stop(ctx), service1.Wait(ctx))
+	})
+	app.OnStop("storage", conf.Shutdown.CLOSE_TIMEOUT, func(context.Context) error {
+		service1.User.Close()
+		return nil
+	})
+	app.OnStop("redis", conf.Shutdown.CLOSE_TIMEOUT, func(context.Context) error {
+		return rdb.Close()
+	})
+
+	if err := app.Run(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// stopServers adds the shutdown of the servers to app. The HTTP server goes
+// first: its handlers call the gRPC server, which keeps answering until the
+// requests in flight are done.
+func stopServers(app *lifecycle.App, conf config.ShutdownConfig, httpServer *http.Server, server *grpc.Server) {
+	app.OnStop("http server", conf.HTTP_TIMEOUT, httpServer.Shutdown)
+	app.OnStop("grpc server", conf.GRPC_TIMEOUT, func(ctx context.Context) error {
+		return gracefulStop(ctx, server)
+	})
+}
+
+// gracefulStop lets the RPCs in flight finish, and cuts them off when ctx
+// is done first.
+func gracefulStop(ctx context.Context, server *grpc.Server) error {
+	done := make(chan struct{})
 	go func() {
-		err := server.Serve(listener)
+		server.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		server.Stop()
+		return fmt.Errorf("cut off the RPCs in flight: %w", ctx.Err())
+	}
+}
+
+// openStorage connects the backend chosen with STORAGE_BACKEND and returns
+// its readiness checks with it.
+func openStorage(conf *config.Config, logger *slog.Logger) (storage.IStorage, []health.Check, error) {
+	switch conf.Storage.BACKEND {
+	case "postgres":
+		if err := migrateOnStart(conf); err != nil {
+			return nil, nil, err
+		}
+		db, err := postgres.ConnectionDb(conf.Postgres)
 		if err != nil {
-			log.Fatal(err)
+			return nil, nil, err
 		}
-	}()
+		return postgres.NewPostgresStorage(db, conf, logger), []health.Check{
+			{Name: "postgres", Run: db.PingContext},
+			{Name: "migrations", Run: func(ctx context.Context) error { return postgres.CheckSchemaContext(ctx, db) }},
+		}, nil
+	case "memory":
+		return memory.New(conf), nil, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown storage backend %q", conf.Storage.BACKEND)
+	}
+}
+
+// migrateOnStart applies pending migrations if MIGRATE_ON_START is set and
+// fails unless the schema is up to date. The migrator closes its database,
+// so it gets a connection of its own.
+func migrateOnStart(conf *config.Config) error {
+	db, err := postgres.ConnectionDb(conf.Postgres)
+	if err != nil {
+		return err
+	}
+	m, err := postgres.NewMigrator(db, conf.Storage.MIGRATE_LOCK_TIMEOUT)
+	if err != nil {
+		db.Close()
+		return err
+	}
+	defer m.Close()
+	m.Log = migrateLog{}
 
-	hand := NewHandler()
-	router := api.Router(hand)
-	err = router.Run(config.Load().Server.USER_ROUTER)
+	if conf.Storage.MIGRATE_ON_START {
+		if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+			return fmt.Errorf("migrating the database: %w", err)
+		}
+	}
+	err = postgres.CheckSchema(m)
+	if errors.Is(err, postgres.ErrSchemaBehind) {
+		return fmt.Errorf("%w: run myapp migrate up or set MIGRATE_ON_START=true", err)
+	}
+	return err
+}
+
+func rateLimitInterceptor(rdb *redis.Client, tokens *auth.Tokens, limits config.RateLimitConfig, logger *slog.Logger) grpc.UnaryServerInterceptor {
+	fallback, err := ratelimit.ParsePolicy("grpc", limits.GRPC, ratelimit.ByAPIKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+	profile, err := ratelimit.ParsePolicy("grpc_profile", limits.GRPC_PROFILE, ratelimit.ByAPIKey)
 	if err != nil {
 		log.Fatal(err)
 	}
+
+	limiter := ratelimit.New(rdb, func(err error) {
+		logger.Error(fmt.Sprintf("rate limiter falls back to memory: %v", err))
+	})
+	return ratelimit.UnaryServerInterceptor(limiter, ratelimit.NewCallers(tokens, limits.API_KEYS), map[string]ratelimit.Policy{
+		pb.User_GetUserById_FullMethodName:   profile,
+		pb.User_GetUsersByIds_FullMethodName: profile,
+		// The zero policy leaves the load balancers' probes unlimited
+		healthpb.Health_Check_FullMethodName: {},
+	}, fallback)
 }
 
-func NewHandler() *handler.Handler {
+func NewHandler(conf *config.Config, store storage.IStorage, rdb *redis.Client, gateway *middleware.Gateway, checker *health.Checker) *handler.Handler {
+	tokens := auth.New(conf.Token)
 
-	conn, err := grpc.NewClient(config.Load().Server.USER_SERVICE, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.NewClient(conf.Server.USER_SERVICE,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(gateway.ForwardRequestMeta))
 	if err != nil {
 		log.Println("error while connecting authentication service ", err)
 	}
 
 	return &handler.Handler{
-		User: pb.NewUserClient(conn),
-		Log:  logs.NewLogger(),
+		User:   pb.NewUserClient(conn),
+		Tokens: tokens,
+		Redis:  rdb,
+		Minio:  minioStorage.New(conf.Minio),
+		Email:  email.NewSender(conf.Email),
+		Auth:   &middleware.Auth{Tokens: tokens, Redis: rdb, Store: store},
+		Config: conf,
+		Log:    logs.NewLogger(),
+		Health: checker,
 	}
 }
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+	"wegugin/config"
+	"wegugin/lifecycle"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// TestInFlightRequestSurvivesSIGTERM sends SIGTERM while an HTTP request is
+// being handled, before it makes its RPC, and expects the request to get
+// its answer from the gRPC server all the same.
+func TestInFlightRequestSurvivesSIGTERM(t *testing.T) {
+	// Keeps the signal from killing the test if Run is not listening yet
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	app := lifecycle.New(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	grpcListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	healthpb.RegisterHealthServer(server, grpchealth.NewServer())
+	app.Go("grpc server", func() error { return server.Serve(grpcListener) })
+
+	conn, err := grpc.NewClient(grpcListener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+	// The gateway's connection is up before the shutdown, as it is in main
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+
+	inFlight := make(chan struct{})
+	httpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpServer := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(inFlight)
+		time.Sleep(200 * time.Millisecond)
+		if _, err := client.Check(r.Context(), &healthpb.HealthCheckRequest{}); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})}
+	app.Go("http server", func() error {
+		if err := httpServer.Serve(httpListener); err != http.ErrServerClosed {
+			return err
+		}
+		return nil
+	})
+
+	stopServers(app, config.ShutdownConfig{HTTP_TIMEOUT: 5 * time.Second, GRPC_TIMEOUT: 5 * time.Second}, httpServer, server)
+	stopped := make(chan error, 1)
+	go func() { stopped <- app.Run(context.Background()) }()
+
+	answered := make(chan *http.Response, 1)
+	go func() {
+		res, err := http.Get("http://" + httpListener.Addr().String())
+		if err != nil {
+			t.Error(err)
+			close(answered)
+			return
+		}
+		answered <- res
+	}()
+
+	<-inFlight
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case res, ok := <-answered:
+		if ok {
+			body, _ := io.ReadAll(res.Body)
+			res.Body.Close()
+			if res.StatusCode != http.StatusOK {
+				t.Errorf("request in flight: %d %s, want 200", res.StatusCode, body)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the request in flight got no answer")
+	}
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Errorf("shutdown: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the app did not shut down")
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"wegugin/config"
+	"wegugin/migrations"
+	"wegugin/storage/postgres"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+const migrateUsage = `usage: myapp migrate [-dir migrations] <command>
+
+commands:
+  up [N]       apply all pending migrations, or the next N
+  down [N]     roll back the last N migrations, one by default
+  status       list the migrations and whether they are applied
+  force V      mark version V as applied and clean, after fixing a failed migration
+  create NAME  add an empty migration to the directory given with -dir
+`
+
+// migrateLog prints the progress of the migrator.
+type migrateLog struct{}
+
+func (migrateLog) Printf(format string, v ...interface{}) { log.Printf(format, v...) }
+func (migrateLog) Verbose() bool                          { return false }
+
+// runMigrate runs the migrate subcommand, all but create on the database
+// of the PDB_* settings or DATABASE_URL.
+func runMigrate(conf *config.Config, args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dir := flags.String("dir", "migrations", "migrations directory, for create")
+	flags.Usage = func() { fmt.Fprint(flags.Output(), migrateUsage) }
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	command, args := flags.Arg(0), flags.Args()[1:]
+
+	if command == "create" {
+		if len(args) != 1 {
+			return errors.New("migrate create needs a name")
+		}
+		return createMigration(*dir, args[0])
+	}
+
+	db, err := postgres.ConnectionDb(conf.Postgres)
+	if err != nil {
+		return err
+	}
+	m, err := postgres.NewMigrator(db, conf.Storage.MIGRATE_LOCK_TIMEOUT)
+	if err != nil {
+		db.Close()
+		return err
+	}
+	defer m.Close()
+	m.Log = migrateLog{}
+
+	switch command {
+	case "up":
+		n, err := stepsArg(args, 0)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			err = m.Up()
+		} else {
+			err = m.Steps(n)
+		}
+		return noChange(err)
+	case "down":
+		n, err := stepsArg(args, 1)
+		if err != nil {
+			return err
+		}
+		return noChange(m.Steps(-n))
+	case "status":
+		return migrationStatus(m)
+	case "force":
+		if len(args) != 1 {
+			return errors.New("migrate force needs a version")
+		}
+		version, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[0])
+		}
+		return m.Force(version)
+	default:
+		flags.Usage()
+		return fmt.Errorf("unknown migrate command %q", command)
+	}
+}
+
+// stepsArg returns the optional step count of up and down.
+func stepsArg(args []string, def int) (int, error) {
+	if len(args) == 0 {
+		return def, nil
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n <= 0 || len(args) > 1 {
+		return 0, fmt.Errorf("invalid number of migrations %q", strings.Join(args, " "))
+	}
+	return n, nil
+}
+
+func noChange(err error) error {
+	if errors.Is(err, migrate.ErrNoChange) {
+		log.Println("no change")
+		return nil
+	}
+	return err
+}
+
+func migrationStatus(m *migrate.Migrate) error {
+	list, err := migrations.List(migrations.FS)
+	if err != nil {
+		return err
+	}
+	version, dirty, err := postgres.SchemaVersion(m)
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range list {
+		state := "pending"
+		switch {
+		case migration.Version == version && dirty:
+			state = "dirty"
+		case migration.Version <= version:
+			state = "applied"
+		}
+		fmt.Printf("%06d  %-8s %s\n", migration.Version, state, migration.Name)
+	}
+	if len(list) > 0 && version > list[len(list)-1].Version {
+		fmt.Printf("the database is at version %d, newer than this build\n", version)
+	}
+	return nil
+}
+
+var migrationName = regexp.MustCompile(`[^a-z0-9]+`)
+
+// createMigration adds empty up and down files numbered after the last
+// migration in dir.
+func createMigration(dir, name string) error {
+	name = strings.Trim(migrationName.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return errors.New("the migration name needs letters or digits")
+	}
+	list, err := migrations.List(os.DirFS(dir))
+	if err != nil {
+		return err
+	}
+	var version uint = 1
+	if len(list) > 0 {
+		version = list[len(list)-1].Version + 1
+	}
+
+	for _, direction := range []string{"up", "down"} {
+		path := filepath.Join(dir, fmt.Sprintf("%06d_%s.%s.sql", version, name, direction))
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
+		}
+		file.Close()
+		fmt.Println(path)
+	}
+	return nil
+}
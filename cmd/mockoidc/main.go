@@ -0,0 +1,230 @@
+// Command mockoidc is an OpenID Connect provider for trying social login
+// locally. It signs everybody in without asking, as the email given in the
+// login_hint parameter or -email. Point a provider at it with, for example,
+// OIDC_GOOGLE_ISSUER=http://localhost:9999 and any OIDC_GOOGLE_CLIENT_ID.
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"html/template"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const keyId = "mock"
+
+type grant struct {
+	clientId    string
+	redirectURI string
+	nonce       string
+	challenge   string
+	email       string
+}
+
+type server struct {
+	issuer   string
+	email    string
+	verified bool
+	key      *rsa.PrivateKey
+
+	mu     sync.Mutex
+	codes  map[string]grant
+	tokens map[string]string
+}
+
+var formPost = template.Must(template.New("form").Parse(`<html><body onload="document.forms[0].submit()">
+<form method="post" action="{{.Action}}">
+<input type="hidden" name="code" value="{{.Code}}">
+<input type="hidden" name="state" value="{{.State}}">
+</form></body></html>`))
+
+func main() {
+	addr := flag.String("addr", ":9999", "listen address")
+	issuer := flag.String("issuer", "http://localhost:9999", "issuer, the url this server is reached at")
+	email := flag.String("email", "user@example.com", "email of the signed in user when there is no login_hint")
+	verified := flag.Bool("verified", true, "whether the email is verified")
+	flag.Parse()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s := &server{
+		issuer:   *issuer,
+		email:    *email,
+		verified: *verified,
+		key:      key,
+		codes:    map[string]grant{},
+		tokens:   map[string]string{},
+	}
+
+	http.HandleFunc("/.well-known/openid-configuration", s.discovery)
+	http.HandleFunc("/authorize", s.authorize)
+	http.HandleFunc("/token", s.token)
+	http.HandleFunc("/userinfo", s.userinfo)
+	http.HandleFunc("/jwks", s.jwks)
+	log.Printf("mock OIDC provider %s listening on %s", s.issuer, *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
+
+func (s *server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                 s.issuer,
+		"authorization_endpoint": s.issuer + "/authorize",
+		"token_endpoint":         s.issuer + "/token",
+		"userinfo_endpoint":      s.issuer + "/userinfo",
+		"jwks_uri":               s.issuer + "/jwks",
+	})
+}
+
+func (s *server) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	redirectURI := q.Get("redirect_uri")
+	if redirectURI == "" || q.Get("code_challenge_method") != "S256" {
+		http.Error(w, "redirect_uri and an S256 code_challenge are required", http.StatusBadRequest)
+		return
+	}
+	email := q.Get("login_hint")
+	if email == "" {
+		email = s.email
+	}
+
+	code := randomString()
+	s.mu.Lock()
+	s.codes[code] = grant{
+		clientId:    q.Get("client_id"),
+		redirectURI: redirectURI,
+		nonce:       q.Get("nonce"),
+		challenge:   q.Get("code_challenge"),
+		email:       email,
+	}
+	s.mu.Unlock()
+
+	if q.Get("response_mode") == "form_post" {
+		formPost.Execute(w, map[string]string{"Action": redirectURI, "Code": code, "State": q.Get("state")})
+		return
+	}
+	target, err := url.Parse(redirectURI)
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	params := target.Query()
+	params.Set("code", code)
+	params.Set("state", q.Get("state"))
+	target.RawQuery = params.Encode()
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+func (s *server) token(w http.ResponseWriter, r *http.Request) {
+	code := r.PostFormValue("code")
+	s.mu.Lock()
+	g, ok := s.codes[code]
+	delete(s.codes, code)
+	s.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	switch {
+	case !ok:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "unknown code"})
+		return
+	case g.clientId != r.PostFormValue("client_id") || g.redirectURI != r.PostFormValue("redirect_uri"):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "client or redirect_uri mismatch"})
+		return
+	case base64.RawURLEncoding.EncodeToString(sum[:]) != g.challenge:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "code_verifier mismatch"})
+		return
+	}
+
+	now := time.Now()
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            s.issuer,
+		"aud":            g.clientId,
+		"sub":            subject(g.email),
+		"email":          g.email,
+		"email_verified": s.verified,
+		"nonce":          g.nonce,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+	})
+	idToken.Header["kid"] = keyId
+	signed, err := idToken.SignedString(s.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	accessToken := randomString()
+	s.mu.Lock()
+	s.tokens[accessToken] = g.email
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     signed,
+	})
+}
+
+func (s *server) userinfo(w http.ResponseWriter, r *http.Request) {
+	const prefix = "Bearer "
+	header := r.Header.Get("Authorization")
+	s.mu.Lock()
+	email, ok := "", false
+	if len(header) > len(prefix) {
+		email, ok = s.tokens[header[len(prefix):]]
+	}
+	s.mu.Unlock()
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_token"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"sub":            subject(email),
+		"email":          email,
+		"email_verified": s.verified,
+	})
+}
+
+func (s *server) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := s.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kid": keyId,
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+// subject keeps the same user id at the provider for the same email.
+func subject(email string) string {
+	sum := sha256.Sum256([]byte(email))
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
+
+func randomString() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
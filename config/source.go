@@ -0,0 +1,134 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/spf13/cast"
+	"gopkg.in/yaml.v3"
+)
+
+// source looks settings up by their environment variable name. A value
+// comes from, in order: the environment, the .env file, the YAML file and
+// the default. At each layer KEY_FILE names a file holding the value, for
+// secrets mounted by Docker or Kubernetes.
+type source struct {
+	file map[string]string
+	errs []error
+}
+
+// newSource reads the .env file into the environment, without overriding
+// variables that are set, and the flat YAML file at path, if any.
+func newSource(path string) (*source, error) {
+	if err := godotenv.Load(".env"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("reading .env: %w", err)
+	}
+
+	s := &source{file: map[string]string{}}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for key, value := range values {
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			return nil, fmt.Errorf("%s: %s must be a single value, the file uses the environment variable names", path, key)
+		case nil:
+			s.file[key] = ""
+		default:
+			s.file[key] = fmt.Sprint(value)
+		}
+	}
+	return s, nil
+}
+
+// lookup returns the value of key from the first layer that sets it.
+func (s *source) lookup(key string) (string, bool) {
+	if value, ok := os.LookupEnv(key); ok {
+		return value, true
+	}
+	if path, ok := os.LookupEnv(key + "_FILE"); ok {
+		return s.readFile(key, path)
+	}
+	if value, ok := s.file[key]; ok {
+		return value, true
+	}
+	if path, ok := s.file[key+"_FILE"]; ok {
+		return s.readFile(key, path)
+	}
+	return "", false
+}
+
+func (s *source) readFile(key, path string) (string, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		s.errs = append(s.errs, fmt.Errorf("%s_FILE: %w", key, err))
+		return "", false
+	}
+	return strings.TrimRight(string(data), "\r\n"), true
+}
+
+func (s *source) string(key, def string) string {
+	if value, ok := s.lookup(key); ok {
+		return value
+	}
+	return def
+}
+
+// parse converts the value of key with to, recording an error instead of
+// silently using the zero value like cast does.
+func parse[T any](s *source, key, def string, to func(interface{}) (T, error)) T {
+	value, err := to(s.string(key, def))
+	if err != nil {
+		s.errs = append(s.errs, fmt.Errorf("%s: %w", key, err))
+	}
+	return value
+}
+
+func (s *source) int(key, def string) int {
+	return parse(s, key, def, cast.ToIntE)
+}
+
+func (s *source) int64(key, def string) int64 {
+	return parse(s, key, def, cast.ToInt64E)
+}
+
+func (s *source) uint32(key, def string) uint32 {
+	return parse(s, key, def, cast.ToUint32E)
+}
+
+func (s *source) uint8(key, def string) uint8 {
+	return parse(s, key, def, cast.ToUint8E)
+}
+
+func (s *source) float64(key, def string) float64 {
+	return parse(s, key, def, cast.ToFloat64E)
+}
+
+func (s *source) bool(key, def string) bool {
+	return parse(s, key, def, cast.ToBoolE)
+}
+
+func (s *source) duration(key, def string) time.Duration {
+	return parse(s, key, def, cast.ToDurationE)
+}
+
+// port returns key as a listen address, ":8080" for "8080" as well.
+func (s *source) port(key, def string) string {
+	port := s.string(key, def)
+	if port != "" && port[0] != ':' {
+		return ":" + port
+	}
+	return port
+}
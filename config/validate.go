@@ -0,0 +1,119 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// The development defaults of secrets and addresses, refused in production.
+const (
+	defaultTokenKey      = "your_secret_key"
+	defaultPDBPassword   = "3333"
+	defaultMinioKey      = "access_key"
+	defaultSenderEmail   = "your_email@example.com"
+	defaultEmailPassword = "your_password"
+	defaultPublicURL     = "http://localhost:8080"
+)
+
+// Validate checks the settings the service can not run with, and in
+// production the development defaults.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	oneOf := func(name, value string, allowed ...string) {
+		for _, a := range allowed {
+			if value == a {
+				return
+			}
+		}
+		errs = append(errs, fmt.Errorf("%s must be one of %s, not %q", name, strings.Join(allowed, ", "), value))
+	}
+	positive := func(name string, d time.Duration) {
+		check(d > 0, "%s must be positive, not %s", name, d)
+	}
+
+	oneOf("APP_ENV", c.App.ENV, "development", "production")
+	oneOf("STORAGE_BACKEND", c.Storage.BACKEND, "postgres", "memory")
+	oneOf("RDB_MODE", c.Redis.RDB_MODE, "single", "sentinel", "cluster")
+	oneOf("ACCOUNT_PURGE_MODE", c.Account.PURGE_MODE, "delete", "anonymize")
+	oneOf("PASSWORD_HASH_ALGORITHM", c.Password.HASH_ALGORITHM, "argon2id", "bcrypt")
+
+	check(c.Server.BATCH_MAX_IDS > 0, "BATCH_MAX_IDS must be positive")
+	for _, proxy := range c.Server.TrustedProxies() {
+		_, _, err := net.ParseCIDR(proxy)
+		check(err == nil || net.ParseIP(proxy) != nil, "TRUSTED_PROXIES: %q is not an IP or CIDR", proxy)
+	}
+	check(c.Password.MIN_LENGTH > 0, "PASSWORD_MIN_LENGTH must be positive")
+	check(c.Password.BCRYPT_COST >= 4 && c.Password.BCRYPT_COST <= 31, "BCRYPT_COST must be between 4 and 31")
+	check(c.Password.ARGON2_MEMORY > 0 && c.Password.ARGON2_TIME > 0 && c.Password.ARGON2_THREADS > 0,
+		"ARGON2_MEMORY, ARGON2_TIME and ARGON2_THREADS must be positive")
+	// The workers tick at these intervals
+	positive("SAVED_SEARCH_INTERVAL", c.Worker.SAVED_SEARCH_INTERVAL)
+	positive("PRICE_DROP_INTERVAL", c.Worker.PRICE_DROP_INTERVAL)
+	check(c.Worker.SAVED_SEARCH_LAG >= 0, "SAVED_SEARCH_LAG must not be negative")
+	positive("SUSPENSION_SYNC_INTERVAL", c.Worker.SUSPENSION_SYNC_INTERVAL)
+	positive("ACCOUNT_PURGE_INTERVAL", c.Account.PURGE_INTERVAL)
+	positive("EXPORT_INTERVAL", c.Export.EXPORT_INTERVAL)
+	positive("EXPORT_LEASE", c.Export.EXPORT_LEASE)
+	positive("MIGRATE_LOCK_TIMEOUT", c.Storage.MIGRATE_LOCK_TIMEOUT)
+	positive("SHUTDOWN_GRPC_TIMEOUT", c.Shutdown.GRPC_TIMEOUT)
+	positive("SHUTDOWN_HTTP_TIMEOUT", c.Shutdown.HTTP_TIMEOUT)
+	positive("SHUTDOWN_WORKER_TIMEOUT", c.Shutdown.WORKER_TIMEOUT)
+	positive("SHUTDOWN_CLOSE_TIMEOUT", c.Shutdown.CLOSE_TIMEOUT)
+	positive("HEALTH_CHECK_TIMEOUT", c.Health.CHECK_TIMEOUT)
+	positive("HEALTH_INTERVAL", c.Health.INTERVAL)
+
+	if c.App.ENV == "production" {
+		check(c.Token.TOKEN_KEY != defaultTokenKey && len(c.Token.TOKEN_KEY) >= 32,
+			"TOKEN_KEY must be set to at least 32 random characters in production")
+		for _, key := range c.Token.OldKeys() {
+			check(key != defaultTokenKey && len(key) >= 32,
+				"TOKEN_OLD_KEYS must be at least 32 random characters each in production")
+		}
+		check(c.Storage.BACKEND == "postgres", "STORAGE_BACKEND must be postgres in production")
+		check(c.Postgres.PDB_PASSWORD != defaultPDBPassword, "PDB_PASSWORD must be set in production")
+		check(c.Minio.MINIO_ACCESS_KEY_ID != defaultMinioKey && c.Minio.MINIO_SECRET_ACCESS_KEY != defaultMinioKey,
+			"MINIO_ACCESS_KEY_ID and MINIO_SECRET_ACCESS_KEY must be set in production")
+		check(c.Email.SENDER_EMAIL != defaultSenderEmail && c.Email.APP_PASSWORD != defaultEmailPassword,
+			"SENDER_EMAIL and APP_PASSWORD must be set in production")
+		check(c.Server.PUBLIC_URL != defaultPublicURL, "PUBLIC_URL must be set in production")
+	}
+
+	return errors.Join(errs...)
+}
+
+// Redacted lists the settings one per line for the startup log, with the
+// fields tagged secret masked.
+func (c *Config) Redacted() string {
+	var b strings.Builder
+	sections := reflect.ValueOf(c).Elem()
+	for i := 0; i < sections.NumField(); i++ {
+		section := sections.Field(i)
+		for j := 0; j < section.NumField(); j++ {
+			field := section.Type().Field(j)
+			value := fmt.Sprint(section.Field(j).Interface())
+			if field.Tag.Get("secret") == "true" && value != "" {
+				value = redact(value)
+			}
+			fmt.Fprintf(&b, "%s.%s=%s\n", sections.Type().Field(i).Name, field.Name, value)
+		}
+	}
+	return b.String()
+}
+
+// redact masks a secret, URLs keep everything but the password.
+func redact(value string) string {
+	if u, err := url.Parse(value); err == nil && u.User != nil {
+		return u.Redacted()
+	}
+	return "***"
+}
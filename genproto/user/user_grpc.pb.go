@@ -19,16 +19,52 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	User_Register_FullMethodName        = "/user.User/Register"
-	User_Login_FullMethodName           = "/user.User/Login"
-	User_GetUSerByEmail_FullMethodName  = "/user.User/GetUSerByEmail"
-	User_GetUserById_FullMethodName     = "/user.User/GetUserById"
-	User_UpdatePassword_FullMethodName  = "/user.User/UpdatePassword"
-	User_ResetPassword_FullMethodName   = "/user.User/ResetPassword"
-	User_UpdateUser_FullMethodName      = "/user.User/UpdateUser"
-	User_DeleteUser_FullMethodName      = "/user.User/DeleteUser"
-	User_IsUserExist_FullMethodName     = "/user.User/IsUserExist"
-	User_DeleteMediaUser_FullMethodName = "/user.User/DeleteMediaUser"
+	User_Register_FullMethodName                = "/user.User/Register"
+	User_Login_FullMethodName                   = "/user.User/Login"
+	User_GetUSerByEmail_FullMethodName          = "/user.User/GetUSerByEmail"
+	User_GetUserById_FullMethodName             = "/user.User/GetUserById"
+	User_UpdatePassword_FullMethodName          = "/user.User/UpdatePassword"
+	User_ResetPassword_FullMethodName           = "/user.User/ResetPassword"
+	User_UpdateUser_FullMethodName              = "/user.User/UpdateUser"
+	User_DeleteUser_FullMethodName              = "/user.User/DeleteUser"
+	User_IsUserExist_FullMethodName             = "/user.User/IsUserExist"
+	User_DeleteMediaUser_FullMethodName         = "/user.User/DeleteMediaUser"
+	User_CreateSavedSearch_FullMethodName       = "/user.User/CreateSavedSearch"
+	User_GetSavedSearch_FullMethodName          = "/user.User/GetSavedSearch"
+	User_ListSavedSearches_FullMethodName       = "/user.User/ListSavedSearches"
+	User_UpdateSavedSearch_FullMethodName       = "/user.User/UpdateSavedSearch"
+	User_DeleteSavedSearch_FullMethodName       = "/user.User/DeleteSavedSearch"
+	User_GetCarPriceHistory_FullMethodName      = "/user.User/GetCarPriceHistory"
+	User_AdminListUsers_FullMethodName          = "/user.User/AdminListUsers"
+	User_AdminGetUser_FullMethodName            = "/user.User/AdminGetUser"
+	User_AdminUpdateRole_FullMethodName         = "/user.User/AdminUpdateRole"
+	User_AdminForcePasswordReset_FullMethodName = "/user.User/AdminForcePasswordReset"
+	User_AdminRestoreUser_FullMethodName        = "/user.User/AdminRestoreUser"
+	User_AdminHardDeleteUser_FullMethodName     = "/user.User/AdminHardDeleteUser"
+	User_AdminSuspendUser_FullMethodName        = "/user.User/AdminSuspendUser"
+	User_AdminLiftSuspension_FullMethodName     = "/user.User/AdminLiftSuspension"
+	User_AdminListSuspensions_FullMethodName    = "/user.User/AdminListSuspensions"
+	User_RestoreAccount_FullMethodName          = "/user.User/RestoreAccount"
+	User_RequestDataExport_FullMethodName       = "/user.User/RequestDataExport"
+	User_GetDataExport_FullMethodName           = "/user.User/GetDataExport"
+	User_AdminListAuditEvents_FullMethodName    = "/user.User/AdminListAuditEvents"
+	User_ListSecurityActivity_FullMethodName    = "/user.User/ListSecurityActivity"
+	User_ListSessions_FullMethodName            = "/user.User/ListSessions"
+	User_RevokeSession_FullMethodName           = "/user.User/RevokeSession"
+	User_UnlockLogin_FullMethodName             = "/user.User/UnlockLogin"
+	User_StartOIDC_FullMethodName               = "/user.User/StartOIDC"
+	User_FinishOIDC_FullMethodName              = "/user.User/FinishOIDC"
+	User_ListIdentities_FullMethodName          = "/user.User/ListIdentities"
+	User_UnlinkIdentity_FullMethodName          = "/user.User/UnlinkIdentity"
+	User_RequestMagicLink_FullMethodName        = "/user.User/RequestMagicLink"
+	User_ConsumeMagicLink_FullMethodName        = "/user.User/ConsumeMagicLink"
+	User_RequestEmailChange_FullMethodName      = "/user.User/RequestEmailChange"
+	User_ConfirmEmailChange_FullMethodName      = "/user.User/ConfirmEmailChange"
+	User_RevertEmailChange_FullMethodName       = "/user.User/RevertEmailChange"
+	User_GetPublicProfile_FullMethodName        = "/user.User/GetPublicProfile"
+	User_GetPrivacySettings_FullMethodName      = "/user.User/GetPrivacySettings"
+	User_UpdatePrivacySettings_FullMethodName   = "/user.User/UpdatePrivacySettings"
+	User_GetUsersByIds_FullMethodName           = "/user.User/GetUsersByIds"
 )
 
 // UserClient is the client API for User service.
@@ -45,6 +81,42 @@ type UserClient interface {
 	DeleteUser(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*Void, error)
 	IsUserExist(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*Void, error)
 	DeleteMediaUser(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*Void, error)
+	CreateSavedSearch(ctx context.Context, in *CreateSavedSearchReq, opts ...grpc.CallOption) (*SavedSearch, error)
+	GetSavedSearch(ctx context.Context, in *SavedSearchId, opts ...grpc.CallOption) (*SavedSearch, error)
+	ListSavedSearches(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*SavedSearchList, error)
+	UpdateSavedSearch(ctx context.Context, in *UpdateSavedSearchReq, opts ...grpc.CallOption) (*Void, error)
+	DeleteSavedSearch(ctx context.Context, in *SavedSearchId, opts ...grpc.CallOption) (*Void, error)
+	GetCarPriceHistory(ctx context.Context, in *CarId, opts ...grpc.CallOption) (*PriceHistory, error)
+	AdminListUsers(ctx context.Context, in *AdminListUsersReq, opts ...grpc.CallOption) (*AdminUserList, error)
+	AdminGetUser(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*AdminUser, error)
+	AdminUpdateRole(ctx context.Context, in *UpdateRoleReq, opts ...grpc.CallOption) (*Void, error)
+	AdminForcePasswordReset(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*Void, error)
+	AdminRestoreUser(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*Void, error)
+	AdminHardDeleteUser(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*Void, error)
+	AdminSuspendUser(ctx context.Context, in *SuspendUserReq, opts ...grpc.CallOption) (*Suspension, error)
+	AdminLiftSuspension(ctx context.Context, in *LiftSuspensionReq, opts ...grpc.CallOption) (*Void, error)
+	AdminListSuspensions(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*SuspensionList, error)
+	RestoreAccount(ctx context.Context, in *RestoreAccountReq, opts ...grpc.CallOption) (*LoginRes, error)
+	RequestDataExport(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*DataExport, error)
+	GetDataExport(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*DataExport, error)
+	AdminListAuditEvents(ctx context.Context, in *AuditEventFilter, opts ...grpc.CallOption) (*AuditEventList, error)
+	ListSecurityActivity(ctx context.Context, in *AuditEventFilter, opts ...grpc.CallOption) (*AuditEventList, error)
+	ListSessions(ctx context.Context, in *SessionReq, opts ...grpc.CallOption) (*SessionList, error)
+	RevokeSession(ctx context.Context, in *SessionReq, opts ...grpc.CallOption) (*Void, error)
+	UnlockLogin(ctx context.Context, in *UnlockLoginReq, opts ...grpc.CallOption) (*Void, error)
+	StartOIDC(ctx context.Context, in *OIDCStartReq, opts ...grpc.CallOption) (*OIDCStartRes, error)
+	FinishOIDC(ctx context.Context, in *OIDCFinishReq, opts ...grpc.CallOption) (*OIDCFinishRes, error)
+	ListIdentities(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*IdentityList, error)
+	UnlinkIdentity(ctx context.Context, in *IdentityReq, opts ...grpc.CallOption) (*Void, error)
+	RequestMagicLink(ctx context.Context, in *MagicLinkReq, opts ...grpc.CallOption) (*Void, error)
+	ConsumeMagicLink(ctx context.Context, in *ConsumeMagicLinkReq, opts ...grpc.CallOption) (*LoginRes, error)
+	RequestEmailChange(ctx context.Context, in *EmailChangeReq, opts ...grpc.CallOption) (*Void, error)
+	ConfirmEmailChange(ctx context.Context, in *EmailChangeTokenReq, opts ...grpc.CallOption) (*Void, error)
+	RevertEmailChange(ctx context.Context, in *EmailChangeTokenReq, opts ...grpc.CallOption) (*Void, error)
+	GetPublicProfile(ctx context.Context, in *PublicProfileReq, opts ...grpc.CallOption) (*PublicProfile, error)
+	GetPrivacySettings(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*PrivacySettings, error)
+	UpdatePrivacySettings(ctx context.Context, in *PrivacySettings, opts ...grpc.CallOption) (*Void, error)
+	GetUsersByIds(ctx context.Context, in *GetUsersByIdsReq, opts ...grpc.CallOption) (*GetUsersByIdsRes, error)
 }
 
 type userClient struct {
@@ -155,6 +227,366 @@ func (c *userClient) DeleteMediaUser(ctx context.Context, in *UserId, opts ...gr
 	return out, nil
 }
 
+func (c *userClient) CreateSavedSearch(ctx context.Context, in *CreateSavedSearchReq, opts ...grpc.CallOption) (*SavedSearch, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SavedSearch)
+	err := c.cc.Invoke(ctx, User_CreateSavedSearch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userClient) GetSavedSearch(ctx context.Context, in *SavedSearchId, opts ...grpc.CallOption) (*SavedSearch, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SavedSearch)
+	err := c.cc.Invoke(ctx, User_GetSavedSearch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userClient) ListSavedSearches(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*SavedSearchList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SavedSearchList)
+	err := c.cc.Invoke(ctx, User_ListSavedSearches_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userClient) UpdateSavedSearch(ctx context.Context, in *UpdateSavedSearchReq, opts ...grpc.CallOption) (*Void, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Void)
+	err := c.cc.Invoke(ctx, User_UpdateSavedSearch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userClient) DeleteSavedSearch(ctx context.Context, in *SavedSearchId, opts ...grpc.CallOption) (*Void, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Void)
+	err := c.cc.Invoke(ctx, User_DeleteSavedSearch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userClient) GetCarPriceHistory(ctx context.Context, in *CarId, opts ...grpc.CallOption) (*PriceHistory, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PriceHistory)
+	err := c.cc.Invoke(ctx, User_GetCarPriceHistory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userClient) AdminListUsers(ctx context.Context, in *AdminListUsersReq, opts ...grpc.CallOption) (*AdminUserList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AdminUserList)
+	err := c.cc.Invoke(ctx, User_AdminListUsers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userClient) AdminGetUser(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*AdminUser, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AdminUser)
+	err := c.cc.Invoke(ctx, User_AdminGetUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userClient) AdminUpdateRole(ctx context.Context, in *UpdateRoleReq, opts ...grpc.CallOption) (*Void, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Void)
+	err := c.cc.Invoke(ctx, User_AdminUpdateRole_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userClient) AdminForcePasswordReset(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*Void, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Void)
+	err := c.cc.Invoke(ctx, User_AdminForcePasswordReset_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userClient) AdminRestoreUser(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*Void, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Void)
+	err := c.cc.Invoke(ctx, User_AdminRestoreUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userClient) AdminHardDeleteUser(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*Void, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Void)
+	err := c.cc.Invoke(ctx, User_AdminHardDeleteUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userClient) AdminSuspendUser(ctx context.Context, in *SuspendUserReq, opts ...grpc.CallOption) (*Suspension, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Suspension)
+	err := c.cc.Invoke(ctx, User_AdminSuspendUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userClient) AdminLiftSuspension(ctx context.Context, in *LiftSuspensionReq, opts ...grpc.CallOption) (*Void, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Void)
+	err := c.cc.Invoke(ctx, User_AdminLiftSuspension_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userClient) AdminListSuspensions(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*SuspensionList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SuspensionList)
+	err := c.cc.Invoke(ctx, User_AdminListSuspensions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userClient) RestoreAccount(ctx context.Context, in *RestoreAccountReq, opts ...grpc.CallOption) (*LoginRes, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LoginRes)
+	err := c.cc.Invoke(ctx, User_RestoreAccount_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userClient) RequestDataExport(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*DataExport, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DataExport)
+	err := c.cc.Invoke(ctx, User_RequestDataExport_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userClient) GetDataExport(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*DataExport, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DataExport)
+	err := c.cc.Invoke(ctx, User_GetDataExport_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userClient) AdminListAuditEvents(ctx context.Context, in *AuditEventFilter, opts ...grpc.CallOption) (*AuditEventList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AuditEventList)
+	err := c.cc.Invoke(ctx, User_AdminListAuditEvents_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userClient) ListSecurityActivity(ctx context.Context, in *AuditEventFilter, opts ...grpc.CallOption) (*AuditEventList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AuditEventList)
+	err := c.cc.Invoke(ctx, User_ListSecurityActivity_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userClient) ListSessions(ctx context.Context, in *SessionReq, opts ...grpc.CallOption) (*SessionList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SessionList)
+	err := c.cc.Invoke(ctx, User_ListSessions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userClient) RevokeSession(ctx context.Context, in *SessionReq, opts ...grpc.CallOption) (*Void, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Void)
+	err := c.cc.Invoke(ctx, User_RevokeSession_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userClient) UnlockLogin(ctx context.Context, in *UnlockLoginReq, opts ...grpc.CallOption) (*Void, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Void)
+	err := c.cc.Invoke(ctx, User_UnlockLogin_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userClient) StartOIDC(ctx context.Context, in *OIDCStartReq, opts ...grpc.CallOption) (*OIDCStartRes, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OIDCStartRes)
+	err := c.cc.Invoke(ctx, User_StartOIDC_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userClient) FinishOIDC(ctx context.Context, in *OIDCFinishReq, opts ...grpc.CallOption) (*OIDCFinishRes, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OIDCFinishRes)
+	err := c.cc.Invoke(ctx, User_FinishOIDC_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userClient) ListIdentities(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*IdentityList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(IdentityList)
+	err := c.cc.Invoke(ctx, User_ListIdentities_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userClient) UnlinkIdentity(ctx context.Context, in *IdentityReq, opts ...grpc.CallOption) (*Void, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Void)
+	err := c.cc.Invoke(ctx, User_UnlinkIdentity_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userClient) RequestMagicLink(ctx context.Context, in *MagicLinkReq, opts ...grpc.CallOption) (*Void, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Void)
+	err := c.cc.Invoke(ctx, User_RequestMagicLink_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userClient) ConsumeMagicLink(ctx context.Context, in *ConsumeMagicLinkReq, opts ...grpc.CallOption) (*LoginRes, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LoginRes)
+	err := c.cc.Invoke(ctx, User_ConsumeMagicLink_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userClient) RequestEmailChange(ctx context.Context, in *EmailChangeReq, opts ...grpc.CallOption) (*Void, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Void)
+	err := c.cc.Invoke(ctx, User_RequestEmailChange_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userClient) ConfirmEmailChange(ctx context.Context, in *EmailChangeTokenReq, opts ...grpc.CallOption) (*Void, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Void)
+	err := c.cc.Invoke(ctx, User_ConfirmEmailChange_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userClient) RevertEmailChange(ctx context.Context, in *EmailChangeTokenReq, opts ...grpc.CallOption) (*Void, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Void)
+	err := c.cc.Invoke(ctx, User_RevertEmailChange_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userClient) GetPublicProfile(ctx context.Context, in *PublicProfileReq, opts ...grpc.CallOption) (*PublicProfile, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PublicProfile)
+	err := c.cc.Invoke(ctx, User_GetPublicProfile_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userClient) GetPrivacySettings(ctx context.Context, in *UserId, opts ...grpc.CallOption) (*PrivacySettings, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PrivacySettings)
+	err := c.cc.Invoke(ctx, User_GetPrivacySettings_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userClient) UpdatePrivacySettings(ctx context.Context, in *PrivacySettings, opts ...grpc.CallOption) (*Void, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Void)
+	err := c.cc.Invoke(ctx, User_UpdatePrivacySettings_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userClient) GetUsersByIds(ctx context.Context, in *GetUsersByIdsReq, opts ...grpc.CallOption) (*GetUsersByIdsRes, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetUsersByIdsRes)
+	err := c.cc.Invoke(ctx, User_GetUsersByIds_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // UserServer is the server API for User service.
 // All implementations must embed UnimplementedUserServer
 // for forward compatibility.
@@ -169,243 +601,1035 @@ type UserServer interface {
 	DeleteUser(context.Context, *UserId) (*Void, error)
 	IsUserExist(context.Context, *UserId) (*Void, error)
 	DeleteMediaUser(context.Context, *UserId) (*Void, error)
+	CreateSavedSearch(context.Context, *CreateSavedSearchReq) (*SavedSearch, error)
+	GetSavedSearch(context.Context, *SavedSearchId) (*SavedSearch, error)
+	ListSavedSearches(context.Context, *UserId) (*SavedSearchList, error)
+	UpdateSavedSearch(context.Context, *UpdateSavedSearchReq) (*Void, error)
+	DeleteSavedSearch(context.Context, *SavedSearchId) (*Void, error)
+	GetCarPriceHistory(context.Context, *CarId) (*PriceHistory, error)
+	AdminListUsers(context.Context, *AdminListUsersReq) (*AdminUserList, error)
+	AdminGetUser(context.Context, *UserId) (*AdminUser, error)
+	AdminUpdateRole(context.Context, *UpdateRoleReq) (*Void, error)
+	AdminForcePasswordReset(context.Context, *UserId) (*Void, error)
+	AdminRestoreUser(context.Context, *UserId) (*Void, error)
+	AdminHardDeleteUser(context.Context, *UserId) (*Void, error)
+	AdminSuspendUser(context.Context, *SuspendUserReq) (*Suspension, error)
+	AdminLiftSuspension(context.Context, *LiftSuspensionReq) (*Void, error)
+	AdminListSuspensions(context.Context, *UserId) (*SuspensionList, error)
+	RestoreAccount(context.Context, *RestoreAccountReq) (*LoginRes, error)
+	RequestDataExport(context.Context, *UserId) (*DataExport, error)
+	GetDataExport(context.Context, *UserId) (*DataExport, error)
+	AdminListAuditEvents(context.Context, *AuditEventFilter) (*AuditEventList, error)
+	ListSecurityActivity(context.Context, *AuditEventFilter) (*AuditEventList, error)
+	ListSessions(context.Context, *SessionReq) (*SessionList, error)
+	RevokeSession(context.Context, *SessionReq) (*Void, error)
+	UnlockLogin(context.Context, *UnlockLoginReq) (*Void, error)
+	StartOIDC(context.Context, *OIDCStartReq) (*OIDCStartRes, error)
+	FinishOIDC(context.Context, *OIDCFinishReq) (*OIDCFinishRes, error)
+	ListIdentities(context.Context, *UserId) (*IdentityList, error)
+	UnlinkIdentity(context.Context, *IdentityReq) (*Void, error)
+	RequestMagicLink(context.Context, *MagicLinkReq) (*Void, error)
+	ConsumeMagicLink(context.Context, *ConsumeMagicLinkReq) (*LoginRes, error)
+	RequestEmailChange(context.Context, *EmailChangeReq) (*Void, error)
+	ConfirmEmailChange(context.Context, *EmailChangeTokenReq) (*Void, error)
+	RevertEmailChange(context.Context, *EmailChangeTokenReq) (*Void, error)
+	GetPublicProfile(context.Context, *PublicProfileReq) (*PublicProfile, error)
+	GetPrivacySettings(context.Context, *UserId) (*PrivacySettings, error)
+	UpdatePrivacySettings(context.Context, *PrivacySettings) (*Void, error)
+	GetUsersByIds(context.Context, *GetUsersByIdsReq) (*GetUsersByIdsRes, error)
+	mustEmbedUnimplementedUserServer()
+}
+
+// UnimplementedUserServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedUserServer struct{}
+
+func (UnimplementedUserServer) Register(context.Context, *RegisterReq) (*LoginRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Register not implemented")
+}
+func (UnimplementedUserServer) Login(context.Context, *LoginReq) (*LoginRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Login not implemented")
+}
+func (UnimplementedUserServer) GetUSerByEmail(context.Context, *GetUSerByEmailReq) (*GetUserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUSerByEmail not implemented")
+}
+func (UnimplementedUserServer) GetUserById(context.Context, *UserId) (*GetUserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUserById not implemented")
+}
+func (UnimplementedUserServer) UpdatePassword(context.Context, *UpdatePasswordReq) (*Void, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdatePassword not implemented")
+}
+func (UnimplementedUserServer) ResetPassword(context.Context, *ResetPasswordReq) (*Void, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResetPassword not implemented")
+}
+func (UnimplementedUserServer) UpdateUser(context.Context, *UpdateUserRequest) (*Void, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateUser not implemented")
+}
+func (UnimplementedUserServer) DeleteUser(context.Context, *UserId) (*Void, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteUser not implemented")
+}
+func (UnimplementedUserServer) IsUserExist(context.Context, *UserId) (*Void, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IsUserExist not implemented")
+}
+func (UnimplementedUserServer) DeleteMediaUser(context.Context, *UserId) (*Void, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteMediaUser not implemented")
+}
+func (UnimplementedUserServer) CreateSavedSearch(context.Context, *CreateSavedSearchReq) (*SavedSearch, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateSavedSearch not implemented")
+}
+func (UnimplementedUserServer) GetSavedSearch(context.Context, *SavedSearchId) (*SavedSearch, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSavedSearch not implemented")
+}
+func (UnimplementedUserServer) ListSavedSearches(context.Context, *UserId) (*SavedSearchList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSavedSearches not implemented")
+}
+func (UnimplementedUserServer) UpdateSavedSearch(context.Context, *UpdateSavedSearchReq) (*Void, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateSavedSearch not implemented")
+}
+func (UnimplementedUserServer) DeleteSavedSearch(context.Context, *SavedSearchId) (*Void, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteSavedSearch not implemented")
+}
+func (UnimplementedUserServer) GetCarPriceHistory(context.Context, *CarId) (*PriceHistory, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCarPriceHistory not implemented")
+}
+func (UnimplementedUserServer) AdminListUsers(context.Context, *AdminListUsersReq) (*AdminUserList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AdminListUsers not implemented")
+}
+func (UnimplementedUserServer) AdminGetUser(context.Context, *UserId) (*AdminUser, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AdminGetUser not implemented")
+}
+func (UnimplementedUserServer) AdminUpdateRole(context.Context, *UpdateRoleReq) (*Void, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AdminUpdateRole not implemented")
+}
+func (UnimplementedUserServer) AdminForcePasswordReset(context.Context, *UserId) (*Void, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AdminForcePasswordReset not implemented")
+}
+func (UnimplementedUserServer) AdminRestoreUser(context.Context, *UserId) (*Void, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AdminRestoreUser not implemented")
+}
+func (UnimplementedUserServer) AdminHardDeleteUser(context.Context, *UserId) (*Void, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AdminHardDeleteUser not implemented")
+}
+func (UnimplementedUserServer) AdminSuspendUser(context.Context, *SuspendUserReq) (*Suspension, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AdminSuspendUser not implemented")
+}
+func (UnimplementedUserServer) AdminLiftSuspension(context.Context, *LiftSuspensionReq) (*Void, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AdminLiftSuspension not implemented")
+}
+func (UnimplementedUserServer) AdminListSuspensions(context.Context, *UserId) (*SuspensionList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AdminListSuspensions not implemented")
+}
+func (UnimplementedUserServer) RestoreAccount(context.Context, *RestoreAccountReq) (*LoginRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreAccount not implemented")
+}
+func (UnimplementedUserServer) RequestDataExport(context.Context, *UserId) (*DataExport, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequestDataExport not implemented")
+}
+func (UnimplementedUserServer) GetDataExport(context.Context, *UserId) (*DataExport, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDataExport not implemented")
+}
+func (UnimplementedUserServer) AdminListAuditEvents(context.Context, *AuditEventFilter) (*AuditEventList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AdminListAuditEvents not implemented")
+}
+func (UnimplementedUserServer) ListSecurityActivity(context.Context, *AuditEventFilter) (*AuditEventList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSecurityActivity not implemented")
+}
+func (UnimplementedUserServer) ListSessions(context.Context, *SessionReq) (*SessionList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSessions not implemented")
+}
+func (UnimplementedUserServer) RevokeSession(context.Context, *SessionReq) (*Void, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeSession not implemented")
+}
+func (UnimplementedUserServer) UnlockLogin(context.Context, *UnlockLoginReq) (*Void, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnlockLogin not implemented")
+}
+func (UnimplementedUserServer) StartOIDC(context.Context, *OIDCStartReq) (*OIDCStartRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StartOIDC not implemented")
+}
+func (UnimplementedUserServer) FinishOIDC(context.Context, *OIDCFinishReq) (*OIDCFinishRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FinishOIDC not implemented")
+}
+func (UnimplementedUserServer) ListIdentities(context.Context, *UserId) (*IdentityList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListIdentities not implemented")
+}
+func (UnimplementedUserServer) UnlinkIdentity(context.Context, *IdentityReq) (*Void, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnlinkIdentity not implemented")
+}
+func (UnimplementedUserServer) RequestMagicLink(context.Context, *MagicLinkReq) (*Void, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequestMagicLink not implemented")
+}
+func (UnimplementedUserServer) ConsumeMagicLink(context.Context, *ConsumeMagicLinkReq) (*LoginRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ConsumeMagicLink not implemented")
+}
+func (UnimplementedUserServer) RequestEmailChange(context.Context, *EmailChangeReq) (*Void, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequestEmailChange not implemented")
+}
+func (UnimplementedUserServer) ConfirmEmailChange(context.Context, *EmailChangeTokenReq) (*Void, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ConfirmEmailChange not implemented")
+}
+func (UnimplementedUserServer) RevertEmailChange(context.Context, *EmailChangeTokenReq) (*Void, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevertEmailChange not implemented")
+}
+func (UnimplementedUserServer) GetPublicProfile(context.Context, *PublicProfileReq) (*PublicProfile, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPublicProfile not implemented")
+}
+func (UnimplementedUserServer) GetPrivacySettings(context.Context, *UserId) (*PrivacySettings, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPrivacySettings not implemented")
+}
+func (UnimplementedUserServer) UpdatePrivacySettings(context.Context, *PrivacySettings) (*Void, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdatePrivacySettings not implemented")
+}
+func (UnimplementedUserServer) GetUsersByIds(context.Context, *GetUsersByIdsReq) (*GetUsersByIdsRes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUsersByIds not implemented")
+}
+func (UnimplementedUserServer) mustEmbedUnimplementedUserServer() {}
+func (UnimplementedUserServer) testEmbeddedByValue()              {}
+
+// UnsafeUserServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to UserServer will
+// result in compilation errors.
+type UnsafeUserServer interface {
 	mustEmbedUnimplementedUserServer()
 }
 
-// UnimplementedUserServer must be embedded to have
-// forward compatible implementations.
-//
-// NOTE: this should be embedded by value instead of pointer to avoid a nil
-// pointer dereference when methods are called.
-type UnimplementedUserServer struct{}
+func RegisterUserServer(s grpc.ServiceRegistrar, srv UserServer) {
+	// If the following call pancis, it indicates UnimplementedUserServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&User_ServiceDesc, srv)
+}
+
+func _User_Register_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServer).Register(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_Register_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).Register(ctx, req.(*RegisterReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _User_Login_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LoginReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServer).Login(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_Login_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).Login(ctx, req.(*LoginReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _User_GetUSerByEmail_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUSerByEmailReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServer).GetUSerByEmail(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_GetUSerByEmail_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).GetUSerByEmail(ctx, req.(*GetUSerByEmailReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _User_GetUserById_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServer).GetUserById(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_GetUserById_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).GetUserById(ctx, req.(*UserId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _User_UpdatePassword_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdatePasswordReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServer).UpdatePassword(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_UpdatePassword_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).UpdatePassword(ctx, req.(*UpdatePasswordReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _User_ResetPassword_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResetPasswordReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServer).ResetPassword(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_ResetPassword_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).ResetPassword(ctx, req.(*ResetPasswordReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _User_UpdateUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServer).UpdateUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_UpdateUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).UpdateUser(ctx, req.(*UpdateUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _User_DeleteUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServer).DeleteUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_DeleteUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).DeleteUser(ctx, req.(*UserId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _User_IsUserExist_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServer).IsUserExist(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_IsUserExist_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).IsUserExist(ctx, req.(*UserId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _User_DeleteMediaUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServer).DeleteMediaUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_DeleteMediaUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).DeleteMediaUser(ctx, req.(*UserId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
 
-func (UnimplementedUserServer) Register(context.Context, *RegisterReq) (*LoginRes, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method Register not implemented")
+func _User_CreateSavedSearch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateSavedSearchReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServer).CreateSavedSearch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_CreateSavedSearch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).CreateSavedSearch(ctx, req.(*CreateSavedSearchReq))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedUserServer) Login(context.Context, *LoginReq) (*LoginRes, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method Login not implemented")
+
+func _User_GetSavedSearch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SavedSearchId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServer).GetSavedSearch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_GetSavedSearch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).GetSavedSearch(ctx, req.(*SavedSearchId))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedUserServer) GetUSerByEmail(context.Context, *GetUSerByEmailReq) (*GetUserResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetUSerByEmail not implemented")
+
+func _User_ListSavedSearches_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServer).ListSavedSearches(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_ListSavedSearches_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).ListSavedSearches(ctx, req.(*UserId))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedUserServer) GetUserById(context.Context, *UserId) (*GetUserResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetUserById not implemented")
+
+func _User_UpdateSavedSearch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateSavedSearchReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServer).UpdateSavedSearch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_UpdateSavedSearch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).UpdateSavedSearch(ctx, req.(*UpdateSavedSearchReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _User_DeleteSavedSearch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SavedSearchId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServer).DeleteSavedSearch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_DeleteSavedSearch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).DeleteSavedSearch(ctx, req.(*SavedSearchId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _User_GetCarPriceHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CarId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServer).GetCarPriceHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_GetCarPriceHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).GetCarPriceHistory(ctx, req.(*CarId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _User_AdminListUsers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AdminListUsersReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServer).AdminListUsers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_AdminListUsers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).AdminListUsers(ctx, req.(*AdminListUsersReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _User_AdminGetUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServer).AdminGetUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_AdminGetUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).AdminGetUser(ctx, req.(*UserId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _User_AdminUpdateRole_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateRoleReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServer).AdminUpdateRole(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_AdminUpdateRole_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).AdminUpdateRole(ctx, req.(*UpdateRoleReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _User_AdminForcePasswordReset_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServer).AdminForcePasswordReset(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_AdminForcePasswordReset_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).AdminForcePasswordReset(ctx, req.(*UserId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _User_AdminRestoreUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServer).AdminRestoreUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_AdminRestoreUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).AdminRestoreUser(ctx, req.(*UserId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _User_AdminHardDeleteUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServer).AdminHardDeleteUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_AdminHardDeleteUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).AdminHardDeleteUser(ctx, req.(*UserId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _User_AdminSuspendUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SuspendUserReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServer).AdminSuspendUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_AdminSuspendUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).AdminSuspendUser(ctx, req.(*SuspendUserReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _User_AdminLiftSuspension_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LiftSuspensionReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServer).AdminLiftSuspension(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_AdminLiftSuspension_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).AdminLiftSuspension(ctx, req.(*LiftSuspensionReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _User_AdminListSuspensions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServer).AdminListSuspensions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_AdminListSuspensions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).AdminListSuspensions(ctx, req.(*UserId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _User_RestoreAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreAccountReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServer).RestoreAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_RestoreAccount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).RestoreAccount(ctx, req.(*RestoreAccountReq))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _User_RequestDataExport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServer).RequestDataExport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_RequestDataExport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).RequestDataExport(ctx, req.(*UserId))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedUserServer) UpdatePassword(context.Context, *UpdatePasswordReq) (*Void, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method UpdatePassword not implemented")
+
+func _User_GetDataExport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServer).GetDataExport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_GetDataExport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).GetDataExport(ctx, req.(*UserId))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedUserServer) ResetPassword(context.Context, *ResetPasswordReq) (*Void, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method ResetPassword not implemented")
+
+func _User_AdminListAuditEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuditEventFilter)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServer).AdminListAuditEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_AdminListAuditEvents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).AdminListAuditEvents(ctx, req.(*AuditEventFilter))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedUserServer) UpdateUser(context.Context, *UpdateUserRequest) (*Void, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method UpdateUser not implemented")
+
+func _User_ListSecurityActivity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuditEventFilter)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServer).ListSecurityActivity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_ListSecurityActivity_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).ListSecurityActivity(ctx, req.(*AuditEventFilter))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedUserServer) DeleteUser(context.Context, *UserId) (*Void, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method DeleteUser not implemented")
+
+func _User_ListSessions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SessionReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServer).ListSessions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_ListSessions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).ListSessions(ctx, req.(*SessionReq))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedUserServer) IsUserExist(context.Context, *UserId) (*Void, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method IsUserExist not implemented")
+
+func _User_RevokeSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SessionReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServer).RevokeSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_RevokeSession_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).RevokeSession(ctx, req.(*SessionReq))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedUserServer) DeleteMediaUser(context.Context, *UserId) (*Void, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method DeleteMediaUser not implemented")
+
+func _User_UnlockLogin_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnlockLoginReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServer).UnlockLogin(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_UnlockLogin_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).UnlockLogin(ctx, req.(*UnlockLoginReq))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedUserServer) mustEmbedUnimplementedUserServer() {}
-func (UnimplementedUserServer) testEmbeddedByValue()              {}
 
-// UnsafeUserServer may be embedded to opt out of forward compatibility for this service.
-// Use of this interface is not recommended, as added methods to UserServer will
-// result in compilation errors.
-type UnsafeUserServer interface {
-	mustEmbedUnimplementedUserServer()
+func _User_StartOIDC_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OIDCStartReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServer).StartOIDC(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_StartOIDC_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).StartOIDC(ctx, req.(*OIDCStartReq))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func RegisterUserServer(s grpc.ServiceRegistrar, srv UserServer) {
-	// If the following call pancis, it indicates UnimplementedUserServer was
-	// embedded by pointer and is nil.  This will cause panics if an
-	// unimplemented method is ever invoked, so we test this at initialization
-	// time to prevent it from happening at runtime later due to I/O.
-	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
-		t.testEmbeddedByValue()
+func _User_FinishOIDC_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OIDCFinishReq)
+	if err := dec(in); err != nil {
+		return nil, err
 	}
-	s.RegisterService(&User_ServiceDesc, srv)
+	if interceptor == nil {
+		return srv.(UserServer).FinishOIDC(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_FinishOIDC_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).FinishOIDC(ctx, req.(*OIDCFinishReq))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func _User_Register_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(RegisterReq)
+func _User_ListIdentities_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserId)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(UserServer).Register(ctx, in)
+		return srv.(UserServer).ListIdentities(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: User_Register_FullMethodName,
+		FullMethod: User_ListIdentities_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(UserServer).Register(ctx, req.(*RegisterReq))
+		return srv.(UserServer).ListIdentities(ctx, req.(*UserId))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _User_Login_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(LoginReq)
+func _User_UnlinkIdentity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IdentityReq)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(UserServer).Login(ctx, in)
+		return srv.(UserServer).UnlinkIdentity(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: User_Login_FullMethodName,
+		FullMethod: User_UnlinkIdentity_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(UserServer).Login(ctx, req.(*LoginReq))
+		return srv.(UserServer).UnlinkIdentity(ctx, req.(*IdentityReq))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _User_GetUSerByEmail_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetUSerByEmailReq)
+func _User_RequestMagicLink_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MagicLinkReq)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(UserServer).GetUSerByEmail(ctx, in)
+		return srv.(UserServer).RequestMagicLink(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: User_GetUSerByEmail_FullMethodName,
+		FullMethod: User_RequestMagicLink_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(UserServer).GetUSerByEmail(ctx, req.(*GetUSerByEmailReq))
+		return srv.(UserServer).RequestMagicLink(ctx, req.(*MagicLinkReq))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _User_GetUserById_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(UserId)
+func _User_ConsumeMagicLink_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConsumeMagicLinkReq)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(UserServer).GetUserById(ctx, in)
+		return srv.(UserServer).ConsumeMagicLink(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: User_GetUserById_FullMethodName,
+		FullMethod: User_ConsumeMagicLink_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(UserServer).GetUserById(ctx, req.(*UserId))
+		return srv.(UserServer).ConsumeMagicLink(ctx, req.(*ConsumeMagicLinkReq))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _User_UpdatePassword_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(UpdatePasswordReq)
+func _User_RequestEmailChange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EmailChangeReq)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(UserServer).UpdatePassword(ctx, in)
+		return srv.(UserServer).RequestEmailChange(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: User_UpdatePassword_FullMethodName,
+		FullMethod: User_RequestEmailChange_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(UserServer).UpdatePassword(ctx, req.(*UpdatePasswordReq))
+		return srv.(UserServer).RequestEmailChange(ctx, req.(*EmailChangeReq))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _User_ResetPassword_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(ResetPasswordReq)
+func _User_ConfirmEmailChange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EmailChangeTokenReq)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(UserServer).ResetPassword(ctx, in)
+		return srv.(UserServer).ConfirmEmailChange(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: User_ResetPassword_FullMethodName,
+		FullMethod: User_ConfirmEmailChange_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(UserServer).ResetPassword(ctx, req.(*ResetPasswordReq))
+		return srv.(UserServer).ConfirmEmailChange(ctx, req.(*EmailChangeTokenReq))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _User_UpdateUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(UpdateUserRequest)
+func _User_RevertEmailChange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EmailChangeTokenReq)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(UserServer).UpdateUser(ctx, in)
+		return srv.(UserServer).RevertEmailChange(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: User_UpdateUser_FullMethodName,
+		FullMethod: User_RevertEmailChange_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(UserServer).UpdateUser(ctx, req.(*UpdateUserRequest))
+		return srv.(UserServer).RevertEmailChange(ctx, req.(*EmailChangeTokenReq))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _User_DeleteUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(UserId)
+func _User_GetPublicProfile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PublicProfileReq)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(UserServer).DeleteUser(ctx, in)
+		return srv.(UserServer).GetPublicProfile(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: User_DeleteUser_FullMethodName,
+		FullMethod: User_GetPublicProfile_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(UserServer).DeleteUser(ctx, req.(*UserId))
+		return srv.(UserServer).GetPublicProfile(ctx, req.(*PublicProfileReq))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _User_IsUserExist_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+func _User_GetPrivacySettings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(UserId)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(UserServer).IsUserExist(ctx, in)
+		return srv.(UserServer).GetPrivacySettings(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: User_IsUserExist_FullMethodName,
+		FullMethod: User_GetPrivacySettings_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(UserServer).IsUserExist(ctx, req.(*UserId))
+		return srv.(UserServer).GetPrivacySettings(ctx, req.(*UserId))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _User_DeleteMediaUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(UserId)
+func _User_UpdatePrivacySettings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PrivacySettings)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(UserServer).DeleteMediaUser(ctx, in)
+		return srv.(UserServer).UpdatePrivacySettings(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: User_DeleteMediaUser_FullMethodName,
+		FullMethod: User_UpdatePrivacySettings_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(UserServer).DeleteMediaUser(ctx, req.(*UserId))
+		return srv.(UserServer).UpdatePrivacySettings(ctx, req.(*PrivacySettings))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _User_GetUsersByIds_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUsersByIdsReq)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServer).GetUsersByIds(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: User_GetUsersByIds_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServer).GetUsersByIds(ctx, req.(*GetUsersByIdsReq))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -457,6 +1681,150 @@ var User_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteMediaUser",
 			Handler:    _User_DeleteMediaUser_Handler,
 		},
+		{
+			MethodName: "CreateSavedSearch",
+			Handler:    _User_CreateSavedSearch_Handler,
+		},
+		{
+			MethodName: "GetSavedSearch",
+			Handler:    _User_GetSavedSearch_Handler,
+		},
+		{
+			MethodName: "ListSavedSearches",
+			Handler:    _User_ListSavedSearches_Handler,
+		},
+		{
+			MethodName: "UpdateSavedSearch",
+			Handler:    _User_UpdateSavedSearch_Handler,
+		},
+		{
+			MethodName: "DeleteSavedSearch",
+			Handler:    _User_DeleteSavedSearch_Handler,
+		},
+		{
+			MethodName: "GetCarPriceHistory",
+			Handler:    _User_GetCarPriceHistory_Handler,
+		},
+		{
+			MethodName: "AdminListUsers",
+			Handler:    _User_AdminListUsers_Handler,
+		},
+		{
+			MethodName: "AdminGetUser",
+			Handler:    _User_AdminGetUser_Handler,
+		},
+		{
+			MethodName: "AdminUpdateRole",
+			Handler:    _User_AdminUpdateRole_Handler,
+		},
+		{
+			MethodName: "AdminForcePasswordReset",
+			Handler:    _User_AdminForcePasswordReset_Handler,
+		},
+		{
+			MethodName: "AdminRestoreUser",
+			Handler:    _User_AdminRestoreUser_Handler,
+		},
+		{
+			MethodName: "AdminHardDeleteUser",
+			Handler:    _User_AdminHardDeleteUser_Handler,
+		},
+		{
+			MethodName: "AdminSuspendUser",
+			Handler:    _User_AdminSuspendUser_Handler,
+		},
+		{
+			MethodName: "AdminLiftSuspension",
+			Handler:    _User_AdminLiftSuspension_Handler,
+		},
+		{
+			MethodName: "AdminListSuspensions",
+			Handler:    _User_AdminListSuspensions_Handler,
+		},
+		{
+			MethodName: "RestoreAccount",
+			Handler:    _User_RestoreAccount_Handler,
+		},
+		{
+			MethodName: "RequestDataExport",
+			Handler:    _User_RequestDataExport_Handler,
+		},
+		{
+			MethodName: "GetDataExport",
+			Handler:    _User_GetDataExport_Handler,
+		},
+		{
+			MethodName: "AdminListAuditEvents",
+			Handler:    _User_AdminListAuditEvents_Handler,
+		},
+		{
+			MethodName: "ListSecurityActivity",
+			Handler:    _User_ListSecurityActivity_Handler,
+		},
+		{
+			MethodName: "ListSessions",
+			Handler:    _User_ListSessions_Handler,
+		},
+		{
+			MethodName: "RevokeSession",
+			Handler:    _User_RevokeSession_Handler,
+		},
+		{
+			MethodName: "UnlockLogin",
+			Handler:    _User_UnlockLogin_Handler,
+		},
+		{
+			MethodName: "StartOIDC",
+			Handler:    _User_StartOIDC_Handler,
+		},
+		{
+			MethodName: "FinishOIDC",
+			Handler:    _User_FinishOIDC_Handler,
+		},
+		{
+			MethodName: "ListIdentities",
+			Handler:    _User_ListIdentities_Handler,
+		},
+		{
+			MethodName: "UnlinkIdentity",
+			Handler:    _User_UnlinkIdentity_Handler,
+		},
+		{
+			MethodName: "RequestMagicLink",
+			Handler:    _User_RequestMagicLink_Handler,
+		},
+		{
+			MethodName: "ConsumeMagicLink",
+			Handler:    _User_ConsumeMagicLink_Handler,
+		},
+		{
+			MethodName: "RequestEmailChange",
+			Handler:    _User_RequestEmailChange_Handler,
+		},
+		{
+			MethodName: "ConfirmEmailChange",
+			Handler:    _User_ConfirmEmailChange_Handler,
+		},
+		{
+			MethodName: "RevertEmailChange",
+			Handler:    _User_RevertEmailChange_Handler,
+		},
+		{
+			MethodName: "GetPublicProfile",
+			Handler:    _User_GetPublicProfile_Handler,
+		},
+		{
+			MethodName: "GetPrivacySettings",
+			Handler:    _User_GetPrivacySettings_Handler,
+		},
+		{
+			MethodName: "UpdatePrivacySettings",
+			Handler:    _User_UpdatePrivacySettings_Handler,
+		},
+		{
+			MethodName: "GetUsersByIds",
+			Handler:    _User_GetUsersByIds_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "user.proto",
@@ -0,0 +1,145 @@
+// Package health checks the dependencies of the service for the readiness
+// probes, over HTTP on /readyz and over gRPC with grpc.health.v1.
+package health
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Check is one dependency of the service.
+type Check struct {
+	Name string
+	// Optional checks are reported but do not make the service unready
+	Optional bool
+	Run      func(ctx context.Context) error
+}
+
+// Status is the outcome of one check.
+type Status struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Optional bool   `json:"optional,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Report is the outcome of all checks. Ready is false while shutting down
+// or when a check that is not optional fails.
+type Report struct {
+	Ready  bool              `json:"ready"`
+	Checks map[string]Status `json:"checks"`
+}
+
+// Summary is the public side of a Report: whether the service is ready and
+// whether each check is ok or failing. The errors, which may name hosts and
+// users, only go to the log.
+type Summary struct {
+	Ready  bool              `json:"ready"`
+	Checks map[string]string `json:"checks"`
+}
+
+// Checker runs the checks, each with at most timeout.
+type Checker struct {
+	timeout  time.Duration
+	checks   []Check
+	logger   *slog.Logger
+	draining atomic.Bool
+	last     atomic.Pointer[Report]
+}
+
+func NewChecker(timeout time.Duration, logger *slog.Logger, checks ...Check) *Checker {
+	return &Checker{timeout: timeout, logger: logger, checks: checks}
+}
+
+// Drain makes the service unready for good, so the load balancers stop
+// sending it traffic while it shuts down.
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Check runs the checks concurrently.
+func (c *Checker) Check(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	report := Report{Ready: !c.draining.Load(), Checks: make(map[string]Status, len(c.checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range c.checks {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+			start := time.Now()
+			err := check.Run(ctx)
+			status := Status{Status: "ok", Optional: check.Optional, Duration: time.Since(start).Round(time.Millisecond).String()}
+			if err != nil {
+				status.Status = "failing"
+				status.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[check.Name] = status
+			if err != nil && !check.Optional {
+				report.Ready = false
+			}
+		}(check)
+	}
+	wg.Wait()
+	return report
+}
+
+// Last summarizes the report Serve made last. The service is not ready
+// before the first one, nor once draining.
+func (c *Checker) Last() Summary {
+	summary := Summary{Checks: map[string]string{}}
+	report := c.last.Load()
+	if report == nil {
+		return summary
+	}
+	summary.Ready = report.Ready && !c.draining.Load()
+	for name, status := range report.Checks {
+		summary.Checks[name] = status.Status
+	}
+	return summary
+}
+
+// Serve keeps the serving status of server, for the whole server and each
+// of services, and the report Last summarizes in line with the checks every
+// interval until ctx is done. Failing checks are logged.
+func (c *Checker) Serve(ctx context.Context, interval time.Duration, server *health.Server, services ...string) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		report := c.Check(ctx)
+		c.last.Store(&report)
+		for name, status := range report.Checks {
+			if status.Error != "" {
+				c.logger.Error(fmt.Sprintf("readiness check %s failed: %s", name, status.Error))
+			}
+		}
+
+		status := healthpb.HealthCheckResponse_SERVING
+		if !report.Ready {
+			status = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+		server.SetServingStatus("", status)
+		for _, service := range services {
+			server.SetServingStatus(service, status)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
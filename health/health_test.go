@@ -0,0 +1,47 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/health"
+)
+
+// TestLastIsCached checks that Last answers from the report Serve made,
+// without running the checks again or showing their errors.
+func TestLastIsCached(t *testing.T) {
+	var runs atomic.Int32
+	checker := NewChecker(time.Second, slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Check{Name: "db", Run: func(ctx context.Context) error {
+			runs.Add(1)
+			return errors.New("dial tcp db.internal:5432: password authentication failed for user app")
+		}},
+	)
+	if summary := checker.Last(); summary.Ready || len(summary.Checks) != 0 {
+		t.Errorf("before the first check: %+v, want unready", summary)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go checker.Serve(ctx, time.Hour, health.NewServer())
+	for deadline := time.Now().Add(time.Second); checker.last.Load() == nil; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Serve made no report")
+		}
+	}
+
+	for i := 0; i < 10; i++ {
+		summary := checker.Last()
+		if summary.Ready || summary.Checks["db"] != "failing" || len(summary.Checks) != 1 {
+			t.Fatalf("summary = %+v, want db failing", summary)
+		}
+	}
+	if n := runs.Load(); n != 1 {
+		t.Errorf("the check ran %d times, want once", n)
+	}
+}
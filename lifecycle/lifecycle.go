@@ -0,0 +1,94 @@
+// Package lifecycle runs the long lived parts of the service, such as the
+// servers, and shuts everything down in order when the process is asked to
+// stop, so requests in flight can finish before their dependencies close.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+type stopper struct {
+	name    string
+	timeout time.Duration
+	stop    func(ctx context.Context) error
+}
+
+// App collects what to run and how to stop it.
+type App struct {
+	logger   *slog.Logger
+	failed   chan error
+	stoppers []stopper
+}
+
+func New(logger *slog.Logger) *App {
+	return &App{logger: logger, failed: make(chan error, 1)}
+}
+
+// Go runs fn in the background, fn returns when its part stops. A part that
+// stops with an error before shutdown shuts the app down.
+func (a *App) Go(name string, fn func() error) {
+	go func() {
+		if err := fn(); err != nil {
+			select {
+			case a.failed <- fmt.Errorf("%s: %w", name, err):
+			default:
+			}
+		}
+	}()
+}
+
+// OnStop adds a step to the shutdown. The steps run one after another in
+// the order they were added, each with at most timeout; a step that runs
+// out of time is abandoned and the next one starts.
+func (a *App) OnStop(name string, timeout time.Duration, stop func(ctx context.Context) error) {
+	a.stoppers = append(a.stoppers, stopper{name: name, timeout: timeout, stop: stop})
+}
+
+// Run blocks until SIGINT or SIGTERM arrives, ctx is cancelled or a part
+// fails, then shuts down. It returns the failure together with the errors
+// of the shutdown steps.
+func (a *App) Run(ctx context.Context) error {
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	var errs []error
+	select {
+	case <-ctx.Done():
+		a.logger.Info("shutting down")
+	case err := <-a.failed:
+		a.logger.Error(fmt.Sprintf("shutting down: %v", err))
+		errs = append(errs, err)
+	}
+
+	for _, s := range a.stoppers {
+		if err := a.stop(s); err != nil {
+			a.logger.Error(fmt.Sprintf("stopping %s: %v", s.name, err))
+			errs = append(errs, fmt.Errorf("stopping %s: %w", s.name, err))
+		}
+	}
+	a.logger.Info("shut down")
+	return errors.Join(errs...)
+}
+
+func (a *App) stop(s stopper) error {
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- s.stop(ctx) }()
+	select {
+	case err := <-done:
+		a.logger.Info(fmt.Sprintf("stopped %s in %s", s.name, time.Since(start).Round(time.Millisecond)))
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("gave up after %s", s.timeout)
+	}
+}
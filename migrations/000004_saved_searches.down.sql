@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS cars_updated_at_idx;
+DROP TRIGGER IF EXISTS cars_touch_updated_at ON cars;
+DROP FUNCTION IF EXISTS cars_touch_updated_at();
+DROP TABLE IF EXISTS saved_search_matches;
+DROP TABLE IF EXISTS saved_searches;
@@ -0,0 +1,49 @@
+CREATE TABLE IF NOT EXISTS saved_searches (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    type VARCHAR(255),
+    make VARCHAR(255),
+    model VARCHAR(255),
+    year_from INTEGER,
+    year_to INTEGER,
+    price_from DECIMAL(10,2),
+    price_to DECIMAL(10,2),
+    mileage_from INTEGER,
+    mileage_to INTEGER,
+    color VARCHAR(255),
+    location VARCHAR(255),
+    frequency VARCHAR(10) NOT NULL DEFAULT 'instant' CHECK (frequency IN ('instant', 'daily')),
+    active BOOLEAN DEFAULT true,
+    last_checked_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'Asia/Seoul'),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'Asia/Seoul'),
+    deleted_at BIGINT DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS saved_searches_user_id_idx ON saved_searches (user_id) WHERE deleted_at = 0;
+
+-- Remembers which cars were already announced for a search so the matcher
+-- does not notify about the same listing again inside the dedup window.
+CREATE TABLE IF NOT EXISTS saved_search_matches (
+    saved_search_id UUID NOT NULL REFERENCES saved_searches(id) ON DELETE CASCADE,
+    car_id UUID NOT NULL REFERENCES cars(id) ON DELETE CASCADE,
+    notified_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (saved_search_id, car_id)
+);
+
+-- The matcher picks up new and edited listings by updated_at, so keep it
+-- current no matter which service writes to cars.
+CREATE OR REPLACE FUNCTION cars_touch_updated_at() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = CURRENT_TIMESTAMP;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS cars_touch_updated_at ON cars;
+CREATE TRIGGER cars_touch_updated_at
+    BEFORE UPDATE ON cars
+    FOR EACH ROW EXECUTE FUNCTION cars_touch_updated_at();
+
+CREATE INDEX IF NOT EXISTS cars_updated_at_idx ON cars (updated_at) WHERE deleted_at = 0;
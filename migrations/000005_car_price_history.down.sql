@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS cars_record_price ON cars;
+DROP FUNCTION IF EXISTS cars_record_price();
+DROP TABLE IF EXISTS car_price_history;
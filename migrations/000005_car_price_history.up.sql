@@ -0,0 +1,33 @@
+-- Every price a car has had. Rows are appended by a trigger so that price
+-- changes made by any service writing to cars are recorded.
+CREATE TABLE IF NOT EXISTS car_price_history (
+    id BIGSERIAL PRIMARY KEY,
+    car_id UUID NOT NULL REFERENCES cars(id) ON DELETE CASCADE,
+    old_price DECIMAL(10,2),
+    price DECIMAL(10,2) NOT NULL,
+    changed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    alerts_sent BOOLEAN NOT NULL DEFAULT false
+);
+
+CREATE INDEX IF NOT EXISTS car_price_history_car_id_idx ON car_price_history (car_id, changed_at);
+CREATE INDEX IF NOT EXISTS car_price_history_pending_idx ON car_price_history (id) WHERE alerts_sent = false;
+
+CREATE OR REPLACE FUNCTION cars_record_price() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO car_price_history (car_id, price, alerts_sent) VALUES (NEW.id, NEW.price, true);
+    ELSIF NEW.price IS DISTINCT FROM OLD.price THEN
+        INSERT INTO car_price_history (car_id, old_price, price) VALUES (NEW.id, OLD.price, NEW.price);
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS cars_record_price ON cars;
+CREATE TRIGGER cars_record_price
+    AFTER INSERT OR UPDATE OF price ON cars
+    FOR EACH ROW EXECUTE FUNCTION cars_record_price();
+
+-- Start the history of existing cars with their current price
+INSERT INTO car_price_history (car_id, price, changed_at, alerts_sent)
+SELECT id, price, created_at, true FROM cars;
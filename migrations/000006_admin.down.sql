@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS users_created_at_idx;
+ALTER TABLE users DROP COLUMN IF EXISTS password_reset_required;
@@ -0,0 +1,4 @@
+-- Set by an admin to make the user choose a new password before logging in again
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_reset_required BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS users_created_at_idx ON users (created_at);
@@ -0,0 +1,9 @@
+CREATE OR REPLACE FUNCTION cars_touch_updated_at() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = CURRENT_TIMESTAMP;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE cars DROP COLUMN IF EXISTS hidden;
+DROP TABLE IF EXISTS user_suspensions;
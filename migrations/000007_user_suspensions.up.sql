@@ -0,0 +1,33 @@
+-- A suspension without expires_at is a ban. Rows are never deleted, lifting
+-- a suspension only fills lifted_at so the history stays complete.
+CREATE TABLE IF NOT EXISTS user_suspensions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    lifted_at TIMESTAMP WITH TIME ZONE,
+    lifted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    lift_reason TEXT
+);
+
+CREATE INDEX IF NOT EXISTS user_suspensions_user_id_idx ON user_suspensions (user_id, starts_at);
+
+-- Listings of suspended users are hidden, the car service must filter on it
+ALTER TABLE cars ADD COLUMN IF NOT EXISTS hidden BOOLEAN NOT NULL DEFAULT false;
+
+-- Hiding and showing listings is no edit: updates changing nothing else
+-- keep updated_at, so the saved search matcher does not announce the
+-- listings of a user whose suspension ends.
+CREATE OR REPLACE FUNCTION cars_touch_updated_at() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'UPDATE'
+       AND to_jsonb(NEW) - 'hidden' - 'updated_at' = to_jsonb(OLD) - 'hidden' - 'updated_at' THEN
+        NEW.updated_at = OLD.updated_at;
+        RETURN NEW;
+    END IF;
+    NEW.updated_at = CURRENT_TIMESTAMP;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS users_deleted_at_idx;
+ALTER TABLE users DROP COLUMN IF EXISTS purged_at;
+DROP INDEX IF EXISTS users_phone_number_active_key;
+DROP INDEX IF EXISTS users_email_active_key;
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+ALTER TABLE users ADD CONSTRAINT users_phone_number_key UNIQUE (phone_number);
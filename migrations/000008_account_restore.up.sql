@@ -0,0 +1,12 @@
+-- Soft-deleted users keep their row during the restore window. Emails and
+-- phone numbers only have to be unique among active users so that the
+-- person can register again once the account is gone.
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_phone_number_key;
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_active_key ON users (email) WHERE deleted_at = 0;
+CREATE UNIQUE INDEX IF NOT EXISTS users_phone_number_active_key ON users (phone_number) WHERE deleted_at = 0;
+
+-- Set when the purge job anonymized the user instead of deleting the row
+ALTER TABLE users ADD COLUMN IF NOT EXISTS purged_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS users_deleted_at_idx ON users (deleted_at) WHERE deleted_at <> 0 AND purged_at IS NULL;
@@ -0,0 +1,18 @@
+-- Personal data exports requested through /user/export. The archive lives in
+-- MinIO until expires_at, after which the row is kept as status 'expired'.
+-- claimed_at is when a builder took the export; a running export claimed
+-- longer ago than the lease is taken over, its builder having died.
+CREATE TABLE IF NOT EXISTS data_exports (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'ready', 'failed', 'expired')),
+    object_name VARCHAR(255),
+    error TEXT,
+    requested_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    claimed_at TIMESTAMP WITH TIME ZONE,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS data_exports_user_id_idx ON data_exports (user_id, requested_at);
+CREATE INDEX IF NOT EXISTS data_exports_status_idx ON data_exports (status) WHERE status IN ('pending', 'running', 'ready');
@@ -0,0 +1,30 @@
+-- Append-only trail of security and profile relevant events. There are no
+-- foreign keys on purpose: the trail outlives purged users.
+CREATE TABLE IF NOT EXISTS audit_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    actor_id UUID,
+    target_id UUID,
+    action VARCHAR(50) NOT NULL,
+    -- {"field": {"old": ..., "new": ...}} for the changed fields
+    changes JSONB,
+    reason TEXT,
+    ip VARCHAR(64),
+    user_agent TEXT,
+    request_id VARCHAR(64),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS audit_events_target_id_idx ON audit_events (target_id, created_at);
+CREATE INDEX IF NOT EXISTS audit_events_actor_id_idx ON audit_events (actor_id, created_at);
+CREATE INDEX IF NOT EXISTS audit_events_action_idx ON audit_events (action, created_at);
+
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_events_append_only ON audit_events;
+CREATE TRIGGER audit_events_append_only
+    BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
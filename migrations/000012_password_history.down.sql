@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS users_record_password ON users;
+DROP FUNCTION IF EXISTS users_record_password();
+DROP TABLE IF EXISTS password_history;
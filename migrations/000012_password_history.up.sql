@@ -0,0 +1,24 @@
+-- Previous password hashes, so a password change can refuse recent ones.
+-- The trigger records the hash being replaced whoever changes it.
+CREATE TABLE IF NOT EXISTS password_history (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    password_hash VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS password_history_user_id_idx ON password_history (user_id, created_at);
+
+CREATE OR REPLACE FUNCTION users_record_password() RETURNS TRIGGER AS $$
+BEGIN
+    IF OLD.password_hash IS DISTINCT FROM NEW.password_hash AND OLD.password_hash <> '' THEN
+        INSERT INTO password_history (user_id, password_hash) VALUES (OLD.id, OLD.password_hash);
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS users_record_password ON users;
+CREATE TRIGGER users_record_password
+    AFTER UPDATE OF password_hash ON users
+    FOR EACH ROW EXECUTE FUNCTION users_record_password();
@@ -0,0 +1,8 @@
+CREATE OR REPLACE FUNCTION users_record_password() RETURNS TRIGGER AS $$
+BEGIN
+    IF OLD.password_hash IS DISTINCT FROM NEW.password_hash AND OLD.password_hash <> '' THEN
+        INSERT INTO password_history (user_id, password_hash) VALUES (OLD.id, OLD.password_hash);
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
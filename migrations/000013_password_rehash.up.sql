@@ -0,0 +1,13 @@
+-- Upgrading the hash of an unchanged password on login is not a password
+-- change, Login sets app.rehash for its transaction to skip the history.
+CREATE OR REPLACE FUNCTION users_record_password() RETURNS TRIGGER AS $$
+BEGIN
+    IF coalesce(current_setting('app.rehash', true), '') = 'on' THEN
+        RETURN NEW;
+    END IF;
+    IF OLD.password_hash IS DISTINCT FROM NEW.password_hash AND OLD.password_hash <> '' THEN
+        INSERT INTO password_history (user_id, password_hash) VALUES (OLD.id, OLD.password_hash);
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,11 @@
+-- Social login accounts linked to users, one per provider and user.
+CREATE TABLE IF NOT EXISTS user_identities (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(20) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(100),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (provider, subject),
+    UNIQUE (user_id, provider)
+);
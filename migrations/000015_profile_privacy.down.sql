@@ -0,0 +1,4 @@
+ALTER TABLE users DROP COLUMN IF EXISTS rating_count;
+ALTER TABLE users DROP COLUMN IF EXISTS rating_average;
+ALTER TABLE users DROP COLUMN IF EXISTS email_visibility;
+ALTER TABLE users DROP COLUMN IF EXISTS phone_visibility;
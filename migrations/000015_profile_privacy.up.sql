@@ -0,0 +1,11 @@
+-- Who sees the phone number and email on the public profile: nobody,
+-- logged_in users or everyone.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS phone_visibility VARCHAR(10) NOT NULL DEFAULT 'nobody'
+    CHECK (phone_visibility IN ('nobody', 'logged_in', 'everyone'));
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_visibility VARCHAR(10) NOT NULL DEFAULT 'nobody'
+    CHECK (email_visibility IN ('nobody', 'logged_in', 'everyone'));
+
+-- Seller rating shown on the public profile, kept up to date by the service
+-- collecting seller reviews. rating_count 0 means not rated yet.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS rating_average NUMERIC(3,2);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS rating_count INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+-- The scrubbed values are gone for good.
+SELECT 1;
@@ -0,0 +1,12 @@
+-- Profile and email changes used to be recorded with their values. Keep only
+-- which fields changed, as the service does now; the append-only trigger is
+-- lifted for this one scrub.
+ALTER TABLE audit_events DISABLE TRIGGER audit_events_append_only;
+
+UPDATE audit_events
+SET changes = (SELECT jsonb_object_agg(key, '{"redacted": true}'::jsonb) FROM jsonb_each(changes))
+WHERE changes IS NOT NULL AND changes <> '{}'::jsonb
+    AND action IN ('profile.update', 'photo.upload', 'photo.delete',
+                   'email.change_request', 'email.change', 'email.revert');
+
+ALTER TABLE audit_events ENABLE TRIGGER audit_events_append_only;
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS cars_touch_updated_at ON cars;
+CREATE TRIGGER cars_touch_updated_at
+    BEFORE UPDATE ON cars
+    FOR EACH ROW EXECUTE FUNCTION cars_touch_updated_at();
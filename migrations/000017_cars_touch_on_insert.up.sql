@@ -0,0 +1,7 @@
+-- The matcher picks up new listings by updated_at too, and the column
+-- default is off by the Asia/Seoul offset: stamp inserts like updates, no
+-- matter what the car service writes.
+DROP TRIGGER IF EXISTS cars_touch_updated_at ON cars;
+CREATE TRIGGER cars_touch_updated_at
+    BEFORE INSERT OR UPDATE ON cars
+    FOR EACH ROW EXECUTE FUNCTION cars_touch_updated_at();
@@ -0,0 +1,7 @@
+-- The watchers each price change was announced to, so that a failure to
+-- notify one of them does not announce the change again to the others.
+CREATE TABLE IF NOT EXISTS price_drop_deliveries (
+    change_id BIGINT NOT NULL REFERENCES car_price_history(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (change_id, user_id)
+);
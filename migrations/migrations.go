@@ -0,0 +1,55 @@
+// Package migrations embeds the SQL migrations, so the binary can apply
+// them itself instead of relying on the migrate CLI being around.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+//go:embed *.sql
+var FS embed.FS
+
+// Migration is one numbered step of the schema.
+type Migration struct {
+	Version uint
+	Name    string
+}
+
+var fileName = regexp.MustCompile(`^([0-9]+)_(.*)\.up\.sql$`)
+
+// List returns the migrations of fsys, oldest first. fsys is FS, or the
+// migrations directory when creating a new one.
+func List(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []Migration
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, Migration{Version: uint(version), Name: match[2]})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Latest returns the version the embedded migrations bring the schema to.
+func Latest() (uint, error) {
+	migrations, err := List(FS)
+	if err != nil || len(migrations) == 0 {
+		return 0, err
+	}
+	return migrations[len(migrations)-1].Version, nil
+}
@@ -1,5 +1,7 @@
 package model
 
+import pb "wegugin/genproto/user"
+
 type UpdateUser struct {
 	Name        string `json:"name,omitempty"`
 	Surname     string `json:"surname,omitempty"`
@@ -13,3 +15,51 @@ type ResetPassword struct {
 	NewPassword string `json:"new_password,omitempty"`
 	OldPassword string `json:"old_password,omitempty"`
 }
+
+// ChangeEmail starts an email change, UpdateUser leaves the email alone.
+type ChangeEmail struct {
+	NewEmail string `json:"new_email,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+type SavedSearch struct {
+	Name      string        `json:"name,omitempty"`
+	Filter    *pb.CarFilter `json:"filter,omitempty"`
+	Frequency string        `json:"frequency,omitempty"`
+	Active    *bool         `json:"active,omitempty"`
+}
+
+type UpdateRole struct {
+	Role string `json:"role,omitempty"`
+}
+
+type AdminListUsers struct {
+	Email       string `form:"email"`
+	PhoneNumber string `form:"phone_number"`
+	Name        string `form:"name"`
+	Role        string `form:"role"`
+	CreatedFrom string `form:"created_from"`
+	CreatedTo   string `form:"created_to"`
+	Deleted     string `form:"deleted"`
+	Page        int32  `form:"page"`
+	Limit       int32  `form:"limit"`
+}
+
+type SuspendUser struct {
+	Reason    string `json:"reason,omitempty"`
+	ExpiresAt string `json:"expires_at,omitempty"`
+}
+
+type LiftSuspension struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+type AuditEventFilter struct {
+	ActorId  string `form:"actor_id"`
+	TargetId string `form:"target_id"`
+	Action   string `form:"action"`
+	From     string `form:"from"`
+	To       string `form:"to"`
+	Page     int32  `form:"page"`
+	Limit    int32  `form:"limit"`
+}
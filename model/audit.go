@@ -0,0 +1,54 @@
+package model
+
+// Actions recorded in the audit_events table.
+const (
+	AuditRegister       = "register"
+	AuditLoginSuccess   = "login.success"
+	AuditLoginFailure   = "login.failure"
+	AuditPasswordChange = "password.change"
+	AuditPasswordReset  = "password.reset"
+	AuditForceReset     = "password.force_reset"
+	AuditProfileUpdate  = "profile.update"
+	AuditPhotoUpload    = "photo.upload"
+	AuditPhotoDelete    = "photo.delete"
+	AuditRoleChange     = "role.change"
+	AuditUserDelete     = "user.delete"
+	AuditUserHardDelete = "user.hard_delete"
+	AuditUserRestore    = "user.restore"
+	AuditUserPurge      = "user.purge"
+	AuditUserLookup     = "user.lookup"
+	AuditSessionRevoke  = "session.revoke"
+	AuditIdentityLink   = "identity.link"
+	AuditIdentityUnlink = "identity.unlink"
+	AuditEmailRequest   = "email.change_request"
+	AuditEmailChange    = "email.change"
+	AuditEmailRevert    = "email.revert"
+	AuditEmailResend    = "email.resend"
+	AuditPrivacyUpdate  = "privacy.update"
+	AuditTokenKeyRotate = "token.key_rotate"
+)
+
+// AuditEvent is a row of the audit_events table. ActorId is empty for
+// anonymous callers, TargetId when the affected user is unknown.
+type AuditEvent struct {
+	ActorId   string
+	TargetId  string
+	Action    string
+	Changes   map[string]Change
+	Reason    string
+	IP        string
+	UserAgent string
+	RequestId string
+}
+
+// Change is the old and new value of one field.
+type Change struct {
+	Old      interface{} `json:"old,omitempty"`
+	New      interface{} `json:"new,omitempty"`
+	Redacted bool        `json:"redacted,omitempty"`
+}
+
+// Redacted records that a field of personal data changed, without its values:
+// the audit trail is append-only and outlives purged users, so it must not
+// keep what the purge erases.
+var Redacted = Change{Redacted: true}
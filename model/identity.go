@@ -0,0 +1,20 @@
+package model
+
+// Identity is a provider account linked to a user.
+type Identity struct {
+	UserId   string
+	Provider string
+	Subject  string
+	Email    string
+}
+
+// OIDCState is kept server side while the user signs in at the provider.
+// UserId is set when a logged in user links the provider account. Browser
+// is the hash of the nonce cookie of the browser that started the sign in.
+type OIDCState struct {
+	Provider string `json:"provider"`
+	Verifier string `json:"verifier"`
+	Nonce    string `json:"nonce"`
+	UserId   string `json:"user_id,omitempty"`
+	Browser  string `json:"browser"`
+}
@@ -0,0 +1,30 @@
+package model
+
+// Notification is a row of the notifications table. Background workers
+// create them; the mobile apps read them through the notification service.
+type Notification struct {
+	UserId  string
+	Type    string
+	Message string
+}
+
+// CarMatch is the part of a cars row needed to describe it in a notification.
+type CarMatch struct {
+	Id       string
+	Make     string
+	Model    string
+	Year     int32
+	Price    float64
+	Location string
+}
+
+// PriceChange is a car_price_history row together with the car it belongs to.
+type PriceChange struct {
+	Id       int64
+	CarId    string
+	Make     string
+	Model    string
+	Year     int32
+	OldPrice float64
+	Price    float64
+}
@@ -0,0 +1,31 @@
+package model
+
+// Visibility of a profile field to other users.
+const (
+	VisibilityNobody   = "nobody"
+	VisibilityLoggedIn = "logged_in"
+	VisibilityEveryone = "everyone"
+)
+
+// Visible reports whether a field with the given visibility is shown to a
+// viewer, who is logged in when viewerId is not empty.
+func Visible(visibility, viewerId string) bool {
+	switch visibility {
+	case VisibilityEveryone:
+		return true
+	case VisibilityLoggedIn:
+		return viewerId != ""
+	default:
+		return false
+	}
+}
+
+func ValidVisibility(visibility string) bool {
+	return visibility == VisibilityNobody || visibility == VisibilityLoggedIn || visibility == VisibilityEveryone
+}
+
+// PrivacySettings says who sees each field on the public profile.
+type PrivacySettings struct {
+	PhoneNumber string `json:"phone_number"`
+	Email       string `json:"email"`
+}
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// Session is a new user_sessions row, created on every login.
+type Session struct {
+	UserId     string
+	DeviceName string
+	Platform   string
+	IP         string
+	UserAgent  string
+	ExpiresAt  time.Time
+}
@@ -14,14 +14,8 @@ fi
 echo "Running migrations..."
 echo "Database: $PDB_NAME"
 
-# Check if migrate command exists
-if ! command -v migrate >/dev/null 2>&1; then
-    echo "Installing migrate tool..."
-    go install -tags 'postgres' github.com/golang-migrate/migrate/v4/cmd/migrate@latest
-fi
-
-# Run migrations
-migrate -path migrations -database "$DATABASE_URL" up
+# The migrations are embedded in the service binary
+go run ./cmd migrate up
 
 if [ $? -eq 0 ]; then
     echo "Migrations completed successfully!"
@@ -29,5 +23,3 @@ else
     echo "Migration failed!"
     exit 1
 fi
-
-
@@ -0,0 +1,324 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"wegugin/api/password"
+	"wegugin/api/throttle"
+	pb "wegugin/genproto/user"
+	"wegugin/model"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const adminMethodPrefix = "/user.User/Admin"
+
+type actorKey struct{}
+
+// actorId returns the id of the admin who made the call, see AdminInterceptor.
+func actorId(ctx context.Context) string {
+	id, _ := ctx.Value(actorKey{}).(string)
+	return id
+}
+
+// AdminInterceptor guards the Admin* rpc methods. The caller must pass the
+// token of a user whose current role in the database is admin in the
+// "authorization" metadata. The user must not be suspended and the token's
+// session not revoked, as the database has it: the Redis markers the
+// gateway checks may be missing.
+func (s *UserService) AdminInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !strings.HasPrefix(info.FullMethod, adminMethodPrefix) {
+		return handler(ctx, req)
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	tokens := md.Get("authorization")
+	if len(tokens) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "authorization is required")
+	}
+	id, _, err := s.Tokens.GetUserIdFromToken(tokens[0])
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid token provided")
+	}
+	user, err := s.User.User().GetUserById(ctx, &pb.UserId{Id: id})
+	if err != nil || user.Role != "admin" {
+		return nil, status.Error(codes.PermissionDenied, "admin role is required")
+	}
+	suspended, err := s.User.Suspension().IsSuspended(ctx, id)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error checking suspension: %v", err))
+		return nil, status.Error(codes.Unavailable, "account can not be checked, try again later")
+	}
+	if suspended {
+		return nil, status.Error(codes.PermissionDenied, "account is suspended")
+	}
+	if sid := s.Tokens.GetSessionId(tokens[0]); sid != "" {
+		revoked, err := s.User.Session().IsSessionRevoked(ctx, sid)
+		if err != nil {
+			s.Logger.Error(fmt.Sprintf("error checking session: %v", err))
+			return nil, status.Error(codes.Unavailable, "session can not be checked, try again later")
+		}
+		if revoked {
+			return nil, status.Error(codes.Unauthenticated, "session has been revoked")
+		}
+	}
+
+	return handler(context.WithValue(ctx, actorKey{}, id), req)
+}
+
+func (s *UserService) AdminListUsers(ctx context.Context, req *pb.AdminListUsersReq) (*pb.AdminUserList, error) {
+	s.Logger.Info("AdminListUsers rpc method is working")
+	resp, err := s.User.User().ListUsers(ctx, req)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error listing users: %v", err))
+		return nil, err
+	}
+	s.Logger.Info("AdminListUsers rpc method finished")
+	return resp, nil
+}
+
+func (s *UserService) AdminGetUser(ctx context.Context, req *pb.UserId) (*pb.AdminUser, error) {
+	s.Logger.Info("AdminGetUser rpc method is working")
+	resp, err := s.User.User().GetUserByIdWithDeleted(ctx, req)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error getting user: %v", err))
+		return nil, err
+	}
+	s.Logger.Info("AdminGetUser rpc method finished")
+	return resp, nil
+}
+
+func (s *UserService) AdminUpdateRole(ctx context.Context, req *pb.UpdateRoleReq) (*pb.Void, error) {
+	s.Logger.Info("AdminUpdateRole rpc method is working")
+	if req.Id == actorId(ctx) {
+		s.Logger.Error("admin tried to change own role")
+		return nil, status.Error(codes.FailedPrecondition, "admins cannot change their own role")
+	}
+	before, err := s.User.User().GetUserByIdWithDeleted(ctx, &pb.UserId{Id: req.Id})
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error getting user: %v", err))
+		return nil, err
+	}
+	err = s.User.User().UpdateRole(ctx, req)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error updating role: %v", err))
+		return nil, err
+	}
+	s.audit(ctx, &model.AuditEvent{
+		TargetId: req.Id,
+		Action:   model.AuditRoleChange,
+		Changes:  map[string]model.Change{"role": {Old: before.Role, New: req.Role}},
+	})
+	s.Logger.Info("AdminUpdateRole rpc method finished")
+	return &pb.Void{}, nil
+}
+
+// AdminForcePasswordReset blocks logins with the current password and mails
+// the user the same reset code that /auth/forgot-password sends.
+func (s *UserService) AdminForcePasswordReset(ctx context.Context, req *pb.UserId) (*pb.Void, error) {
+	s.Logger.Info("AdminForcePasswordReset rpc method is working")
+	user, err := s.User.User().GetUserById(ctx, req)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error getting user: %v", err))
+		return nil, err
+	}
+	err = s.User.User().RequirePasswordReset(ctx, req)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error requiring password reset: %v", err))
+		return nil, err
+	}
+	code, err := s.Email.EmailCode(user.Email)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error sending reset code: %v", err))
+		return nil, err
+	}
+	err = s.Redis.StoreCodes(ctx, code, user.Email)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error storing reset code: %v", err))
+		return nil, err
+	}
+	s.audit(ctx, &model.AuditEvent{TargetId: req.Id, Action: model.AuditForceReset})
+	s.Logger.Info("AdminForcePasswordReset rpc method finished")
+	return &pb.Void{}, nil
+}
+
+func (s *UserService) AdminRestoreUser(ctx context.Context, req *pb.UserId) (*pb.Void, error) {
+	s.Logger.Info("AdminRestoreUser rpc method is working")
+	err := s.User.User().RestoreUser(ctx, req)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error restoring user: %v", err))
+		return nil, err
+	}
+	s.audit(ctx, &model.AuditEvent{TargetId: req.Id, Action: model.AuditUserRestore})
+	s.Logger.Info("AdminRestoreUser rpc method finished")
+	return &pb.Void{}, nil
+}
+
+func (s *UserService) AdminHardDeleteUser(ctx context.Context, req *pb.UserId) (*pb.Void, error) {
+	s.Logger.Info("AdminHardDeleteUser rpc method is working")
+	if req.Id == actorId(ctx) {
+		s.Logger.Error("admin tried to delete own account")
+		return nil, status.Error(codes.FailedPrecondition, "admins cannot delete their own account")
+	}
+	user, err := s.User.User().GetUserByIdWithDeleted(ctx, req)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error hard deleting user: %v", err))
+		return nil, err
+	}
+	// The rows naming the files go with the user
+	images, exports, err := s.User.User().PurgeFiles(ctx, req.Id)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error hard deleting user: %v", err))
+		return nil, err
+	}
+	err = s.User.User().HardDeleteUser(ctx, req)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error hard deleting user: %v", err))
+		return nil, err
+	}
+	err = s.Minio.RemoveUserFiles(ctx, user.Photo, images, exports, s.Config.Export.EXPORT_IMAGES_BUCKET, s.Config.Export.EXPORT_BUCKET)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error removing files of deleted user: %v", err))
+	}
+	s.audit(ctx, &model.AuditEvent{TargetId: req.Id, Action: model.AuditUserHardDelete})
+	s.Logger.Info("AdminHardDeleteUser rpc method finished")
+	return &pb.Void{}, nil
+}
+
+// The methods below are not served over gRPC, cmd/admin calls them for
+// operators with database access. Their audit events have no actor.
+
+// CreateAdmin registers a user with the admin role and returns the id. Unlike
+// Register it starts no session.
+func (s *UserService) CreateAdmin(ctx context.Context, req *pb.RegisterReq) (string, error) {
+	owner := password.Owner{Email: req.Email, Name: req.Name, Surname: req.Surname}
+	if err := s.checkPassword(ctx, req.Password, owner, ""); err != nil {
+		return "", err
+	}
+	// One write, so a failure does not leave a regular user with the email
+	resp, err := s.User.User().CreateUserWithRole(ctx, req, "admin")
+	if err != nil {
+		return "", err
+	}
+	id, _, err := s.Tokens.GetUserIdFromToken(resp.Token)
+	if err != nil {
+		return "", err
+	}
+	s.audit(ctx, &model.AuditEvent{
+		TargetId: id,
+		Action:   model.AuditRegister,
+		Changes:  map[string]model.Change{"role": {New: "admin"}},
+	})
+	return id, nil
+}
+
+// LookupUser finds a user, deleted ones included, by email or phone number.
+func (s *UserService) LookupUser(ctx context.Context, login string) (*pb.AdminUser, error) {
+	id, err := s.User.User().UserIdByLogin(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.User.User().GetUserByIdWithDeleted(ctx, &pb.UserId{Id: id})
+	if err != nil {
+		return nil, err
+	}
+	// The login itself would put the email or phone number in the trail
+	kind := "by phone number"
+	if strings.Contains(login, "@") {
+		kind = "by email"
+	}
+	s.audit(ctx, &model.AuditEvent{TargetId: id, Action: model.AuditUserLookup, Reason: kind})
+	return user, nil
+}
+
+// ResendRestoreLink mails a deleted user the restore link again, while the
+// restore window is open.
+func (s *UserService) ResendRestoreLink(ctx context.Context, id string) error {
+	user, err := s.User.User().GetUserByIdWithDeleted(ctx, &pb.UserId{Id: id})
+	if err != nil {
+		return err
+	}
+	if user.DeletedAt == 0 {
+		return fmt.Errorf("user %s is not deleted", id)
+	}
+	if time.Unix(user.DeletedAt, 0).Add(s.Config.Account.RESTORE_WINDOW).Before(time.Now()) {
+		return fmt.Errorf("the restore window of user %s has closed", id)
+	}
+	if err := s.sendRestoreLink(ctx, id); err != nil {
+		return err
+	}
+	s.audit(ctx, &model.AuditEvent{TargetId: id, Action: model.AuditEmailResend, Reason: "restore link"})
+	return nil
+}
+
+// ResendUnlockLink mails the owner of a locked out login the unlock link
+// again, good for as long as the lock still lasts.
+func (s *UserService) ResendUnlockLink(ctx context.Context, login string) error {
+	id, err := s.User.User().UserIdByLogin(ctx, login)
+	if err != nil {
+		return err
+	}
+	err = throttle.Login(s.Config.Throttle, s.Redis).Check(ctx, login, "")
+	lock, ok := throttle.IsThrottled(err)
+	if !ok && err != nil {
+		return err
+	}
+	if !ok || !lock.Locked {
+		return fmt.Errorf("%s is not locked", login)
+	}
+	if err := s.sendUnlockLink(ctx, id, login, lock.RetryAfter); err != nil {
+		return err
+	}
+	s.audit(ctx, &model.AuditEvent{TargetId: id, Action: model.AuditEmailResend, Reason: "unlock link"})
+	return nil
+}
+
+// RecordTokenKeyRotation notes in the audit trail that a new TOKEN_KEY, with
+// the given kid, was made.
+func (s *UserService) RecordTokenKeyRotation(ctx context.Context, kid string) {
+	s.audit(ctx, &model.AuditEvent{Action: model.AuditTokenKeyRotate, Reason: "kid " + kid})
+}
+
+// RevokeAllSessions ends every session of the user.
+func (s *UserService) RevokeAllSessions(ctx context.Context, userId string) error {
+	if err := s.revokeOtherSessions(ctx, userId, ""); err != nil {
+		return err
+	}
+	s.audit(ctx, &model.AuditEvent{TargetId: userId, Action: model.AuditSessionRevoke, Reason: "all sessions"})
+	return nil
+}
+
+// PurgeDeletedUser does what the account purger does once the restore window
+// has passed, right away: the photo, car images and export archives go from
+// MinIO, then the user is deleted or anonymized. Active users are refused.
+func (s *UserService) PurgeDeletedUser(ctx context.Context, id string, anonymize bool) error {
+	user, err := s.User.User().GetUserByIdWithDeleted(ctx, &pb.UserId{Id: id})
+	if err != nil {
+		return err
+	}
+	if user.DeletedAt == 0 {
+		return fmt.Errorf("user %s is not deleted", id)
+	}
+	images, exports, err := s.User.User().PurgeFiles(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = s.Minio.RemoveUserFiles(ctx, user.Photo, images, exports, s.Config.Export.EXPORT_IMAGES_BUCKET, s.Config.Export.EXPORT_BUCKET)
+	if err != nil {
+		return err
+	}
+	if err := s.User.User().PurgeUser(ctx, id, anonymize); err != nil {
+		return err
+	}
+	mode := "delete"
+	if anonymize {
+		mode = "anonymize"
+	}
+	s.audit(ctx, &model.AuditEvent{TargetId: id, Action: model.AuditUserPurge, Reason: mode})
+	return nil
+}
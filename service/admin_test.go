@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"wegugin/api/auth"
+	"wegugin/config"
+	pb "wegugin/genproto/user"
+	"wegugin/model"
+	"wegugin/storage/memory"
+
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// TestAdminInterceptor checks that an admin token is refused once its
+// session is revoked or the admin suspended, with only the database to
+// tell.
+func TestAdminInterceptor(t *testing.T) {
+	conf, err := config.New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.Password.BCRYPT_COST = bcrypt.MinCost
+	s := &UserService{
+		User: memory.New(conf), Tokens: auth.New(conf.Token), Config: conf,
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	ctx := context.Background()
+	res, err := s.User.User().CreateUser(ctx, &pb.RegisterReq{
+		Email: "admin@example.com", Name: "Admin", Password: "correct horse battery staple",
+		Phone: "+821012345678", BirthDate: "02-01-1990", Gender: "other",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _, err := s.Tokens.GetUserIdFromToken(res.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.User.User().UpdateRole(ctx, &pb.UpdateRoleReq{Id: id, Role: "admin"}); err != nil {
+		t.Fatal(err)
+	}
+	session := func() (string, string) {
+		sid, err := s.User.Session().CreateSession(ctx, &model.Session{UserId: id, ExpiresAt: auth.TokenExpiry()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, err := s.Tokens.GenerateSessionToken(id, "admin", sid, auth.TokenExpiry())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sid, token
+	}
+	call := func(token string) error {
+		ctx := metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", token))
+		info := &grpc.UnaryServerInfo{FullMethod: adminMethodPrefix + "ListUsers"}
+		_, err := s.AdminInterceptor(ctx, nil, info, func(context.Context, interface{}) (interface{}, error) {
+			return nil, nil
+		})
+		return err
+	}
+
+	sid, token := session()
+	if err := call(token); err != nil {
+		t.Fatalf("admin with an active session: %v", err)
+	}
+	if _, err := s.User.Session().RevokeSession(ctx, &pb.SessionReq{UserId: id, SessionId: sid}); err != nil {
+		t.Fatal(err)
+	}
+	if err := call(token); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("revoked session: got %v, want Unauthenticated", err)
+	}
+
+	_, token = session()
+	if _, err := s.User.Suspension().SuspendUser(ctx, &pb.SuspendUserReq{UserId: id, Reason: "spam"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := call(token); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("suspended admin: got %v, want PermissionDenied", err)
+	}
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"wegugin/api/middleware"
+	pb "wegugin/genproto/user"
+	"wegugin/model"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// audit records event with the caller details forwarded by the gateway, see
+// middleware.Gateway. An admin making the call is the actor
+// unless the event names one. Failing to record never fails the request.
+func (s *UserService) audit(ctx context.Context, event *model.AuditEvent) {
+	if admin := actorId(ctx); admin != "" && event.ActorId == "" {
+		event.ActorId = admin
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	event.RequestId = firstValue(md, middleware.RequestIdKey)
+	event.IP = firstValue(md, middleware.ClientIPKey)
+	event.UserAgent = firstValue(md, middleware.UserAgentKey)
+
+	if err := s.User.Audit().RecordEvent(ctx, event); err != nil {
+		s.Logger.Error(fmt.Sprintf("error recording audit event %s: %v", event.Action, err))
+	}
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// profileChanges diffs the fields an UpdateUserRequest sets against the
+// stored user. Every profile field is personal data, so only the names of
+// the changed ones are recorded.
+func profileChanges(before *pb.GetUserResponse, req *pb.UpdateUserRequest) map[string]model.Change {
+	changes := make(map[string]model.Change)
+	diff := func(field, old, new string) {
+		if new != "" && new != old {
+			changes[field] = model.Redacted
+		}
+	}
+	diff("name", before.Name, req.Name)
+	diff("surname", before.Surname, req.Surname)
+	// birth_date comes in as DD-MM-YYYY and is read back as YYYY-MM-DD
+	if birthDate, err := time.Parse("02-01-2006", req.BirthDate); err == nil {
+		diff("birth_date", before.BirthDate, birthDate.Format("2006-01-02"))
+	}
+	diff("gender", before.Gender, req.Gender)
+	diff("address", before.Address, req.Address)
+	diff("phone_number", before.PhoneNumber, req.PhoneNumber)
+	diff("photo", before.Photo, req.Photo)
+	return changes
+}
+
+func (s *UserService) AdminListAuditEvents(ctx context.Context, req *pb.AuditEventFilter) (*pb.AuditEventList, error) {
+	s.Logger.Info("AdminListAuditEvents rpc method is working")
+	resp, err := s.User.Audit().ListAuditEvents(ctx, req)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error listing audit events: %v", err))
+		return nil, err
+	}
+	s.Logger.Info("AdminListAuditEvents rpc method finished")
+	return resp, nil
+}
+
+// ListSecurityActivity lists the events that affected the user in
+// req.TargetId, whoever the actor was.
+func (s *UserService) ListSecurityActivity(ctx context.Context, req *pb.AuditEventFilter) (*pb.AuditEventList, error) {
+	s.Logger.Info("ListSecurityActivity rpc method is working")
+	if req.TargetId == "" {
+		return nil, fmt.Errorf("target_id is required")
+	}
+	resp, err := s.User.Audit().ListAuditEvents(ctx, &pb.AuditEventFilter{
+		TargetId: req.TargetId,
+		Action:   req.Action,
+		From:     req.From,
+		To:       req.To,
+		Page:     req.Page,
+		Limit:    req.Limit,
+	})
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error listing security activity: %v", err))
+		return nil, err
+	}
+	s.Logger.Info("ListSecurityActivity rpc method finished")
+	return resp, nil
+}
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	pb "wegugin/genproto/user"
+)
+
+// TestProfileChangesRedacted checks that a profile update is recorded by the
+// names of the changed fields, without their values.
+func TestProfileChangesRedacted(t *testing.T) {
+	before := &pb.GetUserResponse{Name: "Old", PhoneNumber: "+821011112222", Address: "Seoul"}
+	changes := profileChanges(before, &pb.UpdateUserRequest{
+		Name: "New", PhoneNumber: "+821033334444", Address: "Seoul", BirthDate: "02-01-1990",
+	})
+	data, err := json.Marshal(changes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"birth_date":{"redacted":true},"name":{"redacted":true},"phone_number":{"redacted":true}}`
+	if string(data) != want {
+		t.Errorf("changes = %s, want %s", data, want)
+	}
+	for _, value := range []string{"Old", "New", "1111", "3333", "1990"} {
+		if strings.Contains(string(data), value) {
+			t.Errorf("changes hold %q", value)
+		}
+	}
+}
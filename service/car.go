@@ -0,0 +1,18 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	pb "wegugin/genproto/user"
+)
+
+func (s *UserService) GetCarPriceHistory(ctx context.Context, req *pb.CarId) (*pb.PriceHistory, error) {
+	s.Logger.Info("GetCarPriceHistory rpc method is working")
+	resp, err := s.User.Car().GetPriceHistory(ctx, req)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error getting car price history: %v", err))
+		return nil, err
+	}
+	s.Logger.Info("GetCarPriceHistory rpc method finished")
+	return resp, nil
+}
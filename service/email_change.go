@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"wegugin/api/email"
+	pb "wegugin/genproto/user"
+	"wegugin/model"
+	"wegugin/storage"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cast"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	emailChangePurpose = "email-change"
+	emailRevertPurpose = "email-revert"
+)
+
+// RequestEmailChange mails a confirmation link to the new address and a
+// notice with a revert link to the current one. The email only changes once
+// the link is confirmed.
+func (s *UserService) RequestEmailChange(ctx context.Context, req *pb.EmailChangeReq) (*pb.Void, error) {
+	s.Logger.Info("RequestEmailChange rpc method is working")
+	user, err := s.User.User().GetUserById(ctx, &pb.UserId{Id: req.UserId})
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error getting user: %v", err))
+		return nil, err
+	}
+	if strings.EqualFold(user.Email, req.NewEmail) {
+		return nil, status.Error(codes.InvalidArgument, "new email is the current one")
+	}
+	err = s.User.User().CheckPassword(ctx, req.UserId, req.Password)
+	if errors.Is(err, storage.ErrInvalidCredentials) {
+		s.Logger.Error("wrong password for email change")
+		return nil, status.Error(codes.Unauthenticated, "password is incorrect")
+	}
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error checking password: %v", err))
+		return nil, err
+	}
+	_, err = s.User.User().GetUserByEmail(ctx, &pb.GetUSerByEmailReq{Email: req.NewEmail})
+	if err == nil {
+		return nil, status.Error(codes.AlreadyExists, storage.ErrEmailTaken.Error())
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		s.Logger.Error(fmt.Sprintf("error checking new email: %v", err))
+		return nil, err
+	}
+
+	conf := s.Config
+	changeId := uuid.NewString()
+	emails := map[string]interface{}{"old_email": user.Email, "new_email": req.NewEmail}
+	confirmToken, err := s.Tokens.GeneratePurposeToken(emailChangePurpose, req.UserId, map[string]interface{}{
+		"jti":       changeId,
+		"sid":       req.SessionId,
+		"old_email": user.Email,
+		"new_email": req.NewEmail,
+	}, time.Now().Add(conf.Account.EMAIL_CHANGE_TTL))
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error generating confirmation link: %v", err))
+		return nil, err
+	}
+	revertToken, err := s.Tokens.GeneratePurposeToken(emailRevertPurpose, req.UserId, emails,
+		time.Now().Add(conf.Account.EMAIL_REVERT_TTL))
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error generating revert link: %v", err))
+		return nil, err
+	}
+	err = s.Redis.StoreEmailChange(ctx, req.UserId, changeId, conf.Account.EMAIL_CHANGE_TTL)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error storing email change: %v", err))
+		return nil, err
+	}
+
+	err = s.Email.SendLink(req.NewEmail, email.LinkMessage{
+		Subject: "Confirm your new email",
+		Title:   "Confirm your new email",
+		Text: fmt.Sprintf("You asked to use this address for your account instead of %s. "+
+			"Confirm it within %s to make the change.", user.Email, conf.Account.EMAIL_CHANGE_TTL),
+		Link:   conf.Server.PUBLIC_URL + "/auth/email-change/confirm?token=" + url.QueryEscape(confirmToken),
+		Button: "Confirm my new email",
+	})
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error sending confirmation link: %v", err))
+		return nil, err
+	}
+	err = s.Email.SendLink(user.Email, email.LinkMessage{
+		Subject: "Your email is being changed",
+		Title:   "Your email is being changed",
+		Text: fmt.Sprintf("Someone asked to change the email of your account to %s. If it was not you, "+
+			"undo it below: this keeps your current email, logs you out everywhere and asks for a new password.",
+			req.NewEmail),
+		Link:   conf.Server.PUBLIC_URL + "/auth/email-change/revert?token=" + url.QueryEscape(revertToken),
+		Button: "This was not me",
+	})
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error sending email change notice: %v", err))
+		return nil, err
+	}
+	s.audit(ctx, &model.AuditEvent{
+		ActorId:  req.UserId,
+		TargetId: req.UserId,
+		Action:   model.AuditEmailRequest,
+		Changes:  map[string]model.Change{"email": model.Redacted},
+	})
+	s.Logger.Info("RequestEmailChange rpc method finished")
+	return &pb.Void{}, nil
+}
+
+// ConfirmEmailChange swaps in the new email and logs out every session but
+// the one that asked for the change.
+func (s *UserService) ConfirmEmailChange(ctx context.Context, req *pb.EmailChangeTokenReq) (*pb.Void, error) {
+	s.Logger.Info("ConfirmEmailChange rpc method is working")
+	claims, err := s.Tokens.ParsePurposeToken(emailChangePurpose, req.Token)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("invalid email change token: %v", err))
+		return nil, status.Error(codes.Unauthenticated, "invalid or expired link")
+	}
+	id := cast.ToString(claims["user_id"])
+	pending, err := s.Redis.TakeEmailChange(ctx, id, cast.ToString(claims["jti"]))
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error taking email change: %v", err))
+		return nil, err
+	}
+	if !pending {
+		s.Logger.Error("email change is not pending anymore")
+		return nil, status.Error(codes.Unauthenticated, "invalid or expired link")
+	}
+
+	oldEmail, newEmail := cast.ToString(claims["old_email"]), cast.ToString(claims["new_email"])
+	err = s.User.User().ChangeEmail(ctx, id, oldEmail, newEmail)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error changing email: %v", err))
+		if errors.Is(err, storage.ErrEmailTaken) {
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, status.Error(codes.Unauthenticated, "invalid or expired link")
+		}
+		return nil, err
+	}
+	if err := s.revokeOtherSessions(ctx, id, cast.ToString(claims["sid"])); err != nil {
+		s.Logger.Error(fmt.Sprintf("error revoking sessions: %v", err))
+		return nil, err
+	}
+	s.audit(ctx, &model.AuditEvent{
+		ActorId:  id,
+		TargetId: id,
+		Action:   model.AuditEmailChange,
+		Changes:  map[string]model.Change{"email": model.Redacted},
+	})
+	s.Logger.Info("ConfirmEmailChange rpc method finished")
+	return &pb.Void{}, nil
+}
+
+// RevertEmailChange is for owners who did not ask for the change. It keeps
+// or puts back the old email and, since whoever asked knew the password,
+// logs out every session and requires a password reset.
+func (s *UserService) RevertEmailChange(ctx context.Context, req *pb.EmailChangeTokenReq) (*pb.Void, error) {
+	s.Logger.Info("RevertEmailChange rpc method is working")
+	claims, err := s.Tokens.ParsePurposeToken(emailRevertPurpose, req.Token)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("invalid email revert token: %v", err))
+		return nil, status.Error(codes.Unauthenticated, "invalid or expired link")
+	}
+	id := cast.ToString(claims["user_id"])
+	if err := s.Redis.CancelEmailChange(ctx, id); err != nil {
+		s.Logger.Error(fmt.Sprintf("error cancelling email change: %v", err))
+		return nil, err
+	}
+
+	oldEmail, newEmail := cast.ToString(claims["old_email"]), cast.ToString(claims["new_email"])
+	// Nothing to put back when the change was never confirmed
+	err = s.User.User().ChangeEmail(ctx, id, newEmail, oldEmail)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		s.Logger.Error(fmt.Sprintf("error reverting email: %v", err))
+		if errors.Is(err, storage.ErrEmailTaken) {
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		}
+		return nil, err
+	}
+	if err := s.revokeOtherSessions(ctx, id, ""); err != nil {
+		s.Logger.Error(fmt.Sprintf("error revoking sessions: %v", err))
+		return nil, err
+	}
+	if err := s.User.User().RequirePasswordReset(ctx, &pb.UserId{Id: id}); err != nil {
+		s.Logger.Error(fmt.Sprintf("error requiring password reset: %v", err))
+		return nil, err
+	}
+	s.audit(ctx, &model.AuditEvent{
+		ActorId:  id,
+		TargetId: id,
+		Action:   model.AuditEmailRevert,
+		Changes:  map[string]model.Change{"email": model.Redacted},
+	})
+	s.Logger.Info("RevertEmailChange rpc method finished")
+	return &pb.Void{}, nil
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	pb "wegugin/genproto/user"
+	"wegugin/storage"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *UserService) RequestDataExport(ctx context.Context, req *pb.UserId) (*pb.DataExport, error) {
+	s.Logger.Info("RequestDataExport rpc method is working")
+	conf := s.Config
+	resp, err := s.User.Export().CreateExport(ctx, req.Id, conf.Export.EXPORT_COOLDOWN)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error requesting data export: %v", err))
+		if errors.Is(err, storage.ErrExportLimited) {
+			return nil, status.Error(codes.ResourceExhausted, err.Error())
+		}
+		return nil, err
+	}
+	s.Logger.Info("RequestDataExport rpc method finished")
+	return resp, nil
+}
+
+func (s *UserService) GetDataExport(ctx context.Context, req *pb.UserId) (*pb.DataExport, error) {
+	s.Logger.Info("GetDataExport rpc method is working")
+	resp, err := s.User.Export().LatestExport(ctx, req)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error getting data export: %v", err))
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, err
+	}
+	if resp.Status == "ready" {
+		conf := s.Config
+		object := fmt.Sprintf("%s/%s.zip", resp.UserId, resp.Id)
+		resp.DownloadUrl, err = s.Minio.PresignedURL(ctx, conf.Export.EXPORT_BUCKET, object, conf.Export.EXPORT_LINK_TTL)
+		if err != nil {
+			s.Logger.Error(fmt.Sprintf("error presigning data export: %v", err))
+			return nil, err
+		}
+	}
+	s.Logger.Info("GetDataExport rpc method finished")
+	return resp, nil
+}
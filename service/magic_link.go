@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+	"wegugin/api/email"
+	pb "wegugin/genproto/user"
+	"wegugin/model"
+	"wegugin/storage"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cast"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const magicLinkPurpose = "magic-link"
+
+// hashNonce keeps the device nonce itself out of the emailed link.
+func hashNonce(nonce string) string {
+	sum := sha256.Sum256([]byte(nonce))
+	return hex.EncodeToString(sum[:])
+}
+
+// RequestMagicLink mails a single use login link bound to req.Nonce, the
+// nonce cookie of the requesting device. Unknown emails get no mail and no
+// error, so the answer does not tell whether an account exists; the link is
+// made and mailed after answering, so neither does the time it takes.
+func (s *UserService) RequestMagicLink(ctx context.Context, req *pb.MagicLinkReq) (*pb.Void, error) {
+	s.Logger.Info("RequestMagicLink rpc method is working")
+	if req.Nonce == "" {
+		return nil, status.Error(codes.InvalidArgument, "nonce is required")
+	}
+	user, err := s.User.User().GetUserByEmail(ctx, &pb.GetUSerByEmailReq{Email: req.Email})
+	if errors.Is(err, sql.ErrNoRows) {
+		s.Logger.Info("RequestMagicLink rpc method finished")
+		return &pb.Void{}, nil
+	}
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error getting user: %v", err))
+		return nil, err
+	}
+
+	s.background.Add(1)
+	go func(ctx context.Context) {
+		defer s.background.Done()
+		if err := s.sendMagicLink(ctx, user, req.Nonce); err != nil {
+			s.Logger.Error(fmt.Sprintf("error sending magic link: %v", err))
+		}
+	}(context.WithoutCancel(ctx))
+	s.Logger.Info("RequestMagicLink rpc method finished")
+	return &pb.Void{}, nil
+}
+
+// sendMagicLink makes a login link for user, bound to nonce, and mails it.
+func (s *UserService) sendMagicLink(ctx context.Context, user *pb.GetUserResponse, nonce string) error {
+	conf := s.Config
+	linkId := uuid.NewString()
+	token, err := s.Tokens.GeneratePurposeToken(magicLinkPurpose, user.Id, map[string]interface{}{
+		"jti":   linkId,
+		"nonce": hashNonce(nonce),
+	}, time.Now().Add(conf.Account.MAGIC_LINK_TTL))
+	if err != nil {
+		return fmt.Errorf("generating the link: %w", err)
+	}
+	if err := s.Redis.StoreMagicLink(ctx, linkId, conf.Account.MAGIC_LINK_TTL); err != nil {
+		return fmt.Errorf("storing the link: %w", err)
+	}
+	return s.Email.SendLink(user.Email, email.LinkMessage{
+		Subject: "Your login link",
+		Title:   "Log in to your account",
+		Text: fmt.Sprintf("Use the button below to log in. The link works once, for %s, and only in the browser "+
+			"or app you requested it from. If you did not ask for it, you can ignore this email.", conf.Account.MAGIC_LINK_TTL),
+		Link:   conf.Server.PUBLIC_URL + "/auth/magic-link/consume?token=" + url.QueryEscape(token),
+		Button: "Log in",
+	})
+}
+
+// ConsumeMagicLink logs in with a link from RequestMagicLink. The nonce is
+// checked before the link is used up, so mail scanners opening the link
+// without the cookie do not spend it.
+func (s *UserService) ConsumeMagicLink(ctx context.Context, req *pb.ConsumeMagicLinkReq) (*pb.LoginRes, error) {
+	s.Logger.Info("ConsumeMagicLink rpc method is working")
+	claims, err := s.Tokens.ParsePurposeToken(magicLinkPurpose, req.Token)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("invalid magic link: %v", err))
+		return nil, status.Error(codes.Unauthenticated, "invalid or expired login link")
+	}
+	expected := cast.ToString(claims["nonce"])
+	if req.Nonce == "" || subtle.ConstantTimeCompare([]byte(hashNonce(req.Nonce)), []byte(expected)) != 1 {
+		s.Logger.Error("magic link opened on another device")
+		return nil, status.Error(codes.FailedPrecondition, "login link was requested on another device")
+	}
+	unused, err := s.Redis.TakeMagicLink(ctx, cast.ToString(claims["jti"]))
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error taking magic link: %v", err))
+		return nil, err
+	}
+	if !unused {
+		s.Logger.Error("magic link used again")
+		return nil, status.Error(codes.Unauthenticated, "invalid or expired login link")
+	}
+
+	id := cast.ToString(claims["user_id"])
+	resp, err := s.User.User().LoginById(ctx, id)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("login error: %v", err))
+		s.audit(ctx, &model.AuditEvent{TargetId: id, Action: model.AuditLoginFailure, Reason: err.Error()})
+		if errors.Is(err, storage.ErrUserSuspended) {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+		return nil, err
+	}
+	resp, err = s.startSession(ctx, resp)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error starting session: %v", err))
+		return nil, err
+	}
+	s.audit(ctx, &model.AuditEvent{ActorId: id, TargetId: id, Action: model.AuditLoginSuccess, Reason: magicLinkPurpose})
+	s.Logger.Info("ConsumeMagicLink rpc method finished")
+	return resp, nil
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"wegugin/api/middleware"
+	"wegugin/api/oidc"
+	pb "wegugin/genproto/user"
+	"wegugin/model"
+	"wegugin/storage"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// StartOIDC returns the provider's sign in page for a login, or for linking
+// the provider account to req.UserId when it is set. The sign in is bound to
+// req.Nonce, the nonce cookie of the browser starting it.
+func (s *UserService) StartOIDC(ctx context.Context, req *pb.OIDCStartReq) (*pb.OIDCStartRes, error) {
+	s.Logger.Info("StartOIDC rpc method is working")
+	provider := s.OIDC.Get(req.Provider)
+	if provider == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown provider %q", req.Provider)
+	}
+	if req.Nonce == "" {
+		return nil, status.Error(codes.InvalidArgument, "nonce is required")
+	}
+
+	state := &model.OIDCState{Provider: req.Provider, UserId: req.UserId, Browser: hashNonce(req.Nonce)}
+	key, err := oidc.RandomToken()
+	if err == nil {
+		state.Verifier, err = oidc.RandomToken()
+	}
+	if err == nil {
+		state.Nonce, err = oidc.RandomToken()
+	}
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error generating oidc state: %v", err))
+		return nil, err
+	}
+	err = s.Redis.StoreOIDCState(ctx, key, state, s.Config.OIDC.STATE_TTL)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error storing oidc state: %v", err))
+		return nil, err
+	}
+	url, err := provider.AuthCodeURL(ctx, key, state.Nonce, state.Verifier)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error building %s sign in url: %v", req.Provider, err))
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	s.Logger.Info("StartOIDC rpc method finished")
+	return &pb.OIDCStartRes{Url: url}, nil
+}
+
+// FinishOIDC handles the provider's callback, in the browser that started
+// the sign in only, so nobody can slip their own sign in into someone else's
+// browser. A login finds the user by the linked provider account only: local
+// emails are never verified, so one matching the provider's email is no proof
+// the same person owns both, and the user has to link from their account.
+func (s *UserService) FinishOIDC(ctx context.Context, req *pb.OIDCFinishReq) (*pb.OIDCFinishRes, error) {
+	s.Logger.Info("FinishOIDC rpc method is working")
+	provider := s.OIDC.Get(req.Provider)
+	if provider == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown provider %q", req.Provider)
+	}
+	state, err := s.Redis.TakeOIDCState(ctx, req.State)
+	if err != nil || state.Provider != req.Provider {
+		s.Logger.Error(fmt.Sprintf("invalid oidc state: %v", err))
+		return nil, status.Error(codes.InvalidArgument, "invalid or expired sign in, please try again")
+	}
+	if req.Nonce == "" || subtle.ConstantTimeCompare([]byte(hashNonce(req.Nonce)), []byte(state.Browser)) != 1 {
+		s.Logger.Error("oidc callback in another browser")
+		return nil, status.Error(codes.FailedPrecondition, "sign in was started in another browser")
+	}
+	identity, err := provider.Exchange(ctx, req.Code, state.Verifier, state.Nonce)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error signing in with %s: %v", req.Provider, err))
+		return nil, status.Error(codes.Unauthenticated, "sign in with the provider failed")
+	}
+
+	if state.UserId != "" {
+		if err := s.linkIdentity(ctx, state.UserId, req.Provider, identity); err != nil {
+			return nil, err
+		}
+		s.Logger.Info("FinishOIDC rpc method finished")
+		return &pb.OIDCFinishRes{Linked: true}, nil
+	}
+
+	userId, err := s.User.Identity().IdentityUser(ctx, req.Provider, identity.Subject)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = s.unlinkedSignIn(ctx, req.Provider, identity)
+	}
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error finding %s user: %v", req.Provider, err))
+		return nil, err
+	}
+
+	resp, err := s.User.User().LoginById(ctx, userId)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("login error: %v", err))
+		s.audit(ctx, &model.AuditEvent{TargetId: userId, Action: model.AuditLoginFailure, Reason: err.Error()})
+		if errors.Is(err, storage.ErrUserSuspended) {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+		return nil, err
+	}
+	resp, err = s.startSession(ctx, resp)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error starting session: %v", err))
+		return nil, err
+	}
+	s.audit(ctx, &model.AuditEvent{ActorId: userId, TargetId: userId, Action: model.AuditLoginSuccess, Reason: req.Provider})
+	s.Logger.Info("FinishOIDC rpc method finished")
+	return &pb.OIDCFinishRes{Token: resp.Token}, nil
+}
+
+// unlinkedSignIn explains why a sign in no account is linked to fails. When
+// the provider verified an email an account has, the owner of the email is
+// told to link from that account; an unverified email tells nobody anything.
+func (s *UserService) unlinkedSignIn(ctx context.Context, provider string, identity *oidc.Identity) error {
+	notFound := status.Error(codes.NotFound, "no account is linked to this sign in")
+	if identity.Email == "" || !identity.EmailVerified {
+		return notFound
+	}
+	_, err := s.User.User().GetUserByEmail(ctx, &pb.GetUSerByEmailReq{Email: identity.Email})
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFound
+	}
+	if err != nil {
+		return err
+	}
+	message := fmt.Sprintf("an account has this email, log in with its password and link your %s account from it", provider)
+	st, err := status.New(codes.FailedPrecondition, message).WithDetails(&errdetails.ErrorInfo{Reason: middleware.LinkRequired})
+	if err != nil {
+		return status.Error(codes.FailedPrecondition, message)
+	}
+	return st.Err()
+}
+
+func (s *UserService) linkIdentity(ctx context.Context, userId, provider string, identity *oidc.Identity) error {
+	err := s.User.Identity().LinkIdentity(ctx, &model.Identity{
+		UserId:   userId,
+		Provider: provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	})
+	if errors.Is(err, storage.ErrIdentityTaken) {
+		s.Logger.Error(fmt.Sprintf("error linking %s account: %v", provider, err))
+		return status.Errorf(codes.AlreadyExists, "a %s account is already linked", provider)
+	}
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error linking %s account: %v", provider, err))
+		return err
+	}
+	s.audit(ctx, &model.AuditEvent{
+		ActorId:  userId,
+		TargetId: userId,
+		Action:   model.AuditIdentityLink,
+		Changes:  map[string]model.Change{"provider": {New: provider}},
+	})
+	return nil
+}
+
+func (s *UserService) ListIdentities(ctx context.Context, req *pb.UserId) (*pb.IdentityList, error) {
+	s.Logger.Info("ListIdentities rpc method is working")
+	resp, err := s.User.Identity().ListIdentities(ctx, req)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error listing identities: %v", err))
+		return nil, err
+	}
+	s.Logger.Info("ListIdentities rpc method finished")
+	return resp, nil
+}
+
+// UnlinkIdentity removes a linked provider account. The user keeps their
+// password, so they can always log in without it.
+func (s *UserService) UnlinkIdentity(ctx context.Context, req *pb.IdentityReq) (*pb.Void, error) {
+	s.Logger.Info("UnlinkIdentity rpc method is working")
+	err := s.User.Identity().UnlinkIdentity(ctx, req)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error unlinking identity: %v", err))
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, status.Error(codes.NotFound, "provider is not linked")
+		}
+		return nil, err
+	}
+	s.audit(ctx, &model.AuditEvent{
+		ActorId:  req.UserId,
+		TargetId: req.UserId,
+		Action:   model.AuditIdentityUnlink,
+		Changes:  map[string]model.Change{"provider": {Old: req.Provider}},
+	})
+	s.Logger.Info("UnlinkIdentity rpc method finished")
+	return &pb.Void{}, nil
+}
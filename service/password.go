@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"wegugin/api/password"
+	pb "wegugin/genproto/user"
+)
+
+// checkPassword applies the password policy to a new password of owner. The
+// history is only checked when userId is set, new users have none.
+func (s *UserService) checkPassword(ctx context.Context, newPassword string, owner password.Owner, userId string) error {
+	policy := password.NewPolicy(s.Config.Password)
+	err := policy.Validate(newPassword, owner)
+	var invalid *password.ValidationError
+	if errors.As(err, &invalid) {
+		return invalid.Status()
+	}
+	if err != nil {
+		// An unreadable breach list must not block password changes
+		s.Logger.Error(fmt.Sprintf("error checking breached passwords: %v", err))
+	}
+
+	if userId == "" || policy.History < 1 {
+		return nil
+	}
+	hashes, err := s.User.User().RecentPasswordHashes(ctx, userId, policy.History)
+	if err != nil {
+		return err
+	}
+	hasher := password.NewHasher(s.Config.Password)
+	for _, hash := range hashes {
+		if ok, _ := hasher.Verify(hash, newPassword); ok {
+			return policy.ReuseViolation().Status()
+		}
+	}
+	return nil
+}
+
+// checkNewPassword is checkPassword for an existing user.
+func (s *UserService) checkNewPassword(ctx context.Context, userId, newPassword string) error {
+	user, err := s.User.User().GetUserById(ctx, &pb.UserId{Id: userId})
+	if err != nil {
+		return err
+	}
+	owner := password.Owner{Email: user.Email, Name: user.Name, Surname: user.Surname}
+	return s.checkPassword(ctx, newPassword, owner, userId)
+}
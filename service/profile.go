@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	pb "wegugin/genproto/user"
+	"wegugin/model"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GetPublicProfile returns the seller card anyone can see. The phone number
+// and email are only on it when the user's privacy settings show them to
+// req.ViewerId, who is anonymous when empty.
+func (s *UserService) GetPublicProfile(ctx context.Context, req *pb.PublicProfileReq) (*pb.PublicProfile, error) {
+	s.Logger.Info("GetPublicProfile rpc method is working")
+	profile, privacy, err := s.User.User().GetPublicProfile(ctx, req.Id)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error getting public profile: %v", err))
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		return nil, err
+	}
+	if !model.Visible(privacy.PhoneNumber, req.ViewerId) {
+		profile.PhoneNumber = ""
+	}
+	if !model.Visible(privacy.Email, req.ViewerId) {
+		profile.Email = ""
+	}
+	s.Logger.Info("GetPublicProfile rpc method finished")
+	return profile, nil
+}
+
+func (s *UserService) GetPrivacySettings(ctx context.Context, req *pb.UserId) (*pb.PrivacySettings, error) {
+	s.Logger.Info("GetPrivacySettings rpc method is working")
+	resp, err := s.User.User().GetPrivacySettings(ctx, req)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error getting privacy settings: %v", err))
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		return nil, err
+	}
+	s.Logger.Info("GetPrivacySettings rpc method finished")
+	return resp, nil
+}
+
+// UpdatePrivacySettings replaces the user's privacy settings. Every field
+// takes one of the model.Visibility values.
+func (s *UserService) UpdatePrivacySettings(ctx context.Context, req *pb.PrivacySettings) (*pb.Void, error) {
+	s.Logger.Info("UpdatePrivacySettings rpc method is working")
+	if !model.ValidVisibility(req.PhoneNumber) || !model.ValidVisibility(req.Email) {
+		return nil, status.Errorf(codes.InvalidArgument, "visibility must be one of %s, %s or %s",
+			model.VisibilityNobody, model.VisibilityLoggedIn, model.VisibilityEveryone)
+	}
+	before, err := s.User.User().GetPrivacySettings(ctx, &pb.UserId{Id: req.UserId})
+	if err == nil {
+		err = s.User.User().UpdatePrivacySettings(ctx, req)
+	}
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error updating privacy settings: %v", err))
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		return nil, err
+	}
+
+	changes := map[string]model.Change{}
+	if before.PhoneNumber != req.PhoneNumber {
+		changes["phone_number"] = model.Change{Old: before.PhoneNumber, New: req.PhoneNumber}
+	}
+	if before.Email != req.Email {
+		changes["email"] = model.Change{Old: before.Email, New: req.Email}
+	}
+	if len(changes) > 0 {
+		s.audit(ctx, &model.AuditEvent{ActorId: req.UserId, TargetId: req.UserId, Action: model.AuditPrivacyUpdate, Changes: changes})
+	}
+	s.Logger.Info("UpdatePrivacySettings rpc method finished")
+	return &pb.Void{}, nil
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+	"wegugin/api/email"
+	pb "wegugin/genproto/user"
+
+	"github.com/spf13/cast"
+)
+
+const restorePurpose = "restore"
+
+// sendRestoreLink mails a just deleted user a link that undoes the deletion
+// until the restore window closes.
+func (s *UserService) sendRestoreLink(ctx context.Context, id string) error {
+	user, err := s.User.User().GetUserByIdWithDeleted(ctx, &pb.UserId{Id: id})
+	if err != nil {
+		return err
+	}
+
+	conf := s.Config
+	expires := time.Unix(user.DeletedAt, 0).Add(conf.Account.RESTORE_WINDOW)
+	token, err := s.Tokens.GeneratePurposeToken(restorePurpose, id, map[string]interface{}{
+		"deleted_at": user.DeletedAt,
+	}, expires)
+	if err != nil {
+		return err
+	}
+
+	return s.Email.SendLink(user.Email, email.LinkMessage{
+		Subject: "Your account was deleted",
+		Title:   "Your account was deleted",
+		Text: fmt.Sprintf("Changed your mind? You can restore your account until %s. After that it is removed for good.",
+			expires.Format("2006-01-02")),
+		Link:   conf.Server.PUBLIC_URL + "/auth/restore?token=" + url.QueryEscape(token),
+		Button: "Restore my account",
+	})
+}
+
+func (s *UserService) RestoreAccount(ctx context.Context, req *pb.RestoreAccountReq) (*pb.LoginRes, error) {
+	s.Logger.Info("RestoreAccount rpc method is working")
+	claims, err := s.Tokens.ParsePurposeToken(restorePurpose, req.Token)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("invalid restore token: %v", err))
+		return nil, fmt.Errorf("invalid or expired restore link")
+	}
+	id := cast.ToString(claims["user_id"])
+	role, err := s.User.User().RestoreDeletedUser(ctx, id, cast.ToInt64(claims["deleted_at"]))
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error restoring account: %v", err))
+		return nil, err
+	}
+	token, err := s.Tokens.GenerateJWTToken(id, role)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error generating token: %v", err))
+		return nil, err
+	}
+	resp, err := s.startSession(ctx, &pb.LoginRes{Token: token})
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error starting session: %v", err))
+		return nil, err
+	}
+	s.Logger.Info("RestoreAccount rpc method finished")
+	return resp, nil
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	pb "wegugin/genproto/user"
+)
+
+func (s *UserService) CreateSavedSearch(ctx context.Context, req *pb.CreateSavedSearchReq) (*pb.SavedSearch, error) {
+	s.Logger.Info("CreateSavedSearch rpc method is working")
+	resp, err := s.User.SavedSearch().CreateSavedSearch(ctx, req)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error creating saved search: %v", err))
+		return nil, err
+	}
+	s.Logger.Info("CreateSavedSearch rpc method finished")
+	return resp, nil
+}
+
+func (s *UserService) GetSavedSearch(ctx context.Context, req *pb.SavedSearchId) (*pb.SavedSearch, error) {
+	s.Logger.Info("GetSavedSearch rpc method is working")
+	resp, err := s.User.SavedSearch().GetSavedSearch(ctx, req)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error getting saved search: %v", err))
+		return nil, err
+	}
+	s.Logger.Info("GetSavedSearch rpc method finished")
+	return resp, nil
+}
+
+func (s *UserService) ListSavedSearches(ctx context.Context, req *pb.UserId) (*pb.SavedSearchList, error) {
+	s.Logger.Info("ListSavedSearches rpc method is working")
+	resp, err := s.User.SavedSearch().ListSavedSearches(ctx, req)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error listing saved searches: %v", err))
+		return nil, err
+	}
+	s.Logger.Info("ListSavedSearches rpc method finished")
+	return resp, nil
+}
+
+func (s *UserService) UpdateSavedSearch(ctx context.Context, req *pb.UpdateSavedSearchReq) (*pb.Void, error) {
+	s.Logger.Info("UpdateSavedSearch rpc method is working")
+	err := s.User.SavedSearch().UpdateSavedSearch(ctx, req)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error updating saved search: %v", err))
+		return nil, err
+	}
+	s.Logger.Info("UpdateSavedSearch rpc method finished")
+	return &pb.Void{}, nil
+}
+
+func (s *UserService) DeleteSavedSearch(ctx context.Context, req *pb.SavedSearchId) (*pb.Void, error) {
+	s.Logger.Info("DeleteSavedSearch rpc method is working")
+	err := s.User.SavedSearch().DeleteSavedSearch(ctx, req)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error deleting saved search: %v", err))
+		return nil, err
+	}
+	s.Logger.Info("DeleteSavedSearch rpc method finished")
+	return &pb.Void{}, nil
+}
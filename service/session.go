@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"wegugin/api/auth"
+	"wegugin/api/middleware"
+	pb "wegugin/genproto/user"
+	"wegugin/model"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// startSession records a session for the user the storage just logged in
+// and returns an access token bound to it, replacing the unbound one.
+func (s *UserService) startSession(ctx context.Context, res *pb.LoginRes) (*pb.LoginRes, error) {
+	id, role, err := s.Tokens.GetUserIdFromToken(res.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	expiresAt := auth.TokenExpiry()
+	sessionId, err := s.User.Session().CreateSession(ctx, &model.Session{
+		UserId:     id,
+		DeviceName: firstValue(md, middleware.DeviceKey),
+		Platform:   firstValue(md, middleware.PlatformKey),
+		IP:         firstValue(md, middleware.ClientIPKey),
+		UserAgent:  firstValue(md, middleware.UserAgentKey),
+		ExpiresAt:  expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := s.Tokens.GenerateSessionToken(id, role, sessionId, expiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate jwt token: %w", err)
+	}
+	return &pb.LoginRes{Token: token}, nil
+}
+
+// ListSessions lists the active sessions of req.UserId and flags
+// req.SessionId as the current one.
+func (s *UserService) ListSessions(ctx context.Context, req *pb.SessionReq) (*pb.SessionList, error) {
+	s.Logger.Info("ListSessions rpc method is working")
+	sessions, err := s.User.Session().ListSessions(ctx, req.UserId)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error listing sessions: %v", err))
+		return nil, err
+	}
+
+	ids := make([]string, len(sessions))
+	for i, session := range sessions {
+		ids[i] = session.Id
+	}
+	// Recent activity is only kept in Redis, fall back to the login time
+	seen, err := s.Redis.SessionsLastSeen(ctx, ids)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error getting session activity: %v", err))
+	}
+	for _, session := range sessions {
+		if at, ok := seen[session.Id]; ok {
+			session.LastSeenAt = at.Format(time.RFC3339)
+		}
+		session.Current = session.Id == req.SessionId
+	}
+
+	s.Logger.Info("ListSessions rpc method finished")
+	return &pb.SessionList{Sessions: sessions}, nil
+}
+
+// RevokeSession ends one of the user's sessions. The Redis marker goes in
+// before the row is revoked, so a failure on either side leaves the session
+// listed and a retry finishes the job.
+func (s *UserService) RevokeSession(ctx context.Context, req *pb.SessionReq) (*pb.Void, error) {
+	s.Logger.Info("RevokeSession rpc method is working")
+	sessions, err := s.User.Session().ListSessions(ctx, req.UserId)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error listing sessions: %v", err))
+		return nil, err
+	}
+	found := false
+	for _, session := range sessions {
+		found = found || session.Id == req.SessionId
+	}
+	if !found {
+		return nil, status.Error(codes.NotFound, "session not found")
+	}
+	// No session outlives a token issued now
+	err = s.Redis.MarkSessionRevoked(ctx, req.SessionId, auth.TokenExpiry())
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error marking session revoked: %v", err))
+		return nil, err
+	}
+	_, err = s.User.Session().RevokeSession(ctx, req)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error revoking session: %v", err))
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, err
+	}
+	s.audit(ctx, &model.AuditEvent{
+		ActorId:  req.UserId,
+		TargetId: req.UserId,
+		Action:   model.AuditSessionRevoke,
+		Reason:   "session " + req.SessionId,
+	})
+	s.Logger.Info("RevokeSession rpc method finished")
+	return &pb.Void{}, nil
+}
+
+// revokeOtherSessions ends every session of the user but keepId, which may
+// be empty to end them all. Like RevokeSession it marks the sessions in
+// Redis first; the ones started while revoking are marked after.
+func (s *UserService) revokeOtherSessions(ctx context.Context, userId, keepId string) error {
+	sessions, err := s.User.Session().ListSessions(ctx, userId)
+	if err != nil {
+		return err
+	}
+	marked := map[string]bool{}
+	for _, session := range sessions {
+		if session.Id == keepId {
+			continue
+		}
+		if err := s.Redis.MarkSessionRevoked(ctx, session.Id, auth.TokenExpiry()); err != nil {
+			return err
+		}
+		marked[session.Id] = true
+	}
+
+	revoked, err := s.User.Session().RevokeOtherSessions(ctx, userId, keepId)
+	if err != nil {
+		return err
+	}
+	for sessionId, expiresAt := range revoked {
+		if marked[sessionId] {
+			continue
+		}
+		if err := s.Redis.MarkSessionRevoked(ctx, sessionId, expiresAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
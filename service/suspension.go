@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+	pb "wegugin/genproto/user"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *UserService) AdminSuspendUser(ctx context.Context, req *pb.SuspendUserReq) (*pb.Suspension, error) {
+	s.Logger.Info("AdminSuspendUser rpc method is working")
+	if req.UserId == actorId(ctx) {
+		s.Logger.Error("admin tried to suspend own account")
+		return nil, status.Error(codes.FailedPrecondition, "admins cannot suspend their own account")
+	}
+	req.ActorId = actorId(ctx)
+	resp, err := s.User.Suspension().SuspendUser(ctx, req)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error suspending user: %v", err))
+		return nil, err
+	}
+
+	var until time.Time
+	if resp.ExpiresAt != "" {
+		until, _ = time.Parse(time.RFC3339, resp.ExpiresAt)
+	}
+	if err := s.Redis.MarkSuspended(ctx, req.UserId, until); err != nil {
+		s.Logger.Error(fmt.Sprintf("error caching suspension: %v", err))
+	}
+	if err := s.User.Suspension().SyncHiddenCars(ctx); err != nil {
+		s.Logger.Error(fmt.Sprintf("error hiding cars: %v", err))
+	}
+	s.Logger.Info("AdminSuspendUser rpc method finished")
+	return resp, nil
+}
+
+func (s *UserService) AdminLiftSuspension(ctx context.Context, req *pb.LiftSuspensionReq) (*pb.Void, error) {
+	s.Logger.Info("AdminLiftSuspension rpc method is working")
+	req.ActorId = actorId(ctx)
+	err := s.User.Suspension().LiftSuspension(ctx, req)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error lifting suspension: %v", err))
+		return nil, err
+	}
+	if err := s.Redis.UnmarkSuspended(ctx, req.UserId); err != nil {
+		s.Logger.Error(fmt.Sprintf("error clearing cached suspension: %v", err))
+	}
+	if err := s.User.Suspension().SyncHiddenCars(ctx); err != nil {
+		s.Logger.Error(fmt.Sprintf("error showing cars: %v", err))
+	}
+	s.Logger.Info("AdminLiftSuspension rpc method finished")
+	return &pb.Void{}, nil
+}
+
+func (s *UserService) AdminListSuspensions(ctx context.Context, req *pb.UserId) (*pb.SuspensionList, error) {
+	s.Logger.Info("AdminListSuspensions rpc method is working")
+	resp, err := s.User.Suspension().ListSuspensions(ctx, req)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error listing suspensions: %v", err))
+		return nil, err
+	}
+	s.Logger.Info("AdminListSuspensions rpc method finished")
+	return resp, nil
+}
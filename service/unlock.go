@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+	"wegugin/api/email"
+	"wegugin/api/throttle"
+	pb "wegugin/genproto/user"
+
+	"github.com/spf13/cast"
+)
+
+const unlockPurpose = "unlock"
+
+// sendUnlockLink tells the owner of a locked out login about it. The link
+// lifts the lock at once, for when it was the owner mistyping.
+func (s *UserService) sendUnlockLink(ctx context.Context, id, login string, lock time.Duration) error {
+	user, err := s.User.User().GetUserById(ctx, &pb.UserId{Id: id})
+	if err != nil {
+		return err
+	}
+
+	token, err := s.Tokens.GeneratePurposeToken(unlockPurpose, id, map[string]interface{}{
+		"login": login,
+	}, time.Now().Add(lock))
+	if err != nil {
+		return err
+	}
+
+	conf := s.Config
+	return s.Email.SendLink(user.Email, email.LinkMessage{
+		Subject: "Too many failed logins",
+		Title:   "We paused logins to your account",
+		Text: fmt.Sprintf("There were too many failed attempts to log in to your account, so logins are paused for %s. "+
+			"If it was you, unlock your account below. If not, consider changing your password.", lock),
+		Link:   conf.Server.PUBLIC_URL + "/auth/unlock?token=" + url.QueryEscape(token),
+		Button: "Unlock my account",
+	})
+}
+
+func (s *UserService) UnlockLogin(ctx context.Context, req *pb.UnlockLoginReq) (*pb.Void, error) {
+	s.Logger.Info("UnlockLogin rpc method is working")
+	claims, err := s.Tokens.ParsePurposeToken(unlockPurpose, req.Token)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("invalid unlock token: %v", err))
+		return nil, fmt.Errorf("invalid or expired unlock link")
+	}
+	err = throttle.Login(s.Config.Throttle, s.Redis).Reset(ctx, cast.ToString(claims["login"]))
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error unlocking login: %v", err))
+		return nil, err
+	}
+	s.Logger.Info("UnlockLogin rpc method finished")
+	return &pb.Void{}, nil
+}
@@ -2,45 +2,169 @@ package service
 
 import (
 	"context"
-	"database/sql"
+	"crypto/subtle"
+	"errors"
 	"fmt"
 	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"wegugin/api/auth"
+	"wegugin/api/email"
+	"wegugin/api/middleware"
+	"wegugin/api/oidc"
+	"wegugin/api/password"
+	"wegugin/api/ratelimit"
+	"wegugin/api/throttle"
+	"wegugin/config"
 	pb "wegugin/genproto/user"
+	"wegugin/model"
 	"wegugin/storage"
-	"wegugin/storage/postgres"
+	"wegugin/storage/cache"
+	minioStorage "wegugin/storage/minio"
+	"wegugin/storage/redis"
+
+	"github.com/google/uuid"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 type UserService struct {
 	pb.UnimplementedUserServer
 	User   storage.IStorage
+	Redis  *redis.Client
+	Tokens *auth.Tokens
+	Email  *email.Sender
+	Minio  *minioStorage.Storage
+	Config *config.Config
 	Logger *slog.Logger
+	OIDC   *oidc.Registry
+
+	// background counts the work left running after answering an RPC
+	background sync.WaitGroup
 }
 
-func NewUserService(db *sql.DB, Logger *slog.Logger) *UserService {
+// Wait waits for the work the RPCs left running after answering, or for
+// ctx to be done.
+func (s *UserService) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.background.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func NewUserService(store storage.IStorage, rdb *redis.Client, conf *config.Config, Logger *slog.Logger) *UserService {
+	registry, err := oidc.NewRegistry(conf.OIDC, conf.Server.PUBLIC_URL)
+	if err != nil {
+		Logger.Error(fmt.Sprintf("error setting up social login: %v", err))
+	}
+	if conf := conf.Cache; conf.USER_ENABLED {
+		store = cache.New(store, rdb, conf.USER_TTL, conf.USER_JITTER, Logger)
+	}
 	return &UserService{
-		User:   postgres.NewPostgresStorage(db),
+		User:   store,
+		Redis:  rdb,
+		Tokens: auth.New(conf.Token),
+		Email:  email.NewSender(conf.Email),
+		Minio:  minioStorage.New(conf.Minio),
+		Config: conf,
 		Logger: Logger,
+		OIDC:   registry,
 	}
 }
 
 func (s *UserService) Register(ctx context.Context, req *pb.RegisterReq) (*pb.LoginRes, error) {
 	s.Logger.Info("Register rpc methos is working")
+	owner := password.Owner{Email: req.Email, Name: req.Name, Surname: req.Surname}
+	if err := s.checkPassword(ctx, req.Password, owner, ""); err != nil {
+		s.Logger.Error(fmt.Sprintf("registration error: %v", err))
+		return nil, err
+	}
 	resp, err := s.User.User().CreateUser(ctx, req)
 	if err != nil {
 		s.Logger.Error(fmt.Sprintf("registration error: %v", err))
 		return nil, err
 	}
+	resp, err = s.startSession(ctx, resp)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error starting session: %v", err))
+		return nil, err
+	}
+	if id, _, err := s.Tokens.GetUserIdFromToken(resp.Token); err == nil {
+		s.audit(ctx, &model.AuditEvent{ActorId: id, TargetId: id, Action: model.AuditRegister})
+	}
 	s.Logger.Info("Register rpc method finished")
 	return resp, nil
 }
 
 func (s *UserService) Login(ctx context.Context, req *pb.LoginReq) (*pb.LoginRes, error) {
 	s.Logger.Info("Login rpc method is working")
+	md, _ := metadata.FromIncomingContext(ctx)
+	ip := firstValue(md, middleware.ClientIPKey)
+	guard := throttle.Login(s.Config.Throttle, s.Redis)
+	if err := guard.Check(ctx, req.EmailOrPhoneNumber, ip); err != nil {
+		if throttled, ok := throttle.IsThrottled(err); ok {
+			s.Logger.Error(fmt.Sprintf("login throttled: %v", err))
+			target, _ := s.User.User().UserIdByLogin(ctx, req.EmailOrPhoneNumber)
+			s.audit(ctx, &model.AuditEvent{TargetId: target, Action: model.AuditLoginFailure, Reason: err.Error()})
+			return nil, throttled.Status()
+		}
+		// Without Redis logins go on unthrottled
+		s.Logger.Error(fmt.Sprintf("error checking login throttle: %v", err))
+	}
+
 	resp, err := s.User.User().Login(ctx, req)
 	if err != nil {
 		s.Logger.Error(fmt.Sprintf("login error: %v", err))
+		// The target stays empty for unknown logins
+		target, _ := s.User.User().UserIdByLogin(ctx, req.EmailOrPhoneNumber)
+		s.audit(ctx, &model.AuditEvent{TargetId: target, Action: model.AuditLoginFailure, Reason: err.Error()})
+		if errors.Is(err, storage.ErrUserSuspended) {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+		if errors.Is(err, storage.ErrPasswordResetRequired) {
+			message := "the password must be reset before logging in"
+			st, err := status.New(codes.FailedPrecondition, message).WithDetails(
+				&errdetails.ErrorInfo{Reason: middleware.PasswordResetRequired})
+			if err != nil {
+				return nil, status.Error(codes.FailedPrecondition, message)
+			}
+			return nil, st.Err()
+		}
+		if errors.Is(err, storage.ErrInvalidCredentials) {
+			locked, ferr := guard.Fail(ctx, req.EmailOrPhoneNumber, ip)
+			if ferr != nil {
+				s.Logger.Error(fmt.Sprintf("error recording failed login: %v", ferr))
+			}
+			if locked && target != "" {
+				if err := s.sendUnlockLink(ctx, target, req.EmailOrPhoneNumber, guard.LockDuration); err != nil {
+					s.Logger.Error(fmt.Sprintf("error sending unlock link: %v", err))
+				}
+			}
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
 		return nil, err
 	}
+	if err := guard.Reset(ctx, req.EmailOrPhoneNumber); err != nil {
+		s.Logger.Error(fmt.Sprintf("error resetting login throttle: %v", err))
+	}
+	resp, err = s.startSession(ctx, resp)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("error starting session: %v", err))
+		return nil, err
+	}
+	if id, _, err := s.Tokens.GetUserIdFromToken(resp.Token); err == nil {
+		s.audit(ctx, &model.AuditEvent{ActorId: id, TargetId: id, Action: model.AuditLoginSuccess})
+	}
 	s.Logger.Info("Login rpc method finished")
 	return resp, nil
 }
@@ -67,24 +191,135 @@ func (s *UserService) GetUserById(ctx context.Context, req *pb.UserId) (*pb.GetU
 	return resp, nil
 }
 
+// publicUserFields are the GetUserResponse fields of the seller card, what
+// GetUsersByIds returns without a field mask. The email and phone number
+// follow the privacy settings of each user.
+var publicUserFields = []string{"id", "name", "surname", "photo", "createdAt", "email", "phone_number"}
+
+// privateUserFields are only returned to callers with a PRIVATE_FIELD_KEYS
+// key that name them in the field mask.
+var privateUserFields = map[string]bool{"birth_date": true, "gender": true, "address": true, "role": true}
+
+// GetUsersByIds looks up many users at once for other services. Ids without
+// an active user, malformed ones included, are listed in MissingIds instead
+// of failing the call.
+func (s *UserService) GetUsersByIds(ctx context.Context, req *pb.GetUsersByIdsReq) (*pb.GetUsersByIdsRes, error) {
+	s.Logger.Info("GetUsersByIds rpc method is working")
+	if max := s.Config.Server.BATCH_MAX_IDS; len(req.Ids) > max {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d ids are allowed", max)
+	}
+	fields := publicUserFields
+	// Contacts are only shown regardless of the privacy settings to the
+	// services that may see private fields, and ask for them by name
+	privileged := false
+	if mask := req.FieldMask; mask != nil && len(mask.GetPaths()) > 0 {
+		if !mask.IsValid(&pb.GetUserResponse{}) {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid field mask %v", mask.GetPaths())
+		}
+		mask.Normalize()
+		fields = mask.GetPaths()
+		privileged = s.privateFieldCaller(ctx)
+		for _, field := range fields {
+			if privateUserFields[field] && !privileged {
+				return nil, status.Errorf(codes.PermissionDenied, "%s is a private field", field)
+			}
+		}
+	}
+
+	ids := make([]string, 0, len(req.Ids))
+	seen := map[string]bool{}
+	for _, id := range req.Ids {
+		// Ids come back from the database in canonical form only
+		if parsed, err := uuid.Parse(id); err == nil && parsed.String() == id && !seen[id] {
+			ids = append(ids, id)
+		}
+		seen[id] = true
+	}
+
+	users := map[string]*pb.GetUserResponse{}
+	if len(ids) > 0 {
+		var (
+			privacy map[string]*pb.PrivacySettings
+			err     error
+		)
+		users, privacy, err = s.User.User().GetUsersByIds(ctx, ids, fields)
+		if err != nil {
+			s.Logger.Error(fmt.Sprintf("error getting users: %v", err))
+			return nil, err
+		}
+		if !privileged {
+			for id, user := range users {
+				if !model.Visible(privacy[id].GetPhoneNumber(), req.ViewerId) {
+					user.PhoneNumber = ""
+				}
+				if !model.Visible(privacy[id].GetEmail(), req.ViewerId) {
+					user.Email = ""
+				}
+			}
+		}
+	}
+
+	resp := &pb.GetUsersByIdsRes{Users: users}
+	for id := range seen {
+		if users[id] == nil {
+			resp.MissingIds = append(resp.MissingIds, id)
+		}
+	}
+	sort.Strings(resp.MissingIds)
+	s.Logger.Info("GetUsersByIds rpc method finished")
+	return resp, nil
+}
+
+// privateFieldCaller reports whether the call carries one of the
+// PRIVATE_FIELD_KEYS as its x-api-key.
+func (s *UserService) privateFieldCaller(ctx context.Context) bool {
+	md, _ := metadata.FromIncomingContext(ctx)
+	keys := md.Get(strings.ToLower(ratelimit.APIKeyHeader))
+	if len(keys) != 1 {
+		return false
+	}
+	for _, key := range s.Config.Server.PrivateFieldKeys() {
+		if subtle.ConstantTimeCompare([]byte(keys[0]), []byte(key)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *UserService) UpdatePassword(ctx context.Context, req *pb.UpdatePasswordReq) (*pb.Void, error) {
 	s.Logger.Info("UpdatePassword rpc method is working")
+	if err := s.checkNewPassword(ctx, req.Id, req.Password); err != nil {
+		s.Logger.Error(fmt.Sprintf("Error update pasword: %v", err))
+		return nil, err
+	}
 	err := s.User.User().UpdatePassword(ctx, req)
 	if err != nil {
 		s.Logger.Error(fmt.Sprintf("Error update pasword: %v", err))
 		return nil, err
 	}
+	s.audit(ctx, &model.AuditEvent{TargetId: req.Id, Action: model.AuditPasswordReset})
 	s.Logger.Info("UpdatePassword rpc method finished")
 	return &pb.Void{}, nil
 }
 
 func (s *UserService) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest) (*pb.Void, error) {
 	s.Logger.Info("UpdateUser rpc method is working")
-	err := s.User.User().UpdateUser(ctx, req)
+	before, err := s.User.User().GetUserById(ctx, &pb.UserId{Id: req.Id})
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("Error Update user: %v", err))
+		return nil, err
+	}
+	err = s.User.User().UpdateUser(ctx, req)
 	if err != nil {
 		s.Logger.Error(fmt.Sprintf("Error Update user: %v", err))
 		return nil, err
 	}
+	action := model.AuditProfileUpdate
+	changes := profileChanges(before, req)
+	if _, ok := changes["photo"]; ok && len(changes) == 1 {
+		action = model.AuditPhotoUpload
+	}
+	s.audit(ctx, &model.AuditEvent{ActorId: req.Id, TargetId: req.Id, Action: action, Changes: changes})
 	s.Logger.Info("UpdateUser rpc method finished")
 	return &pb.Void{}, nil
 }
@@ -96,17 +331,26 @@ func (s *UserService) DeleteUser(ctx context.Context, req *pb.UserId) (*pb.Void,
 		s.Logger.Error(fmt.Sprintf("error delete user: %v", err))
 		return nil, err
 	}
+	s.audit(ctx, &model.AuditEvent{ActorId: req.Id, TargetId: req.Id, Action: model.AuditUserDelete})
+	if err := s.sendRestoreLink(ctx, req.Id); err != nil {
+		s.Logger.Error(fmt.Sprintf("error sending restore link: %v", err))
+	}
 	s.Logger.Info("DeleteUser rpc method finished")
 	return &pb.Void{}, nil
 }
 
 func (s *UserService) ResetPassword(ctx context.Context, req *pb.ResetPasswordReq) (*pb.Void, error) {
 	s.Logger.Info("ResetPassword rpc method is working")
+	if err := s.checkNewPassword(ctx, req.Id, req.Newpassword); err != nil {
+		s.Logger.Error(fmt.Sprintf("error reset password: %v", err))
+		return nil, err
+	}
 	err := s.User.User().ResetPassword(ctx, req)
 	if err != nil {
 		s.Logger.Error(fmt.Sprintf("error reset password: %v", err))
 		return nil, err
 	}
+	s.audit(ctx, &model.AuditEvent{ActorId: req.Id, TargetId: req.Id, Action: model.AuditPasswordChange})
 	s.Logger.Info("ResetPassword rpc method finished")
 	return &pb.Void{}, nil
 }
@@ -129,6 +373,12 @@ func (s *UserService) DeleteMediaUser(ctx context.Context, req *pb.UserId) (*pb.
 		s.Logger.Error(fmt.Sprintf("error deleting media user: %v", err))
 		return nil, err
 	}
+	s.audit(ctx, &model.AuditEvent{
+		ActorId:  req.Id,
+		TargetId: req.Id,
+		Action:   model.AuditPhotoDelete,
+		Changes:  map[string]model.Change{"photo": model.Redacted},
+	})
 	s.Logger.Info("DeleteMediaUser rpc method finished")
 	return &pb.Void{}, nil
 }
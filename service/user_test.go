@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"wegugin/config"
+	pb "wegugin/genproto/user"
+	"wegugin/model"
+	"wegugin/storage/memory"
+
+	"github.com/dgrijalva/jwt-go"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// TestGetUsersByIdsProjection checks that the batch lookup shows the seller
+// card by default, hides contacts as the privacy settings say, and only
+// hands private fields to a PRIVATE_FIELD_KEYS caller that asks for them.
+func TestGetUsersByIdsProjection(t *testing.T) {
+	conf, err := config.New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.Password.BCRYPT_COST = bcrypt.MinCost
+	conf.Server.PRIVATE_FIELD_KEYS = "chat-key, car-key"
+	s := &UserService{User: memory.New(conf), Config: conf, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	ctx := context.Background()
+	res, err := s.User.User().CreateUser(ctx, &pb.RegisterReq{
+		Email: "seller@example.com", Name: "Seller", Password: "correct horse battery staple",
+		Phone: "+821012345678", BirthDate: "02-01-1990", Gender: "other",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, _, err := new(jwt.Parser).ParseUnverified(res.Token, jwt.MapClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := parsed.Claims.(jwt.MapClaims)["user_id"].(string)
+	err = s.User.User().UpdatePrivacySettings(ctx, &pb.PrivacySettings{
+		UserId: id, PhoneNumber: model.VisibilityLoggedIn, Email: model.VisibilityNobody,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(ctx context.Context, viewer string, paths ...string) (*pb.GetUserResponse, error) {
+		req := &pb.GetUsersByIdsReq{Ids: []string{id}, ViewerId: viewer}
+		if len(paths) > 0 {
+			req.FieldMask = &fieldmaskpb.FieldMask{Paths: paths}
+		}
+		res, err := s.GetUsersByIds(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return res.Users[id], nil
+	}
+	service := metadata.NewIncomingContext(ctx, metadata.Pairs("x-api-key", "car-key"))
+
+	user, err := get(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Name != "Seller" || user.Email != "" || user.PhoneNumber != "" || user.BirthDate != "" || user.Role != "" {
+		t.Errorf("anonymous card = %v, want the name only", user)
+	}
+	user, err = get(ctx, "viewer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.PhoneNumber != "+821012345678" || user.Email != "" {
+		t.Errorf("logged in card = %v, want the phone number but not the email", user)
+	}
+	user, err = get(service, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.PhoneNumber != "" || user.BirthDate != "" {
+		t.Errorf("card for a service without a mask = %v, want the public card", user)
+	}
+
+	for _, ctx := range []context.Context{ctx, metadata.NewIncomingContext(ctx, metadata.Pairs("x-api-key", "other-key"))} {
+		if _, err := get(ctx, "viewer", "name", "address"); status.Code(err) != codes.PermissionDenied {
+			t.Errorf("address without a private field key: got %v, want PermissionDenied", err)
+		}
+	}
+	user, err = get(service, "", "email", "gender")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Email != "seller@example.com" || user.Gender != "other" || user.Name != "" {
+		t.Errorf("private fields for a service = %v, want the email and gender", user)
+	}
+}
@@ -0,0 +1,51 @@
+// Package cache puts a Redis read-through cache in front of a storage.
+package cache
+
+import (
+	"expvar"
+	"log/slog"
+	"time"
+	"wegugin/storage"
+	"wegugin/storage/redis"
+)
+
+// Lookups of the user profile cache, published as the user_cache expvar.
+var (
+	hits          expvar.Int
+	misses        expvar.Int
+	failures      expvar.Int
+	invalidations expvar.Int
+)
+
+func init() {
+	metrics := expvar.NewMap("user_cache")
+	metrics.Set("hits", &hits)
+	metrics.Set("misses", &misses)
+	metrics.Set("errors", &failures)
+	metrics.Set("invalidations", &invalidations)
+	metrics.Set("hit_rate", expvar.Func(func() interface{} {
+		h, m := hits.Value(), misses.Value()
+		if h+m == 0 {
+			return 0.0
+		}
+		return float64(h) / float64(h+m)
+	}))
+}
+
+type cachedStorage struct {
+	storage.IStorage
+	users *UserCache
+}
+
+// New wraps s so User() reads profiles through the cache in rdb. Cache
+// errors are logged and the lookup falls back to s.
+func New(s storage.IStorage, rdb *redis.Client, ttl, jitter time.Duration, logger *slog.Logger) storage.IStorage {
+	return &cachedStorage{
+		IStorage: s,
+		users:    NewUserCache(s.User(), rdb, ttl, jitter, logger),
+	}
+}
+
+func (c *cachedStorage) User() storage.IUserStorage {
+	return c.users
+}
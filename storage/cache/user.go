@@ -0,0 +1,135 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"time"
+	pb "wegugin/genproto/user"
+	"wegugin/storage"
+	"wegugin/storage/redis"
+
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/protobuf/proto"
+)
+
+// UserCache caches GetUserById and drops the cached profile whenever a
+// method changing the user is called. Everything else goes straight to the
+// wrapped storage.
+type UserCache struct {
+	storage.IUserStorage
+	rdb    *redis.Client
+	ttl    time.Duration
+	jitter time.Duration
+	logger *slog.Logger
+	group  singleflight.Group
+}
+
+func NewUserCache(users storage.IUserStorage, rdb *redis.Client, ttl, jitter time.Duration, logger *slog.Logger) *UserCache {
+	return &UserCache{IUserStorage: users, rdb: rdb, ttl: ttl, jitter: jitter, logger: logger}
+}
+
+// GetUserById serves the profile from Redis, or loads it once for all
+// concurrent callers asking for the same user and caches it. Missing and
+// deleted users are not cached.
+func (u *UserCache) GetUserById(ctx context.Context, req *pb.UserId) (*pb.GetUserResponse, error) {
+	user, err := u.rdb.GetCachedUser(ctx, req.Id)
+	if err != nil {
+		failures.Add(1)
+		u.logger.Error(fmt.Sprintf("error reading user cache: %v", err))
+	}
+	if user != nil {
+		hits.Add(1)
+		return user, nil
+	}
+	misses.Add(1)
+
+	v, err, _ := u.group.Do(req.Id, func() (interface{}, error) {
+		// Read before the row, so a change committed during the load is seen
+		gen, genErr := u.rdb.UserCacheGeneration(ctx, req.Id)
+		user, err := u.IUserStorage.GetUserById(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		if genErr != nil {
+			failures.Add(1)
+			u.logger.Error(fmt.Sprintf("error reading user cache generation: %v", genErr))
+			return user, nil
+		}
+		if err := u.rdb.CacheUser(ctx, user, gen, u.expiry()); err != nil {
+			failures.Add(1)
+			u.logger.Error(fmt.Sprintf("error writing user cache: %v", err))
+		}
+		return user, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Callers sharing the load must not share the message
+	return proto.Clone(v.(*pb.GetUserResponse)).(*pb.GetUserResponse), nil
+}
+
+func (u *UserCache) expiry() time.Duration {
+	if u.jitter <= 0 {
+		return u.ttl
+	}
+	return u.ttl + time.Duration(rand.Int63n(int64(u.jitter)))
+}
+
+// invalidate drops the cached profile after a change. A load already in
+// flight may have read the old row, so it is not shared with later callers,
+// and moving the generation on keeps it from caching what it read.
+func (u *UserCache) invalidate(ctx context.Context, id string) {
+	u.group.Forget(id)
+	invalidations.Add(1)
+	if err := u.rdb.DeleteCachedUser(ctx, id, u.ttl+u.jitter); err != nil {
+		failures.Add(1)
+		u.logger.Error(fmt.Sprintf("error invalidating user cache: %v", err))
+	}
+}
+
+func (u *UserCache) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest) error {
+	defer u.invalidate(ctx, req.Id)
+	return u.IUserStorage.UpdateUser(ctx, req)
+}
+
+func (u *UserCache) DeleteUser(ctx context.Context, req *pb.UserId) error {
+	defer u.invalidate(ctx, req.Id)
+	return u.IUserStorage.DeleteUser(ctx, req)
+}
+
+func (u *UserCache) DeleteMediaUser(ctx context.Context, req *pb.UserId) error {
+	defer u.invalidate(ctx, req.Id)
+	return u.IUserStorage.DeleteMediaUser(ctx, req)
+}
+
+func (u *UserCache) UpdatePassword(ctx context.Context, req *pb.UpdatePasswordReq) error {
+	defer u.invalidate(ctx, req.Id)
+	return u.IUserStorage.UpdatePassword(ctx, req)
+}
+
+func (u *UserCache) ResetPassword(ctx context.Context, req *pb.ResetPasswordReq) error {
+	defer u.invalidate(ctx, req.Id)
+	return u.IUserStorage.ResetPassword(ctx, req)
+}
+
+func (u *UserCache) ChangeEmail(ctx context.Context, userId, from, to string) error {
+	defer u.invalidate(ctx, userId)
+	return u.IUserStorage.ChangeEmail(ctx, userId, from, to)
+}
+
+func (u *UserCache) UpdateRole(ctx context.Context, req *pb.UpdateRoleReq) error {
+	defer u.invalidate(ctx, req.Id)
+	return u.IUserStorage.UpdateRole(ctx, req)
+}
+
+func (u *UserCache) HardDeleteUser(ctx context.Context, req *pb.UserId) error {
+	defer u.invalidate(ctx, req.Id)
+	return u.IUserStorage.HardDeleteUser(ctx, req)
+}
+
+func (u *UserCache) PurgeUser(ctx context.Context, id string, anonymize bool) error {
+	defer u.invalidate(ctx, id)
+	return u.IUserStorage.PurgeUser(ctx, id, anonymize)
+}
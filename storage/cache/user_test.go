@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+	"wegugin/config"
+	pb "wegugin/genproto/user"
+	"wegugin/storage"
+	"wegugin/storage/memory"
+	"wegugin/storage/redis"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// The tests here run against the Redis at TEST_REDIS_ADDR and are skipped
+// without it. Their keys live in a namespace of their own.
+
+// slowLoad holds GetUserById between reading the row and returning it.
+type slowLoad struct {
+	storage.IUserStorage
+	read    chan struct{}
+	release chan struct{}
+}
+
+func (s *slowLoad) GetUserById(ctx context.Context, req *pb.UserId) (*pb.GetUserResponse, error) {
+	user, err := s.IUserStorage.GetUserById(ctx, req)
+	close(s.read)
+	<-s.release
+	return user, err
+}
+
+// TestUpdateDuringLoad changes the user while a load holds the old row and
+// expects the old row to stay out of the cache.
+func TestUpdateDuringLoad(t *testing.T) {
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("TEST_REDIS_ADDR is not set")
+	}
+	rdb, err := redis.New(config.RedisConfig{RDB_ADDRESS: addr, RDB_NAMESPACE: "test-" + uuid.NewString()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rdb.Close()
+
+	conf, err := config.New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.Password.BCRYPT_COST = bcrypt.MinCost
+	ctx := context.Background()
+	users := memory.New(conf).User()
+	res, err := users.CreateUser(ctx, &pb.RegisterReq{
+		Email: "user@example.com", Name: "Before", Password: "correct horse battery staple",
+		Phone: "+821012345678", BirthDate: "02-01-1990", Gender: "other",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, _, err := new(jwt.Parser).ParseUnverified(res.Token, jwt.MapClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := parsed.Claims.(jwt.MapClaims)["user_id"].(string)
+
+	slow := &slowLoad{IUserStorage: users, read: make(chan struct{}), release: make(chan struct{})}
+	cache := NewUserCache(slow, rdb, time.Minute, 0, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	loaded := make(chan error, 1)
+	go func() {
+		_, err := cache.GetUserById(ctx, &pb.UserId{Id: id})
+		loaded <- err
+	}()
+
+	<-slow.read
+	if err := cache.UpdateUser(ctx, &pb.UpdateUserRequest{Id: id, Name: "After"}); err != nil {
+		t.Fatal(err)
+	}
+	close(slow.release)
+	if err := <-loaded; err != nil {
+		t.Fatal(err)
+	}
+
+	cached, err := rdb.GetCachedUser(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached != nil {
+		t.Errorf("the load from before the update cached %q", cached.Name)
+	}
+	// The next load is not racing anything and is cached
+	slow.read, slow.release = make(chan struct{}), make(chan struct{})
+	close(slow.release)
+	user, err := cache.GetUserById(ctx, &pb.UserId{Id: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Name != "After" {
+		t.Errorf("name %q after the update, want After", user.Name)
+	}
+	if cached, err := rdb.GetCachedUser(ctx, id); err != nil || cached == nil || cached.Name != "After" {
+		t.Errorf("cached %v, %v after a quiet load, want the updated profile", cached, err)
+	}
+}
@@ -0,0 +1,117 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+	pb "wegugin/genproto/user"
+	"wegugin/model"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// auditEvent is a row of audit_events. The table is append-only and has no
+// foreign keys, the trail outlives purged users.
+type auditEvent struct {
+	event     *pb.AuditEvent
+	createdAt time.Time
+}
+
+type AuditRepository struct {
+	db *db
+}
+
+func (a *AuditRepository) RecordEvent(ctx context.Context, req *model.AuditEvent) error {
+	var changes []byte
+	if len(req.Changes) > 0 {
+		var err error
+		if changes, err = json.Marshal(req.Changes); err != nil {
+			return fmt.Errorf("failed to encode changes: %w", err)
+		}
+	}
+
+	now := time.Now()
+	a.db.mu.Lock()
+	defer a.db.mu.Unlock()
+
+	a.db.auditEvents = append(a.db.auditEvents, &auditEvent{
+		event: &pb.AuditEvent{
+			Id:        newId(),
+			ActorId:   req.ActorId,
+			TargetId:  req.TargetId,
+			Action:    req.Action,
+			Changes:   string(changes),
+			Reason:    req.Reason,
+			Ip:        req.IP,
+			UserAgent: req.UserAgent,
+			RequestId: req.RequestId,
+			CreatedAt: formatTime(now),
+		},
+		createdAt: now,
+	})
+	return nil
+}
+
+func (a *AuditRepository) ListAuditEvents(ctx context.Context, req *pb.AuditEventFilter) (*pb.AuditEventList, error) {
+	var conditions []func(*auditEvent) bool
+
+	if len(req.ActorId) > 0 {
+		conditions = append(conditions, func(e *auditEvent) bool { return e.event.ActorId == req.ActorId })
+	}
+	if len(req.TargetId) > 0 {
+		conditions = append(conditions, func(e *auditEvent) bool { return e.event.TargetId == req.TargetId })
+	}
+	if len(req.Action) > 0 {
+		conditions = append(conditions, func(e *auditEvent) bool { return e.event.Action == req.Action })
+	}
+	if len(req.From) > 0 {
+		from, err := time.Parse("2006-01-02", req.From)
+		if err != nil {
+			return nil, fmt.Errorf("invalid from format: %w", err)
+		}
+		conditions = append(conditions, func(e *auditEvent) bool { return !e.createdAt.Before(from) })
+	}
+	if len(req.To) > 0 {
+		to, err := time.Parse("2006-01-02", req.To)
+		if err != nil {
+			return nil, fmt.Errorf("invalid to format: %w", err)
+		}
+		to = to.AddDate(0, 0, 1)
+		conditions = append(conditions, func(e *auditEvent) bool { return e.createdAt.Before(to) })
+	}
+
+	page, limit := req.Page, req.Limit
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+
+	a.db.mu.RLock()
+	defer a.db.mu.RUnlock()
+
+	var matched []*auditEvent
+	for _, e := range a.db.auditEvents {
+		if matches(e, conditions) {
+			matched = append(matched, e)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if !matched[i].createdAt.Equal(matched[j].createdAt) {
+			return matched[i].createdAt.After(matched[j].createdAt)
+		}
+		return matched[i].event.Id < matched[j].event.Id
+	})
+
+	res := &pb.AuditEventList{Page: page, Limit: limit, Total: int32(len(matched))}
+	for _, e := range paginate(matched, page, limit) {
+		res.Events = append(res.Events, proto.Clone(e.event).(*pb.AuditEvent))
+	}
+	return res, nil
+}
@@ -0,0 +1,32 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	pb "wegugin/genproto/user"
+	"wegugin/model"
+)
+
+// CarRepository has no cars: listings and their price history are written
+// by the car service, which does not share the memory.
+type CarRepository struct{}
+
+func (c *CarRepository) GetPriceHistory(ctx context.Context, req *pb.CarId) (*pb.PriceHistory, error) {
+	return nil, fmt.Errorf("car not found")
+}
+
+func (c *CarRepository) PendingPriceChanges(ctx context.Context) ([]*model.PriceChange, error) {
+	return nil, nil
+}
+
+func (c *CarRepository) PriceWatchers(ctx context.Context, carId string) ([]string, error) {
+	return nil, nil
+}
+
+func (c *CarRepository) NotifyPriceDrop(ctx context.Context, changeId int64, notification *model.Notification) error {
+	return nil
+}
+
+func (c *CarRepository) MarkPriceChangesAlerted(ctx context.Context, ids []int64) error {
+	return nil
+}
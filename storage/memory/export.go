@@ -0,0 +1,269 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+	pb "wegugin/genproto/user"
+	"wegugin/storage"
+)
+
+type export struct {
+	id          string
+	userId      string
+	status      string
+	objectName  string
+	error       string
+	requestedAt time.Time
+	claimedAt   time.Time
+	completedAt *time.Time
+	expiresAt   *time.Time
+}
+
+func (e *export) toProto() *pb.DataExport {
+	return &pb.DataExport{
+		Id:          e.id,
+		UserId:      e.userId,
+		Status:      e.status,
+		RequestedAt: formatTime(e.requestedAt),
+		CompletedAt: formatNullTime(e.completedAt),
+		ExpiresAt:   formatNullTime(e.expiresAt),
+		Error:       e.error,
+	}
+}
+
+type ExportRepository struct {
+	db *db
+}
+
+func (e *ExportRepository) CreateExport(ctx context.Context, userId string, cooldown time.Duration) (*pb.DataExport, error) {
+	e.db.mu.Lock()
+	defer e.db.mu.Unlock()
+
+	if !e.db.userExists(userId) {
+		return nil, fmt.Errorf("failed to insert export: user %s does not exist", userId)
+	}
+	now := time.Now()
+	since := now.Add(-cooldown)
+	var busy, recent bool
+	for _, previous := range e.db.exports {
+		if previous.userId != userId {
+			continue
+		}
+		if previous.status == "pending" || previous.status == "running" {
+			busy = true
+		}
+		if previous.status != "failed" && previous.requestedAt.After(since) {
+			recent = true
+		}
+	}
+	if busy {
+		return nil, fmt.Errorf("%w: an export is already in progress", storage.ErrExportLimited)
+	}
+	if recent {
+		return nil, fmt.Errorf("%w: an export can be requested once every %s", storage.ErrExportLimited, cooldown)
+	}
+
+	created := &export{id: newId(), userId: userId, status: "pending", requestedAt: now}
+	e.db.exports = append(e.db.exports, created)
+	return created.toProto(), nil
+}
+
+func (e *ExportRepository) LatestExport(ctx context.Context, req *pb.UserId) (*pb.DataExport, error) {
+	e.db.mu.RLock()
+	defer e.db.mu.RUnlock()
+
+	// Exports are appended in the order they are requested
+	for i := len(e.db.exports) - 1; i >= 0; i-- {
+		if e.db.exports[i].userId == req.Id {
+			return e.db.exports[i].toProto(), nil
+		}
+	}
+	return nil, fmt.Errorf("no export requested: %w", sql.ErrNoRows)
+}
+
+func (e *ExportRepository) ClaimPendingExport(ctx context.Context, lease time.Duration) (*pb.DataExport, time.Time, error) {
+	e.db.mu.Lock()
+	defer e.db.mu.Unlock()
+
+	now := time.Now()
+	for _, pending := range e.db.exports {
+		abandoned := pending.status == "running" && pending.claimedAt.Before(now.Add(-lease))
+		if pending.status == "pending" || abandoned {
+			pending.status = "running"
+			pending.claimedAt = now
+			return pending.toProto(), now, nil
+		}
+	}
+	return nil, time.Time{}, nil
+}
+
+// find returns the export with the id, or nil. The caller holds the lock.
+func (e *ExportRepository) find(id string) *export {
+	for _, found := range e.db.exports {
+		if found.id == id {
+			return found
+		}
+	}
+	return nil
+}
+
+// claimed returns the export with the id while it is running under the
+// claim made at claimedAt. The caller holds the lock.
+func (e *ExportRepository) claimed(id string, claimedAt time.Time) (*export, error) {
+	found := e.find(id)
+	if found == nil || found.status != "running" || !found.claimedAt.Equal(claimedAt) {
+		return nil, storage.ErrExportLeaseLost
+	}
+	return found, nil
+}
+
+func (e *ExportRepository) CompleteExport(ctx context.Context, id string, claimedAt time.Time, objectName string, expiresAt time.Time) error {
+	e.db.mu.Lock()
+	defer e.db.mu.Unlock()
+
+	found, err := e.claimed(id, claimedAt)
+	if err != nil {
+		return fmt.Errorf("failed to complete export: %w", err)
+	}
+	now := time.Now()
+	found.status = "ready"
+	found.objectName = objectName
+	found.completedAt = &now
+	found.expiresAt = &expiresAt
+	return nil
+}
+
+func (e *ExportRepository) FailExport(ctx context.Context, id string, claimedAt time.Time, reason string) error {
+	e.db.mu.Lock()
+	defer e.db.mu.Unlock()
+
+	found, err := e.claimed(id, claimedAt)
+	if err != nil {
+		return fmt.Errorf("failed to mark export failed: %w", err)
+	}
+	now := time.Now()
+	found.status = "failed"
+	found.error = reason
+	found.completedAt = &now
+	return nil
+}
+
+func (e *ExportRepository) ExpireExports(ctx context.Context) ([]string, error) {
+	e.db.mu.Lock()
+	defer e.db.mu.Unlock()
+
+	now := time.Now()
+	var objects []string
+	for _, ready := range e.db.exports {
+		if ready.status == "ready" && ready.expiresAt != nil && !ready.expiresAt.After(now) {
+			ready.status = "expired"
+			objects = append(objects, ready.objectName)
+		}
+	}
+	return objects, nil
+}
+
+// nullable returns v, or nil for the zero value that stands for NULL.
+func nullable[T comparable](v T) interface{} {
+	var zero T
+	if v == zero {
+		return nil
+	}
+	return v
+}
+
+// CollectUserData returns the same keys as in Postgres. The tables of the
+// car service are always empty.
+func (e *ExportRepository) CollectUserData(ctx context.Context, userId string) (map[string]json.RawMessage, error) {
+	e.db.mu.RLock()
+	defer e.db.mu.RUnlock()
+
+	u, ok := e.db.users[userId]
+	if !ok {
+		return nil, fmt.Errorf("failed to collect profile: %w", sql.ErrNoRows)
+	}
+	var birthDate interface{}
+	if u.birthDate != nil {
+		birthDate = u.birthDate.Format("2006-01-02")
+	}
+	var purgedAt interface{}
+	if u.purgedAt != nil {
+		purgedAt = *u.purgedAt
+	}
+	rows := map[string]interface{}{
+		"profile": map[string]interface{}{
+			"id": u.id, "name": nullable(u.name), "surname": nullable(u.surname), "email": u.email,
+			"birth_date": birthDate, "gender": nullable(u.gender), "phone_number": u.phoneNumber,
+			"address": nullable(u.address), "photo": nullable(u.photo), "role": u.role,
+			"created_at": u.createdAt, "updated_at": u.updatedAt, "deleted_at": u.deletedAt,
+			"purged_at": purgedAt, "password_reset_required": u.passwordResetRequired,
+			"phone_visibility": u.phoneVisibility, "email_visibility": u.emailVisibility,
+			"rating_average": u.ratingAverage, "rating_count": u.ratingCount,
+		},
+		"cars":          []interface{}{},
+		"images":        []interface{}{},
+		"comments":      []interface{}{},
+		"messages":      []interface{}{},
+		"saved_cars":    []interface{}{},
+		"device_tokens": []interface{}{},
+	}
+
+	var saved []*savedSearch
+	for _, s := range e.db.savedSearches {
+		if s.userId == userId {
+			saved = append(saved, s)
+		}
+	}
+	sort.Slice(saved, func(i, j int) bool { return saved[i].createdAt.Before(saved[j].createdAt) })
+	searches := []interface{}{}
+	for _, s := range saved {
+		f := copyFilter(s.filter)
+		searches = append(searches, map[string]interface{}{
+			"id": s.id, "user_id": s.userId, "name": s.name,
+			"type": nullable(f.Type), "make": nullable(f.Make), "model": nullable(f.Model),
+			"year_from": nullable(f.YearFrom), "year_to": nullable(f.YearTo),
+			"price_from": nullable(f.PriceFrom), "price_to": nullable(f.PriceTo),
+			"mileage_from": nullable(f.MileageFrom), "mileage_to": nullable(f.MileageTo),
+			"color": nullable(f.Color), "location": nullable(f.Location),
+			"frequency": s.frequency, "active": s.active, "last_checked_at": s.lastCheckedAt,
+			"created_at": s.createdAt, "updated_at": s.updatedAt, "deleted_at": s.deletedAt,
+		})
+	}
+	rows["saved_searches"] = searches
+
+	notifications := []interface{}{}
+	for _, n := range e.db.notifications {
+		if n.userId == userId {
+			notifications = append(notifications, map[string]interface{}{
+				"id": n.id, "user_id": n.userId, "type": n.kind, "message": n.message,
+				"seen": false, "created_at": n.createdAt, "updated_at": n.createdAt, "deleted_at": 0,
+			})
+		}
+	}
+	rows["notifications"] = notifications
+
+	linked := []interface{}{}
+	for _, i := range e.db.identities {
+		if i.userId == userId {
+			linked = append(linked, map[string]interface{}{
+				"user_id": i.userId, "provider": i.provider, "subject": i.subject,
+				"email": nullable(i.email), "created_at": i.createdAt,
+			})
+		}
+	}
+	rows["linked_accounts"] = linked
+
+	data := make(map[string]json.RawMessage, len(rows))
+	for name, row := range rows {
+		raw, err := json.Marshal(row)
+		if err != nil {
+			return nil, fmt.Errorf("failed to collect %s: %w", name, err)
+		}
+		data[name] = raw
+	}
+	return data, nil
+}
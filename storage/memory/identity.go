@@ -0,0 +1,95 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+	pb "wegugin/genproto/user"
+	"wegugin/model"
+	"wegugin/storage"
+)
+
+type identity struct {
+	id        string
+	userId    string
+	provider  string
+	subject   string
+	email     string
+	createdAt time.Time
+}
+
+type IdentityRepository struct {
+	db *db
+}
+
+func (i *IdentityRepository) LinkIdentity(ctx context.Context, req *model.Identity) error {
+	i.db.mu.Lock()
+	defer i.db.mu.Unlock()
+
+	if !i.db.userExists(req.UserId) {
+		return fmt.Errorf("failed to link identity: user %s does not exist", req.UserId)
+	}
+	// UNIQUE (provider, subject) and UNIQUE (user_id, provider)
+	for _, linked := range i.db.identities {
+		if linked.provider != req.Provider {
+			continue
+		}
+		if linked.subject == req.Subject || linked.userId == req.UserId {
+			return storage.ErrIdentityTaken
+		}
+	}
+	i.db.identities = append(i.db.identities, &identity{
+		id:        newId(),
+		userId:    req.UserId,
+		provider:  req.Provider,
+		subject:   req.Subject,
+		email:     req.Email,
+		createdAt: time.Now(),
+	})
+	return nil
+}
+
+func (i *IdentityRepository) IdentityUser(ctx context.Context, provider, subject string) (string, error) {
+	i.db.mu.RLock()
+	defer i.db.mu.RUnlock()
+
+	for _, linked := range i.db.identities {
+		if linked.provider == provider && linked.subject == subject {
+			return linked.userId, nil
+		}
+	}
+	return "", fmt.Errorf("identity not linked: %w", sql.ErrNoRows)
+}
+
+func (i *IdentityRepository) ListIdentities(ctx context.Context, req *pb.UserId) (*pb.IdentityList, error) {
+	i.db.mu.RLock()
+	defer i.db.mu.RUnlock()
+
+	// Identities are appended in the order they are created
+	list := &pb.IdentityList{}
+	for _, linked := range i.db.identities {
+		if linked.userId == req.Id {
+			list.Identities = append(list.Identities, &pb.Identity{
+				Provider:  linked.provider,
+				Email:     linked.email,
+				CreatedAt: formatTime(linked.createdAt),
+			})
+		}
+	}
+	return list, nil
+}
+
+func (i *IdentityRepository) UnlinkIdentity(ctx context.Context, req *pb.IdentityReq) error {
+	i.db.mu.Lock()
+	defer i.db.mu.Unlock()
+
+	before := len(i.db.identities)
+	i.db.identities = filter(i.db.identities, func(linked *identity) bool {
+		return linked.userId != req.UserId || linked.provider != req.Provider
+	})
+	if len(i.db.identities) == before {
+		return fmt.Errorf("identity not linked: %w", sql.ErrNoRows)
+	}
+	return nil
+}
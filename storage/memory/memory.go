@@ -0,0 +1,152 @@
+// Package memory keeps everything storage.IStorage stores in process memory.
+// It follows the Postgres repositories, soft deletes and unique indexes
+// included, so the service runs without a database for development and
+// tests. Nothing survives a restart.
+package memory
+
+import (
+	"sync"
+	"time"
+	"wegugin/api/auth"
+	"wegugin/api/password"
+	"wegugin/config"
+	"wegugin/storage"
+
+	"github.com/google/uuid"
+)
+
+// db holds the tables. One lock guards all of them, like a transaction
+// would, since some changes span several tables.
+type db struct {
+	mu sync.RWMutex
+
+	users         map[string]*user
+	savedSearches map[string]*savedSearch
+	suspensions   []*suspension
+	exports       []*export
+	auditEvents   []*auditEvent
+	sessions      []*session
+	notifications []*notification
+	identities    []*identity
+}
+
+type memoryStorage struct {
+	db   *db
+	conf *config.Config
+}
+
+// New returns an empty storage.
+func New(conf *config.Config) storage.IStorage {
+	return &memoryStorage{
+		conf: conf,
+		db: &db{
+			users:         make(map[string]*user),
+			savedSearches: make(map[string]*savedSearch),
+		},
+	}
+}
+
+func (m *memoryStorage) Close() {}
+
+func (m *memoryStorage) User() storage.IUserStorage {
+	// Passwords are hashed like in Postgres, bcrypt hashes keep working and
+	// are upgraded on login
+	return &UserRepository{
+		db:            m.db,
+		Hasher:        password.NewHasher(m.conf.Password),
+		Tokens:        auth.New(m.conf.Token),
+		RestoreWindow: m.conf.Account.RESTORE_WINDOW,
+	}
+}
+
+func (m *memoryStorage) SavedSearch() storage.ISavedSearchStorage {
+	return &SavedSearchRepository{db: m.db}
+}
+
+func (m *memoryStorage) Car() storage.ICarStorage {
+	return &CarRepository{}
+}
+
+func (m *memoryStorage) Suspension() storage.ISuspensionStorage {
+	return &SuspensionRepository{db: m.db}
+}
+
+func (m *memoryStorage) Export() storage.IExportStorage {
+	return &ExportRepository{db: m.db}
+}
+
+func (m *memoryStorage) Audit() storage.IAuditStorage {
+	return &AuditRepository{db: m.db}
+}
+
+func (m *memoryStorage) Session() storage.ISessionStorage {
+	return &SessionRepository{db: m.db}
+}
+
+func (m *memoryStorage) Notification() storage.INotificationStorage {
+	return &NotificationRepository{db: m.db}
+}
+
+func (m *memoryStorage) Identity() storage.IIdentityStorage {
+	return &IdentityRepository{db: m.db}
+}
+
+func newId() string {
+	return uuid.NewString()
+}
+
+// formatTime renders t the way database/sql scans a timestamptz into a string.
+func formatTime(t time.Time) string {
+	return t.Format(time.RFC3339Nano)
+}
+
+// formatNullTime is formatTime for nullable columns, "" standing for NULL.
+func formatNullTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return formatTime(*t)
+}
+
+// userExists is the foreign key check of the tables referencing users.
+// The caller holds the lock.
+func (d *db) userExists(id string) bool {
+	_, ok := d.users[id]
+	return ok
+}
+
+// deleteUserRows removes the rows ON DELETE CASCADE removes with the user
+// and clears the references ON DELETE SET NULL clears. The caller holds the
+// write lock.
+func (d *db) deleteUserRows(id string) {
+	for searchId, search := range d.savedSearches {
+		if search.userId == id {
+			delete(d.savedSearches, searchId)
+		}
+	}
+	d.suspensions = filter(d.suspensions, func(s *suspension) bool { return s.userId != id })
+	for _, s := range d.suspensions {
+		if s.actorId == id {
+			s.actorId = ""
+		}
+		if s.liftedBy == id {
+			s.liftedBy = ""
+		}
+	}
+	d.exports = filter(d.exports, func(e *export) bool { return e.userId != id })
+	d.sessions = filter(d.sessions, func(s *session) bool { return s.userId != id })
+	d.notifications = filter(d.notifications, func(n *notification) bool { return n.userId != id })
+	d.identities = filter(d.identities, func(i *identity) bool { return i.userId != id })
+}
+
+// filter returns the rows keep is true for, reusing rows' array.
+func filter[T any](rows []T, keep func(T) bool) []T {
+	kept := rows[:0]
+	for _, row := range rows {
+		if keep(row) {
+			kept = append(kept, row)
+		}
+	}
+	clear(rows[len(kept):])
+	return kept
+}
@@ -0,0 +1,13 @@
+package memory_test
+
+import (
+	"testing"
+	"wegugin/config"
+	"wegugin/storage"
+	"wegugin/storage/memory"
+	"wegugin/storage/storagetest"
+)
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T, conf *config.Config) storage.IStorage { return memory.New(conf) })
+}
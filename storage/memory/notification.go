@@ -0,0 +1,37 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"wegugin/model"
+)
+
+type notification struct {
+	id        string
+	userId    string
+	kind      string
+	message   string
+	createdAt time.Time
+}
+
+type NotificationRepository struct {
+	db *db
+}
+
+func (n *NotificationRepository) CreateNotification(ctx context.Context, req *model.Notification) error {
+	n.db.mu.Lock()
+	defer n.db.mu.Unlock()
+
+	if !n.db.userExists(req.UserId) {
+		return fmt.Errorf("failed to insert notification: user %s does not exist", req.UserId)
+	}
+	n.db.notifications = append(n.db.notifications, &notification{
+		id:        newId(),
+		userId:    req.UserId,
+		kind:      req.Type,
+		message:   req.Message,
+		createdAt: time.Now(),
+	})
+	return nil
+}
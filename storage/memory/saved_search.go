@@ -0,0 +1,222 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	pb "wegugin/genproto/user"
+	"wegugin/model"
+
+	"google.golang.org/protobuf/proto"
+)
+
+type savedSearch struct {
+	id            string
+	userId        string
+	name          string
+	filter        *pb.CarFilter
+	frequency     string
+	active        bool
+	lastCheckedAt time.Time
+	createdAt     time.Time
+	updatedAt     time.Time
+	deletedAt     int64
+}
+
+func (s *savedSearch) toProto() *pb.SavedSearch {
+	return &pb.SavedSearch{
+		Id:            s.id,
+		UserId:        s.userId,
+		Name:          s.name,
+		Filter:        copyFilter(s.filter),
+		Frequency:     s.frequency,
+		Active:        s.active,
+		LastCheckedAt: formatTime(s.lastCheckedAt),
+		CreatedAt:     formatTime(s.createdAt),
+		UpdatedAt:     formatTime(s.updatedAt),
+	}
+}
+
+type SavedSearchRepository struct {
+	db *db
+}
+
+func validFrequency(frequency string) bool {
+	return frequency == "instant" || frequency == "daily"
+}
+
+// copyFilter keeps a copy of f, so that callers changing their request
+// afterwards do not change the stored search.
+func copyFilter(f *pb.CarFilter) *pb.CarFilter {
+	if f == nil {
+		return &pb.CarFilter{}
+	}
+	return proto.Clone(f).(*pb.CarFilter)
+}
+
+func (s *SavedSearchRepository) CreateSavedSearch(ctx context.Context, req *pb.CreateSavedSearchReq) (*pb.SavedSearch, error) {
+	if strings.TrimSpace(req.Name) == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if req.Frequency == "" {
+		req.Frequency = "instant"
+	}
+	if !validFrequency(req.Frequency) {
+		return nil, fmt.Errorf("invalid frequency: %s", req.Frequency)
+	}
+
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if !s.db.userExists(req.UserId) {
+		return nil, fmt.Errorf("failed to insert saved search: user %s does not exist", req.UserId)
+	}
+	now := time.Now()
+	search := &savedSearch{
+		id:            newId(),
+		userId:        req.UserId,
+		name:          req.Name,
+		filter:        copyFilter(req.Filter),
+		frequency:     req.Frequency,
+		active:        true,
+		lastCheckedAt: now,
+		createdAt:     now,
+		updatedAt:     now,
+	}
+	s.db.savedSearches[search.id] = search
+	return search.toProto(), nil
+}
+
+// find returns the user's saved search unless deleted. The caller holds
+// the lock.
+func (s *SavedSearchRepository) find(id, userId string) (*savedSearch, bool) {
+	search, ok := s.db.savedSearches[id]
+	if !ok || search.userId != userId || search.deletedAt != 0 {
+		return nil, false
+	}
+	return search, true
+}
+
+func (s *SavedSearchRepository) GetSavedSearch(ctx context.Context, req *pb.SavedSearchId) (*pb.SavedSearch, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	search, ok := s.find(req.Id, req.UserId)
+	if !ok {
+		return nil, fmt.Errorf("saved search not found")
+	}
+	return search.toProto(), nil
+}
+
+func (s *SavedSearchRepository) ListSavedSearches(ctx context.Context, req *pb.UserId) (*pb.SavedSearchList, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var searches []*savedSearch
+	for _, search := range s.db.savedSearches {
+		if search.userId == req.Id && search.deletedAt == 0 {
+			searches = append(searches, search)
+		}
+	}
+	sort.Slice(searches, func(i, j int) bool { return searches[i].createdAt.After(searches[j].createdAt) })
+
+	res := &pb.SavedSearchList{}
+	for _, search := range searches {
+		res.SavedSearches = append(res.SavedSearches, search.toProto())
+	}
+	return res, nil
+}
+
+func (s *SavedSearchRepository) UpdateSavedSearch(ctx context.Context, req *pb.UpdateSavedSearchReq) error {
+	var updates []func(*savedSearch)
+
+	if len(req.Name) > 0 {
+		updates = append(updates, func(search *savedSearch) { search.name = req.Name })
+	}
+	if len(req.Frequency) > 0 {
+		if !validFrequency(req.Frequency) {
+			return fmt.Errorf("invalid frequency: %s", req.Frequency)
+		}
+		updates = append(updates, func(search *savedSearch) { search.frequency = req.Frequency })
+	}
+	if req.Active != nil {
+		active := *req.Active
+		updates = append(updates, func(search *savedSearch) { search.active = active })
+	}
+	// A new filter replaces the old one as a whole
+	if req.Filter != nil {
+		filter := copyFilter(req.Filter)
+		updates = append(updates, func(search *savedSearch) { search.filter = filter })
+	}
+
+	if len(updates) == 0 {
+		return fmt.Errorf("no fields to update")
+	}
+
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	search, ok := s.find(req.Id, req.UserId)
+	if !ok {
+		return fmt.Errorf("saved search not found")
+	}
+	for _, update := range updates {
+		update(search)
+	}
+	search.updatedAt = time.Now()
+	return nil
+}
+
+func (s *SavedSearchRepository) DeleteSavedSearch(ctx context.Context, req *pb.SavedSearchId) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	search, ok := s.find(req.Id, req.UserId)
+	if !ok {
+		return fmt.Errorf("saved search not found")
+	}
+	search.deletedAt = time.Now().Unix()
+	return nil
+}
+
+func (s *SavedSearchRepository) DueSavedSearches(ctx context.Context, now time.Time) ([]*pb.SavedSearch, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var searches []*pb.SavedSearch
+	for _, search := range s.db.savedSearches {
+		if search.deletedAt != 0 || !search.active {
+			continue
+		}
+		if _, ok := s.db.activeUser(search.userId); !ok {
+			continue
+		}
+		if search.frequency == "instant" || !search.lastCheckedAt.After(now.AddDate(0, 0, -1)) {
+			searches = append(searches, search.toProto())
+		}
+	}
+	return searches, nil
+}
+
+func (s *SavedSearchRepository) CheckCutoff(ctx context.Context, lag time.Duration) (time.Time, error) {
+	return time.Now().Add(-lag), nil
+}
+
+// MatchCars finds nothing: listings belong to the car service and are not
+// kept in memory.
+func (s *SavedSearchRepository) MatchCars(ctx context.Context, search *pb.SavedSearch, until time.Time, dedup time.Duration) ([]*model.CarMatch, error) {
+	return nil, nil
+}
+
+// MarkChecked only moves the last check, as MatchCars never announces a car.
+func (s *SavedSearchRepository) MarkChecked(ctx context.Context, searchId string, carIds []string, checkedAt time.Time) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if search, ok := s.db.savedSearches[searchId]; ok {
+		search.lastCheckedAt = checkedAt
+	}
+	return nil
+}
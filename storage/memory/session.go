@@ -0,0 +1,121 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+	pb "wegugin/genproto/user"
+	"wegugin/model"
+)
+
+type session struct {
+	id         string
+	userId     string
+	deviceName string
+	platform   string
+	ip         string
+	userAgent  string
+	createdAt  time.Time
+	lastSeenAt time.Time
+	expiresAt  time.Time
+	revokedAt  *time.Time
+}
+
+type SessionRepository struct {
+	db *db
+}
+
+func (s *SessionRepository) CreateSession(ctx context.Context, req *model.Session) (string, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if !s.db.userExists(req.UserId) {
+		return "", fmt.Errorf("failed to insert session: user %s does not exist", req.UserId)
+	}
+	now := time.Now()
+	created := &session{
+		id:         newId(),
+		userId:     req.UserId,
+		deviceName: req.DeviceName,
+		platform:   req.Platform,
+		ip:         req.IP,
+		userAgent:  req.UserAgent,
+		createdAt:  now,
+		lastSeenAt: now,
+		expiresAt:  req.ExpiresAt,
+	}
+	s.db.sessions = append(s.db.sessions, created)
+	return created.id, nil
+}
+
+func (s *SessionRepository) ListSessions(ctx context.Context, userId string) ([]*pb.Session, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	now := time.Now()
+	var found []*session
+	for _, session := range s.db.sessions {
+		if session.userId == userId && session.revokedAt == nil && session.expiresAt.After(now) {
+			found = append(found, session)
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].lastSeenAt.After(found[j].lastSeenAt) })
+
+	var sessions []*pb.Session
+	for _, session := range found {
+		sessions = append(sessions, &pb.Session{
+			Id:         session.id,
+			DeviceName: session.deviceName,
+			Platform:   session.platform,
+			Ip:         session.ip,
+			UserAgent:  session.userAgent,
+			CreatedAt:  formatTime(session.createdAt),
+			LastSeenAt: formatTime(session.lastSeenAt),
+		})
+	}
+	return sessions, nil
+}
+
+func (s *SessionRepository) RevokeSession(ctx context.Context, req *pb.SessionReq) (time.Time, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for _, session := range s.db.sessions {
+		if session.id == req.SessionId && session.userId == req.UserId && session.revokedAt == nil {
+			now := time.Now()
+			session.revokedAt = &now
+			return session.expiresAt, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("session not found: %w", sql.ErrNoRows)
+}
+
+func (s *SessionRepository) RevokeOtherSessions(ctx context.Context, userId, keepId string) (map[string]time.Time, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	now := time.Now()
+	revoked := make(map[string]time.Time)
+	for _, session := range s.db.sessions {
+		if session.userId != userId || session.id == keepId || session.revokedAt != nil || !session.expiresAt.After(now) {
+			continue
+		}
+		session.revokedAt = &now
+		revoked[session.id] = session.expiresAt
+	}
+	return revoked, nil
+}
+
+func (s *SessionRepository) IsSessionRevoked(ctx context.Context, sessionId string) (bool, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	for _, session := range s.db.sessions {
+		if session.id == sessionId {
+			return session.revokedAt != nil, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,157 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+	pb "wegugin/genproto/user"
+)
+
+// suspension is a row of user_suspensions. Rows are never deleted, lifting
+// only fills liftedAt.
+type suspension struct {
+	id         string
+	userId     string
+	reason     string
+	actorId    string
+	startsAt   time.Time
+	expiresAt  *time.Time
+	liftedAt   *time.Time
+	liftedBy   string
+	liftReason string
+}
+
+// activeAt reports whether the suspension is in force at now.
+func (s *suspension) activeAt(now time.Time) bool {
+	return s.liftedAt == nil && !s.startsAt.After(now) && (s.expiresAt == nil || s.expiresAt.After(now))
+}
+
+func (s *suspension) toProto() *pb.Suspension {
+	return &pb.Suspension{
+		Id:         s.id,
+		UserId:     s.userId,
+		Reason:     s.reason,
+		ActorId:    s.actorId,
+		StartsAt:   formatTime(s.startsAt),
+		ExpiresAt:  formatNullTime(s.expiresAt),
+		LiftedAt:   formatNullTime(s.liftedAt),
+		LiftedBy:   s.liftedBy,
+		LiftReason: s.liftReason,
+	}
+}
+
+// activeSuspension returns the suspension of the user in force at now, or
+// nil. The caller holds the lock.
+func (d *db) activeSuspension(userId string, now time.Time) *suspension {
+	for _, s := range d.suspensions {
+		if s.userId == userId && s.activeAt(now) {
+			return s
+		}
+	}
+	return nil
+}
+
+type SuspensionRepository struct {
+	db *db
+}
+
+func (s *SuspensionRepository) SuspendUser(ctx context.Context, req *pb.SuspendUserReq) (*pb.Suspension, error) {
+	if len(req.Reason) == 0 {
+		return nil, fmt.Errorf("reason is required")
+	}
+	var expiresAt *time.Time
+	if len(req.ExpiresAt) > 0 {
+		t, err := time.Parse(time.RFC3339, req.ExpiresAt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expires_at format: %w", err)
+		}
+		if !t.After(time.Now()) {
+			return nil, fmt.Errorf("expires_at must be in the future")
+		}
+		expiresAt = &t
+	}
+
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	now := time.Now()
+	if _, ok := s.db.activeUser(req.UserId); !ok {
+		return nil, fmt.Errorf("user not found")
+	}
+	if s.db.activeSuspension(req.UserId, now) != nil {
+		return nil, fmt.Errorf("user is already suspended")
+	}
+	if req.ActorId != "" && !s.db.userExists(req.ActorId) {
+		return nil, fmt.Errorf("failed to insert suspension: user %s does not exist", req.ActorId)
+	}
+
+	created := &suspension{
+		id:        newId(),
+		userId:    req.UserId,
+		reason:    req.Reason,
+		actorId:   req.ActorId,
+		startsAt:  now,
+		expiresAt: expiresAt,
+	}
+	s.db.suspensions = append(s.db.suspensions, created)
+	return created.toProto(), nil
+}
+
+func (s *SuspensionRepository) LiftSuspension(ctx context.Context, req *pb.LiftSuspensionReq) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	now := time.Now()
+	lifted := false
+	for _, suspension := range s.db.suspensions {
+		if suspension.userId == req.UserId && suspension.activeAt(now) {
+			suspension.liftedAt = &now
+			suspension.liftedBy = req.ActorId
+			suspension.liftReason = req.Reason
+			lifted = true
+		}
+	}
+	if !lifted {
+		return fmt.Errorf("user is not suspended")
+	}
+	return nil
+}
+
+func (s *SuspensionRepository) ListSuspensions(ctx context.Context, req *pb.UserId) (*pb.SuspensionList, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	// Newest first, suspensions are appended in the order they start
+	res := &pb.SuspensionList{}
+	for i := len(s.db.suspensions) - 1; i >= 0; i-- {
+		if suspension := s.db.suspensions[i]; suspension.userId == req.Id {
+			res.Suspensions = append(res.Suspensions, suspension.toProto())
+		}
+	}
+	return res, nil
+}
+
+func (s *SuspensionRepository) ActiveSuspensions(ctx context.Context) ([]*pb.Suspension, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	now := time.Now()
+	var suspensions []*pb.Suspension
+	for _, suspension := range s.db.suspensions {
+		if suspension.activeAt(now) {
+			suspensions = append(suspensions, suspension.toProto())
+		}
+	}
+	return suspensions, nil
+}
+
+func (s *SuspensionRepository) IsSuspended(ctx context.Context, userId string) (bool, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+	return s.db.activeSuspension(userId, time.Now()) != nil, nil
+}
+
+// SyncHiddenCars has nothing to hide, there are no cars in memory.
+func (s *SuspensionRepository) SyncHiddenCars(ctx context.Context) error {
+	return nil
+}
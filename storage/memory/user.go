@@ -0,0 +1,958 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"wegugin/api/auth"
+	"wegugin/api/password"
+	pb "wegugin/genproto/user"
+	"wegugin/model"
+	"wegugin/storage"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// user is a row of the users table. Empty strings stand for NULL.
+type user struct {
+	id          string
+	name        string
+	surname     string
+	email       string
+	birthDate   *time.Time
+	gender      string
+	phoneNumber string
+	address     string
+	photo       string
+	role        string
+
+	passwordHash string
+	// passwordHistory is the password_history table of the user, oldest first
+	passwordHistory       []passwordEntry
+	passwordResetRequired bool
+
+	phoneVisibility string
+	emailVisibility string
+	ratingAverage   float64
+	ratingCount     int32
+
+	createdAt time.Time
+	updatedAt time.Time
+	deletedAt int64
+	purgedAt  *time.Time
+}
+
+type passwordEntry struct {
+	hash      string
+	createdAt time.Time
+}
+
+// setPassword replaces the password hash. Unless it is a rehash the old hash
+// goes to the history, as the users_record_password trigger does.
+func (u *user) setPassword(hash string, rehash bool) {
+	if !rehash && u.passwordHash != "" && u.passwordHash != hash {
+		u.passwordHistory = append(u.passwordHistory, passwordEntry{hash: u.passwordHash, createdAt: time.Now()})
+	}
+	u.passwordHash = hash
+}
+
+func (u *user) active() bool {
+	return u.deletedAt == 0
+}
+
+func (u *user) response() *pb.GetUserResponse {
+	res := &pb.GetUserResponse{
+		Id:          u.id,
+		Name:        u.name,
+		Surname:     u.surname,
+		Email:       u.email,
+		Gender:      u.gender,
+		PhoneNumber: u.phoneNumber,
+		Address:     u.address,
+		Photo:       u.photo,
+		Role:        u.role,
+		CreatedAt:   formatTime(u.createdAt),
+	}
+	if u.birthDate != nil {
+		res.BirthDate = u.birthDate.Format("2006-01-02")
+	}
+	return res
+}
+
+func (u *user) adminUser() *pb.AdminUser {
+	res := &pb.AdminUser{
+		Id:                    u.id,
+		Name:                  u.name,
+		Surname:               u.surname,
+		Email:                 u.email,
+		Gender:                u.gender,
+		PhoneNumber:           u.phoneNumber,
+		Address:               u.address,
+		Photo:                 u.photo,
+		Role:                  u.role,
+		CreatedAt:             formatTime(u.createdAt),
+		UpdatedAt:             formatTime(u.updatedAt),
+		DeletedAt:             u.deletedAt,
+		PasswordResetRequired: u.passwordResetRequired,
+		Rating:                u.ratingAverage,
+		RatingCount:           u.ratingCount,
+		PhoneVisibility:       u.phoneVisibility,
+		EmailVisibility:       u.emailVisibility,
+	}
+	if u.birthDate != nil {
+		res.BirthDate = u.birthDate.Format("2006-01-02")
+	}
+	return res
+}
+
+type UserRepository struct {
+	db     *db
+	Hasher *password.Hasher
+	Tokens *auth.Tokens
+	// RestoreWindow is how long deleted users can log in again
+	RestoreWindow time.Duration
+}
+
+// conflict is the check of the unique indexes on the email and phone number
+// of active users. The caller holds the lock.
+func (d *db) conflict(exceptId, email, phoneNumber string) error {
+	for _, u := range d.users {
+		if u.id == exceptId || !u.active() {
+			continue
+		}
+		if email != "" && u.email == email {
+			return storage.ErrEmailTaken
+		}
+		if phoneNumber != "" && u.phoneNumber == phoneNumber {
+			return fmt.Errorf("phone number is already used")
+		}
+	}
+	return nil
+}
+
+// activeUser returns the user with the id unless deleted. The caller holds
+// the lock.
+func (d *db) activeUser(id string) (*user, bool) {
+	u, ok := d.users[id]
+	if !ok || !u.active() {
+		return nil, false
+	}
+	return u, true
+}
+
+// byLogin returns the user with the email or phone number among those
+// matching, preferring active users and then the latest deleted one. The
+// caller holds the lock.
+func (d *db) byLogin(login string, match func(*user) bool) *user {
+	var found *user
+	for _, u := range d.users {
+		if u.email != login && u.phoneNumber != login {
+			continue
+		}
+		if u.purgedAt != nil || !match(u) {
+			continue
+		}
+		if found == nil || u.active() || (!found.active() && u.deletedAt > found.deletedAt) {
+			found = u
+		}
+	}
+	return found
+}
+
+func validGender(gender string) bool {
+	return gender == "male" || gender == "female" || gender == "other"
+}
+
+func (u *UserRepository) CreateUser(ctx context.Context, req *pb.RegisterReq) (*pb.LoginRes, error) {
+	return u.CreateUserWithRole(ctx, req, "user")
+}
+
+func (u *UserRepository) CreateUserWithRole(ctx context.Context, req *pb.RegisterReq, role string) (*pb.LoginRes, error) {
+	hashedPassword, err := u.Hasher.Hash(req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	birthDate, err := time.Parse("02-01-2006", req.BirthDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid birth_date format: %w", err)
+	}
+	if !validGender(req.Gender) {
+		return nil, fmt.Errorf("failed to insert user: invalid gender: %s", req.Gender)
+	}
+	if role != "user" && role != "admin" {
+		return nil, fmt.Errorf("failed to insert user: invalid role: %s", role)
+	}
+
+	u.db.mu.Lock()
+	if err := u.db.conflict("", req.Email, req.Phone); err != nil {
+		u.db.mu.Unlock()
+		return nil, fmt.Errorf("failed to insert user: %w", err)
+	}
+	now := time.Now()
+	created := &user{
+		id:              newId(),
+		name:            req.Name,
+		surname:         req.Surname,
+		email:           req.Email,
+		birthDate:       &birthDate,
+		gender:          req.Gender,
+		phoneNumber:     req.Phone,
+		role:            role,
+		passwordHash:    hashedPassword,
+		phoneVisibility: model.VisibilityNobody,
+		emailVisibility: model.VisibilityNobody,
+		createdAt:       now,
+		updatedAt:       now,
+	}
+	u.db.users[created.id] = created
+	u.db.mu.Unlock()
+
+	token, err := u.Tokens.GenerateJWTToken(created.id, created.role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate jwt token: %w", err)
+	}
+
+	return &pb.LoginRes{
+		Token: token,
+	}, nil
+}
+
+var (
+	dummyHash     string
+	dummyHashOnce sync.Once
+)
+
+// verifyDummy spends the time of a password check when the login is unknown.
+func (u *UserRepository) verifyDummy(pw string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = u.Hasher.Hash("dummy password")
+	})
+	u.Hasher.Verify(dummyHash, pw)
+}
+
+// restoreWindowStart is the oldest deleted_at that can still be restored.
+func (u *UserRepository) restoreWindowStart() int64 {
+	return time.Now().Add(-u.RestoreWindow).Unix()
+}
+
+// restorable matches users that can log in, deleted ones within the
+// restore window included.
+func restorable(windowStart int64) func(*user) bool {
+	return func(u *user) bool {
+		return u.active() || u.deletedAt > windowStart
+	}
+}
+
+func (u *UserRepository) Login(ctx context.Context, req *pb.LoginReq) (*pb.LoginRes, error) {
+	windowStart := u.restoreWindowStart()
+	u.db.mu.RLock()
+	found := u.db.byLogin(req.EmailOrPhoneNumber, restorable(windowStart))
+	var id, passwordHash, role string
+	var resetRequired bool
+	var deletedAt int64
+	if found != nil {
+		id, passwordHash, role = found.id, found.passwordHash, found.role
+		resetRequired, deletedAt = found.passwordResetRequired, found.deletedAt
+	}
+	u.db.mu.RUnlock()
+
+	if found == nil {
+		// Spend the same time as for a wrong password
+		u.verifyDummy(req.Password)
+		return nil, storage.ErrInvalidCredentials
+	}
+	ok, err := u.Hasher.Verify(passwordHash, req.Password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify password: %w", err)
+	}
+	// Checked whatever the password, so the answer does not confirm it
+	if resetRequired {
+		return nil, storage.ErrPasswordResetRequired
+	}
+	if !ok {
+		return nil, storage.ErrInvalidCredentials
+	}
+
+	if err := u.checkSuspended(id); err != nil {
+		return nil, err
+	}
+
+	if u.Hasher.NeedsRehash(passwordHash) {
+		u.rehash(id, passwordHash, req.Password)
+	}
+
+	return u.finishLogin(ctx, id, role, deletedAt)
+}
+
+func (u *UserRepository) CheckPassword(ctx context.Context, userId, password string) error {
+	u.db.mu.RLock()
+	found, ok := u.db.activeUser(userId)
+	var passwordHash string
+	if ok {
+		passwordHash = found.passwordHash
+	}
+	u.db.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("user not found: %w", sql.ErrNoRows)
+	}
+
+	ok, err := u.Hasher.Verify(passwordHash, password)
+	if err != nil {
+		return fmt.Errorf("failed to verify password: %w", err)
+	}
+	if !ok {
+		return storage.ErrInvalidCredentials
+	}
+	return nil
+}
+
+func (u *UserRepository) ChangeEmail(ctx context.Context, userId, from, to string) error {
+	u.db.mu.Lock()
+	defer u.db.mu.Unlock()
+
+	found, ok := u.db.activeUser(userId)
+	if !ok || found.email != from {
+		return fmt.Errorf("email has changed: %w", sql.ErrNoRows)
+	}
+	if err := u.db.conflict(userId, to, ""); err != nil {
+		return err
+	}
+	found.email = to
+	found.updatedAt = time.Now()
+	return nil
+}
+
+func (u *UserRepository) LoginById(ctx context.Context, id string) (*pb.LoginRes, error) {
+	u.db.mu.RLock()
+	found, ok := u.db.users[id]
+	var role string
+	var deletedAt int64
+	if ok && found.purgedAt == nil && restorable(u.restoreWindowStart())(found) {
+		role, deletedAt = found.role, found.deletedAt
+	} else {
+		ok = false
+	}
+	u.db.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("user not found: %w", sql.ErrNoRows)
+	}
+	if err := u.checkSuspended(id); err != nil {
+		return nil, err
+	}
+
+	return u.finishLogin(ctx, id, role, deletedAt)
+}
+
+func (u *UserRepository) checkSuspended(id string) error {
+	u.db.mu.RLock()
+	defer u.db.mu.RUnlock()
+	if u.db.activeSuspension(id, time.Now()) != nil {
+		return storage.ErrUserSuspended
+	}
+	return nil
+}
+
+// finishLogin restores a deleted user logging in and returns the token.
+func (u *UserRepository) finishLogin(ctx context.Context, id, role string, deletedAt int64) (*pb.LoginRes, error) {
+	if deletedAt != 0 {
+		if _, err := u.RestoreDeletedUser(ctx, id, deletedAt); err != nil {
+			return nil, err
+		}
+	}
+
+	token, err := u.Tokens.GenerateJWTToken(id, role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate jwt token: %w", err)
+	}
+
+	return &pb.LoginRes{
+		Token: token,
+	}, nil
+}
+
+// rehash stores password hashed with the current algorithm and parameters,
+// without adding the old hash of the same password to the history.
+func (u *UserRepository) rehash(id, oldHash, pw string) error {
+	hash, err := u.Hasher.Hash(pw)
+	if err != nil {
+		return err
+	}
+	u.db.mu.Lock()
+	defer u.db.mu.Unlock()
+	// A password changed meanwhile is left alone
+	if found, ok := u.db.users[id]; ok && found.passwordHash == oldHash {
+		found.setPassword(hash, true)
+	}
+	return nil
+}
+
+func (u *UserRepository) UserIdByLogin(ctx context.Context, login string) (string, error) {
+	u.db.mu.RLock()
+	defer u.db.mu.RUnlock()
+
+	found := u.db.byLogin(login, func(*user) bool { return true })
+	if found == nil {
+		return "", errors.New("user not found")
+	}
+	return found.id, nil
+}
+
+func (u *UserRepository) RecentPasswordHashes(ctx context.Context, userId string, n int) ([]string, error) {
+	u.db.mu.RLock()
+	defer u.db.mu.RUnlock()
+
+	found, ok := u.db.users[userId]
+	if !ok || n < 1 {
+		return nil, nil
+	}
+	hashes := []string{found.passwordHash}
+	for i := len(found.passwordHistory) - 1; i >= 0 && len(hashes) < n; i-- {
+		hashes = append(hashes, found.passwordHistory[i].hash)
+	}
+	return hashes, nil
+}
+
+func (u *UserRepository) GetUserByEmail(ctx context.Context, req *pb.GetUSerByEmailReq) (*pb.GetUserResponse, error) {
+	u.db.mu.RLock()
+	defer u.db.mu.RUnlock()
+
+	for _, found := range u.db.users {
+		if found.active() && found.email == req.Email {
+			return found.response(), nil
+		}
+	}
+	return nil, fmt.Errorf("user not found: %w", sql.ErrNoRows)
+}
+
+func (u *UserRepository) GetUserById(ctx context.Context, req *pb.UserId) (*pb.GetUserResponse, error) {
+	u.db.mu.RLock()
+	defer u.db.mu.RUnlock()
+
+	found, ok := u.db.activeUser(req.Id)
+	if !ok {
+		return nil, fmt.Errorf("user not found")
+	}
+	return found.response(), nil
+}
+
+// userFields copies each GetUserResponse field by its name in a field mask.
+var userFields = map[string]func(dst, src *pb.GetUserResponse){
+	"id":           func(dst, src *pb.GetUserResponse) { dst.Id = src.Id },
+	"name":         func(dst, src *pb.GetUserResponse) { dst.Name = src.Name },
+	"surname":      func(dst, src *pb.GetUserResponse) { dst.Surname = src.Surname },
+	"email":        func(dst, src *pb.GetUserResponse) { dst.Email = src.Email },
+	"birth_date":   func(dst, src *pb.GetUserResponse) { dst.BirthDate = src.BirthDate },
+	"gender":       func(dst, src *pb.GetUserResponse) { dst.Gender = src.Gender },
+	"phone_number": func(dst, src *pb.GetUserResponse) { dst.PhoneNumber = src.PhoneNumber },
+	"address":      func(dst, src *pb.GetUserResponse) { dst.Address = src.Address },
+	"photo":        func(dst, src *pb.GetUserResponse) { dst.Photo = src.Photo },
+	"role":         func(dst, src *pb.GetUserResponse) { dst.Role = src.Role },
+	"createdAt":    func(dst, src *pb.GetUserResponse) { dst.CreatedAt = src.CreatedAt },
+}
+
+func (u *UserRepository) GetUsersByIds(ctx context.Context, ids []string, fields []string) (map[string]*pb.GetUserResponse, map[string]*pb.PrivacySettings, error) {
+	for _, field := range fields {
+		if _, ok := userFields[field]; !ok {
+			return nil, nil, fmt.Errorf("unknown user field %q", field)
+		}
+	}
+
+	u.db.mu.RLock()
+	defer u.db.mu.RUnlock()
+
+	users := make(map[string]*pb.GetUserResponse, len(ids))
+	privacy := make(map[string]*pb.PrivacySettings, len(ids))
+	for _, id := range ids {
+		found, ok := u.db.activeUser(id)
+		if !ok {
+			continue
+		}
+		privacy[id] = &pb.PrivacySettings{UserId: id, PhoneNumber: found.phoneVisibility, Email: found.emailVisibility}
+		full := found.response()
+		if len(fields) == 0 {
+			users[id] = full
+			continue
+		}
+		user := &pb.GetUserResponse{}
+		for _, field := range fields {
+			userFields[field](user, full)
+		}
+		users[id] = user
+	}
+	return users, privacy, nil
+}
+
+func (u *UserRepository) GetPublicProfile(ctx context.Context, id string) (*pb.PublicProfile, *pb.PrivacySettings, error) {
+	u.db.mu.RLock()
+	defer u.db.mu.RUnlock()
+
+	found, ok := u.db.activeUser(id)
+	if !ok {
+		return nil, nil, fmt.Errorf("user not found: %w", sql.ErrNoRows)
+	}
+	// Listings belong to the car service, there are none in memory
+	profile := &pb.PublicProfile{
+		Id:          found.id,
+		Name:        found.name,
+		Surname:     found.surname,
+		Photo:       found.photo,
+		MemberSince: formatTime(found.createdAt),
+		Rating:      found.ratingAverage,
+		RatingCount: found.ratingCount,
+		PhoneNumber: found.phoneNumber,
+		Email:       found.email,
+	}
+	privacy := &pb.PrivacySettings{UserId: id, PhoneNumber: found.phoneVisibility, Email: found.emailVisibility}
+	return profile, privacy, nil
+}
+
+func (u *UserRepository) GetPrivacySettings(ctx context.Context, req *pb.UserId) (*pb.PrivacySettings, error) {
+	u.db.mu.RLock()
+	defer u.db.mu.RUnlock()
+
+	found, ok := u.db.activeUser(req.Id)
+	if !ok {
+		return nil, fmt.Errorf("user not found: %w", sql.ErrNoRows)
+	}
+	return &pb.PrivacySettings{UserId: req.Id, PhoneNumber: found.phoneVisibility, Email: found.emailVisibility}, nil
+}
+
+func (u *UserRepository) UpdatePrivacySettings(ctx context.Context, req *pb.PrivacySettings) error {
+	if !model.ValidVisibility(req.PhoneNumber) || !model.ValidVisibility(req.Email) {
+		return fmt.Errorf("failed to update privacy settings: invalid visibility")
+	}
+
+	u.db.mu.Lock()
+	defer u.db.mu.Unlock()
+
+	found, ok := u.db.activeUser(req.UserId)
+	if !ok {
+		return fmt.Errorf("user not found: %w", sql.ErrNoRows)
+	}
+	found.phoneVisibility = req.PhoneNumber
+	found.emailVisibility = req.Email
+	found.updatedAt = time.Now()
+	return nil
+}
+
+func (u *UserRepository) UpdatePassword(ctx context.Context, req *pb.UpdatePasswordReq) error {
+	hashedPassword, err := u.Hasher.Hash(req.Password)
+	if err != nil {
+		return err
+	}
+
+	u.db.mu.Lock()
+	defer u.db.mu.Unlock()
+
+	found, ok := u.db.activeUser(req.Id)
+	if !ok {
+		return fmt.Errorf("user not found")
+	}
+	found.setPassword(hashedPassword, false)
+	found.passwordResetRequired = false
+	return nil
+}
+
+func (u *UserRepository) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest) error {
+	var updates []func(*user)
+
+	if len(req.Name) > 0 {
+		updates = append(updates, func(found *user) { found.name = req.Name })
+	}
+	if len(req.Surname) > 0 {
+		updates = append(updates, func(found *user) { found.surname = req.Surname })
+	}
+	if len(req.BirthDate) > 0 {
+		birthDate, err := time.Parse("02-01-2006", req.BirthDate)
+		if err != nil {
+			return fmt.Errorf("invalid birth_date format: %w", err)
+		}
+		updates = append(updates, func(found *user) { found.birthDate = &birthDate })
+	}
+	if len(req.Gender) > 0 {
+		if !validGender(req.Gender) {
+			return fmt.Errorf("invalid gender: %s", req.Gender)
+		}
+		updates = append(updates, func(found *user) { found.gender = req.Gender })
+	}
+	if len(req.Address) > 0 {
+		updates = append(updates, func(found *user) { found.address = req.Address })
+	}
+	if len(req.PhoneNumber) > 0 {
+		updates = append(updates, func(found *user) { found.phoneNumber = req.PhoneNumber })
+	}
+	if len(req.Photo) > 0 {
+		updates = append(updates, func(found *user) { found.photo = req.Photo })
+	}
+
+	if len(updates) == 0 {
+		return fmt.Errorf("no fields to update")
+	}
+
+	u.db.mu.Lock()
+	defer u.db.mu.Unlock()
+
+	found, ok := u.db.activeUser(req.Id)
+	if !ok {
+		return fmt.Errorf("user not found or no changes made")
+	}
+	if len(req.PhoneNumber) > 0 {
+		if err := u.db.conflict(req.Id, "", req.PhoneNumber); err != nil {
+			return err
+		}
+	}
+	for _, update := range updates {
+		update(found)
+	}
+	found.updatedAt = time.Now()
+	return nil
+}
+
+func (u *UserRepository) DeleteUser(ctx context.Context, req *pb.UserId) error {
+	u.db.mu.Lock()
+	defer u.db.mu.Unlock()
+
+	found, ok := u.db.activeUser(req.Id)
+	if !ok {
+		return fmt.Errorf("user not found")
+	}
+	found.deletedAt = time.Now().Unix()
+	return nil
+}
+
+func (u *UserRepository) ResetPassword(ctx context.Context, req *pb.ResetPasswordReq) error {
+	u.db.mu.RLock()
+	found, ok := u.db.activeUser(req.Id)
+	var passwordHash string
+	if ok {
+		passwordHash = found.passwordHash
+	}
+	u.db.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("user not found")
+	}
+
+	ok, err := u.Hasher.Verify(passwordHash, req.Oldpassword)
+	if err != nil {
+		return fmt.Errorf("failed to verify password: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("password is incorrect")
+	}
+	hashedPassword, err := u.Hasher.Hash(req.Newpassword)
+	if err != nil {
+		return err
+	}
+
+	u.db.mu.Lock()
+	defer u.db.mu.Unlock()
+
+	found, ok = u.db.activeUser(req.Id)
+	if !ok {
+		return fmt.Errorf("user not found")
+	}
+	found.setPassword(hashedPassword, false)
+	found.passwordResetRequired = false
+	return nil
+}
+
+func (u *UserRepository) IsUserExist(ctx context.Context, req *pb.UserId) error {
+	u.db.mu.RLock()
+	defer u.db.mu.RUnlock()
+
+	if _, ok := u.db.activeUser(req.GetId()); !ok {
+		return fmt.Errorf("user with id %s does not exist", req.GetId())
+	}
+	return nil
+}
+
+func (u *UserRepository) DeleteMediaUser(ctx context.Context, req *pb.UserId) error {
+	u.db.mu.Lock()
+	defer u.db.mu.Unlock()
+
+	found, ok := u.db.activeUser(req.Id)
+	if !ok {
+		return fmt.Errorf("user not found or user is deleted")
+	}
+	found.photo = ""
+	return nil
+}
+
+func (u *UserRepository) ListUsers(ctx context.Context, req *pb.AdminListUsersReq) (*pb.AdminUserList, error) {
+	var conditions []func(*user) bool
+
+	if len(req.Email) > 0 {
+		email := strings.ToLower(req.Email)
+		conditions = append(conditions, func(found *user) bool {
+			return strings.Contains(strings.ToLower(found.email), email)
+		})
+	}
+	if len(req.PhoneNumber) > 0 {
+		conditions = append(conditions, func(found *user) bool {
+			return strings.Contains(found.phoneNumber, req.PhoneNumber)
+		})
+	}
+	if len(req.Name) > 0 {
+		name := strings.ToLower(req.Name)
+		conditions = append(conditions, func(found *user) bool {
+			return strings.Contains(strings.ToLower(found.name), name) ||
+				strings.Contains(strings.ToLower(found.surname), name)
+		})
+	}
+	if len(req.Role) > 0 {
+		conditions = append(conditions, func(found *user) bool { return found.role == req.Role })
+	}
+	if len(req.CreatedFrom) > 0 {
+		from, err := time.Parse("2006-01-02", req.CreatedFrom)
+		if err != nil {
+			return nil, fmt.Errorf("invalid created_from format: %w", err)
+		}
+		conditions = append(conditions, func(found *user) bool { return !found.createdAt.Before(from) })
+	}
+	if len(req.CreatedTo) > 0 {
+		to, err := time.Parse("2006-01-02", req.CreatedTo)
+		if err != nil {
+			return nil, fmt.Errorf("invalid created_to format: %w", err)
+		}
+		to = to.AddDate(0, 0, 1)
+		conditions = append(conditions, func(found *user) bool { return found.createdAt.Before(to) })
+	}
+	switch req.Deleted {
+	case "", "active":
+		conditions = append(conditions, (*user).active)
+	case "deleted":
+		conditions = append(conditions, func(found *user) bool { return !found.active() })
+	case "all":
+	default:
+		return nil, fmt.Errorf("invalid deleted filter: %s", req.Deleted)
+	}
+
+	page, limit := req.Page, req.Limit
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+
+	u.db.mu.RLock()
+	defer u.db.mu.RUnlock()
+
+	var matched []*user
+	for _, found := range u.db.users {
+		if matches(found, conditions) {
+			matched = append(matched, found)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if !matched[i].createdAt.Equal(matched[j].createdAt) {
+			return matched[i].createdAt.After(matched[j].createdAt)
+		}
+		return matched[i].id < matched[j].id
+	})
+
+	res := &pb.AdminUserList{Page: page, Limit: limit, Total: int32(len(matched))}
+	for _, found := range paginate(matched, page, limit) {
+		res.Users = append(res.Users, found.adminUser())
+	}
+	return res, nil
+}
+
+// matches reports whether row meets every condition.
+func matches[T any](row T, conditions []func(T) bool) bool {
+	for _, condition := range conditions {
+		if !condition(row) {
+			return false
+		}
+	}
+	return true
+}
+
+// paginate returns the rows of the 1-based page.
+func paginate[T any](rows []T, page, limit int32) []T {
+	offset := int(page-1) * int(limit)
+	if offset >= len(rows) {
+		return nil
+	}
+	return rows[offset:min(offset+int(limit), len(rows))]
+}
+
+func (u *UserRepository) GetUserByIdWithDeleted(ctx context.Context, req *pb.UserId) (*pb.AdminUser, error) {
+	u.db.mu.RLock()
+	defer u.db.mu.RUnlock()
+
+	found, ok := u.db.users[req.Id]
+	if !ok {
+		return nil, fmt.Errorf("user not found")
+	}
+	return found.adminUser(), nil
+}
+
+func (u *UserRepository) UpdateRole(ctx context.Context, req *pb.UpdateRoleReq) error {
+	if req.Role != "admin" && req.Role != "user" {
+		return fmt.Errorf("invalid role: %s", req.Role)
+	}
+
+	u.db.mu.Lock()
+	defer u.db.mu.Unlock()
+
+	found, ok := u.db.activeUser(req.Id)
+	if !ok {
+		return fmt.Errorf("user not found")
+	}
+	found.role = req.Role
+	found.updatedAt = time.Now()
+	return nil
+}
+
+func (u *UserRepository) RequirePasswordReset(ctx context.Context, req *pb.UserId) error {
+	u.db.mu.Lock()
+	defer u.db.mu.Unlock()
+
+	found, ok := u.db.activeUser(req.Id)
+	if !ok {
+		return fmt.Errorf("user not found")
+	}
+	found.passwordResetRequired = true
+	found.updatedAt = time.Now()
+	return nil
+}
+
+func (u *UserRepository) RestoreUser(ctx context.Context, req *pb.UserId) error {
+	u.db.mu.Lock()
+	defer u.db.mu.Unlock()
+
+	found, ok := u.db.users[req.Id]
+	if !ok || found.active() || found.purgedAt != nil {
+		return fmt.Errorf("deleted user not found")
+	}
+	if err := u.db.conflict(found.id, found.email, found.phoneNumber); err != nil {
+		return fmt.Errorf("failed to restore user: %w", err)
+	}
+	found.deletedAt = 0
+	found.updatedAt = time.Now()
+	return nil
+}
+
+func (u *UserRepository) HardDeleteUser(ctx context.Context, req *pb.UserId) error {
+	u.db.mu.Lock()
+	defer u.db.mu.Unlock()
+
+	if !u.db.userExists(req.Id) {
+		return fmt.Errorf("user not found")
+	}
+	delete(u.db.users, req.Id)
+	u.db.deleteUserRows(req.Id)
+	return nil
+}
+
+func (u *UserRepository) RestoreDeletedUser(ctx context.Context, id string, deletedAt int64) (string, error) {
+	if deletedAt <= u.restoreWindowStart() {
+		return "", fmt.Errorf("restore window has expired")
+	}
+
+	u.db.mu.Lock()
+	defer u.db.mu.Unlock()
+
+	found, ok := u.db.users[id]
+	if !ok || found.deletedAt != deletedAt || found.purgedAt != nil {
+		return "", fmt.Errorf("deleted user not found: %w", sql.ErrNoRows)
+	}
+	if err := u.db.conflict(found.id, found.email, found.phoneNumber); err != nil {
+		return "", fmt.Errorf("email or phone number is already used by another account")
+	}
+	found.deletedAt = 0
+	found.updatedAt = time.Now()
+	return found.role, nil
+}
+
+func (u *UserRepository) PurgeCandidates(ctx context.Context, deletedBefore int64) ([]*pb.AdminUser, error) {
+	u.db.mu.RLock()
+	defer u.db.mu.RUnlock()
+
+	var candidates []*user
+	for _, found := range u.db.users {
+		if !found.active() && found.deletedAt <= deletedBefore && found.purgedAt == nil {
+			candidates = append(candidates, found)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].deletedAt < candidates[j].deletedAt })
+
+	var users []*pb.AdminUser
+	for _, found := range paginate(candidates, 1, 100) {
+		users = append(users, found.adminUser())
+	}
+	return users, nil
+}
+
+func (u *UserRepository) PurgeUser(ctx context.Context, id string, anonymize bool) error {
+	u.db.mu.Lock()
+	defer u.db.mu.Unlock()
+
+	found, ok := u.db.users[id]
+	if !anonymize {
+		if ok && !found.active() {
+			delete(u.db.users, id)
+			u.db.deleteUserRows(id)
+		}
+		return nil
+	}
+
+	// Rows that only matter to the user go, the rest stays but no longer
+	// points to a person.
+	u.db.notifications = filter(u.db.notifications, func(n *notification) bool { return n.userId != id })
+	for searchId, search := range u.db.savedSearches {
+		if search.userId == id {
+			delete(u.db.savedSearches, searchId)
+		}
+	}
+	if ok && !found.active() {
+		now := time.Now()
+		*found = user{
+			id:              found.id,
+			role:            found.role,
+			passwordHistory: found.passwordHistory,
+			phoneVisibility: found.phoneVisibility,
+			emailVisibility: found.emailVisibility,
+			ratingAverage:   found.ratingAverage,
+			ratingCount:     found.ratingCount,
+			createdAt:       found.createdAt,
+			updatedAt:       found.updatedAt,
+			deletedAt:       found.deletedAt,
+			purgedAt:        &now,
+		}
+	}
+	if ok {
+		found.passwordHistory = nil
+	}
+	u.db.exports = filter(u.db.exports, func(e *export) bool { return e.userId != id })
+	u.db.sessions = filter(u.db.sessions, func(s *session) bool { return s.userId != id })
+	u.db.identities = filter(u.db.identities, func(i *identity) bool { return i.userId != id })
+	return nil
+}
+
+// PurgeFiles returns no car images, listings are not kept in memory.
+func (u *UserRepository) PurgeFiles(ctx context.Context, id string) ([]string, []string, error) {
+	u.db.mu.RLock()
+	defer u.db.mu.RUnlock()
+
+	var exports []string
+	for _, e := range u.db.exports {
+		if e.userId == id && e.objectName != "" && e.status != "expired" {
+			exports = append(exports, e.objectName)
+		}
+	}
+	return nil, exports, nil
+}
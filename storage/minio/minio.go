@@ -0,0 +1,157 @@
+package minio
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"time"
+	"wegugin/config"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// PhotosBucket holds the profile photos, its objects are public.
+const PhotosBucket = "photos"
+
+// Storage reaches the MinIO server of the config.
+type Storage struct {
+	conf config.MinioConfig
+}
+
+func New(conf config.MinioConfig) *Storage {
+	return &Storage{conf: conf}
+}
+
+func (s *Storage) ConnectDB() (*minio.Client, error) {
+	return minio.New(s.conf.MINIO_ENDPOINT, &minio.Options{
+		Creds:  credentials.NewStaticV4(s.conf.MINIO_ACCESS_KEY_ID, s.conf.MINIO_SECRET_ACCESS_KEY, ""),
+		Secure: false,
+	})
+}
+
+// Health checks MinIO is reachable and the photos bucket exists, uploads
+// of profile photos fail without it.
+func (s *Storage) Health(ctx context.Context) error {
+	minioClient, err := s.ConnectDB()
+	if err != nil {
+		return fmt.Errorf("error initializing MinIO client: %v", err)
+	}
+
+	exists, err := minioClient.BucketExists(ctx, PhotosBucket)
+	if err != nil {
+		return fmt.Errorf("error checking bucket %s: %v", PhotosBucket, err)
+	}
+	if !exists {
+		return fmt.Errorf("bucket %s does not exist", PhotosBucket)
+	}
+	return nil
+}
+
+// RemovePhoto deletes the object behind a profile photo url.
+func (s *Storage) RemovePhoto(ctx context.Context, photo string) error {
+	minioClient, err := s.ConnectDB()
+	if err != nil {
+		return fmt.Errorf("error initializing MinIO client: %v", err)
+	}
+
+	err = minioClient.RemoveObject(ctx, PhotosBucket, filepath.Base(photo), minio.RemoveObjectOptions{})
+	if err != nil {
+		return fmt.Errorf("error deleting photo from MinIO: %v", err)
+	}
+	return nil
+}
+
+// PutPrivateObject uploads an object into bucket, creating the bucket
+// without any public policy when it does not exist yet.
+func (s *Storage) PutPrivateObject(ctx context.Context, bucket, name string, reader io.Reader, size int64, contentType string) error {
+	minioClient, err := s.ConnectDB()
+	if err != nil {
+		return fmt.Errorf("error initializing MinIO client: %v", err)
+	}
+
+	exists, err := minioClient.BucketExists(ctx, bucket)
+	if err != nil {
+		return fmt.Errorf("error checking bucket %s: %v", bucket, err)
+	}
+	if !exists {
+		if err := minioClient.MakeBucket(ctx, bucket, minio.MakeBucketOptions{}); err != nil {
+			return fmt.Errorf("error creating bucket %s: %v", bucket, err)
+		}
+	}
+
+	_, err = minioClient.PutObject(ctx, bucket, name, reader, size, minio.PutObjectOptions{ContentType: contentType})
+	if err != nil {
+		return fmt.Errorf("error uploading %s to MinIO: %v", name, err)
+	}
+	return nil
+}
+
+// PresignedURL returns a download link for a private object valid for ttl.
+func (s *Storage) PresignedURL(ctx context.Context, bucket, name string, ttl time.Duration) (string, error) {
+	minioClient, err := s.ConnectDB()
+	if err != nil {
+		return "", fmt.Errorf("error initializing MinIO client: %v", err)
+	}
+
+	link, err := minioClient.PresignedGetObject(ctx, bucket, name, ttl, url.Values{})
+	if err != nil {
+		return "", fmt.Errorf("error presigning %s: %v", name, err)
+	}
+	return link.String(), nil
+}
+
+// GetObject opens an object for reading, the caller closes it.
+func (s *Storage) GetObject(ctx context.Context, bucket, name string) (*minio.Object, error) {
+	minioClient, err := s.ConnectDB()
+	if err != nil {
+		return nil, fmt.Errorf("error initializing MinIO client: %v", err)
+	}
+
+	return minioClient.GetObject(ctx, bucket, name, minio.GetObjectOptions{})
+}
+
+func (s *Storage) RemoveObject(ctx context.Context, bucket, name string) error {
+	minioClient, err := s.ConnectDB()
+	if err != nil {
+		return fmt.Errorf("error initializing MinIO client: %v", err)
+	}
+
+	err = minioClient.RemoveObject(ctx, bucket, name, minio.RemoveObjectOptions{})
+	if err != nil {
+		return fmt.Errorf("error deleting %s from MinIO: %v", name, err)
+	}
+	return nil
+}
+
+// RemoveUserFiles deletes the files of a user being purged: the profile
+// photo, the images of their cars from imagesBucket, which holds no files
+// of this service and is skipped when empty, and their export archives.
+func (s *Storage) RemoveUserFiles(ctx context.Context, photo string, images, exports []string, imagesBucket, exportBucket string) error {
+	minioClient, err := s.ConnectDB()
+	if err != nil {
+		return fmt.Errorf("error initializing MinIO client: %v", err)
+	}
+
+	var objects [][2]string
+	if photo != "" {
+		objects = append(objects, [2]string{PhotosBucket, filepath.Base(photo)})
+	}
+	if imagesBucket != "" {
+		for _, image := range images {
+			objects = append(objects, [2]string{imagesBucket, filepath.Base(image)})
+		}
+	}
+	for _, archive := range exports {
+		objects = append(objects, [2]string{exportBucket, archive})
+	}
+	for _, object := range objects {
+		err = minioClient.RemoveObject(ctx, object[0], object[1], minio.RemoveObjectOptions{})
+		if err != nil {
+			return fmt.Errorf("error deleting %s from MinIO: %v", object[1], err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	pb "wegugin/genproto/user"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+func scanAdminUser(row rowScanner, extra ...interface{}) (*pb.AdminUser, error) {
+	var (
+		user      pb.AdminUser
+		name      sql.NullString
+		surname   sql.NullString
+		birthDate sql.NullTime
+		gender    sql.NullString
+		address   sql.NullString
+		photo     sql.NullString
+	)
+
+	dest := []interface{}{
+		&user.Id, &name, &surname, &user.Email, &birthDate, &gender, &user.PhoneNumber,
+		&address, &photo, &user.Role, &user.CreatedAt, &user.UpdatedAt, &user.DeletedAt,
+		&user.PasswordResetRequired, &user.Rating, &user.RatingCount, &user.PhoneVisibility,
+		&user.EmailVisibility,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+
+	user.Name = name.String
+	user.Surname = surname.String
+	user.Gender = gender.String
+	user.Address = address.String
+	user.Photo = photo.String
+	if birthDate.Valid {
+		user.BirthDate = birthDate.Time.Format("2006-01-02")
+	}
+
+	return &user, nil
+}
+
+const adminUserColumns = `id, name, surname, email, birth_date, gender, phone_number, address, photo, role,
+	created_at, updated_at, deleted_at, password_reset_required, COALESCE(rating_average, 0), rating_count,
+	phone_visibility, email_visibility`
+
+func (u *UserRepository) ListUsers(ctx context.Context, req *pb.AdminListUsersReq) (*pb.AdminUserList, error) {
+	n := 1
+	var arr []interface{}
+	var conditions []string
+
+	if len(req.Email) > 0 {
+		conditions = append(conditions, fmt.Sprintf("email ILIKE '%%' || $%d || '%%'", n))
+		arr = append(arr, req.Email)
+		n++
+	}
+	if len(req.PhoneNumber) > 0 {
+		conditions = append(conditions, fmt.Sprintf("phone_number LIKE '%%' || $%d || '%%'", n))
+		arr = append(arr, req.PhoneNumber)
+		n++
+	}
+	if len(req.Name) > 0 {
+		conditions = append(conditions, fmt.Sprintf("(name ILIKE '%%' || $%d || '%%' OR surname ILIKE '%%' || $%d || '%%')", n, n))
+		arr = append(arr, req.Name)
+		n++
+	}
+	if len(req.Role) > 0 {
+		conditions = append(conditions, fmt.Sprintf("role = $%d", n))
+		arr = append(arr, req.Role)
+		n++
+	}
+	if len(req.CreatedFrom) > 0 {
+		from, err := time.Parse("2006-01-02", req.CreatedFrom)
+		if err != nil {
+			return nil, fmt.Errorf("invalid created_from format: %w", err)
+		}
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", n))
+		arr = append(arr, from)
+		n++
+	}
+	if len(req.CreatedTo) > 0 {
+		to, err := time.Parse("2006-01-02", req.CreatedTo)
+		if err != nil {
+			return nil, fmt.Errorf("invalid created_to format: %w", err)
+		}
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", n))
+		arr = append(arr, to.AddDate(0, 0, 1))
+		n++
+	}
+	switch req.Deleted {
+	case "", "active":
+		conditions = append(conditions, "deleted_at = 0")
+	case "deleted":
+		conditions = append(conditions, "deleted_at <> 0")
+	case "all":
+	default:
+		return nil, fmt.Errorf("invalid deleted filter: %s", req.Deleted)
+	}
+
+	page, limit := req.Page, req.Limit
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+
+	query := `SELECT ` + adminUserColumns + `, COUNT(*) OVER() FROM users`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id LIMIT $%d OFFSET $%d", n, n+1)
+	arr = append(arr, limit, (page-1)*limit)
+
+	rows, err := u.Db.QueryContext(ctx, query, arr...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	res := &pb.AdminUserList{Page: page, Limit: limit}
+	for rows.Next() {
+		user, err := scanAdminUser(rows, &res.Total)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		res.Users = append(res.Users, user)
+	}
+
+	return res, rows.Err()
+}
+
+func (u *UserRepository) GetUserByIdWithDeleted(ctx context.Context, req *pb.UserId) (*pb.AdminUser, error) {
+	query := `SELECT ` + adminUserColumns + ` FROM users WHERE id = $1`
+
+	user, err := scanAdminUser(u.Db.QueryRowContext(ctx, query, req.Id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (u *UserRepository) UpdateRole(ctx context.Context, req *pb.UpdateRoleReq) error {
+	if req.Role != "admin" && req.Role != "user" {
+		return fmt.Errorf("invalid role: %s", req.Role)
+	}
+
+	query := `UPDATE users SET role = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 AND deleted_at = 0`
+	result, err := u.Db.ExecContext(ctx, query, req.Role, req.Id)
+	if err != nil {
+		return fmt.Errorf("failed to update role: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
+
+func (u *UserRepository) RequirePasswordReset(ctx context.Context, req *pb.UserId) error {
+	query := `UPDATE users SET password_reset_required = true, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $1 AND deleted_at = 0`
+	result, err := u.Db.ExecContext(ctx, query, req.Id)
+	if err != nil {
+		return fmt.Errorf("failed to update password_reset_required: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
+
+func (u *UserRepository) RestoreUser(ctx context.Context, req *pb.UserId) error {
+	query := `UPDATE users SET deleted_at = 0, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $1 AND deleted_at <> 0 AND purged_at IS NULL`
+	result, err := u.Db.ExecContext(ctx, query, req.Id)
+	if err != nil {
+		return fmt.Errorf("failed to restore user: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("deleted user not found")
+	}
+
+	return nil
+}
+
+func (u *UserRepository) HardDeleteUser(ctx context.Context, req *pb.UserId) error {
+	result, err := u.Db.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, req.Id)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
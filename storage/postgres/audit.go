@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	pb "wegugin/genproto/user"
+	"wegugin/model"
+	"wegugin/storage"
+)
+
+type AuditRepository struct {
+	Db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) storage.IAuditStorage {
+	return &AuditRepository{Db: db}
+}
+
+func (a *AuditRepository) RecordEvent(ctx context.Context, req *model.AuditEvent) error {
+	var changes []byte
+	if len(req.Changes) > 0 {
+		var err error
+		if changes, err = json.Marshal(req.Changes); err != nil {
+			return fmt.Errorf("failed to encode changes: %w", err)
+		}
+	}
+
+	query := `INSERT INTO audit_events (actor_id, target_id, action, changes, reason, ip, user_agent, request_id)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err := a.Db.ExecContext(ctx, query, nullString(req.ActorId), nullString(req.TargetId), req.Action,
+		nullString(string(changes)), nullString(req.Reason), nullString(req.IP), nullString(req.UserAgent), nullString(req.RequestId))
+	if err != nil {
+		return fmt.Errorf("failed to insert audit event: %w", err)
+	}
+	return nil
+}
+
+func (a *AuditRepository) ListAuditEvents(ctx context.Context, req *pb.AuditEventFilter) (*pb.AuditEventList, error) {
+	n := 1
+	var arr []interface{}
+	var conditions []string
+
+	if len(req.ActorId) > 0 {
+		conditions = append(conditions, fmt.Sprintf("actor_id = $%d", n))
+		arr = append(arr, req.ActorId)
+		n++
+	}
+	if len(req.TargetId) > 0 {
+		conditions = append(conditions, fmt.Sprintf("target_id = $%d", n))
+		arr = append(arr, req.TargetId)
+		n++
+	}
+	if len(req.Action) > 0 {
+		conditions = append(conditions, fmt.Sprintf("action = $%d", n))
+		arr = append(arr, req.Action)
+		n++
+	}
+	if len(req.From) > 0 {
+		from, err := time.Parse("2006-01-02", req.From)
+		if err != nil {
+			return nil, fmt.Errorf("invalid from format: %w", err)
+		}
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", n))
+		arr = append(arr, from)
+		n++
+	}
+	if len(req.To) > 0 {
+		to, err := time.Parse("2006-01-02", req.To)
+		if err != nil {
+			return nil, fmt.Errorf("invalid to format: %w", err)
+		}
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", n))
+		arr = append(arr, to.AddDate(0, 0, 1))
+		n++
+	}
+
+	page, limit := req.Page, req.Limit
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+
+	query := `SELECT id, actor_id, target_id, action, changes, reason, ip, user_agent, request_id, created_at,
+	          COUNT(*) OVER() FROM audit_events`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id LIMIT $%d OFFSET $%d", n, n+1)
+	arr = append(arr, limit, (page-1)*limit)
+
+	rows, err := a.Db.QueryContext(ctx, query, arr...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+	defer rows.Close()
+
+	res := &pb.AuditEventList{Page: page, Limit: limit}
+	for rows.Next() {
+		var (
+			event                                         pb.AuditEvent
+			actorId, targetId, changes, reason, ip, agent sql.NullString
+			requestId                                     sql.NullString
+		)
+		err := rows.Scan(&event.Id, &actorId, &targetId, &event.Action, &changes, &reason, &ip, &agent,
+			&requestId, &event.CreatedAt, &res.Total)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit event: %w", err)
+		}
+		event.ActorId = actorId.String
+		event.TargetId = targetId.String
+		event.Changes = changes.String
+		event.Reason = reason.String
+		event.Ip = ip.String
+		event.UserAgent = agent.String
+		event.RequestId = requestId.String
+		res.Events = append(res.Events, &event)
+	}
+
+	return res, rows.Err()
+}
@@ -0,0 +1,139 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	pb "wegugin/genproto/user"
+	"wegugin/model"
+	"wegugin/storage"
+
+	"github.com/lib/pq"
+)
+
+type CarRepository struct {
+	Db *sql.DB
+}
+
+func NewCarRepository(db *sql.DB) storage.ICarStorage {
+	return &CarRepository{Db: db}
+}
+
+func (c *CarRepository) GetPriceHistory(ctx context.Context, req *pb.CarId) (*pb.PriceHistory, error) {
+	var exists bool
+	err := c.Db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM cars WHERE id = $1 AND deleted_at = 0 AND NOT hidden)`,
+		req.Id).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if car exists: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("car not found")
+	}
+
+	query := `SELECT price, old_price, changed_at FROM car_price_history
+	          WHERE car_id = $1 ORDER BY changed_at, id`
+
+	rows, err := c.Db.QueryContext(ctx, query, req.Id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select price history: %w", err)
+	}
+	defer rows.Close()
+
+	res := &pb.PriceHistory{CarId: req.Id}
+	for rows.Next() {
+		var (
+			point    pb.PricePoint
+			oldPrice sql.NullFloat64
+		)
+		if err := rows.Scan(&point.Price, &oldPrice, &point.ChangedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan price history: %w", err)
+		}
+		point.OldPrice = oldPrice.Float64
+		res.Prices = append(res.Prices, &point)
+	}
+
+	return res, rows.Err()
+}
+
+func (c *CarRepository) PendingPriceChanges(ctx context.Context) ([]*model.PriceChange, error) {
+	query := `SELECT h.id, h.car_id, c.make, c.model, c.year, COALESCE(h.old_price, 0), h.price
+	          FROM car_price_history h
+	          JOIN cars c ON c.id = h.car_id
+	          WHERE h.alerts_sent = false
+	          ORDER BY h.id`
+
+	rows, err := c.Db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select pending price changes: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []*model.PriceChange
+	for rows.Next() {
+		var change model.PriceChange
+		err := rows.Scan(&change.Id, &change.CarId, &change.Make, &change.Model, &change.Year,
+			&change.OldPrice, &change.Price)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan price change: %w", err)
+		}
+		changes = append(changes, &change)
+	}
+
+	return changes, rows.Err()
+}
+
+func (c *CarRepository) PriceWatchers(ctx context.Context, carId string) ([]string, error) {
+	query := `SELECT DISTINCT s.user_id
+	          FROM saved_cars s
+	          JOIN cars c ON c.id = s.car_id
+	          JOIN users u ON u.id = s.user_id AND u.deleted_at = 0
+	          WHERE s.car_id = $1 AND s.deleted_at = 0 AND c.deleted_at = 0 AND NOT c.hidden
+	            AND s.user_id <> c.owner_id`
+
+	rows, err := c.Db.QueryContext(ctx, query, carId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select price watchers: %w", err)
+	}
+	defer rows.Close()
+
+	var users []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan price watcher: %w", err)
+		}
+		users = append(users, id)
+	}
+
+	return users, rows.Err()
+}
+
+func (c *CarRepository) NotifyPriceDrop(ctx context.Context, changeId int64, notification *model.Notification) error {
+	// One statement, so the delivery is recorded exactly when the
+	// notification is created
+	query := `WITH delivery AS (
+	              INSERT INTO price_drop_deliveries (change_id, user_id) VALUES ($1, $2)
+	              ON CONFLICT DO NOTHING
+	              RETURNING user_id
+	          )
+	          INSERT INTO notifications (user_id, type, message)
+	          SELECT user_id, $3, $4 FROM delivery`
+
+	_, err := c.Db.ExecContext(ctx, query, changeId, notification.UserId, notification.Type, notification.Message)
+	if err != nil {
+		return fmt.Errorf("failed to insert price drop notification: %w", err)
+	}
+	return nil
+}
+
+func (c *CarRepository) MarkPriceChangesAlerted(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := c.Db.ExecContext(ctx, `UPDATE car_price_history SET alerts_sent = true WHERE id = ANY($1)`,
+		pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to mark price changes alerted: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+	pb "wegugin/genproto/user"
+	"wegugin/storage"
+)
+
+const exportColumns = `id, user_id, status, requested_at, completed_at, expires_at, error`
+
+type ExportRepository struct {
+	Db *sql.DB
+}
+
+func NewExportRepository(db *sql.DB) storage.IExportStorage {
+	return &ExportRepository{Db: db}
+}
+
+// scanExport scans the exportColumns, followed by the columns of extra.
+func scanExport(row rowScanner, extra ...interface{}) (*pb.DataExport, error) {
+	var (
+		export                      pb.DataExport
+		completedAt, expiresAt, msg sql.NullString
+	)
+
+	dest := []interface{}{&export.Id, &export.UserId, &export.Status, &export.RequestedAt, &completedAt, &expiresAt, &msg}
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	export.CompletedAt = completedAt.String
+	export.ExpiresAt = expiresAt.String
+	export.Error = msg.String
+
+	return &export, nil
+}
+
+func (e *ExportRepository) CreateExport(ctx context.Context, userId string, cooldown time.Duration) (*pb.DataExport, error) {
+	tx, err := e.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	// Serializes concurrent requests of the same user
+	if _, err = tx.ExecContext(ctx, `SELECT 1 FROM users WHERE id = $1 FOR UPDATE`, userId); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to lock user: %w", err)
+	}
+
+	var busy, recent bool
+	query := `SELECT EXISTS (SELECT 1 FROM data_exports WHERE user_id = $1 AND status IN ('pending', 'running')),
+	          EXISTS (SELECT 1 FROM data_exports WHERE user_id = $1 AND status <> 'failed' AND requested_at > $2)`
+	if err = tx.QueryRowContext(ctx, query, userId, time.Now().Add(-cooldown)).Scan(&busy, &recent); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to check previous exports: %w", err)
+	}
+	if busy {
+		tx.Rollback()
+		return nil, fmt.Errorf("%w: an export is already in progress", storage.ErrExportLimited)
+	}
+	if recent {
+		tx.Rollback()
+		return nil, fmt.Errorf("%w: an export can be requested once every %s", storage.ErrExportLimited, cooldown)
+	}
+
+	query = `INSERT INTO data_exports (user_id) VALUES ($1) RETURNING ` + exportColumns
+	export, err := scanExport(tx.QueryRowContext(ctx, query, userId))
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to insert export: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return export, nil
+}
+
+func (e *ExportRepository) LatestExport(ctx context.Context, req *pb.UserId) (*pb.DataExport, error) {
+	query := `SELECT ` + exportColumns + ` FROM data_exports WHERE user_id = $1 ORDER BY requested_at DESC LIMIT 1`
+
+	export, err := scanExport(e.Db.QueryRowContext(ctx, query, req.Id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("no export requested: %w", err)
+		}
+		return nil, err
+	}
+
+	return export, nil
+}
+
+func (e *ExportRepository) ClaimPendingExport(ctx context.Context, lease time.Duration) (*pb.DataExport, time.Time, error) {
+	query := `UPDATE data_exports SET status = 'running', claimed_at = CURRENT_TIMESTAMP
+	          WHERE id = (
+	              SELECT id FROM data_exports
+	              WHERE status = 'pending'
+	                 OR (status = 'running' AND claimed_at < CURRENT_TIMESTAMP - make_interval(secs => $1))
+	              ORDER BY requested_at LIMIT 1 FOR UPDATE SKIP LOCKED
+	          )
+	          RETURNING ` + exportColumns + `, claimed_at`
+
+	var claimedAt time.Time
+	export, err := scanExport(e.Db.QueryRowContext(ctx, query, lease.Seconds()), &claimedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, time.Time{}, nil
+		}
+		return nil, time.Time{}, fmt.Errorf("failed to claim export: %w", err)
+	}
+
+	return export, claimedAt, nil
+}
+
+// finishExport runs query, fenced on the claim in $2, and reports a lost
+// lease when the export is no longer running under that claim.
+func (e *ExportRepository) finishExport(ctx context.Context, query string, args ...interface{}) error {
+	res, err := e.Db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrExportLeaseLost
+	}
+	return nil
+}
+
+func (e *ExportRepository) CompleteExport(ctx context.Context, id string, claimedAt time.Time, objectName string, expiresAt time.Time) error {
+	query := `UPDATE data_exports SET status = 'ready', object_name = $3, completed_at = CURRENT_TIMESTAMP, expires_at = $4
+	          WHERE id = $1 AND status = 'running' AND claimed_at = $2`
+	if err := e.finishExport(ctx, query, id, claimedAt, objectName, expiresAt); err != nil {
+		return fmt.Errorf("failed to complete export: %w", err)
+	}
+	return nil
+}
+
+func (e *ExportRepository) FailExport(ctx context.Context, id string, claimedAt time.Time, reason string) error {
+	query := `UPDATE data_exports SET status = 'failed', error = $3, completed_at = CURRENT_TIMESTAMP
+	          WHERE id = $1 AND status = 'running' AND claimed_at = $2`
+	if err := e.finishExport(ctx, query, id, claimedAt, reason); err != nil {
+		return fmt.Errorf("failed to mark export failed: %w", err)
+	}
+	return nil
+}
+
+func (e *ExportRepository) ExpireExports(ctx context.Context) ([]string, error) {
+	query := `UPDATE data_exports SET status = 'expired'
+	          WHERE status = 'ready' AND expires_at <= CURRENT_TIMESTAMP
+	          RETURNING object_name`
+
+	rows, err := e.Db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire exports: %w", err)
+	}
+	defer rows.Close()
+
+	var objects []string
+	for rows.Next() {
+		var object string
+		if err := rows.Scan(&object); err != nil {
+			return nil, fmt.Errorf("failed to scan export: %w", err)
+		}
+		objects = append(objects, object)
+	}
+
+	return objects, rows.Err()
+}
+
+// userDataQueries select the rows of every table holding personal data of
+// the user given as $1, aggregated into one JSON array per table.
+var userDataQueries = map[string]string{
+	"profile": `SELECT to_jsonb(u) - 'password_hash' FROM users u WHERE id = $1`,
+	"cars":    `SELECT COALESCE(jsonb_agg(c ORDER BY c.created_at), '[]') FROM cars c WHERE owner_id = $1`,
+	"images": `SELECT COALESCE(jsonb_agg(i ORDER BY i.uploaded_at), '[]') FROM images i
+	           JOIN cars c ON c.id = i.car_id WHERE c.owner_id = $1`,
+	"comments": `SELECT COALESCE(jsonb_agg(c ORDER BY c.created_at), '[]') FROM comments c WHERE user_id = $1`,
+	"messages": `SELECT COALESCE(jsonb_agg(m ORDER BY m.created_at), '[]') FROM messages m
+	             WHERE sender_id = $1 OR recipient_id = $1`,
+	"saved_cars":     `SELECT COALESCE(jsonb_agg(s ORDER BY s.created_at), '[]') FROM saved_cars s WHERE user_id = $1`,
+	"saved_searches": `SELECT COALESCE(jsonb_agg(s ORDER BY s.created_at), '[]') FROM saved_searches s WHERE user_id = $1`,
+	"notifications":  `SELECT COALESCE(jsonb_agg(n ORDER BY n.created_at), '[]') FROM notifications n WHERE user_id = $1`,
+	"device_tokens": `SELECT COALESCE(jsonb_agg(t ORDER BY t.created_at), '[]') FROM notifications_tokens t
+	                  WHERE user_id = $1`,
+	"linked_accounts": `SELECT COALESCE(jsonb_agg(to_jsonb(i) - 'id' ORDER BY i.created_at), '[]') FROM user_identities i
+	                    WHERE user_id = $1`,
+}
+
+func (e *ExportRepository) CollectUserData(ctx context.Context, userId string) (map[string]json.RawMessage, error) {
+	data := make(map[string]json.RawMessage, len(userDataQueries))
+	for name, query := range userDataQueries {
+		var raw []byte
+		if err := e.Db.QueryRowContext(ctx, query, userId).Scan(&raw); err != nil {
+			return nil, fmt.Errorf("failed to collect %s: %w", name, err)
+		}
+		data[name] = raw
+	}
+	return data, nil
+}
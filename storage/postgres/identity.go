@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	pb "wegugin/genproto/user"
+	"wegugin/model"
+	"wegugin/storage"
+
+	"github.com/lib/pq"
+)
+
+type IdentityRepository struct {
+	Db *sql.DB
+}
+
+func NewIdentityRepository(db *sql.DB) storage.IIdentityStorage {
+	return &IdentityRepository{Db: db}
+}
+
+func (i *IdentityRepository) LinkIdentity(ctx context.Context, req *model.Identity) error {
+	query := `INSERT INTO user_identities (user_id, provider, subject, email) VALUES ($1, $2, $3, $4)`
+	_, err := i.Db.ExecContext(ctx, query, req.UserId, req.Provider, req.Subject, nullString(req.Email))
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return storage.ErrIdentityTaken
+		}
+		return fmt.Errorf("failed to link identity: %w", err)
+	}
+	return nil
+}
+
+func (i *IdentityRepository) IdentityUser(ctx context.Context, provider, subject string) (string, error) {
+	query := `SELECT user_id FROM user_identities WHERE provider = $1 AND subject = $2`
+
+	var userId string
+	err := i.Db.QueryRowContext(ctx, query, provider, subject).Scan(&userId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", fmt.Errorf("identity not linked: %w", err)
+		}
+		return "", fmt.Errorf("failed to get identity: %w", err)
+	}
+	return userId, nil
+}
+
+func (i *IdentityRepository) ListIdentities(ctx context.Context, req *pb.UserId) (*pb.IdentityList, error) {
+	query := `SELECT provider, email, created_at FROM user_identities WHERE user_id = $1 ORDER BY created_at`
+
+	rows, err := i.Db.QueryContext(ctx, query, req.Id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list identities: %w", err)
+	}
+	defer rows.Close()
+
+	list := &pb.IdentityList{}
+	for rows.Next() {
+		var identity pb.Identity
+		var email sql.NullString
+		if err := rows.Scan(&identity.Provider, &email, &identity.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan identity: %w", err)
+		}
+		identity.Email = email.String
+		list.Identities = append(list.Identities, &identity)
+	}
+
+	return list, rows.Err()
+}
+
+func (i *IdentityRepository) UnlinkIdentity(ctx context.Context, req *pb.IdentityReq) error {
+	query := `DELETE FROM user_identities WHERE user_id = $1 AND provider = $2`
+	result, err := i.Db.ExecContext(ctx, query, req.UserId, req.Provider)
+	if err != nil {
+		return fmt.Errorf("failed to unlink identity: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("identity not linked: %w", sql.ErrNoRows)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"wegugin/migrations"
+
+	"github.com/golang-migrate/migrate/v4"
+	pgmigrate "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// ErrSchemaBehind is returned by CheckSchema when migrations are pending.
+var ErrSchemaBehind = errors.New("database schema is behind")
+
+// NewMigrator returns a migrator applying the embedded migrations to db. It
+// keeps its state in the schema_migrations table of the migrate CLI, so a
+// database migrated with either one is up to date for the other. Runs hold
+// a Postgres advisory lock for up to lockTimeout, replicas starting at the
+// same time wait for each other. Closing the migrator closes db.
+func NewMigrator(db *sql.DB, lockTimeout time.Duration) (*migrate.Migrate, error) {
+	source, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return nil, err
+	}
+	driver, err := pgmigrate.WithInstance(db, &pgmigrate.Config{})
+	if err != nil {
+		return nil, err
+	}
+	m, err := migrate.NewWithInstance("iofs", source, "postgres", driver)
+	if err != nil {
+		return nil, err
+	}
+	m.LockTimeout = lockTimeout
+	return m, nil
+}
+
+// SchemaVersion returns the version of the schema m migrates, zero before
+// the first migration.
+func SchemaVersion(m *migrate.Migrate) (version uint, dirty bool, err error) {
+	version, dirty, err = m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+// CheckSchema returns an error when the schema is dirty or older than the
+// embedded migrations. A newer schema is fine: replicas of the previous
+// release keep running while a deploy rolls out.
+func CheckSchema(m *migrate.Migrate) error {
+	version, dirty, err := SchemaVersion(m)
+	if err != nil {
+		return err
+	}
+	return checkVersion(version, dirty)
+}
+
+// CheckSchemaContext is CheckSchema for the readiness probe, it reads the
+// version straight from schema_migrations instead of taking the migrator's
+// lock and connection.
+func CheckSchemaContext(ctx context.Context, db *sql.DB) error {
+	var version uint
+	var dirty bool
+	err := db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	return checkVersion(version, dirty)
+}
+
+func checkVersion(version uint, dirty bool) error {
+	latest, err := migrations.Latest()
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("database schema is dirty at version %d: fix it by hand, then run migrate force", version)
+	}
+	if version < latest {
+		return fmt.Errorf("%w: version %d, this build needs %d", ErrSchemaBehind, version, latest)
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"wegugin/model"
+	"wegugin/storage"
+)
+
+type NotificationRepository struct {
+	Db *sql.DB
+}
+
+func NewNotificationRepository(db *sql.DB) storage.INotificationStorage {
+	return &NotificationRepository{Db: db}
+}
+
+func (n *NotificationRepository) CreateNotification(ctx context.Context, req *model.Notification) error {
+	query := `INSERT INTO notifications (user_id, type, message) VALUES ($1, $2, $3)`
+	_, err := n.Db.ExecContext(ctx, query, req.UserId, req.Type, req.Message)
+	if err != nil {
+		return fmt.Errorf("failed to insert notification: %w", err)
+	}
+	return nil
+}
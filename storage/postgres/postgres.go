@@ -3,7 +3,7 @@ package postgres
 import (
 	"database/sql"
 	"fmt"
-	"os"
+	"log/slog"
 	"wegugin/config"
 	"wegugin/storage"
 
@@ -11,30 +11,27 @@ import (
 )
 
 type postgresStorage struct {
-	db *sql.DB
+	db     *sql.DB
+	conf   *config.Config
+	logger *slog.Logger
 }
 
-func NewPostgresStorage(db *sql.DB) storage.IStorage {
+func NewPostgresStorage(db *sql.DB, conf *config.Config, logger *slog.Logger) storage.IStorage {
 	return &postgresStorage{
-		db: db,
+		db:     db,
+		conf:   conf,
+		logger: logger,
 	}
 }
 
-func ConnectionDb() (*sql.DB, error) {
-	conf := config.Load()
-	
-	// Check if DATABASE_URL is provided (Railway format)
-	// If not, construct connection string from individual variables
-	var conDb string
-	if databaseURL := os.Getenv("DATABASE_URL"); databaseURL != "" {
-		// Use DATABASE_URL directly (Railway provides this)
-		conDb = databaseURL
-	} else {
-		// Construct from individual variables
+func ConnectionDb(conf config.PostgresConfig) (*sql.DB, error) {
+	// DATABASE_URL, as Railway provides it, wins over the single settings
+	conDb := conf.DATABASE_URL
+	if conDb == "" {
 		conDb = fmt.Sprintf("host=%s port=%s user=%s dbname=%s password=%s sslmode=disable",
-			conf.Postgres.PDB_HOST, conf.Postgres.PDB_PORT, conf.Postgres.PDB_USER, conf.Postgres.PDB_NAME, conf.Postgres.PDB_PASSWORD)
+			conf.PDB_HOST, conf.PDB_PORT, conf.PDB_USER, conf.PDB_NAME, conf.PDB_PASSWORD)
 	}
-	
+
 	db, err := sql.Open("postgres", conDb)
 	if err != nil {
 		return nil, err
@@ -52,5 +49,37 @@ func (p *postgresStorage) Close() {
 }
 
 func (p *postgresStorage) User() storage.IUserStorage {
-	return NewUserRepository(p.db)
+	return NewUserRepository(p.db, p.conf, p.logger)
+}
+
+func (p *postgresStorage) SavedSearch() storage.ISavedSearchStorage {
+	return NewSavedSearchRepository(p.db)
+}
+
+func (p *postgresStorage) Car() storage.ICarStorage {
+	return NewCarRepository(p.db)
+}
+
+func (p *postgresStorage) Suspension() storage.ISuspensionStorage {
+	return NewSuspensionRepository(p.db)
+}
+
+func (p *postgresStorage) Export() storage.IExportStorage {
+	return NewExportRepository(p.db)
+}
+
+func (p *postgresStorage) Audit() storage.IAuditStorage {
+	return NewAuditRepository(p.db)
+}
+
+func (p *postgresStorage) Session() storage.ISessionStorage {
+	return NewSessionRepository(p.db)
+}
+
+func (p *postgresStorage) Notification() storage.INotificationStorage {
+	return NewNotificationRepository(p.db)
+}
+
+func (p *postgresStorage) Identity() storage.IIdentityStorage {
+	return NewIdentityRepository(p.db)
 }
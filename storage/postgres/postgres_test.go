@@ -0,0 +1,238 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+	"wegugin/config"
+	pb "wegugin/genproto/user"
+	"wegugin/model"
+	"wegugin/storage"
+	"wegugin/storage/storagetest"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/google/uuid"
+)
+
+// The tests here run against the database at TEST_DATABASE_URL, migrated to
+// the latest schema first, and are skipped without it. They leave their
+// rows behind: use a development database, never production.
+
+var ctx = context.Background()
+
+var migrateOnce sync.Once
+
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	conf := config.PostgresConfig{DATABASE_URL: dsn}
+
+	var migrateErr error
+	migrateOnce.Do(func() {
+		// The migrator closes its connection
+		db, err := ConnectionDb(conf)
+		if err != nil {
+			migrateErr = err
+			return
+		}
+		m, err := NewMigrator(db, time.Minute)
+		if err != nil {
+			db.Close()
+			migrateErr = err
+			return
+		}
+		defer m.Close()
+		if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+			migrateErr = err
+		}
+	})
+	if migrateErr != nil {
+		t.Fatalf("migrating: %v", migrateErr)
+	}
+
+	db, err := ConnectionDb(conf)
+	if err != nil {
+		t.Fatalf("connecting: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// TestStorage runs the conformance suite the memory backend passes too.
+func TestStorage(t *testing.T) {
+	db := testDB(t)
+	storagetest.Run(t, func(t *testing.T, conf *config.Config) storage.IStorage {
+		return NewPostgresStorage(db, conf, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	})
+}
+
+// insertUser adds a bare user and returns the id.
+func insertUser(t *testing.T, db *sql.DB) string {
+	t.Helper()
+	mark := uuid.NewString()
+	var id string
+	err := db.QueryRowContext(ctx, `INSERT INTO users (email, password_hash, phone_number)
+	                                VALUES ($1, 'x', $2) RETURNING id`,
+		"pg-"+mark+"@example.com", mark[:20]).Scan(&id)
+	if err != nil {
+		t.Fatalf("inserting user: %v", err)
+	}
+	return id
+}
+
+// insertCar adds a listing the way the car service does, leaving updated_at
+// a day behind, and returns the id.
+func insertCar(t *testing.T, db *sql.DB, ownerId, carMake string) string {
+	t.Helper()
+	var id string
+	err := db.QueryRowContext(ctx, `INSERT INTO cars (type, make, model, year, color, mileage, price, owner_id, location, updated_at)
+	                                VALUES ('sedan', $1, 'Test', 2020, 'white', 1000, 10000, $2, 'Seoul', CURRENT_TIMESTAMP - INTERVAL '1 day')
+	                                RETURNING id`, carMake, ownerId).Scan(&id)
+	if err != nil {
+		t.Fatalf("inserting car: %v", err)
+	}
+	return id
+}
+
+// matchedIds runs the matcher's query for search the way its next run would.
+func matchedIds(t *testing.T, db *sql.DB, search *pb.SavedSearch) []string {
+	t.Helper()
+	until, err := NewSavedSearchRepository(db).CheckCutoff(ctx, 0)
+	if err != nil {
+		t.Fatalf("CheckCutoff: %v", err)
+	}
+	cars, err := NewSavedSearchRepository(db).MatchCars(ctx, search, until, time.Hour)
+	if err != nil {
+		t.Fatalf("MatchCars: %v", err)
+	}
+	var ids []string
+	for _, car := range cars {
+		ids = append(ids, car.Id)
+	}
+	return ids
+}
+
+func newSearch(t *testing.T, db *sql.DB, userId string) (*pb.SavedSearch, string) {
+	t.Helper()
+	carMake := fmt.Sprintf("Make-%s", uuid.NewString()[:8])
+	search, err := NewSavedSearchRepository(db).CreateSavedSearch(ctx, &pb.CreateSavedSearchReq{
+		UserId: userId, Name: "trigger", Filter: &pb.CarFilter{Make: carMake},
+	})
+	if err != nil {
+		t.Fatalf("CreateSavedSearch: %v", err)
+	}
+	return search, carMake
+}
+
+// TestInsertedCarMatches checks the trigger stamps updated_at on insert, so
+// a new listing is matched on the next run whatever the car service wrote.
+func TestInsertedCarMatches(t *testing.T) {
+	db := testDB(t)
+	search, carMake := newSearch(t, db, insertUser(t, db))
+	carId := insertCar(t, db, insertUser(t, db), carMake)
+
+	ids := matchedIds(t, db, search)
+	if len(ids) != 1 || ids[0] != carId {
+		t.Fatalf("MatchCars = %v, want the inserted car %s", ids, carId)
+	}
+}
+
+// TestHiddenCarsDoNotAlert hides a listing and shows it again, as
+// SyncHiddenCars does around a suspension: neither announces it to saved
+// searches, and its savers get no price alerts while it is hidden.
+func TestHiddenCarsDoNotAlert(t *testing.T) {
+	db := testDB(t)
+	searcher, seller := insertUser(t, db), insertUser(t, db)
+	search, carMake := newSearch(t, db, searcher)
+	carId := insertCar(t, db, seller, carMake)
+
+	// The listing was announced, the search is up to date. The database's
+	// clock is the one updated_at goes by
+	var checkedAt time.Time
+	if err := db.QueryRowContext(ctx, `SELECT clock_timestamp()`).Scan(&checkedAt); err != nil {
+		t.Fatalf("reading the clock: %v", err)
+	}
+	if err := NewSavedSearchRepository(db).MarkChecked(ctx, search.Id, nil, checkedAt); err != nil {
+		t.Fatalf("MarkChecked: %v", err)
+	}
+
+	setHidden := func(hidden bool) {
+		t.Helper()
+		if _, err := db.ExecContext(ctx, `UPDATE cars SET hidden = $2 WHERE id = $1`, carId, hidden); err != nil {
+			t.Fatalf("hiding car: %v", err)
+		}
+	}
+	setHidden(true)
+	if ids := matchedIds(t, db, search); len(ids) != 0 {
+		t.Errorf("MatchCars after hiding = %v, want nothing", ids)
+	}
+
+	if _, err := db.ExecContext(ctx, `INSERT INTO saved_cars (user_id, car_id) VALUES ($1, $2)`, searcher, carId); err != nil {
+		t.Fatalf("saving car: %v", err)
+	}
+	watchers, err := NewCarRepository(db).PriceWatchers(ctx, carId)
+	if err != nil || len(watchers) != 0 {
+		t.Errorf("PriceWatchers of a hidden car = %v, %v, want nobody", watchers, err)
+	}
+	if _, err := NewCarRepository(db).GetPriceHistory(ctx, &pb.CarId{Id: carId}); err == nil {
+		t.Error("GetPriceHistory of a hidden car succeeded")
+	}
+
+	setHidden(false)
+	var updatedAt time.Time
+	if err := db.QueryRowContext(ctx, `SELECT updated_at FROM cars WHERE id = $1`, carId).Scan(&updatedAt); err != nil {
+		t.Fatalf("reading car: %v", err)
+	}
+	if updatedAt.After(checkedAt) {
+		t.Errorf("showing the car moved updated_at to %s", updatedAt)
+	}
+	if ids := matchedIds(t, db, search); len(ids) != 0 {
+		t.Errorf("MatchCars after showing the car again = %v, want nothing", ids)
+	}
+	watchers, err = NewCarRepository(db).PriceWatchers(ctx, carId)
+	if err != nil || len(watchers) != 1 || watchers[0] != searcher {
+		t.Errorf("PriceWatchers of a shown car = %v, %v, want %s", watchers, err, searcher)
+	}
+}
+
+// TestPriceDropNotifiedOnce notifies a watcher of the same price change
+// twice, as a retried run does, and expects a single notification.
+func TestPriceDropNotifiedOnce(t *testing.T) {
+	db := testDB(t)
+	watcher, seller := insertUser(t, db), insertUser(t, db)
+	carId := insertCar(t, db, seller, "Make-"+uuid.NewString())
+	if _, err := db.ExecContext(ctx, `UPDATE cars SET price = price - 1000 WHERE id = $1`, carId); err != nil {
+		t.Fatalf("dropping price: %v", err)
+	}
+	var changeId int64
+	err := db.QueryRowContext(ctx, `SELECT id FROM car_price_history WHERE car_id = $1 AND old_price IS NOT NULL`,
+		carId).Scan(&changeId)
+	if err != nil {
+		t.Fatalf("reading price change: %v", err)
+	}
+
+	cars := NewCarRepository(db)
+	for i := 0; i < 2; i++ {
+		err := cars.NotifyPriceDrop(ctx, changeId, &model.Notification{UserId: watcher, Type: "price_drop", Message: "test"})
+		if err != nil {
+			t.Fatalf("NotifyPriceDrop: %v", err)
+		}
+	}
+	var count int
+	if err := db.QueryRowContext(ctx, `SELECT count(*) FROM notifications WHERE user_id = $1`, watcher).Scan(&count); err != nil {
+		t.Fatalf("counting notifications: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("%d notifications after notifying twice, want 1", count)
+	}
+}
@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	pb "wegugin/genproto/user"
+)
+
+func (u *UserRepository) GetPublicProfile(ctx context.Context, id string) (*pb.PublicProfile, *pb.PrivacySettings, error) {
+	query := `SELECT u.id, u.name, u.surname, u.photo, u.created_at,
+	                 (SELECT COUNT(*) FROM cars c WHERE c.owner_id = u.id AND c.deleted_at = 0 AND NOT c.hidden),
+	                 COALESCE(u.rating_average, 0), u.rating_count,
+	                 u.phone_number, u.email, u.phone_visibility, u.email_visibility
+	          FROM users u WHERE u.id = $1 AND u.deleted_at = 0`
+
+	var (
+		profile pb.PublicProfile
+		privacy = pb.PrivacySettings{UserId: id}
+		name    sql.NullString
+		surname sql.NullString
+		photo   sql.NullString
+	)
+	err := u.Db.QueryRowContext(ctx, query, id).Scan(
+		&profile.Id, &name, &surname, &photo, &profile.MemberSince,
+		&profile.ListingCount, &profile.Rating, &profile.RatingCount,
+		&profile.PhoneNumber, &profile.Email, &privacy.PhoneNumber, &privacy.Email,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, fmt.Errorf("user not found: %w", err)
+		}
+		return nil, nil, fmt.Errorf("failed to get public profile: %w", err)
+	}
+	profile.Name = name.String
+	profile.Surname = surname.String
+	profile.Photo = photo.String
+	return &profile, &privacy, nil
+}
+
+func (u *UserRepository) GetPrivacySettings(ctx context.Context, req *pb.UserId) (*pb.PrivacySettings, error) {
+	query := `SELECT phone_visibility, email_visibility FROM users WHERE id = $1 AND deleted_at = 0`
+
+	privacy := pb.PrivacySettings{UserId: req.Id}
+	err := u.Db.QueryRowContext(ctx, query, req.Id).Scan(&privacy.PhoneNumber, &privacy.Email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("user not found: %w", err)
+		}
+		return nil, fmt.Errorf("failed to get privacy settings: %w", err)
+	}
+	return &privacy, nil
+}
+
+func (u *UserRepository) UpdatePrivacySettings(ctx context.Context, req *pb.PrivacySettings) error {
+	query := `UPDATE users SET phone_visibility = $2, email_visibility = $3, updated_at = CURRENT_TIMESTAMP
+	          WHERE id = $1 AND deleted_at = 0`
+
+	result, err := u.Db.ExecContext(ctx, query, req.UserId, req.PhoneNumber, req.Email)
+	if err != nil {
+		return fmt.Errorf("failed to update privacy settings: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("user not found: %w", sql.ErrNoRows)
+	}
+	return nil
+}
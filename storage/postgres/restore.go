@@ -0,0 +1,132 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+	pb "wegugin/genproto/user"
+
+	"github.com/lib/pq"
+)
+
+// restoreWindowStart is the oldest deleted_at that can still be restored.
+func (u *UserRepository) restoreWindowStart() int64 {
+	return time.Now().Add(-u.RestoreWindow).Unix()
+}
+
+func (u *UserRepository) RestoreDeletedUser(ctx context.Context, id string, deletedAt int64) (string, error) {
+	if deletedAt <= u.restoreWindowStart() {
+		return "", fmt.Errorf("restore window has expired")
+	}
+
+	var role string
+	query := `UPDATE users SET deleted_at = 0, updated_at = CURRENT_TIMESTAMP
+	          WHERE id = $1 AND deleted_at = $2 AND purged_at IS NULL RETURNING role`
+	err := u.Db.QueryRowContext(ctx, query, id, deletedAt).Scan(&role)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return "", fmt.Errorf("email or phone number is already used by another account")
+		}
+		return "", fmt.Errorf("deleted user not found: %w", err)
+	}
+
+	return role, nil
+}
+
+func (u *UserRepository) PurgeCandidates(ctx context.Context, deletedBefore int64) ([]*pb.AdminUser, error) {
+	query := `SELECT ` + adminUserColumns + ` FROM users
+	          WHERE deleted_at <> 0 AND deleted_at <= $1 AND purged_at IS NULL
+	          ORDER BY deleted_at LIMIT 100`
+
+	rows, err := u.Db.QueryContext(ctx, query, deletedBefore)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select purge candidates: %w", err)
+	}
+	defer rows.Close()
+
+	var users []*pb.AdminUser
+	for rows.Next() {
+		user, err := scanAdminUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+func (u *UserRepository) PurgeUser(ctx context.Context, id string, anonymize bool) error {
+	if !anonymize {
+		_, err := u.Db.ExecContext(ctx, `DELETE FROM users WHERE id = $1 AND deleted_at <> 0`, id)
+		if err != nil {
+			return fmt.Errorf("failed to delete user: %w", err)
+		}
+		return nil
+	}
+
+	tx, err := u.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	// Rows that only matter to the user go, listings and conversations stay
+	// but no longer point to a person.
+	for _, query := range []string{
+		`DELETE FROM notifications WHERE user_id = $1`,
+		`DELETE FROM notifications_tokens WHERE user_id = $1`,
+		`DELETE FROM saved_cars WHERE user_id = $1`,
+		`DELETE FROM saved_searches WHERE user_id = $1`,
+		`DELETE FROM data_exports WHERE user_id = $1`,
+		`UPDATE cars SET deleted_at = date_part('epoch', current_timestamp)::INT WHERE owner_id = $1 AND deleted_at = 0`,
+		`UPDATE users SET name = NULL, surname = NULL, email = '', phone_number = '', birth_date = NULL,
+		 gender = NULL, address = NULL, photo = NULL, password_hash = '', purged_at = CURRENT_TIMESTAMP
+		 WHERE id = $1 AND deleted_at <> 0`,
+		`DELETE FROM password_history WHERE user_id = $1`,
+		`DELETE FROM user_sessions WHERE user_id = $1`,
+		`DELETE FROM user_identities WHERE user_id = $1`,
+	} {
+		if _, err = tx.ExecContext(ctx, query, id); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to anonymize user: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (u *UserRepository) PurgeFiles(ctx context.Context, id string) ([]string, []string, error) {
+	images, err := u.names(ctx, `SELECT i.filename FROM images i JOIN cars c ON c.id = i.car_id WHERE c.owner_id = $1`, id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to select car images: %w", err)
+	}
+	exports, err := u.names(ctx, `SELECT object_name FROM data_exports
+	                              WHERE user_id = $1 AND object_name IS NOT NULL AND status <> 'expired'`, id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to select export archives: %w", err)
+	}
+	return images, exports, nil
+}
+
+// names returns the single column of the rows of query.
+func (u *UserRepository) names(ctx context.Context, query string, args ...interface{}) ([]string, error) {
+	rows, err := u.Db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
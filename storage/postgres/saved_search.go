@@ -0,0 +1,396 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	pb "wegugin/genproto/user"
+	"wegugin/model"
+	"wegugin/storage"
+
+	"github.com/lib/pq"
+)
+
+var savedSearchColumns = []string{"id", "user_id", "name", "type", "make", "model", "year_from", "year_to",
+	"price_from", "price_to", "mileage_from", "mileage_to", "color", "location", "frequency", "active",
+	"last_checked_at", "created_at", "updated_at"}
+
+// savedSearchSelect lists the saved_searches columns read by scanSavedSearch,
+// optionally qualified with a table alias.
+func savedSearchSelect(alias string) string {
+	if alias == "" {
+		return strings.Join(savedSearchColumns, ", ")
+	}
+	return alias + "." + strings.Join(savedSearchColumns, ", "+alias+".")
+}
+
+type SavedSearchRepository struct {
+	Db *sql.DB
+}
+
+func NewSavedSearchRepository(db *sql.DB) storage.ISavedSearchStorage {
+	return &SavedSearchRepository{Db: db}
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSavedSearch(row rowScanner) (*pb.SavedSearch, error) {
+	var (
+		search                                      pb.SavedSearch
+		filter                                      pb.CarFilter
+		carType, carMake, carModel, color, location sql.NullString
+		yearFrom, yearTo, mileageFrom, mileageTo    sql.NullInt32
+		priceFrom, priceTo                          sql.NullFloat64
+	)
+
+	err := row.Scan(
+		&search.Id, &search.UserId, &search.Name, &carType, &carMake, &carModel,
+		&yearFrom, &yearTo, &priceFrom, &priceTo, &mileageFrom, &mileageTo,
+		&color, &location, &search.Frequency, &search.Active,
+		&search.LastCheckedAt, &search.CreatedAt, &search.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	filter.Type = carType.String
+	filter.Make = carMake.String
+	filter.Model = carModel.String
+	filter.YearFrom = yearFrom.Int32
+	filter.YearTo = yearTo.Int32
+	filter.PriceFrom = priceFrom.Float64
+	filter.PriceTo = priceTo.Float64
+	filter.MileageFrom = mileageFrom.Int32
+	filter.MileageTo = mileageTo.Int32
+	filter.Color = color.String
+	filter.Location = location.String
+	search.Filter = &filter
+
+	return &search, nil
+}
+
+// filterValues returns the saved_searches filter columns in table order.
+// Zero values are stored as NULL so that they do not restrict the search.
+func filterValues(f *pb.CarFilter) []interface{} {
+	if f == nil {
+		f = &pb.CarFilter{}
+	}
+	return []interface{}{
+		nullString(f.Type), nullString(f.Make), nullString(f.Model),
+		nullInt(f.YearFrom), nullInt(f.YearTo),
+		nullFloat(f.PriceFrom), nullFloat(f.PriceTo),
+		nullInt(f.MileageFrom), nullInt(f.MileageTo),
+		nullString(f.Color), nullString(f.Location),
+	}
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func nullInt(i int32) sql.NullInt32 {
+	return sql.NullInt32{Int32: i, Valid: i != 0}
+}
+
+func nullFloat(f float64) sql.NullFloat64 {
+	return sql.NullFloat64{Float64: f, Valid: f != 0}
+}
+
+func validFrequency(frequency string) bool {
+	return frequency == "instant" || frequency == "daily"
+}
+
+func (s *SavedSearchRepository) CreateSavedSearch(ctx context.Context, req *pb.CreateSavedSearchReq) (*pb.SavedSearch, error) {
+	if strings.TrimSpace(req.Name) == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	if req.Frequency == "" {
+		req.Frequency = "instant"
+	}
+	if !validFrequency(req.Frequency) {
+		return nil, fmt.Errorf("invalid frequency: %s", req.Frequency)
+	}
+
+	query := `INSERT INTO saved_searches (user_id, name, type, make, model, year_from, year_to,
+	          price_from, price_to, mileage_from, mileage_to, color, location, frequency)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	          RETURNING ` + savedSearchSelect("")
+
+	args := []interface{}{req.UserId, req.Name}
+	args = append(args, filterValues(req.Filter)...)
+	args = append(args, req.Frequency)
+
+	search, err := scanSavedSearch(s.Db.QueryRowContext(ctx, query, args...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert saved search: %w", err)
+	}
+
+	return search, nil
+}
+
+func (s *SavedSearchRepository) GetSavedSearch(ctx context.Context, req *pb.SavedSearchId) (*pb.SavedSearch, error) {
+	query := `SELECT ` + savedSearchSelect("") + ` FROM saved_searches
+	          WHERE id = $1 AND user_id = $2 AND deleted_at = 0`
+
+	search, err := scanSavedSearch(s.Db.QueryRowContext(ctx, query, req.Id, req.UserId))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("saved search not found")
+		}
+		return nil, err
+	}
+
+	return search, nil
+}
+
+func (s *SavedSearchRepository) ListSavedSearches(ctx context.Context, req *pb.UserId) (*pb.SavedSearchList, error) {
+	query := `SELECT ` + savedSearchSelect("") + ` FROM saved_searches
+	          WHERE user_id = $1 AND deleted_at = 0 ORDER BY created_at DESC`
+
+	rows, err := s.Db.QueryContext(ctx, query, req.Id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list saved searches: %w", err)
+	}
+	defer rows.Close()
+
+	res := &pb.SavedSearchList{}
+	for rows.Next() {
+		search, err := scanSavedSearch(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan saved search: %w", err)
+		}
+		res.SavedSearches = append(res.SavedSearches, search)
+	}
+
+	return res, rows.Err()
+}
+
+func (s *SavedSearchRepository) UpdateSavedSearch(ctx context.Context, req *pb.UpdateSavedSearchReq) error {
+	n := 1
+	var arr []interface{}
+	var updates []string
+
+	if len(req.Name) > 0 {
+		updates = append(updates, fmt.Sprintf("name=$%d", n))
+		arr = append(arr, req.Name)
+		n++
+	}
+	if len(req.Frequency) > 0 {
+		if !validFrequency(req.Frequency) {
+			return fmt.Errorf("invalid frequency: %s", req.Frequency)
+		}
+		updates = append(updates, fmt.Sprintf("frequency=$%d", n))
+		arr = append(arr, req.Frequency)
+		n++
+	}
+	if req.Active != nil {
+		updates = append(updates, fmt.Sprintf("active=$%d", n))
+		arr = append(arr, *req.Active)
+		n++
+	}
+	// A new filter replaces the old one as a whole
+	if req.Filter != nil {
+		columns := []string{"type", "make", "model", "year_from", "year_to", "price_from", "price_to",
+			"mileage_from", "mileage_to", "color", "location"}
+		for i, value := range filterValues(req.Filter) {
+			updates = append(updates, fmt.Sprintf("%s=$%d", columns[i], n))
+			arr = append(arr, value)
+			n++
+		}
+	}
+
+	if len(updates) == 0 {
+		return fmt.Errorf("no fields to update")
+	}
+
+	updates = append(updates, "updated_at=CURRENT_TIMESTAMP")
+
+	query := `UPDATE saved_searches SET ` + strings.Join(updates, ", ")
+	query += fmt.Sprintf(" WHERE id=$%d AND user_id=$%d AND deleted_at=0", n, n+1)
+	arr = append(arr, req.Id, req.UserId)
+
+	result, err := s.Db.ExecContext(ctx, query, arr...)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("saved search not found")
+	}
+
+	return nil
+}
+
+func (s *SavedSearchRepository) DeleteSavedSearch(ctx context.Context, req *pb.SavedSearchId) error {
+	query := `UPDATE saved_searches SET deleted_at = date_part('epoch', current_timestamp)::INT
+	WHERE id = $1 AND user_id = $2 AND deleted_at = 0`
+
+	result, err := s.Db.ExecContext(ctx, query, req.Id, req.UserId)
+	if err != nil {
+		return fmt.Errorf("failed to update deleted_at: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("saved search not found")
+	}
+
+	return nil
+}
+
+func (s *SavedSearchRepository) DueSavedSearches(ctx context.Context, now time.Time) ([]*pb.SavedSearch, error) {
+	query := `SELECT ` + savedSearchSelect("s") + `
+	          FROM saved_searches s
+	          JOIN users u ON u.id = s.user_id AND u.deleted_at = 0
+	          WHERE s.deleted_at = 0 AND s.active = true
+	            AND (s.frequency = 'instant' OR s.last_checked_at <= $1::timestamptz - INTERVAL '1 day')`
+
+	rows, err := s.Db.QueryContext(ctx, query, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select due saved searches: %w", err)
+	}
+	defer rows.Close()
+
+	var searches []*pb.SavedSearch
+	for rows.Next() {
+		search, err := scanSavedSearch(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan saved search: %w", err)
+		}
+		searches = append(searches, search)
+	}
+
+	return searches, rows.Err()
+}
+
+func (s *SavedSearchRepository) CheckCutoff(ctx context.Context, lag time.Duration) (time.Time, error) {
+	var cutoff time.Time
+	err := s.Db.QueryRowContext(ctx, `SELECT CURRENT_TIMESTAMP - make_interval(secs => $1)`, lag.Seconds()).Scan(&cutoff)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read the database clock: %w", err)
+	}
+	return cutoff, nil
+}
+
+func (s *SavedSearchRepository) MatchCars(ctx context.Context, search *pb.SavedSearch, until time.Time, dedup time.Duration) ([]*model.CarMatch, error) {
+	query := `SELECT c.id, c.make, c.model, c.year, c.price, c.location
+	          FROM cars c
+	          WHERE c.deleted_at = 0 AND c.available = true AND NOT c.hidden
+	            AND c.owner_id <> $1
+	            AND c.updated_at > (SELECT last_checked_at FROM saved_searches WHERE id = $2)
+	            AND c.updated_at <= $3
+	            AND NOT EXISTS (
+	                SELECT 1 FROM saved_search_matches m
+	                WHERE m.saved_search_id = $2 AND m.car_id = c.id AND m.notified_at > $4
+	            )`
+	arr := []interface{}{search.UserId, search.Id, until, until.Add(-dedup)}
+	n := len(arr) + 1
+
+	f := search.Filter
+	if f == nil {
+		f = &pb.CarFilter{}
+	}
+	var conditions []string
+	addCondition := func(format string, value interface{}) {
+		conditions = append(conditions, fmt.Sprintf(format, n))
+		arr = append(arr, value)
+		n++
+	}
+	if f.Type != "" {
+		addCondition("LOWER(c.type) = LOWER($%d)", f.Type)
+	}
+	if f.Make != "" {
+		addCondition("LOWER(c.make) = LOWER($%d)", f.Make)
+	}
+	if f.Model != "" {
+		addCondition("LOWER(c.model) = LOWER($%d)", f.Model)
+	}
+	if f.YearFrom > 0 {
+		addCondition("c.year >= $%d", f.YearFrom)
+	}
+	if f.YearTo > 0 {
+		addCondition("c.year <= $%d", f.YearTo)
+	}
+	if f.PriceFrom > 0 {
+		addCondition("c.price >= $%d", f.PriceFrom)
+	}
+	if f.PriceTo > 0 {
+		addCondition("c.price <= $%d", f.PriceTo)
+	}
+	if f.MileageFrom > 0 {
+		addCondition("c.mileage >= $%d", f.MileageFrom)
+	}
+	if f.MileageTo > 0 {
+		addCondition("c.mileage <= $%d", f.MileageTo)
+	}
+	if f.Color != "" {
+		addCondition("LOWER(c.color) = LOWER($%d)", f.Color)
+	}
+	if f.Location != "" {
+		addCondition("c.location ILIKE '%%' || $%d || '%%'", f.Location)
+	}
+	for _, condition := range conditions {
+		query += " AND " + condition
+	}
+	query += " ORDER BY c.updated_at"
+
+	rows, err := s.Db.QueryContext(ctx, query, arr...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to match cars: %w", err)
+	}
+	defer rows.Close()
+
+	var cars []*model.CarMatch
+	for rows.Next() {
+		var car model.CarMatch
+		if err := rows.Scan(&car.Id, &car.Make, &car.Model, &car.Year, &car.Price, &car.Location); err != nil {
+			return nil, fmt.Errorf("failed to scan car: %w", err)
+		}
+		cars = append(cars, &car)
+	}
+
+	return cars, rows.Err()
+}
+
+func (s *SavedSearchRepository) MarkChecked(ctx context.Context, searchId string, carIds []string, checkedAt time.Time) error {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	if len(carIds) > 0 {
+		query := `INSERT INTO saved_search_matches (saved_search_id, car_id, notified_at)
+		          SELECT $1, car_id, $3 FROM unnest($2::uuid[]) AS car_id
+		          ON CONFLICT (saved_search_id, car_id) DO UPDATE SET notified_at = EXCLUDED.notified_at`
+		_, err = tx.ExecContext(ctx, query, searchId, pq.Array(carIds), checkedAt)
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record matches: %w", err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE saved_searches SET last_checked_at = $1 WHERE id = $2`, checkedAt, searchId)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to update last_checked_at: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
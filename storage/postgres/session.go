@@ -0,0 +1,117 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+	pb "wegugin/genproto/user"
+	"wegugin/model"
+	"wegugin/storage"
+)
+
+type SessionRepository struct {
+	Db *sql.DB
+}
+
+func NewSessionRepository(db *sql.DB) storage.ISessionStorage {
+	return &SessionRepository{Db: db}
+}
+
+func (s *SessionRepository) CreateSession(ctx context.Context, req *model.Session) (string, error) {
+	query := `INSERT INTO user_sessions (user_id, device_name, platform, ip, user_agent, expires_at)
+	          VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+
+	var id string
+	err := s.Db.QueryRowContext(ctx, query, req.UserId, nullString(req.DeviceName), nullString(req.Platform),
+		nullString(req.IP), nullString(req.UserAgent), req.ExpiresAt).Scan(&id)
+	if err != nil {
+		return "", fmt.Errorf("failed to insert session: %w", err)
+	}
+	return id, nil
+}
+
+func (s *SessionRepository) ListSessions(ctx context.Context, userId string) ([]*pb.Session, error) {
+	query := `SELECT id, device_name, platform, ip, user_agent, created_at, last_seen_at FROM user_sessions
+	          WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+	          ORDER BY last_seen_at DESC`
+
+	rows, err := s.Db.QueryContext(ctx, query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []*pb.Session
+	for rows.Next() {
+		var (
+			session                         pb.Session
+			device, platform, ip, userAgent sql.NullString
+		)
+		err := rows.Scan(&session.Id, &device, &platform, &ip, &userAgent, &session.CreatedAt, &session.LastSeenAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		session.DeviceName = device.String
+		session.Platform = platform.String
+		session.Ip = ip.String
+		session.UserAgent = userAgent.String
+		sessions = append(sessions, &session)
+	}
+
+	return sessions, rows.Err()
+}
+
+func (s *SessionRepository) RevokeSession(ctx context.Context, req *pb.SessionReq) (time.Time, error) {
+	query := `UPDATE user_sessions SET revoked_at = CURRENT_TIMESTAMP
+	          WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	          RETURNING expires_at`
+
+	var expiresAt time.Time
+	err := s.Db.QueryRowContext(ctx, query, req.SessionId, req.UserId).Scan(&expiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return time.Time{}, fmt.Errorf("session not found: %w", err)
+		}
+		return time.Time{}, fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return expiresAt, nil
+}
+
+func (s *SessionRepository) RevokeOtherSessions(ctx context.Context, userId, keepId string) (map[string]time.Time, error) {
+	query := `UPDATE user_sessions SET revoked_at = CURRENT_TIMESTAMP
+	          WHERE user_id = $1 AND id::text <> $2 AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+	          RETURNING id, expires_at`
+
+	rows, err := s.Db.QueryContext(ctx, query, userId, keepId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	defer rows.Close()
+
+	revoked := make(map[string]time.Time)
+	for rows.Next() {
+		var id string
+		var expiresAt time.Time
+		if err := rows.Scan(&id, &expiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		revoked[id] = expiresAt
+	}
+
+	return revoked, rows.Err()
+}
+
+func (s *SessionRepository) IsSessionRevoked(ctx context.Context, sessionId string) (bool, error) {
+	query := `SELECT revoked_at IS NOT NULL FROM user_sessions WHERE id = $1`
+
+	var revoked bool
+	err := s.Db.QueryRowContext(ctx, query, sessionId).Scan(&revoked)
+	if err == sql.ErrNoRows {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check session: %w", err)
+	}
+	return revoked, nil
+}
@@ -0,0 +1,177 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+	pb "wegugin/genproto/user"
+	"wegugin/storage"
+)
+
+// activeSuspension matches user_suspensions rows that are in force right now.
+const activeSuspension = `lifted_at IS NULL AND starts_at <= CURRENT_TIMESTAMP
+	AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)`
+
+const suspensionColumns = `id, user_id, reason, actor_id, starts_at, expires_at, lifted_at, lifted_by, lift_reason`
+
+type SuspensionRepository struct {
+	Db *sql.DB
+}
+
+func NewSuspensionRepository(db *sql.DB) storage.ISuspensionStorage {
+	return &SuspensionRepository{Db: db}
+}
+
+func scanSuspension(row rowScanner) (*pb.Suspension, error) {
+	var (
+		suspension                                         pb.Suspension
+		actorId, expiresAt, liftedAt, liftedBy, liftReason sql.NullString
+	)
+
+	err := row.Scan(&suspension.Id, &suspension.UserId, &suspension.Reason, &actorId, &suspension.StartsAt,
+		&expiresAt, &liftedAt, &liftedBy, &liftReason)
+	if err != nil {
+		return nil, err
+	}
+
+	suspension.ActorId = actorId.String
+	suspension.ExpiresAt = expiresAt.String
+	suspension.LiftedAt = liftedAt.String
+	suspension.LiftedBy = liftedBy.String
+	suspension.LiftReason = liftReason.String
+
+	return &suspension, nil
+}
+
+func (s *SuspensionRepository) SuspendUser(ctx context.Context, req *pb.SuspendUserReq) (*pb.Suspension, error) {
+	if len(req.Reason) == 0 {
+		return nil, fmt.Errorf("reason is required")
+	}
+	var expiresAt sql.NullTime
+	if len(req.ExpiresAt) > 0 {
+		t, err := time.Parse(time.RFC3339, req.ExpiresAt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expires_at format: %w", err)
+		}
+		if !t.After(time.Now()) {
+			return nil, fmt.Errorf("expires_at must be in the future")
+		}
+		expiresAt = sql.NullTime{Time: t, Valid: true}
+	}
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	var exists, suspended bool
+	query := `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND deleted_at = 0),
+	          EXISTS (SELECT 1 FROM user_suspensions WHERE user_id = $1 AND ` + activeSuspension + `)`
+	if err = tx.QueryRowContext(ctx, query, req.UserId).Scan(&exists, &suspended); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to check user: %w", err)
+	}
+	if !exists {
+		tx.Rollback()
+		return nil, fmt.Errorf("user not found")
+	}
+	if suspended {
+		tx.Rollback()
+		return nil, fmt.Errorf("user is already suspended")
+	}
+
+	query = `INSERT INTO user_suspensions (user_id, reason, actor_id, expires_at)
+	         VALUES ($1, $2, NULLIF($3, '')::uuid, $4) RETURNING ` + suspensionColumns
+	suspension, err := scanSuspension(tx.QueryRowContext(ctx, query, req.UserId, req.Reason, req.ActorId, expiresAt))
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to insert suspension: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return suspension, nil
+}
+
+func (s *SuspensionRepository) LiftSuspension(ctx context.Context, req *pb.LiftSuspensionReq) error {
+	query := `UPDATE user_suspensions
+	          SET lifted_at = CURRENT_TIMESTAMP, lifted_by = NULLIF($2, '')::uuid, lift_reason = NULLIF($3, '')
+	          WHERE user_id = $1 AND ` + activeSuspension
+
+	result, err := s.Db.ExecContext(ctx, query, req.UserId, req.ActorId, req.Reason)
+	if err != nil {
+		return fmt.Errorf("failed to lift suspension: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("user is not suspended")
+	}
+
+	return nil
+}
+
+func (s *SuspensionRepository) ListSuspensions(ctx context.Context, req *pb.UserId) (*pb.SuspensionList, error) {
+	query := `SELECT ` + suspensionColumns + ` FROM user_suspensions WHERE user_id = $1 ORDER BY starts_at DESC`
+	return s.list(ctx, query, req.Id)
+}
+
+func (s *SuspensionRepository) ActiveSuspensions(ctx context.Context) ([]*pb.Suspension, error) {
+	query := `SELECT ` + suspensionColumns + ` FROM user_suspensions WHERE ` + activeSuspension
+	res, err := s.list(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return res.Suspensions, nil
+}
+
+func (s *SuspensionRepository) IsSuspended(ctx context.Context, userId string) (bool, error) {
+	var suspended bool
+	query := `SELECT EXISTS (SELECT 1 FROM user_suspensions WHERE user_id = $1 AND ` + activeSuspension + `)`
+	if err := s.Db.QueryRowContext(ctx, query, userId).Scan(&suspended); err != nil {
+		return false, fmt.Errorf("failed to check suspension: %w", err)
+	}
+	return suspended, nil
+}
+
+func (s *SuspensionRepository) list(ctx context.Context, query string, args ...interface{}) (*pb.SuspensionList, error) {
+	rows, err := s.Db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select suspensions: %w", err)
+	}
+	defer rows.Close()
+
+	res := &pb.SuspensionList{}
+	for rows.Next() {
+		suspension, err := scanSuspension(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan suspension: %w", err)
+		}
+		res.Suspensions = append(res.Suspensions, suspension)
+	}
+
+	return res, rows.Err()
+}
+
+func (s *SuspensionRepository) SyncHiddenCars(ctx context.Context) error {
+	query := `UPDATE cars c SET hidden = s.suspended
+	          FROM (
+	              SELECT c2.id, EXISTS (
+	                  SELECT 1 FROM user_suspensions WHERE user_id = c2.owner_id AND ` + activeSuspension + `
+	              ) AS suspended
+	              FROM cars c2
+	          ) s
+	          WHERE c.id = s.id AND c.hidden <> s.suspended`
+
+	if _, err := s.Db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to sync hidden cars: %w", err)
+	}
+	return nil
+}
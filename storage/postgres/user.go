@@ -5,27 +5,47 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"log/slog"
 	"strings"
+	"sync"
 	"time"
 	"wegugin/api/auth"
+	"wegugin/api/password"
+	"wegugin/config"
 	pb "wegugin/genproto/user"
 	"wegugin/storage"
 
-	"golang.org/x/crypto/bcrypt"
+	"github.com/lib/pq"
 )
 
 type UserRepository struct {
-	Db *sql.DB
+	Db     *sql.DB
+	Hasher *password.Hasher
+	Tokens *auth.Tokens
+	// RestoreWindow is how long deleted users can log in again
+	RestoreWindow time.Duration
+	// Logger gets the failures that do not fail the request
+	Logger *slog.Logger
 }
 
-func NewUserRepository(db *sql.DB) storage.IUserStorage {
-	return &UserRepository{Db: db}
+func NewUserRepository(db *sql.DB, conf *config.Config, logger *slog.Logger) storage.IUserStorage {
+	return &UserRepository{
+		Db:            db,
+		Hasher:        password.NewHasher(conf.Password),
+		Tokens:        auth.New(conf.Token),
+		RestoreWindow: conf.Account.RESTORE_WINDOW,
+		Logger:        logger,
+	}
 }
 
 func (u UserRepository) CreateUser(ctx context.Context, req *pb.RegisterReq) (*pb.LoginRes, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	return u.CreateUserWithRole(ctx, req, "user")
+}
+
+func (u UserRepository) CreateUserWithRole(ctx context.Context, req *pb.RegisterReq, role string) (*pb.LoginRes, error) {
+	hashedPassword, err := u.Hasher.Hash(req.Password)
 	if err != nil {
-		return nil, fmt.Errorf("failed to hash password: %w", err)
+		return nil, err
 	}
 
 	// birth_date format: dd-mm-yyyy, convert to YYYY-MM-DD for PostgreSQL
@@ -40,9 +60,9 @@ func (u UserRepository) CreateUser(ctx context.Context, req *pb.RegisterReq) (*p
 	}
 
 	var userID, userRole string
-	userQuery := `INSERT INTO users (email, name, surname, password_hash, phone_number, birth_date, gender)
-                  VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, role`
-	err = tx.QueryRowContext(ctx, userQuery, req.Email, req.Name, req.Surname, string(hashedPassword), req.Phone, birthDate, req.Gender).Scan(&userID, &userRole)
+	userQuery := `INSERT INTO users (email, name, surname, password_hash, phone_number, birth_date, gender, role)
+                  VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, role`
+	err = tx.QueryRowContext(ctx, userQuery, req.Email, req.Name, req.Surname, hashedPassword, req.Phone, birthDate, req.Gender, role).Scan(&userID, &userRole)
 	if err != nil {
 		tx.Rollback()
 		return nil, fmt.Errorf("failed to insert user: %w", err)
@@ -52,7 +72,7 @@ func (u UserRepository) CreateUser(ctx context.Context, req *pb.RegisterReq) (*p
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	token, err := auth.GenerateJWTToken(userID, userRole)
+	token, err := u.Tokens.GenerateJWTToken(userID, userRole)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate jwt token: %w", err)
 	}
@@ -62,34 +82,141 @@ func (u UserRepository) CreateUser(ctx context.Context, req *pb.RegisterReq) (*p
 	}, nil
 }
 
+var (
+	dummyHash     string
+	dummyHashOnce sync.Once
+)
+
+// verifyDummy spends the time of a password check when the login is unknown.
+func (u UserRepository) verifyDummy(pw string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = u.Hasher.Hash("dummy password")
+	})
+	u.Hasher.Verify(dummyHash, pw)
+}
+
 func (u UserRepository) Login(ctx context.Context, req *pb.LoginReq) (*pb.LoginRes, error) {
-	query := `SELECT id, password_hash , role FROM users WHERE email = $1 and deleted_at=0`
+	// Accounts deleted within the restore window can still log in, which restores them
+	query := `SELECT id, password_hash, role, password_reset_required, deleted_at FROM users
+	          WHERE (email = $1 OR phone_number = $1) AND purged_at IS NULL AND (deleted_at = 0 OR deleted_at > $2)
+	          ORDER BY deleted_at = 0 DESC, deleted_at DESC LIMIT 1`
 
 	var id, passwordHash, role string
-	err := u.Db.QueryRowContext(ctx, query, req.EmailOrPhoneNumber).Scan(&id, &passwordHash, &role)
+	var resetRequired bool
+	var deletedAt int64
+	err := u.Db.QueryRowContext(ctx, query, req.EmailOrPhoneNumber, u.restoreWindowStart()).Scan(
+		&id, &passwordHash, &role, &resetRequired, &deletedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			query = `SELECT id, password_hash, role FROM users WHERE phone_number = $1`
-			err = u.Db.QueryRowContext(ctx, query, req.EmailOrPhoneNumber).Scan(&id, &passwordHash, &role)
-			if err != nil {
-				if err == sql.ErrNoRows {
-					return nil, errors.New("user not found")
-				}
-				return nil, err
-			}
-		} else {
-			return nil, err
+			// Spend the same time as for a wrong password
+			u.verifyDummy(req.Password)
+			return nil, storage.ErrInvalidCredentials
 		}
+		return nil, err
 	}
-	err = bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(req.Password))
+	ok, err := u.Hasher.Verify(passwordHash, req.Password)
 	if err != nil {
-		if err == bcrypt.ErrMismatchedHashAndPassword {
-			return nil, fmt.Errorf("password is incorrect")
+		return nil, fmt.Errorf("failed to verify password: %w", err)
+	}
+	// Checked whatever the password, so the answer does not confirm it
+	if resetRequired {
+		return nil, storage.ErrPasswordResetRequired
+	}
+	if !ok {
+		return nil, storage.ErrInvalidCredentials
+	}
+
+	if err := u.checkSuspended(ctx, id); err != nil {
+		return nil, err
+	}
+
+	if u.Hasher.NeedsRehash(passwordHash) {
+		// The login succeeds with the old hash as well, it is replaced on a
+		// later login when this fails
+		if err := u.rehash(ctx, id, passwordHash, req.Password); err != nil {
+			u.Logger.Error(fmt.Sprintf("error rehashing password: %v", err))
 		}
+	}
+
+	return u.finishLogin(ctx, id, role, deletedAt)
+}
+
+func (u UserRepository) CheckPassword(ctx context.Context, userId, password string) error {
+	query := `SELECT password_hash FROM users WHERE id = $1 AND deleted_at = 0`
+	var passwordHash string
+	err := u.Db.QueryRowContext(ctx, query, userId).Scan(&passwordHash)
+	if err != nil {
+		return fmt.Errorf("user not found: %w", err)
+	}
+	ok, err := u.Hasher.Verify(passwordHash, password)
+	if err != nil {
+		return fmt.Errorf("failed to verify password: %w", err)
+	}
+	if !ok {
+		return storage.ErrInvalidCredentials
+	}
+	return nil
+}
+
+func (u UserRepository) ChangeEmail(ctx context.Context, userId, from, to string) error {
+	query := `UPDATE users SET email = $3, updated_at = CURRENT_TIMESTAMP
+	          WHERE id = $1 AND email = $2 AND deleted_at = 0`
+	result, err := u.Db.ExecContext(ctx, query, userId, from, to)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return storage.ErrEmailTaken
+		}
+		return fmt.Errorf("failed to change email: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("email has changed: %w", sql.ErrNoRows)
+	}
+	return nil
+}
+
+func (u UserRepository) LoginById(ctx context.Context, id string) (*pb.LoginRes, error) {
+	query := `SELECT role, deleted_at FROM users
+	          WHERE id = $1 AND purged_at IS NULL AND (deleted_at = 0 OR deleted_at > $2)`
+
+	var role string
+	var deletedAt int64
+	err := u.Db.QueryRowContext(ctx, query, id, u.restoreWindowStart()).Scan(&role, &deletedAt)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+	if err := u.checkSuspended(ctx, id); err != nil {
 		return nil, err
 	}
 
-	token, err := auth.GenerateJWTToken(id, role)
+	return u.finishLogin(ctx, id, role, deletedAt)
+}
+
+func (u UserRepository) checkSuspended(ctx context.Context, id string) error {
+	var suspended bool
+	query := `SELECT EXISTS (SELECT 1 FROM user_suspensions WHERE user_id = $1 AND ` + activeSuspension + `)`
+	err := u.Db.QueryRowContext(ctx, query, id).Scan(&suspended)
+	if err != nil {
+		return fmt.Errorf("failed to check suspension: %w", err)
+	}
+	if suspended {
+		return storage.ErrUserSuspended
+	}
+	return nil
+}
+
+// finishLogin restores a deleted user logging in and returns the token.
+func (u UserRepository) finishLogin(ctx context.Context, id, role string, deletedAt int64) (*pb.LoginRes, error) {
+	if deletedAt != 0 {
+		if _, err := u.RestoreDeletedUser(ctx, id, deletedAt); err != nil {
+			return nil, err
+		}
+	}
+
+	token, err := u.Tokens.GenerateJWTToken(id, role)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate jwt token: %w", err)
 	}
@@ -99,6 +226,69 @@ func (u UserRepository) Login(ctx context.Context, req *pb.LoginReq) (*pb.LoginR
 	}, nil
 }
 
+// rehash stores password hashed with the current algorithm and parameters.
+// app.rehash keeps the old hash of the same password out of password_history.
+func (u UserRepository) rehash(ctx context.Context, id, oldHash, pw string) error {
+	hash, err := u.Hasher.Hash(pw)
+	if err != nil {
+		return err
+	}
+	tx, err := u.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, `SET LOCAL app.rehash = 'on'`); err != nil {
+		return fmt.Errorf("failed to mark rehash: %w", err)
+	}
+	// A password changed meanwhile is left alone
+	query := `UPDATE users SET password_hash = $1 WHERE id = $2 AND password_hash = $3`
+	if _, err = tx.ExecContext(ctx, query, hash, id, oldHash); err != nil {
+		return fmt.Errorf("failed to rehash password: %w", err)
+	}
+	return tx.Commit()
+}
+
+func (u UserRepository) UserIdByLogin(ctx context.Context, login string) (string, error) {
+	query := `SELECT id FROM users WHERE (email = $1 OR phone_number = $1) AND purged_at IS NULL
+	          ORDER BY deleted_at = 0 DESC, deleted_at DESC LIMIT 1`
+
+	var id string
+	err := u.Db.QueryRowContext(ctx, query, login).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", errors.New("user not found")
+		}
+		return "", err
+	}
+	return id, nil
+}
+
+func (u UserRepository) RecentPasswordHashes(ctx context.Context, userId string, n int) ([]string, error) {
+	query := `SELECT password_hash FROM (
+	              SELECT password_hash, CURRENT_TIMESTAMP AS created_at FROM users WHERE id = $1
+	              UNION ALL
+	              SELECT password_hash, created_at FROM password_history WHERE user_id = $1
+	          ) p ORDER BY created_at DESC LIMIT $2`
+
+	rows, err := u.Db.QueryContext(ctx, query, userId, n)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get password history: %w", err)
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, fmt.Errorf("failed to scan password hash: %w", err)
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, rows.Err()
+}
+
 func (u *UserRepository) GetUserByEmail(ctx context.Context, req *pb.GetUSerByEmailReq) (*pb.GetUserResponse, error) {
 	query := `SELECT id, name, surname, email, birth_date, gender, phone_number, address, photo, role, created_at 
 	          FROM users WHERE email = $1 AND deleted_at=0`
@@ -121,7 +311,7 @@ func (u *UserRepository) GetUserByEmail(ctx context.Context, req *pb.GetUSerByEm
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("user not found")
+			return nil, fmt.Errorf("user not found: %w", err)
 		}
 		return nil, err
 	}
@@ -224,10 +414,10 @@ func (u *UserRepository) GetUserById(ctx context.Context, req *pb.UserId) (*pb.G
 }
 
 func (u *UserRepository) UpdatePassword(ctx context.Context, req *pb.UpdatePasswordReq) error {
-	query := `update users set password_hash=$1 where id=$2 and deleted_at=0`
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	query := `update users set password_hash=$1, password_reset_required=false where id=$2 and deleted_at=0`
+	hashedPassword, err := u.Hasher.Hash(req.Password)
 	if err != nil {
-		return fmt.Errorf("failed to hash password: %w", err)
+		return err
 	}
 	result, err := u.Db.ExecContext(ctx, query, hashedPassword, req.Id)
 
@@ -352,18 +542,18 @@ func (u *UserRepository) ResetPassword(ctx context.Context, req *pb.ResetPasswor
 		}
 		return err
 	}
-	err = bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(req.Oldpassword))
+	ok, err := u.Hasher.Verify(passwordHash, req.Oldpassword)
 	if err != nil {
-		if err == bcrypt.ErrMismatchedHashAndPassword {
-			return fmt.Errorf("password is incorrect")
-		}
-		return err
+		return fmt.Errorf("failed to verify password: %w", err)
 	}
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Newpassword), bcrypt.DefaultCost)
+	if !ok {
+		return fmt.Errorf("password is incorrect")
+	}
+	hashedPassword, err := u.Hasher.Hash(req.Newpassword)
 	if err != nil {
-		return fmt.Errorf("failed to hash password: %w", err)
+		return err
 	}
-	query = `UPDATE users SET password_hash=$1 WHERE id=$2 AND deleted_at=0`
+	query = `UPDATE users SET password_hash=$1, password_reset_required=false WHERE id=$2 AND deleted_at=0`
 	result, err := u.Db.ExecContext(ctx, query, hashedPassword, req.Id)
 	if err != nil {
 		return err
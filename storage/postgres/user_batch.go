@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	pb "wegugin/genproto/user"
+
+	"github.com/lib/pq"
+)
+
+// userFields maps GetUserResponse fields to the columns that fill them.
+var userFields = map[string]struct {
+	column string
+	dest   func(*pb.GetUserResponse) interface{}
+}{
+	"id":           {"id", func(u *pb.GetUserResponse) interface{} { return &u.Id }},
+	"name":         {"COALESCE(name, '')", func(u *pb.GetUserResponse) interface{} { return &u.Name }},
+	"surname":      {"COALESCE(surname, '')", func(u *pb.GetUserResponse) interface{} { return &u.Surname }},
+	"email":        {"email", func(u *pb.GetUserResponse) interface{} { return &u.Email }},
+	"birth_date":   {"COALESCE(to_char(birth_date, 'YYYY-MM-DD'), '')", func(u *pb.GetUserResponse) interface{} { return &u.BirthDate }},
+	"gender":       {"COALESCE(gender, '')", func(u *pb.GetUserResponse) interface{} { return &u.Gender }},
+	"phone_number": {"phone_number", func(u *pb.GetUserResponse) interface{} { return &u.PhoneNumber }},
+	"address":      {"COALESCE(address, '')", func(u *pb.GetUserResponse) interface{} { return &u.Address }},
+	"photo":        {"COALESCE(photo, '')", func(u *pb.GetUserResponse) interface{} { return &u.Photo }},
+	"role":         {"role", func(u *pb.GetUserResponse) interface{} { return &u.Role }},
+	"createdAt":    {"created_at", func(u *pb.GetUserResponse) interface{} { return &u.CreatedAt }},
+}
+
+// userFieldOrder is every field, for requests that do not pick any.
+var userFieldOrder = []string{
+	"id", "name", "surname", "email", "birth_date", "gender", "phone_number", "address", "photo", "role", "createdAt",
+}
+
+func (u *UserRepository) GetUsersByIds(ctx context.Context, ids []string, fields []string) (map[string]*pb.GetUserResponse, map[string]*pb.PrivacySettings, error) {
+	if len(fields) == 0 {
+		fields = userFieldOrder
+	}
+	// The id keys the result, it is read even when not asked for
+	columns := []string{"id", "phone_visibility", "email_visibility"}
+	for _, field := range fields {
+		f, ok := userFields[field]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown user field %q", field)
+		}
+		columns = append(columns, f.column)
+	}
+
+	query := `SELECT ` + strings.Join(columns, ", ") + ` FROM users WHERE id = ANY($1::uuid[]) AND deleted_at = 0`
+	rows, err := u.Db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get users: %w", err)
+	}
+	defer rows.Close()
+
+	users := make(map[string]*pb.GetUserResponse, len(ids))
+	privacy := make(map[string]*pb.PrivacySettings, len(ids))
+	for rows.Next() {
+		var id string
+		user := &pb.GetUserResponse{}
+		settings := &pb.PrivacySettings{}
+		dest := []interface{}{&id, &settings.PhoneNumber, &settings.Email}
+		for _, field := range fields {
+			dest = append(dest, userFields[field].dest(user))
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		settings.UserId = id
+		users[id] = user
+		privacy[id] = settings
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	return users, privacy, nil
+}
@@ -0,0 +1,111 @@
+package redis
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+	"wegugin/config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Client is the Redis connection shared by the whole service, together with
+// the key builder of its namespace.
+type Client struct {
+	redis.UniversalClient
+	Keys Keys
+}
+
+// New connects to Redis in the mode set in conf. The connection pool is
+// created here, so one Client should be made and reused.
+func New(conf config.RedisConfig) (*Client, error) {
+	opts := &redis.UniversalOptions{
+		Addrs:        strings.Split(conf.RDB_ADDRESS, ","),
+		MasterName:   conf.RDB_MASTER_NAME,
+		Username:     conf.RDB_USERNAME,
+		Password:     conf.RDB_PASSWORD,
+		DB:           conf.RDB_DB,
+		PoolSize:     conf.RDB_POOL_SIZE,
+		MinIdleConns: conf.RDB_MIN_IDLE_CONNS,
+		DialTimeout:  conf.RDB_DIAL_TIMEOUT,
+		ReadTimeout:  conf.RDB_READ_TIMEOUT,
+		WriteTimeout: conf.RDB_WRITE_TIMEOUT,
+	}
+	for i, addr := range opts.Addrs {
+		opts.Addrs[i] = strings.TrimSpace(addr)
+	}
+	if conf.RDB_TLS {
+		tlsConfig, err := tlsConfig(conf.RDB_TLS_CA_FILE)
+		if err != nil {
+			return nil, err
+		}
+		opts.TLSConfig = tlsConfig
+	}
+
+	var rdb redis.UniversalClient
+	switch conf.RDB_MODE {
+	case "", "single":
+		rdb = redis.NewClient(opts.Simple())
+	case "sentinel":
+		if opts.MasterName == "" {
+			return nil, fmt.Errorf("redis sentinel mode needs a master name")
+		}
+		rdb = redis.NewFailoverClient(opts.Failover())
+	case "cluster":
+		rdb = redis.NewClusterClient(opts.Cluster())
+	default:
+		return nil, fmt.Errorf("unknown redis mode %q", conf.RDB_MODE)
+	}
+	return &Client{UniversalClient: rdb, Keys: Keys{Namespace: conf.RDB_NAMESPACE}}, nil
+}
+
+func tlsConfig(caFile string) (*tls.Config, error) {
+	conf := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile == "" {
+		return conf, nil
+	}
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read redis CA file: %w", err)
+	}
+	conf.RootCAs = x509.NewCertPool()
+	if !conf.RootCAs.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates in redis CA file %s", caFile)
+	}
+	return conf, nil
+}
+
+// Health pings Redis, every master node in cluster mode.
+func (c *Client) Health(ctx context.Context) error {
+	var err error
+	if cluster, ok := c.UniversalClient.(*redis.ClusterClient); ok {
+		err = cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			return node.Ping(ctx).Err()
+		})
+	} else {
+		err = c.Ping(ctx).Err()
+	}
+	if err != nil {
+		return fmt.Errorf("redis is unavailable: %w", err)
+	}
+	return nil
+}
+
+// Keys builds the keys of the service as namespace:purpose:parts.
+type Keys struct {
+	Namespace string
+}
+
+func (k Keys) Key(purpose string, parts ...string) string {
+	key := purpose
+	if k.Namespace != "" {
+		key = k.Namespace + ":" + key
+	}
+	for _, part := range parts {
+		key += ":" + part
+	}
+	return key
+}
@@ -0,0 +1,49 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+func (c *Client) emailChangeKey(userId string) string {
+	return c.Keys.Key("email-change", userId)
+}
+
+// takeIfEqual deletes KEYS[1] only when it holds ARGV[1].
+var takeIfEqual = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// StoreEmailChange records the user's pending email change, replacing an
+// earlier one so only the latest confirmation link works.
+func (c *Client) StoreEmailChange(ctx context.Context, userId, changeId string, ttl time.Duration) error {
+	err := c.Set(ctx, c.emailChangeKey(userId), changeId, ttl).Err()
+	if err != nil {
+		return errors.Wrap(err, "failed to store email change in Redis")
+	}
+	return nil
+}
+
+// TakeEmailChange reports whether changeId is the user's pending email change
+// and ends it.
+func (c *Client) TakeEmailChange(ctx context.Context, userId, changeId string) (bool, error) {
+	n, err := takeIfEqual.Run(ctx, c, []string{c.emailChangeKey(userId)}, changeId).Int()
+	if err != nil {
+		return false, errors.Wrap(err, "failed to take email change from Redis")
+	}
+	return n > 0, nil
+}
+
+// CancelEmailChange drops the user's pending email change, if any.
+func (c *Client) CancelEmailChange(ctx context.Context, userId string) error {
+	if err := c.Del(ctx, c.emailChangeKey(userId)).Err(); err != nil {
+		return errors.Wrap(err, "failed to cancel email change in Redis")
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func (c *Client) magicLinkKey(id string) string {
+	return c.Keys.Key("magic-link", id)
+}
+
+// StoreMagicLink records a login link that was sent, see TakeMagicLink.
+func (c *Client) StoreMagicLink(ctx context.Context, id string, ttl time.Duration) error {
+	err := c.Set(ctx, c.magicLinkKey(id), "1", ttl).Err()
+	if err != nil {
+		return errors.Wrap(err, "failed to store magic link in Redis")
+	}
+	return nil
+}
+
+// TakeMagicLink reports whether the link is still unused and marks it used.
+func (c *Client) TakeMagicLink(ctx context.Context, id string) (bool, error) {
+	n, err := c.Del(ctx, c.magicLinkKey(id)).Result()
+	if err != nil {
+		return false, errors.Wrap(err, "failed to take magic link from Redis")
+	}
+	return n > 0, nil
+}
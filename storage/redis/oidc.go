@@ -0,0 +1,45 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+	"wegugin/model"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+func (c *Client) oidcStateKey(state string) string {
+	return c.Keys.Key("oidc", "state", state)
+}
+
+func (c *Client) StoreOIDCState(ctx context.Context, state string, value *model.OIDCState, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	err = c.Set(ctx, c.oidcStateKey(state), data, ttl).Err()
+	if err != nil {
+		return errors.Wrap(err, "failed to store oidc state in Redis")
+	}
+	return nil
+}
+
+// TakeOIDCState returns the state stored under state and removes it, so every
+// callback can be used only once.
+func (c *Client) TakeOIDCState(ctx context.Context, state string) (*model.OIDCState, error) {
+	data, err := c.GetDel(ctx, c.oidcStateKey(state)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, fmt.Errorf("unknown or expired oidc state")
+		}
+		return nil, errors.Wrap(err, "failed to get oidc state from Redis")
+	}
+	var value model.OIDCState
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
@@ -4,26 +4,18 @@ import (
 	"context"
 	"fmt"
 	"time"
-	"wegugin/config"
 
 	"github.com/pkg/errors"
 	"github.com/redis/go-redis/v9"
 )
 
-func ConnectDB() *redis.Client {
-	conf := config.Load()
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     conf.Redis.RDB_ADDRESS,
-		Password: conf.Redis.RDB_PASSWORD,
-		DB:       0,
-	})
-
-	return rdb
+func (c *Client) codeKey(email string) string {
+	return c.Keys.Key("reset-code", email)
 }
-func StoreCodes(ctx context.Context, code, email string) error {
-	rdb := ConnectDB()
 
-	err := rdb.Set(ctx, email, code, 10*time.Minute).Err()
+func (c *Client) StoreCodes(ctx context.Context, code, email string) error {
+
+	err := c.Set(ctx, c.codeKey(email), code, 10*time.Minute).Err()
 	if err != nil {
 		return errors.Wrap(err, "failed to set code in Redis")
 	}
@@ -31,9 +23,8 @@ func StoreCodes(ctx context.Context, code, email string) error {
 	return nil
 }
 
-func GetCodes(ctx context.Context, email string) (string, error) {
-	rdb := ConnectDB()
-	code, err := rdb.Get(ctx, email).Result()
+func (c *Client) GetCodes(ctx context.Context, email string) (string, error) {
+	code, err := c.Get(ctx, c.codeKey(email)).Result()
 	if err != nil {
 		if err == redis.Nil {
 			return "", fmt.Errorf("no code found for email: %s", email)
@@ -42,3 +33,118 @@ func GetCodes(ctx context.Context, email string) (string, error) {
 	}
 	return code, nil
 }
+
+func (c *Client) DeleteCodes(ctx context.Context, email string) error {
+	if err := c.Del(ctx, c.codeKey(email)).Err(); err != nil {
+		return errors.Wrap(err, "failed to delete code in Redis")
+	}
+	return nil
+}
+
+func (c *Client) suspendedKey(userId string) string {
+	return c.Keys.Key("suspended", userId)
+}
+
+// MarkSuspended lets the auth middleware reject tokens of a suspended user
+// without a database round trip. A zero until means the suspension has no expiry.
+func (c *Client) MarkSuspended(ctx context.Context, userId string, until time.Time) error {
+
+	var ttl time.Duration
+	if !until.IsZero() {
+		ttl = time.Until(until)
+		if ttl <= 0 {
+			return nil
+		}
+	}
+	err := c.Set(ctx, c.suspendedKey(userId), "1", ttl).Err()
+	if err != nil {
+		return errors.Wrap(err, "failed to mark user suspended in Redis")
+	}
+	return nil
+}
+
+func (c *Client) UnmarkSuspended(ctx context.Context, userId string) error {
+	err := c.Del(ctx, c.suspendedKey(userId)).Err()
+	if err != nil {
+		return errors.Wrap(err, "failed to unmark user suspended in Redis")
+	}
+	return nil
+}
+
+func (c *Client) IsSuspended(ctx context.Context, userId string) (bool, error) {
+	n, err := c.Exists(ctx, c.suspendedKey(userId)).Result()
+	if err != nil {
+		return false, errors.Wrap(err, "failed to check suspension in Redis")
+	}
+	return n > 0, nil
+}
+
+func (c *Client) revokedSessionKey(sessionId string) string {
+	return c.Keys.Key("session", "revoked", sessionId)
+}
+
+func (c *Client) sessionSeenKey(sessionId string) string {
+	return c.Keys.Key("session", "seen", sessionId)
+}
+
+// MarkSessionRevoked makes the auth middleware reject the session's token
+// until it would have expired anyway.
+func (c *Client) MarkSessionRevoked(ctx context.Context, sessionId string, until time.Time) error {
+	ttl := time.Until(until)
+	if ttl <= 0 {
+		return nil
+	}
+	err := c.Set(ctx, c.revokedSessionKey(sessionId), "1", ttl).Err()
+	if err != nil {
+		return errors.Wrap(err, "failed to mark session revoked in Redis")
+	}
+	return nil
+}
+
+func (c *Client) IsSessionRevoked(ctx context.Context, sessionId string) (bool, error) {
+	n, err := c.Exists(ctx, c.revokedSessionKey(sessionId)).Result()
+	if err != nil {
+		return false, errors.Wrap(err, "failed to check session in Redis")
+	}
+	return n > 0, nil
+}
+
+// TouchSession records that the session was just used. The auth middleware
+// calls it on every request, so it stays out of Postgres.
+func (c *Client) TouchSession(ctx context.Context, sessionId string, ttl time.Duration) error {
+	err := c.Set(ctx, c.sessionSeenKey(sessionId), time.Now().Unix(), ttl).Err()
+	if err != nil {
+		return errors.Wrap(err, "failed to touch session in Redis")
+	}
+	return nil
+}
+
+// SessionsLastSeen returns the last use recorded by TouchSession for each
+// session that has one.
+func (c *Client) SessionsLastSeen(ctx context.Context, sessionIds []string) (map[string]time.Time, error) {
+	seen := make(map[string]time.Time)
+	if len(sessionIds) == 0 {
+		return seen, nil
+	}
+	keys := make([]string, len(sessionIds))
+	for i, id := range sessionIds {
+		keys[i] = c.sessionSeenKey(id)
+	}
+
+	// One GET per key rather than MGET, the keys may live on different
+	// cluster nodes
+	pipe := c.Pipeline()
+	values := make([]*redis.StringCmd, len(keys))
+	for i, key := range keys {
+		values[i] = pipe.Get(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, errors.Wrap(err, "failed to get session activity from Redis")
+	}
+	for i, value := range values {
+		if unix, err := value.Int64(); err == nil {
+			seen[sessionIds[i]] = time.Unix(unix, 0)
+		}
+	}
+	return seen, nil
+}
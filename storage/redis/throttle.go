@@ -0,0 +1,77 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+// AddFailure records a failed attempt in the sorted set at key and returns
+// how many attempts fall into the sliding window ending now.
+func (c *Client) AddFailure(ctx context.Context, key string, window time.Duration) (int64, error) {
+	now := time.Now()
+
+	pipe := c.TxPipeline()
+	pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.Add(-window).UnixNano(), 10))
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(now.UnixNano()), Member: fmt.Sprintf("%d", now.UnixNano())})
+	count := pipe.ZCard(ctx, key)
+	pipe.Expire(ctx, key, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, errors.Wrap(err, "failed to record attempt in Redis")
+	}
+	return count.Val(), nil
+}
+
+// Failures returns the number of attempts in the sliding window and when the
+// last one happened.
+func (c *Client) Failures(ctx context.Context, key string, window time.Duration) (int64, time.Time, error) {
+	now := time.Now()
+
+	pipe := c.TxPipeline()
+	pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.Add(-window).UnixNano(), 10))
+	count := pipe.ZCard(ctx, key)
+	last := pipe.ZRangeWithScores(ctx, key, -1, -1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, time.Time{}, errors.Wrap(err, "failed to get attempts from Redis")
+	}
+
+	var lastAt time.Time
+	if z := last.Val(); len(z) > 0 {
+		lastAt = time.Unix(0, int64(z[0].Score))
+	}
+	return count.Val(), lastAt, nil
+}
+
+func (c *Client) Lock(ctx context.Context, key string, ttl time.Duration) error {
+	if err := c.Set(ctx, key, "1", ttl).Err(); err != nil {
+		return errors.Wrap(err, "failed to set lock in Redis")
+	}
+	return nil
+}
+
+// LockTTL returns how long the lock at key still holds, zero when there is none.
+func (c *Client) LockTTL(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := c.PTTL(ctx, key).Result()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to check lock in Redis")
+	}
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+func (c *Client) Delete(ctx context.Context, keys ...string) error {
+	pipe := c.Pipeline()
+	for _, key := range keys {
+		pipe.Del(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return errors.Wrap(err, "failed to delete keys in Redis")
+	}
+	return nil
+}
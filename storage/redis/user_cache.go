@@ -0,0 +1,94 @@
+package redis
+
+import (
+	"context"
+	"time"
+	pb "wegugin/genproto/user"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/protobuf/proto"
+)
+
+// The profile and its generation share a hash tag so the scripts below can
+// touch both in cluster mode.
+func (c *Client) userCacheKey(id string) string {
+	return c.Keys.Key("user", "profile", "{"+id+"}")
+}
+
+func (c *Client) userGenerationKey(id string) string {
+	return c.Keys.Key("user", "profile-gen", "{"+id+"}")
+}
+
+// setIfGeneration caches ARGV[2] in KEYS[2] only while the generation in
+// KEYS[1] is still ARGV[1].
+var setIfGeneration = redis.NewScript(`
+local gen = redis.call("GET", KEYS[1]) or ""
+if gen ~= ARGV[1] then
+	return 0
+end
+redis.call("SET", KEYS[2], ARGV[2], "PX", ARGV[3])
+return 1
+`)
+
+// bumpGeneration moves the generation in KEYS[1] on, keeping it for ARGV[1]
+// milliseconds, and drops the profile in KEYS[2].
+var bumpGeneration = redis.NewScript(`
+redis.call("INCR", KEYS[1])
+redis.call("PEXPIRE", KEYS[1], ARGV[1])
+return redis.call("DEL", KEYS[2])
+`)
+
+// UserCacheGeneration returns the generation of the user's cached profile,
+// to be handed to CacheUser once the profile is loaded.
+func (c *Client) UserCacheGeneration(ctx context.Context, id string) (string, error) {
+	gen, err := c.Get(ctx, c.userGenerationKey(id)).Result()
+	if err != nil && err != redis.Nil {
+		return "", errors.Wrap(err, "failed to get user cache generation from Redis")
+	}
+	return gen, nil
+}
+
+// GetCachedUser returns the cached profile of the user, or nil when it is not
+// cached.
+func (c *Client) GetCachedUser(ctx context.Context, id string) (*pb.GetUserResponse, error) {
+	data, err := c.Get(ctx, c.userCacheKey(id)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to get cached user from Redis")
+	}
+	var user pb.GetUserResponse
+	if err := proto.Unmarshal(data, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// CacheUser caches the profile unless it was invalidated since gen was read,
+// in which case the profile may be older than the change and is dropped.
+func (c *Client) CacheUser(ctx context.Context, user *pb.GetUserResponse, gen string, ttl time.Duration) error {
+	data, err := proto.Marshal(user)
+	if err != nil {
+		return err
+	}
+	keys := []string{c.userGenerationKey(user.Id), c.userCacheKey(user.Id)}
+	err = setIfGeneration.Run(ctx, c, keys, gen, data, ttl.Milliseconds()).Err()
+	if err != nil {
+		return errors.Wrap(err, "failed to cache user in Redis")
+	}
+	return nil
+}
+
+// DeleteCachedUser drops the cached profile and moves its generation on, so
+// loads that started before are not cached. The generation is kept for ttl,
+// which must outlast any load.
+func (c *Client) DeleteCachedUser(ctx context.Context, id string, ttl time.Duration) error {
+	keys := []string{c.userGenerationKey(id), c.userCacheKey(id)}
+	err := bumpGeneration.Run(ctx, c, keys, ttl.Milliseconds()).Err()
+	if err != nil {
+		return errors.Wrap(err, "failed to delete cached user from Redis")
+	}
+	return nil
+}
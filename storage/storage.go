@@ -2,23 +2,206 @@ package storage
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"time"
 	pb "wegugin/genproto/user"
+	"wegugin/model"
 )
 
+// ErrUserSuspended is returned by Login for users with an active suspension.
+var ErrUserSuspended = errors.New("user is suspended")
+
+// ErrInvalidCredentials is returned by Login for an unknown login as well as
+// a wrong password, so callers cannot tell accounts apart.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// ErrPasswordResetRequired is returned by Login for users who must reset
+// their password, whether or not the password given was right.
+var ErrPasswordResetRequired = errors.New("password reset required")
+
+// ErrIdentityTaken is returned when a provider account is already linked to
+// another user, or the user already linked an account of that provider.
+var ErrIdentityTaken = errors.New("identity is already linked")
+
+// ErrEmailTaken is returned by ChangeEmail when another active user has the
+// new email.
+var ErrEmailTaken = errors.New("email is already used")
+
+// ErrExportLimited is returned when a data export is requested while another
+// one is in progress or within the cooldown.
+var ErrExportLimited = errors.New("data export limit reached")
+
+// ErrExportLeaseLost is returned when a builder finishes an export that
+// another builder took over after the lease ran out.
+var ErrExportLeaseLost = errors.New("export was taken over by another builder")
+
 type IStorage interface {
 	User() IUserStorage
+	SavedSearch() ISavedSearchStorage
+	Car() ICarStorage
+	Suspension() ISuspensionStorage
+	Export() IExportStorage
+	Audit() IAuditStorage
+	Session() ISessionStorage
+	Notification() INotificationStorage
+	Identity() IIdentityStorage
 	Close()
 }
 
 type IUserStorage interface {
 	CreateUser(context.Context, *pb.RegisterReq) (*pb.LoginRes, error)
+	// CreateUserWithRole is CreateUser for a role other than "user".
+	CreateUserWithRole(ctx context.Context, req *pb.RegisterReq, role string) (*pb.LoginRes, error)
 	Login(context.Context, *pb.LoginReq) (*pb.LoginRes, error)
+	// LoginById logs the user in without a password, for logins proven
+	// another way. Like Login it refuses suspended users and restores
+	// deleted ones within the restore window.
+	LoginById(ctx context.Context, id string) (*pb.LoginRes, error)
+	// UserIdByLogin resolves an email or phone number to a user id,
+	// preferring active users over deleted ones.
+	UserIdByLogin(ctx context.Context, login string) (string, error)
+	// RecentPasswordHashes returns the hashes of the user's current and
+	// previous passwords, n at most, newest first.
+	RecentPasswordHashes(ctx context.Context, userId string, n int) ([]string, error)
+	// CheckPassword returns ErrInvalidCredentials when password is not the
+	// user's current one.
+	CheckPassword(ctx context.Context, userId, password string) error
+	// ChangeEmail moves the user's email from one address to another. It
+	// fails with sql.ErrNoRows when the email is not from anymore.
+	ChangeEmail(ctx context.Context, userId, from, to string) error
 	GetUserByEmail(context.Context, *pb.GetUSerByEmailReq) (*pb.GetUserResponse, error)
 	GetUserById(context.Context, *pb.UserId) (*pb.GetUserResponse, error)
+	// GetUsersByIds returns the active users among ids by id, with only the
+	// GetUserResponse fields named in fields filled in, or all when empty,
+	// and their privacy settings. Hiding fields is up to the caller.
+	GetUsersByIds(ctx context.Context, ids []string, fields []string) (map[string]*pb.GetUserResponse, map[string]*pb.PrivacySettings, error)
+	// GetPublicProfile returns the seller card of an active user together
+	// with the user's privacy settings. The phone number and email are
+	// always filled in, hiding them is up to the caller.
+	GetPublicProfile(ctx context.Context, id string) (*pb.PublicProfile, *pb.PrivacySettings, error)
+	GetPrivacySettings(context.Context, *pb.UserId) (*pb.PrivacySettings, error)
+	UpdatePrivacySettings(context.Context, *pb.PrivacySettings) error
 	UpdatePassword(context.Context, *pb.UpdatePasswordReq) error
 	UpdateUser(context.Context, *pb.UpdateUserRequest) error
 	DeleteUser(context.Context, *pb.UserId) error
 	ResetPassword(context.Context, *pb.ResetPasswordReq) error
 	IsUserExist(context.Context, *pb.UserId) error
 	DeleteMediaUser(context.Context, *pb.UserId) error
+	ListUsers(context.Context, *pb.AdminListUsersReq) (*pb.AdminUserList, error)
+	GetUserByIdWithDeleted(context.Context, *pb.UserId) (*pb.AdminUser, error)
+	UpdateRole(context.Context, *pb.UpdateRoleReq) error
+	RequirePasswordReset(context.Context, *pb.UserId) error
+	RestoreUser(context.Context, *pb.UserId) error
+	HardDeleteUser(context.Context, *pb.UserId) error
+	// RestoreDeletedUser undoes DeleteUser if deletedAt is still the user's
+	// deleted_at and within the restore window. It returns the user's role.
+	RestoreDeletedUser(ctx context.Context, id string, deletedAt int64) (string, error)
+	// PurgeCandidates returns users deleted before deletedBefore that were not purged yet.
+	PurgeCandidates(ctx context.Context, deletedBefore int64) ([]*pb.AdminUser, error)
+	PurgeUser(ctx context.Context, id string, anonymize bool) error
+	// PurgeFiles returns the MinIO objects of the user that PurgeUser leaves
+	// behind: the file names of their car images and their export archives.
+	PurgeFiles(ctx context.Context, id string) (images, exports []string, err error)
+}
+
+type ISavedSearchStorage interface {
+	CreateSavedSearch(context.Context, *pb.CreateSavedSearchReq) (*pb.SavedSearch, error)
+	GetSavedSearch(context.Context, *pb.SavedSearchId) (*pb.SavedSearch, error)
+	ListSavedSearches(context.Context, *pb.UserId) (*pb.SavedSearchList, error)
+	UpdateSavedSearch(context.Context, *pb.UpdateSavedSearchReq) error
+	DeleteSavedSearch(context.Context, *pb.SavedSearchId) error
+	// DueSavedSearches returns the active searches that should be evaluated at the given time.
+	DueSavedSearches(context.Context, time.Time) ([]*pb.SavedSearch, error)
+	// CheckCutoff returns the database's time less lag. Listings are stamped
+	// with the start of the transaction writing them, so a check only goes up
+	// to where every such transaction is expected to have committed.
+	CheckCutoff(ctx context.Context, lag time.Duration) (time.Time, error)
+	// MatchCars returns cars changed between the search's last check and until that
+	// match its filter and were not announced for it within the dedup window.
+	// Hidden cars never match.
+	MatchCars(ctx context.Context, search *pb.SavedSearch, until time.Time, dedup time.Duration) ([]*model.CarMatch, error)
+	// MarkChecked records the announced cars and moves the search's last check to checkedAt.
+	MarkChecked(ctx context.Context, searchId string, carIds []string, checkedAt time.Time) error
+}
+
+type ICarStorage interface {
+	GetPriceHistory(context.Context, *pb.CarId) (*pb.PriceHistory, error)
+	// PendingPriceChanges returns the recorded price changes nobody was alerted about yet.
+	PendingPriceChanges(context.Context) ([]*model.PriceChange, error)
+	// PriceWatchers returns the users who saved the car, except its owner, or
+	// nobody while the car is hidden.
+	PriceWatchers(ctx context.Context, carId string) ([]string, error)
+	// NotifyPriceDrop creates the user's notification of the price change,
+	// unless they got one for it already.
+	NotifyPriceDrop(ctx context.Context, changeId int64, notification *model.Notification) error
+	MarkPriceChangesAlerted(ctx context.Context, ids []int64) error
+}
+
+type ISuspensionStorage interface {
+	SuspendUser(context.Context, *pb.SuspendUserReq) (*pb.Suspension, error)
+	LiftSuspension(context.Context, *pb.LiftSuspensionReq) error
+	ListSuspensions(context.Context, *pb.UserId) (*pb.SuspensionList, error)
+	ActiveSuspensions(context.Context) ([]*pb.Suspension, error)
+	// IsSuspended reports whether the user has a suspension in force. The
+	// auth checks ask it when the Redis markers are unavailable.
+	IsSuspended(ctx context.Context, userId string) (bool, error)
+	// SyncHiddenCars hides the listings of suspended users and shows them
+	// again once the suspension was lifted or expired.
+	SyncHiddenCars(context.Context) error
+}
+
+type IExportStorage interface {
+	// CreateExport queues a new export unless the user has one in progress
+	// or requested one within the cooldown.
+	CreateExport(ctx context.Context, userId string, cooldown time.Duration) (*pb.DataExport, error)
+	LatestExport(context.Context, *pb.UserId) (*pb.DataExport, error)
+	// ClaimPendingExport marks the oldest pending export as running and
+	// returns it with the time of the claim, or nil when there is nothing to
+	// do. A running export claimed more than lease ago is claimed again, as
+	// its builder stopped without finishing it.
+	ClaimPendingExport(ctx context.Context, lease time.Duration) (*pb.DataExport, time.Time, error)
+	// CompleteExport and FailExport finish the export claimed at claimedAt,
+	// or return ErrExportLeaseLost when it was claimed again since.
+	CompleteExport(ctx context.Context, id string, claimedAt time.Time, objectName string, expiresAt time.Time) error
+	FailExport(ctx context.Context, id string, claimedAt time.Time, reason string) error
+	// ExpireExports marks ready exports past expires_at as expired and
+	// returns the names of their archives.
+	ExpireExports(context.Context) ([]string, error)
+	// CollectUserData returns everything stored about the user, keyed by table.
+	CollectUserData(ctx context.Context, userId string) (map[string]json.RawMessage, error)
+}
+
+type IAuditStorage interface {
+	RecordEvent(context.Context, *model.AuditEvent) error
+	ListAuditEvents(context.Context, *pb.AuditEventFilter) (*pb.AuditEventList, error)
+}
+
+type ISessionStorage interface {
+	CreateSession(context.Context, *model.Session) (string, error)
+	// ListSessions returns the user's sessions that are neither revoked nor expired.
+	ListSessions(ctx context.Context, userId string) ([]*pb.Session, error)
+	// RevokeSession ends one of the user's sessions and returns when its
+	// token expires.
+	RevokeSession(context.Context, *pb.SessionReq) (time.Time, error)
+	// RevokeOtherSessions ends all of the user's sessions except keepId, which
+	// may be empty, and returns when the tokens of the ended ones expire.
+	RevokeOtherSessions(ctx context.Context, userId, keepId string) (map[string]time.Time, error)
+	// IsSessionRevoked reports whether a session was revoked, or is gone.
+	// The auth middleware asks it when the Redis markers are unavailable.
+	IsSessionRevoked(ctx context.Context, sessionId string) (bool, error)
+}
+
+type INotificationStorage interface {
+	CreateNotification(context.Context, *model.Notification) error
+}
+
+type IIdentityStorage interface {
+	// LinkIdentity returns ErrIdentityTaken when the provider account or the
+	// user's account of that provider is linked already.
+	LinkIdentity(context.Context, *model.Identity) error
+	// IdentityUser returns the user the provider account is linked to.
+	IdentityUser(ctx context.Context, provider, subject string) (string, error)
+	ListIdentities(context.Context, *pb.UserId) (*pb.IdentityList, error)
+	UnlinkIdentity(context.Context, *pb.IdentityReq) error
 }
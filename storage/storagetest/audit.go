@@ -0,0 +1,71 @@
+package storagetest
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+	pb "wegugin/genproto/user"
+	"wegugin/model"
+	"wegugin/storage"
+
+	"github.com/google/uuid"
+)
+
+func testAudit(t *testing.T, open func(t *testing.T) storage.IStorage) {
+	s := open(t)
+	// The trail has no foreign keys, it outlives purged users
+	actor, target := uuid.NewString(), uuid.NewString()
+
+	err := s.Audit().RecordEvent(ctx, &model.AuditEvent{
+		ActorId:  actor,
+		TargetId: target,
+		Action:   model.AuditProfileUpdate,
+		Changes:  map[string]model.Change{"name": {Old: "Old", New: "New"}},
+		IP:       "127.0.0.1",
+	})
+	if err != nil {
+		t.Fatalf("RecordEvent: %v", err)
+	}
+	if err := s.Audit().RecordEvent(ctx, &model.AuditEvent{TargetId: target, Action: model.AuditLoginFailure}); err != nil {
+		t.Fatalf("RecordEvent: %v", err)
+	}
+
+	list, err := s.Audit().ListAuditEvents(ctx, &pb.AuditEventFilter{TargetId: target})
+	if err != nil {
+		t.Fatalf("ListAuditEvents: %v", err)
+	}
+	if list.Total != 2 || len(list.Events) != 2 {
+		t.Fatalf("ListAuditEvents found %d of %d events, want 2", len(list.Events), list.Total)
+	}
+
+	list, err = s.Audit().ListAuditEvents(ctx, &pb.AuditEventFilter{ActorId: actor})
+	if err != nil || len(list.Events) != 1 {
+		t.Fatalf("ListAuditEvents(actor) = %v, %v", list, err)
+	}
+	event := list.Events[0]
+	if event.TargetId != target || event.Action != model.AuditProfileUpdate || event.Ip != "127.0.0.1" || event.ActorId != actor {
+		t.Errorf("event = %v", event)
+	}
+	var changes map[string]model.Change
+	if err := json.Unmarshal([]byte(event.Changes), &changes); err != nil || changes["name"].New != "New" {
+		t.Errorf("changes = %q, %v", event.Changes, err)
+	}
+
+	list, err = s.Audit().ListAuditEvents(ctx, &pb.AuditEventFilter{TargetId: target, Action: model.AuditLoginFailure})
+	if err != nil || list.Total != 1 || list.Events[0].ActorId != "" {
+		t.Errorf("ListAuditEvents(action) = %v, %v", list, err)
+	}
+
+	tomorrow := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+	list, err = s.Audit().ListAuditEvents(ctx, &pb.AuditEventFilter{TargetId: target, From: tomorrow})
+	if err != nil || list.Total != 0 {
+		t.Errorf("ListAuditEvents(from tomorrow) = %v, %v", list, err)
+	}
+	list, err = s.Audit().ListAuditEvents(ctx, &pb.AuditEventFilter{TargetId: target, To: tomorrow, Limit: 1})
+	if err != nil || list.Total != 2 || len(list.Events) != 1 {
+		t.Errorf("ListAuditEvents(limit 1) = %v, %v", list, err)
+	}
+	if _, err := s.Audit().ListAuditEvents(ctx, &pb.AuditEventFilter{From: "yesterday"}); err == nil {
+		t.Error("ListAuditEvents accepted a malformed date")
+	}
+}
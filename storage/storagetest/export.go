@@ -0,0 +1,181 @@
+package storagetest
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+	pb "wegugin/genproto/user"
+	"wegugin/storage"
+)
+
+// Exports are only checked by id: ClaimPendingExport would take exports of
+// other users in a shared database, claim looks for the one it wants.
+func testExports(t *testing.T, open func(t *testing.T) storage.IStorage) {
+	s := open(t)
+	userId, req := newUser(t, s)
+
+	if _, err := s.Export().LatestExport(ctx, &pb.UserId{Id: userId}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("LatestExport without exports = %v, want sql.ErrNoRows", err)
+	}
+
+	first, err := s.Export().CreateExport(ctx, userId, time.Hour)
+	if err != nil {
+		t.Fatalf("CreateExport: %v", err)
+	}
+	if first.Status != "pending" || first.UserId != userId {
+		t.Errorf("CreateExport = %v", first)
+	}
+	if _, err := s.Export().CreateExport(ctx, userId, 0); !errors.Is(err, storage.ErrExportLimited) {
+		t.Errorf("CreateExport while one is pending = %v, want ErrExportLimited", err)
+	}
+
+	// Failed exports neither block nor count for the cooldown
+	claimedAt, ok := claim(t, s, time.Hour, first.Id)
+	if !ok {
+		t.Fatal("ClaimPendingExport did not return the pending export")
+	}
+	if err := s.Export().FailExport(ctx, first.Id, claimedAt, "disk full"); err != nil {
+		t.Fatalf("FailExport: %v", err)
+	}
+	latest, err := s.Export().LatestExport(ctx, &pb.UserId{Id: userId})
+	if err != nil || latest.Id != first.Id || latest.Status != "failed" || latest.Error != "disk full" || latest.CompletedAt == "" {
+		t.Errorf("LatestExport after failure = %v, %v", latest, err)
+	}
+	second, err := s.Export().CreateExport(ctx, userId, time.Hour)
+	if err != nil {
+		t.Fatalf("CreateExport after a failure: %v", err)
+	}
+
+	object := "storagetest/" + second.Id + ".zip"
+	claimedAt, ok = claim(t, s, time.Hour, second.Id)
+	if !ok {
+		t.Fatal("ClaimPendingExport did not return the pending export")
+	}
+	if err := s.Export().CompleteExport(ctx, second.Id, claimedAt, object, time.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("CompleteExport: %v", err)
+	}
+	latest, err = s.Export().LatestExport(ctx, &pb.UserId{Id: userId})
+	if err != nil || latest.Id != second.Id || latest.Status != "ready" || latest.ExpiresAt == "" {
+		t.Errorf("LatestExport after completion = %v, %v", latest, err)
+	}
+	if _, err := s.Export().CreateExport(ctx, userId, time.Hour); !errors.Is(err, storage.ErrExportLimited) {
+		t.Errorf("CreateExport within the cooldown = %v, want ErrExportLimited", err)
+	}
+
+	expired, err := s.Export().ExpireExports(ctx)
+	if err != nil {
+		t.Fatalf("ExpireExports: %v", err)
+	}
+	found := false
+	for _, name := range expired {
+		found = found || name == object
+	}
+	if !found {
+		t.Errorf("ExpireExports returned %v, want %s among them", expired, object)
+	}
+	if latest, _ := s.Export().LatestExport(ctx, &pb.UserId{Id: userId}); latest == nil || latest.Status != "expired" {
+		t.Errorf("LatestExport after expiry = %v", latest)
+	}
+	if _, err := s.Export().CreateExport(ctx, userId, 0); err != nil {
+		t.Errorf("CreateExport without cooldown: %v", err)
+	}
+
+	t.Run("Claim", func(t *testing.T) {
+		userId, _ := newUser(t, s)
+		created, err := s.Export().CreateExport(ctx, userId, 0)
+		if err != nil {
+			t.Fatalf("CreateExport: %v", err)
+		}
+		stale, ok := claim(t, s, time.Hour, created.Id)
+		if !ok {
+			t.Fatal("ClaimPendingExport did not return the pending export")
+		}
+		if _, ok := claim(t, s, time.Hour, created.Id); ok {
+			t.Error("ClaimPendingExport returned an export that is running within its lease")
+		}
+
+		// The builder went away: past the lease the export is claimed again
+		time.Sleep(20 * time.Millisecond)
+		claimedAt, ok := claim(t, s, 10*time.Millisecond, created.Id)
+		if !ok {
+			t.Fatal("ClaimPendingExport did not take back an export running past its lease")
+		}
+		// and the first builder, slow rather than gone, can not finish it
+		object := "storagetest/" + created.Id + ".zip"
+		if err := s.Export().CompleteExport(ctx, created.Id, stale, object, time.Now().Add(time.Hour)); !errors.Is(err, storage.ErrExportLeaseLost) {
+			t.Errorf("CompleteExport of a lost lease = %v, want ErrExportLeaseLost", err)
+		}
+		if err := s.Export().FailExport(ctx, created.Id, stale, "too slow"); !errors.Is(err, storage.ErrExportLeaseLost) {
+			t.Errorf("FailExport of a lost lease = %v, want ErrExportLeaseLost", err)
+		}
+		if err := s.Export().CompleteExport(ctx, created.Id, claimedAt, object, time.Now().Add(time.Hour)); err != nil {
+			t.Fatalf("CompleteExport: %v", err)
+		}
+		if err := s.Export().FailExport(ctx, created.Id, claimedAt, "again"); !errors.Is(err, storage.ErrExportLeaseLost) {
+			t.Errorf("FailExport of a ready export = %v, want ErrExportLeaseLost", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+		if _, ok := claim(t, s, 10*time.Millisecond, created.Id); ok {
+			t.Error("ClaimPendingExport returned a ready export")
+		}
+	})
+
+	t.Run("CollectUserData", func(t *testing.T) {
+		if _, err := s.SavedSearch().CreateSavedSearch(ctx, &pb.CreateSavedSearchReq{UserId: userId, Name: "mine"}); err != nil {
+			t.Fatalf("CreateSavedSearch: %v", err)
+		}
+		data, err := s.Export().CollectUserData(ctx, userId)
+		if err != nil {
+			t.Fatalf("CollectUserData: %v", err)
+		}
+		for _, name := range []string{"profile", "cars", "images", "comments", "messages", "saved_cars",
+			"saved_searches", "notifications", "device_tokens", "linked_accounts"} {
+			if _, ok := data[name]; !ok {
+				t.Errorf("CollectUserData has no %s", name)
+			}
+		}
+
+		var profile map[string]interface{}
+		if err := json.Unmarshal(data["profile"], &profile); err != nil {
+			t.Fatalf("profile: %v", err)
+		}
+		if profile["email"] != req.Email {
+			t.Errorf("profile email = %v, want %s", profile["email"], req.Email)
+		}
+		if _, ok := profile["password_hash"]; ok {
+			t.Error("the profile contains the password hash")
+		}
+		var searches []map[string]interface{}
+		if err := json.Unmarshal(data["saved_searches"], &searches); err != nil {
+			t.Fatalf("saved_searches: %v", err)
+		}
+		if len(searches) != 1 || searches[0]["name"] != "mine" {
+			t.Errorf("saved_searches = %v", searches)
+		}
+	})
+}
+
+// claim claims exports until it gets the one with id, or there are none
+// left, and returns the time of its claim and whether it got it.
+func claim(t *testing.T, s storage.IStorage, lease time.Duration, id string) (time.Time, bool) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		claimed, claimedAt, err := s.Export().ClaimPendingExport(ctx, lease)
+		if err != nil {
+			t.Fatalf("ClaimPendingExport: %v", err)
+		}
+		if claimed == nil {
+			return time.Time{}, false
+		}
+		if claimed.Id == id {
+			if claimed.Status != "running" {
+				t.Errorf("claimed export has status %s, want running", claimed.Status)
+			}
+			return claimedAt, true
+		}
+	}
+	t.Fatal("ClaimPendingExport keeps returning other exports")
+	return time.Time{}, false
+}
@@ -0,0 +1,61 @@
+package storagetest
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	pb "wegugin/genproto/user"
+	"wegugin/model"
+	"wegugin/storage"
+)
+
+func testIdentities(t *testing.T, open func(t *testing.T) storage.IStorage) {
+	s := open(t)
+	userId, _ := newUser(t, s)
+	otherId, _ := newUser(t, s)
+	subject := marker()
+
+	err := s.Identity().LinkIdentity(ctx, &model.Identity{UserId: userId, Provider: "google", Subject: subject, Email: "a@gmail.com"})
+	if err != nil {
+		t.Fatalf("LinkIdentity: %v", err)
+	}
+	err = s.Identity().LinkIdentity(ctx, &model.Identity{UserId: otherId, Provider: "google", Subject: subject})
+	if !errors.Is(err, storage.ErrIdentityTaken) {
+		t.Errorf("linking a linked account = %v, want ErrIdentityTaken", err)
+	}
+	err = s.Identity().LinkIdentity(ctx, &model.Identity{UserId: userId, Provider: "google", Subject: marker()})
+	if !errors.Is(err, storage.ErrIdentityTaken) {
+		t.Errorf("linking a second account of a provider = %v, want ErrIdentityTaken", err)
+	}
+	// The same subject at another provider is another account
+	if err := s.Identity().LinkIdentity(ctx, &model.Identity{UserId: otherId, Provider: "kakao", Subject: subject}); err != nil {
+		t.Errorf("LinkIdentity at another provider: %v", err)
+	}
+
+	if got, err := s.Identity().IdentityUser(ctx, "google", subject); err != nil || got != userId {
+		t.Errorf("IdentityUser = %s, %v; want %s", got, err, userId)
+	}
+	if _, err := s.Identity().IdentityUser(ctx, "apple", subject); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("IdentityUser of an unlinked account = %v, want sql.ErrNoRows", err)
+	}
+
+	list, err := s.Identity().ListIdentities(ctx, &pb.UserId{Id: userId})
+	if err != nil || len(list.Identities) != 1 || list.Identities[0].Provider != "google" || list.Identities[0].Email != "a@gmail.com" {
+		t.Errorf("ListIdentities = %v, %v", list, err)
+	}
+
+	if err := s.Identity().UnlinkIdentity(ctx, &pb.IdentityReq{UserId: userId, Provider: "google"}); err != nil {
+		t.Fatalf("UnlinkIdentity: %v", err)
+	}
+	if err := s.Identity().UnlinkIdentity(ctx, &pb.IdentityReq{UserId: userId, Provider: "google"}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("unlinking twice = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := s.Identity().IdentityUser(ctx, "google", subject); err == nil {
+		t.Error("an unlinked account still finds the user")
+	}
+
+	// Unlinked, the account can go to another user
+	if err := s.Identity().LinkIdentity(ctx, &model.Identity{UserId: otherId, Provider: "google", Subject: subject}); err != nil {
+		t.Errorf("LinkIdentity after unlinking: %v", err)
+	}
+}
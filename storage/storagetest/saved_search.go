@@ -0,0 +1,171 @@
+package storagetest
+
+import (
+	"testing"
+	"time"
+	pb "wegugin/genproto/user"
+	"wegugin/storage"
+)
+
+func containsSearch(searches []*pb.SavedSearch, id string) bool {
+	for _, search := range searches {
+		if search.Id == id {
+			return true
+		}
+	}
+	return false
+}
+
+func testSavedSearches(t *testing.T, open func(t *testing.T) storage.IStorage) {
+	s := open(t)
+	userId, _ := newUser(t, s)
+	otherId, _ := newUser(t, s)
+
+	if _, err := s.SavedSearch().CreateSavedSearch(ctx, &pb.CreateSavedSearchReq{UserId: userId, Name: " "}); err == nil {
+		t.Error("created a saved search without a name")
+	}
+	_, err := s.SavedSearch().CreateSavedSearch(ctx, &pb.CreateSavedSearchReq{UserId: userId, Name: "x", Frequency: "hourly"})
+	if err == nil {
+		t.Error("created a saved search with an unknown frequency")
+	}
+
+	filter := &pb.CarFilter{Make: "Kia", PriceTo: 20000}
+	instant, err := s.SavedSearch().CreateSavedSearch(ctx, &pb.CreateSavedSearchReq{
+		UserId: userId, Name: "cheap kia", Filter: filter,
+	})
+	if err != nil {
+		t.Fatalf("CreateSavedSearch: %v", err)
+	}
+	if instant.Frequency != "instant" || !instant.Active || instant.Filter.Make != "Kia" || instant.Filter.PriceTo != 20000 {
+		t.Errorf("CreateSavedSearch = %v", instant)
+	}
+	daily, err := s.SavedSearch().CreateSavedSearch(ctx, &pb.CreateSavedSearchReq{
+		UserId: userId, Name: "daily", Frequency: "daily",
+	})
+	if err != nil {
+		t.Fatalf("CreateSavedSearch: %v", err)
+	}
+
+	got, err := s.SavedSearch().GetSavedSearch(ctx, &pb.SavedSearchId{Id: instant.Id, UserId: userId})
+	if err != nil || got.Name != "cheap kia" || got.Filter.Make != "Kia" {
+		t.Errorf("GetSavedSearch = %v, %v", got, err)
+	}
+	if _, err := s.SavedSearch().GetSavedSearch(ctx, &pb.SavedSearchId{Id: instant.Id, UserId: otherId}); err == nil {
+		t.Error("another user got the saved search")
+	}
+	list, err := s.SavedSearch().ListSavedSearches(ctx, &pb.UserId{Id: userId})
+	if err != nil || len(list.SavedSearches) != 2 {
+		t.Errorf("ListSavedSearches = %v, %v", list, err)
+	}
+
+	t.Run("Update", func(t *testing.T) {
+		req := &pb.UpdateSavedSearchReq{Id: instant.Id, UserId: userId}
+		if err := s.SavedSearch().UpdateSavedSearch(ctx, req); err == nil {
+			t.Error("UpdateSavedSearch without fields succeeded")
+		}
+		req.Frequency = "hourly"
+		if err := s.SavedSearch().UpdateSavedSearch(ctx, req); err == nil {
+			t.Error("UpdateSavedSearch accepted an unknown frequency")
+		}
+		req.Frequency = ""
+		req.Filter = &pb.CarFilter{Model: "K5"}
+		if err := s.SavedSearch().UpdateSavedSearch(ctx, req); err != nil {
+			t.Fatalf("UpdateSavedSearch: %v", err)
+		}
+		got, err := s.SavedSearch().GetSavedSearch(ctx, &pb.SavedSearchId{Id: instant.Id, UserId: userId})
+		if err != nil {
+			t.Fatalf("GetSavedSearch: %v", err)
+		}
+		// A new filter replaces the old one as a whole
+		if got.Filter.Model != "K5" || got.Filter.Make != "" || got.Filter.PriceTo != 0 || got.Name != "cheap kia" {
+			t.Errorf("after UpdateSavedSearch got %v", got)
+		}
+		req = &pb.UpdateSavedSearchReq{Id: instant.Id, UserId: otherId, Name: "stolen"}
+		if err := s.SavedSearch().UpdateSavedSearch(ctx, req); err == nil {
+			t.Error("another user updated the saved search")
+		}
+	})
+
+	t.Run("Due", func(t *testing.T) {
+		now, err := s.SavedSearch().CheckCutoff(ctx, 0)
+		if err != nil {
+			t.Fatalf("CheckCutoff: %v", err)
+		}
+		behind, err := s.SavedSearch().CheckCutoff(ctx, time.Hour)
+		if err != nil {
+			t.Fatalf("CheckCutoff: %v", err)
+		}
+		if lag := now.Sub(behind); lag < 59*time.Minute || lag > 61*time.Minute {
+			t.Errorf("CheckCutoff with an hour of lag is %s behind, want an hour", lag)
+		}
+		due, err := s.SavedSearch().DueSavedSearches(ctx, now)
+		if err != nil {
+			t.Fatalf("DueSavedSearches: %v", err)
+		}
+		if !containsSearch(due, instant.Id) || containsSearch(due, daily.Id) {
+			t.Errorf("due now: instant %v, daily %v; want only instant",
+				containsSearch(due, instant.Id), containsSearch(due, daily.Id))
+		}
+		due, err = s.SavedSearch().DueSavedSearches(ctx, now.Add(25*time.Hour))
+		if err != nil {
+			t.Fatalf("DueSavedSearches: %v", err)
+		}
+		if !containsSearch(due, daily.Id) {
+			t.Error("the daily search is not due a day later")
+		}
+		if err := s.SavedSearch().MarkChecked(ctx, daily.Id, nil, now.Add(25*time.Hour)); err != nil {
+			t.Fatalf("MarkChecked: %v", err)
+		}
+		due, err = s.SavedSearch().DueSavedSearches(ctx, now.Add(26*time.Hour))
+		if err != nil {
+			t.Fatalf("DueSavedSearches: %v", err)
+		}
+		if containsSearch(due, daily.Id) {
+			t.Error("the daily search is due right after its check")
+		}
+
+		inactive := false
+		req := &pb.UpdateSavedSearchReq{Id: instant.Id, UserId: userId, Active: &inactive}
+		if err := s.SavedSearch().UpdateSavedSearch(ctx, req); err != nil {
+			t.Fatalf("UpdateSavedSearch: %v", err)
+		}
+		if due, _ := s.SavedSearch().DueSavedSearches(ctx, now); containsSearch(due, instant.Id) {
+			t.Error("an inactive search is due")
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		if err := s.SavedSearch().DeleteSavedSearch(ctx, &pb.SavedSearchId{Id: daily.Id, UserId: otherId}); err == nil {
+			t.Error("another user deleted the saved search")
+		}
+		if err := s.SavedSearch().DeleteSavedSearch(ctx, &pb.SavedSearchId{Id: daily.Id, UserId: userId}); err != nil {
+			t.Fatalf("DeleteSavedSearch: %v", err)
+		}
+		if err := s.SavedSearch().DeleteSavedSearch(ctx, &pb.SavedSearchId{Id: daily.Id, UserId: userId}); err == nil {
+			t.Error("deleted a saved search twice")
+		}
+		if _, err := s.SavedSearch().GetSavedSearch(ctx, &pb.SavedSearchId{Id: daily.Id, UserId: userId}); err == nil {
+			t.Error("GetSavedSearch found a deleted search")
+		}
+		due, err := s.SavedSearch().DueSavedSearches(ctx, time.Now().Add(48*time.Hour))
+		if err != nil {
+			t.Fatalf("DueSavedSearches: %v", err)
+		}
+		if containsSearch(due, daily.Id) {
+			t.Error("a deleted search is due")
+		}
+	})
+
+	t.Run("DeletedOwner", func(t *testing.T) {
+		search, err := s.SavedSearch().CreateSavedSearch(ctx, &pb.CreateSavedSearchReq{UserId: otherId, Name: "owner leaves"})
+		if err != nil {
+			t.Fatalf("CreateSavedSearch: %v", err)
+		}
+		if err := s.User().DeleteUser(ctx, &pb.UserId{Id: otherId}); err != nil {
+			t.Fatalf("DeleteUser: %v", err)
+		}
+		if due, _ := s.SavedSearch().DueSavedSearches(ctx, time.Now()); containsSearch(due, search.Id) {
+			t.Error("the search of a deleted user is due")
+		}
+	})
+}
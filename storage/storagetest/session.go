@@ -0,0 +1,79 @@
+package storagetest
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+	pb "wegugin/genproto/user"
+	"wegugin/model"
+	"wegugin/storage"
+
+	"github.com/google/uuid"
+)
+
+func testSessions(t *testing.T, open func(t *testing.T) storage.IStorage) {
+	s := open(t)
+	userId, _ := newUser(t, s)
+	otherId, _ := newUser(t, s)
+
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	var ids []string
+	for _, device := range []string{"phone", "laptop", "tablet"} {
+		id, err := s.Session().CreateSession(ctx, &model.Session{
+			UserId: userId, DeviceName: device, Platform: "test", ExpiresAt: expiresAt,
+		})
+		if err != nil {
+			t.Fatalf("CreateSession: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	_, err := s.Session().CreateSession(ctx, &model.Session{UserId: userId, ExpiresAt: time.Now().Add(-time.Minute)})
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+
+	sessions, err := s.Session().ListSessions(ctx, userId)
+	if err != nil {
+		t.Fatalf("ListSessions: %v", err)
+	}
+	if len(sessions) != 3 {
+		t.Fatalf("ListSessions returned %d sessions, want the 3 unexpired ones", len(sessions))
+	}
+
+	if _, err := s.Session().RevokeSession(ctx, &pb.SessionReq{UserId: otherId, SessionId: ids[0]}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("another user revoked the session: %v", err)
+	}
+	revokedUntil, err := s.Session().RevokeSession(ctx, &pb.SessionReq{UserId: userId, SessionId: ids[0]})
+	if err != nil {
+		t.Fatalf("RevokeSession: %v", err)
+	}
+	if !revokedUntil.Equal(expiresAt) {
+		t.Errorf("RevokeSession returned %v, want %v", revokedUntil, expiresAt)
+	}
+	if _, err := s.Session().RevokeSession(ctx, &pb.SessionReq{UserId: userId, SessionId: ids[0]}); err == nil {
+		t.Error("revoked a session twice")
+	}
+	for id, want := range map[string]bool{ids[0]: true, ids[1]: false, uuid.NewString(): true} {
+		if revoked, err := s.Session().IsSessionRevoked(ctx, id); err != nil || revoked != want {
+			t.Errorf("IsSessionRevoked(%s) = %v, %v, want %v", id, revoked, err, want)
+		}
+	}
+
+	revoked, err := s.Session().RevokeOtherSessions(ctx, userId, ids[1])
+	if err != nil {
+		t.Fatalf("RevokeOtherSessions: %v", err)
+	}
+	if len(revoked) != 1 || !revoked[ids[2]].Equal(expiresAt) {
+		t.Errorf("RevokeOtherSessions = %v, want only %s", revoked, ids[2])
+	}
+	sessions, err = s.Session().ListSessions(ctx, userId)
+	if err != nil || len(sessions) != 1 || sessions[0].Id != ids[1] || sessions[0].DeviceName != "laptop" {
+		t.Errorf("ListSessions after revoking = %v, %v", sessions, err)
+	}
+
+	revoked, err = s.Session().RevokeOtherSessions(ctx, userId, "")
+	if err != nil || len(revoked) != 1 {
+		t.Errorf("RevokeOtherSessions without a session to keep = %v, %v", revoked, err)
+	}
+}
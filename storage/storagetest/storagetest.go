@@ -0,0 +1,127 @@
+// Package storagetest checks that a storage.IStorage keeps the rules the
+// service relies on: soft deletes, emails and phone numbers unique among
+// active users, password history, suspensions, export limits and so on.
+// The same suite runs against every backend from its TestStorage, and from
+// cmd/conformance against a configured database.
+package storagetest
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"wegugin/config"
+	pb "wegugin/genproto/user"
+	"wegugin/storage"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password is the password of the users the suite registers.
+const Password = "correct horse battery staple"
+
+// Run runs the suite as subtests of t. open returns the storage to check
+// with conf, it may be shared with other data: the suite makes up unique
+// emails and phone numbers and only looks at rows it created. The suite
+// changes conf while it runs, the storage must read it on every call.
+func Run(t *testing.T, open func(t *testing.T, conf *config.Config) storage.IStorage) {
+	conf, err := config.New("")
+	if err != nil {
+		t.Fatalf("config: %v", err)
+	}
+	conf.Password.BCRYPT_COST = bcrypt.MinCost
+	run(t, conf, func(t *testing.T) storage.IStorage { return open(t, conf) })
+}
+
+func run(t *testing.T, conf *config.Config, open func(t *testing.T) storage.IStorage) {
+	t.Run("Users", func(t *testing.T) { testUsers(t, open) })
+	t.Run("Login", func(t *testing.T) { testLogin(t, open) })
+	t.Run("Passwords", func(t *testing.T) { testPasswords(t, conf, open) })
+	t.Run("SoftDelete", func(t *testing.T) { testSoftDelete(t, open) })
+	t.Run("Purge", func(t *testing.T) { testPurge(t, open) })
+	t.Run("Admin", func(t *testing.T) { testAdmin(t, open) })
+	t.Run("Profile", func(t *testing.T) { testProfile(t, open) })
+	t.Run("SavedSearches", func(t *testing.T) { testSavedSearches(t, open) })
+	t.Run("Suspensions", func(t *testing.T) { testSuspensions(t, open) })
+	t.Run("Exports", func(t *testing.T) { testExports(t, open) })
+	t.Run("Audit", func(t *testing.T) { testAudit(t, open) })
+	t.Run("Sessions", func(t *testing.T) { testSessions(t, open) })
+	t.Run("Identities", func(t *testing.T) { testIdentities(t, open) })
+}
+
+var ctx = context.Background()
+
+// marker returns a string unique to the call, to find rows again in a
+// shared database.
+func marker() string {
+	return strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
+}
+
+func uniqueEmail(mark string) string {
+	return fmt.Sprintf("st-%s-%s@example.com", mark, marker())
+}
+
+func uniquePhone() string {
+	n, err := rand.Int(rand.Reader, big.NewInt(1e10))
+	if err != nil {
+		panic(err)
+	}
+	return fmt.Sprintf("+82%010d", n)
+}
+
+// registerReq returns a registration with a unique email and phone number.
+// The email contains mark, for ListUsers.
+func registerReq(mark string) *pb.RegisterReq {
+	return &pb.RegisterReq{
+		Email:     uniqueEmail(mark),
+		Name:      "Storage",
+		Surname:   "Test",
+		Password:  Password,
+		Phone:     uniquePhone(),
+		BirthDate: "02-01-1990",
+		Gender:    "other",
+	}
+}
+
+// register creates a user from req and returns the user's id.
+func register(t *testing.T, s storage.IStorage, req *pb.RegisterReq) string {
+	t.Helper()
+	res, err := s.User().CreateUser(ctx, req)
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	return tokenUser(t, res.Token)
+}
+
+// newUser registers a user and returns the id and the registration.
+func newUser(t *testing.T, s storage.IStorage) (string, *pb.RegisterReq) {
+	t.Helper()
+	req := registerReq(marker())
+	return register(t, s, req), req
+}
+
+// tokenUser returns the user id a token was issued for.
+func tokenUser(t *testing.T, token string) string {
+	t.Helper()
+	// The signature is the business of the auth package
+	parsed, _, err := new(jwt.Parser).ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		t.Fatalf("token: %v", err)
+	}
+	id, _ := parsed.Claims.(jwt.MapClaims)["user_id"].(string)
+	return id
+}
+
+// login logs in and returns the user id, failing the test on an error.
+func login(t *testing.T, s storage.IStorage, emailOrPhone, password string) string {
+	t.Helper()
+	res, err := s.User().Login(ctx, &pb.LoginReq{EmailOrPhoneNumber: emailOrPhone, Password: password})
+	if err != nil {
+		t.Fatalf("Login(%s): %v", emailOrPhone, err)
+	}
+	return tokenUser(t, res.Token)
+}
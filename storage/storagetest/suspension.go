@@ -0,0 +1,101 @@
+package storagetest
+
+import (
+	"testing"
+	"time"
+	pb "wegugin/genproto/user"
+	"wegugin/storage"
+)
+
+func containsSuspension(suspensions []*pb.Suspension, id string) bool {
+	for _, suspension := range suspensions {
+		if suspension.Id == id {
+			return true
+		}
+	}
+	return false
+}
+
+func testSuspensions(t *testing.T, open func(t *testing.T) storage.IStorage) {
+	s := open(t)
+	userId, _ := newUser(t, s)
+	adminId, _ := newUser(t, s)
+
+	if _, err := s.Suspension().SuspendUser(ctx, &pb.SuspendUserReq{UserId: userId}); err == nil {
+		t.Error("suspended without a reason")
+	}
+	past := time.Now().Add(-time.Hour).Format(time.RFC3339)
+	if _, err := s.Suspension().SuspendUser(ctx, &pb.SuspendUserReq{UserId: userId, Reason: "spam", ExpiresAt: past}); err == nil {
+		t.Error("suspended until a time in the past")
+	}
+	if err := s.Suspension().LiftSuspension(ctx, &pb.LiftSuspensionReq{UserId: userId}); err == nil {
+		t.Error("lifted a suspension that does not exist")
+	}
+
+	until := time.Now().Add(time.Hour).Format(time.RFC3339)
+	first, err := s.Suspension().SuspendUser(ctx, &pb.SuspendUserReq{
+		UserId: userId, Reason: "spam", ExpiresAt: until, ActorId: adminId,
+	})
+	if err != nil {
+		t.Fatalf("SuspendUser: %v", err)
+	}
+	if first.UserId != userId || first.Reason != "spam" || first.ActorId != adminId || first.ExpiresAt == "" || first.LiftedAt != "" {
+		t.Errorf("SuspendUser = %v", first)
+	}
+	if _, err := s.Suspension().SuspendUser(ctx, &pb.SuspendUserReq{UserId: userId, Reason: "again"}); err == nil {
+		t.Error("suspended a suspended user")
+	}
+	active, err := s.Suspension().ActiveSuspensions(ctx)
+	if err != nil || !containsSuspension(active, first.Id) {
+		t.Errorf("ActiveSuspensions = %v, %v; want %s among them", active, err, first.Id)
+	}
+	if suspended, err := s.Suspension().IsSuspended(ctx, userId); err != nil || !suspended {
+		t.Errorf("IsSuspended of a suspended user = %v, %v", suspended, err)
+	}
+	if suspended, err := s.Suspension().IsSuspended(ctx, adminId); err != nil || suspended {
+		t.Errorf("IsSuspended of another user = %v, %v", suspended, err)
+	}
+
+	err = s.Suspension().LiftSuspension(ctx, &pb.LiftSuspensionReq{UserId: userId, ActorId: adminId, Reason: "appeal"})
+	if err != nil {
+		t.Fatalf("LiftSuspension: %v", err)
+	}
+	if active, _ := s.Suspension().ActiveSuspensions(ctx); containsSuspension(active, first.Id) {
+		t.Error("a lifted suspension is active")
+	}
+	if suspended, err := s.Suspension().IsSuspended(ctx, userId); err != nil || suspended {
+		t.Errorf("IsSuspended after lifting = %v, %v", suspended, err)
+	}
+
+	// Without expires_at it is a ban
+	ban, err := s.Suspension().SuspendUser(ctx, &pb.SuspendUserReq{UserId: userId, Reason: "fraud"})
+	if err != nil {
+		t.Fatalf("SuspendUser: %v", err)
+	}
+	if ban.ExpiresAt != "" {
+		t.Errorf("ban expires at %q", ban.ExpiresAt)
+	}
+
+	list, err := s.Suspension().ListSuspensions(ctx, &pb.UserId{Id: userId})
+	if err != nil {
+		t.Fatalf("ListSuspensions: %v", err)
+	}
+	if len(list.Suspensions) != 2 || list.Suspensions[0].Id != ban.Id || list.Suspensions[1].Id != first.Id {
+		t.Fatalf("ListSuspensions = %v, want the ban then the first suspension", list)
+	}
+	lifted := list.Suspensions[1]
+	if lifted.LiftedAt == "" || lifted.LiftedBy != adminId || lifted.LiftReason != "appeal" {
+		t.Errorf("lifted suspension = %v", lifted)
+	}
+
+	if err := s.Suspension().SyncHiddenCars(ctx); err != nil {
+		t.Errorf("SyncHiddenCars: %v", err)
+	}
+
+	if err := s.User().DeleteUser(ctx, &pb.UserId{Id: adminId}); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if _, err := s.Suspension().SuspendUser(ctx, &pb.SuspendUserReq{UserId: adminId, Reason: "gone"}); err == nil {
+		t.Error("suspended a deleted user")
+	}
+}
@@ -0,0 +1,643 @@
+package storagetest
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"wegugin/api/password"
+	"wegugin/config"
+	pb "wegugin/genproto/user"
+	"wegugin/model"
+	"wegugin/storage"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func testUsers(t *testing.T, open func(t *testing.T) storage.IStorage) {
+	s := open(t)
+	id, req := newUser(t, s)
+
+	user, err := s.User().GetUserById(ctx, &pb.UserId{Id: id})
+	if err != nil {
+		t.Fatalf("GetUserById: %v", err)
+	}
+	if user.Id != id || user.Email != req.Email || user.PhoneNumber != req.Phone || user.Name != req.Name ||
+		user.Surname != req.Surname || user.Gender != req.Gender || user.Role != "user" {
+		t.Errorf("GetUserById = %v, registered %v", user, req)
+	}
+	if user.BirthDate != "1990-01-02" {
+		t.Errorf("birth date = %q, want 1990-01-02", user.BirthDate)
+	}
+	if _, err := time.Parse(time.RFC3339Nano, user.CreatedAt); err != nil {
+		t.Errorf("created at %q: %v", user.CreatedAt, err)
+	}
+
+	byEmail, err := s.User().GetUserByEmail(ctx, &pb.GetUSerByEmailReq{Email: req.Email})
+	if err != nil || byEmail.Id != id {
+		t.Errorf("GetUserByEmail = %v, %v; want user %s", byEmail, err, id)
+	}
+	if err := s.User().IsUserExist(ctx, &pb.UserId{Id: id}); err != nil {
+		t.Errorf("IsUserExist: %v", err)
+	}
+	if err := s.User().IsUserExist(ctx, &pb.UserId{Id: uuid.NewString()}); err == nil {
+		t.Error("IsUserExist of an unknown id succeeded")
+	}
+	if _, err := s.User().GetUserById(ctx, &pb.UserId{Id: uuid.NewString()}); err == nil {
+		t.Error("GetUserById of an unknown id succeeded")
+	}
+
+	res, err := s.User().CreateUserWithRole(ctx, registerReq(marker()), "admin")
+	if err != nil {
+		t.Fatalf("CreateUserWithRole: %v", err)
+	}
+	if admin, err := s.User().GetUserById(ctx, &pb.UserId{Id: tokenUser(t, res.Token)}); err != nil || admin.Role != "admin" {
+		t.Errorf("CreateUserWithRole admin = %v, %v; want the admin role", admin, err)
+	}
+
+	t.Run("Unique", func(t *testing.T) {
+		again := registerReq(marker())
+		again.Email = req.Email
+		if _, err := s.User().CreateUser(ctx, again); err == nil {
+			t.Error("registered the same email twice")
+		}
+		again = registerReq(marker())
+		again.Phone = req.Phone
+		if _, err := s.User().CreateUser(ctx, again); err == nil {
+			t.Error("registered the same phone number twice")
+		}
+	})
+
+	t.Run("ConcurrentRegistration", func(t *testing.T) {
+		req := registerReq(marker())
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		created := 0
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := s.User().CreateUser(ctx, req); err == nil {
+					mu.Lock()
+					created++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		if created != 1 {
+			t.Errorf("%d concurrent registrations of one email succeeded, want 1", created)
+		}
+	})
+
+	t.Run("UpdateUser", func(t *testing.T) {
+		if err := s.User().UpdateUser(ctx, &pb.UpdateUserRequest{Id: id}); err == nil {
+			t.Error("UpdateUser without fields succeeded")
+		}
+		err := s.User().UpdateUser(ctx, &pb.UpdateUserRequest{Id: id, Name: "Renamed", BirthDate: "15-06-1991"})
+		if err != nil {
+			t.Fatalf("UpdateUser: %v", err)
+		}
+		user, err := s.User().GetUserById(ctx, &pb.UserId{Id: id})
+		if err != nil {
+			t.Fatalf("GetUserById: %v", err)
+		}
+		if user.Name != "Renamed" || user.Surname != req.Surname || user.BirthDate != "1991-06-15" {
+			t.Errorf("after UpdateUser got %v", user)
+		}
+
+		_, other := newUser(t, s)
+		if err := s.User().UpdateUser(ctx, &pb.UpdateUserRequest{Id: id, PhoneNumber: other.Phone}); err == nil {
+			t.Error("UpdateUser took the phone number of another user")
+		}
+	})
+
+	t.Run("ChangeEmail", func(t *testing.T) {
+		id, req := newUser(t, s)
+		_, other := newUser(t, s)
+
+		err := s.User().ChangeEmail(ctx, id, req.Email, other.Email)
+		if !errors.Is(err, storage.ErrEmailTaken) {
+			t.Errorf("ChangeEmail to a used email = %v, want ErrEmailTaken", err)
+		}
+		err = s.User().ChangeEmail(ctx, id, uniqueEmail(marker()), uniqueEmail(marker()))
+		if !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("ChangeEmail from a stale email = %v, want sql.ErrNoRows", err)
+		}
+
+		to := uniqueEmail(marker())
+		if err := s.User().ChangeEmail(ctx, id, req.Email, to); err != nil {
+			t.Fatalf("ChangeEmail: %v", err)
+		}
+		if user, err := s.User().GetUserByEmail(ctx, &pb.GetUSerByEmailReq{Email: to}); err != nil || user.Id != id {
+			t.Errorf("GetUserByEmail(new email) = %v, %v", user, err)
+		}
+		if _, err := s.User().GetUserByEmail(ctx, &pb.GetUSerByEmailReq{Email: req.Email}); err == nil {
+			t.Error("the old email still finds the user")
+		}
+	})
+
+	t.Run("GetUsersByIds", func(t *testing.T) {
+		other, otherReq := newUser(t, s)
+		deleted, _ := newUser(t, s)
+		if err := s.User().DeleteUser(ctx, &pb.UserId{Id: deleted}); err != nil {
+			t.Fatalf("DeleteUser: %v", err)
+		}
+		unknown := uuid.NewString()
+
+		err := s.User().UpdatePrivacySettings(ctx, &pb.PrivacySettings{
+			UserId: other, PhoneNumber: model.VisibilityEveryone, Email: model.VisibilityNobody,
+		})
+		if err != nil {
+			t.Fatalf("UpdatePrivacySettings: %v", err)
+		}
+
+		users, privacy, err := s.User().GetUsersByIds(ctx, []string{id, other, deleted, unknown}, []string{"email"})
+		if err != nil {
+			t.Fatalf("GetUsersByIds: %v", err)
+		}
+		if len(users) != 2 || users[id] == nil || users[other] == nil {
+			t.Fatalf("GetUsersByIds returned %v, want %s and %s", users, id, other)
+		}
+		if got := users[other]; got.Email != otherReq.Email || got.Name != "" || got.Id != "" {
+			t.Errorf("masked user = %v, want only the email", got)
+		}
+		if got := privacy[other]; got == nil || got.PhoneNumber != model.VisibilityEveryone || got.Email != model.VisibilityNobody {
+			t.Errorf("privacy settings = %v, want the phone number for everyone and the email for nobody", got)
+		}
+		if len(privacy) != 2 || privacy[deleted] != nil {
+			t.Errorf("privacy settings for %d users, want 2", len(privacy))
+		}
+
+		users, _, err = s.User().GetUsersByIds(ctx, []string{other}, nil)
+		if err != nil {
+			t.Fatalf("GetUsersByIds: %v", err)
+		}
+		if got := users[other]; got == nil || got.Id != other || got.Name != otherReq.Name {
+			t.Errorf("unmasked user = %v", got)
+		}
+		if _, _, err := s.User().GetUsersByIds(ctx, []string{id}, []string{"password_hash"}); err == nil {
+			t.Error("GetUsersByIds accepted an unknown field")
+		}
+	})
+}
+
+func testLogin(t *testing.T, open func(t *testing.T) storage.IStorage) {
+	s := open(t)
+	id, req := newUser(t, s)
+
+	if got := login(t, s, req.Email, Password); got != id {
+		t.Errorf("login by email is user %s, want %s", got, id)
+	}
+	if got := login(t, s, req.Phone, Password); got != id {
+		t.Errorf("login by phone number is user %s, want %s", got, id)
+	}
+
+	_, err := s.User().Login(ctx, &pb.LoginReq{EmailOrPhoneNumber: req.Email, Password: "wrong password"})
+	if !errors.Is(err, storage.ErrInvalidCredentials) {
+		t.Errorf("wrong password = %v, want ErrInvalidCredentials", err)
+	}
+	_, err = s.User().Login(ctx, &pb.LoginReq{EmailOrPhoneNumber: uniqueEmail(marker()), Password: Password})
+	if !errors.Is(err, storage.ErrInvalidCredentials) {
+		t.Errorf("unknown login = %v, want ErrInvalidCredentials", err)
+	}
+
+	if got, err := s.User().UserIdByLogin(ctx, req.Phone); err != nil || got != id {
+		t.Errorf("UserIdByLogin = %s, %v; want %s", got, err, id)
+	}
+	if res, err := s.User().LoginById(ctx, id); err != nil || tokenUser(t, res.Token) != id {
+		t.Errorf("LoginById = %v, %v", res, err)
+	}
+
+	if err := s.User().RequirePasswordReset(ctx, &pb.UserId{Id: id}); err != nil {
+		t.Fatalf("RequirePasswordReset: %v", err)
+	}
+	for _, password := range []string{Password, "wrong password"} {
+		_, err := s.User().Login(ctx, &pb.LoginReq{EmailOrPhoneNumber: req.Email, Password: password})
+		if !errors.Is(err, storage.ErrPasswordResetRequired) {
+			t.Errorf("login with a password reset required = %v, want ErrPasswordResetRequired", err)
+		}
+	}
+	if err := s.User().UpdatePassword(ctx, &pb.UpdatePasswordReq{Id: id, Password: Password}); err != nil {
+		t.Fatalf("UpdatePassword: %v", err)
+	}
+	login(t, s, req.Email, Password)
+
+	t.Run("Suspended", func(t *testing.T) {
+		_, err := s.Suspension().SuspendUser(ctx, &pb.SuspendUserReq{UserId: id, Reason: "storage test"})
+		if err != nil {
+			t.Fatalf("SuspendUser: %v", err)
+		}
+		_, err = s.User().Login(ctx, &pb.LoginReq{EmailOrPhoneNumber: req.Email, Password: Password})
+		if !errors.Is(err, storage.ErrUserSuspended) {
+			t.Errorf("suspended login = %v, want ErrUserSuspended", err)
+		}
+		if _, err := s.User().LoginById(ctx, id); !errors.Is(err, storage.ErrUserSuspended) {
+			t.Errorf("suspended LoginById = %v, want ErrUserSuspended", err)
+		}
+		if err := s.Suspension().LiftSuspension(ctx, &pb.LiftSuspensionReq{UserId: id}); err != nil {
+			t.Fatalf("LiftSuspension: %v", err)
+		}
+		login(t, s, req.Email, Password)
+	})
+}
+
+func testPasswords(t *testing.T, conf *config.Config, open func(t *testing.T) storage.IStorage) {
+	s := open(t)
+	id, _ := newUser(t, s)
+
+	if err := s.User().CheckPassword(ctx, id, Password); err != nil {
+		t.Errorf("CheckPassword: %v", err)
+	}
+	if err := s.User().CheckPassword(ctx, id, "wrong password"); !errors.Is(err, storage.ErrInvalidCredentials) {
+		t.Errorf("CheckPassword(wrong) = %v, want ErrInvalidCredentials", err)
+	}
+
+	err := s.User().ResetPassword(ctx, &pb.ResetPasswordReq{Id: id, Oldpassword: "wrong password", Newpassword: "second"})
+	if err == nil {
+		t.Error("ResetPassword with a wrong old password succeeded")
+	}
+	if err := s.User().ResetPassword(ctx, &pb.ResetPasswordReq{Id: id, Oldpassword: Password, Newpassword: "second"}); err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+	if err := s.User().UpdatePassword(ctx, &pb.UpdatePasswordReq{Id: id, Password: "third"}); err != nil {
+		t.Fatalf("UpdatePassword: %v", err)
+	}
+	if err := s.User().CheckPassword(ctx, id, "third"); err != nil {
+		t.Errorf("CheckPassword(new password): %v", err)
+	}
+	if err := s.User().CheckPassword(ctx, id, Password); err == nil {
+		t.Error("the old password still works")
+	}
+
+	hashes, err := s.User().RecentPasswordHashes(ctx, id, 5)
+	if err != nil {
+		t.Fatalf("RecentPasswordHashes: %v", err)
+	}
+	if len(hashes) != 3 {
+		t.Fatalf("%d password hashes, want 3", len(hashes))
+	}
+	// Newest first
+	for i, pw := range []string{"third", "second", Password} {
+		if !verify(t, hashes[i], pw) {
+			t.Errorf("hash %d is not the one of %q", i, pw)
+		}
+	}
+	if hashes, _ := s.User().RecentPasswordHashes(ctx, id, 2); len(hashes) != 2 {
+		t.Errorf("RecentPasswordHashes(2) returned %d hashes", len(hashes))
+	}
+
+	t.Run("Bcrypt", func(t *testing.T) {
+		setHashAlgorithm(t, conf, "bcrypt")
+		id, req := newUser(t, s)
+		hashes, err := s.User().RecentPasswordHashes(ctx, id, 5)
+		if err != nil || len(hashes) != 1 {
+			t.Fatalf("RecentPasswordHashes = %v, %v", hashes, err)
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(hashes[0]), []byte(Password)); err != nil {
+			t.Fatalf("the stored hash is no bcrypt hash of the password: %v", err)
+		}
+
+		// A login with another algorithm configured upgrades the hash
+		// without counting it as a password change
+		setHashAlgorithm(t, conf, "argon2id")
+		login(t, s, req.Email, Password)
+		hashes, err = s.User().RecentPasswordHashes(ctx, id, 5)
+		if err != nil || len(hashes) != 1 {
+			t.Fatalf("RecentPasswordHashes after rehash = %v, %v", hashes, err)
+		}
+		if !strings.HasPrefix(hashes[0], "$argon2id$") || !verify(t, hashes[0], Password) {
+			t.Errorf("hash after login = %q, want an argon2id hash of the password", hashes[0])
+		}
+		login(t, s, req.Email, Password)
+	})
+}
+
+// setHashAlgorithm changes the hash algorithm of conf until the test ends.
+// The repositories make a new hasher for every call.
+func setHashAlgorithm(t *testing.T, conf *config.Config, algorithm string) {
+	previous := conf.Password.HASH_ALGORITHM
+	conf.Password.HASH_ALGORITHM = algorithm
+	t.Cleanup(func() { conf.Password.HASH_ALGORITHM = previous })
+}
+
+// verify reports whether hash is one of password.
+func verify(t *testing.T, hash, pw string) bool {
+	t.Helper()
+	ok, err := password.NewHasher(config.PasswordConfig{}).Verify(hash, pw)
+	if err != nil {
+		t.Fatalf("verify %q: %v", hash, err)
+	}
+	return ok
+}
+
+func testSoftDelete(t *testing.T, open func(t *testing.T) storage.IStorage) {
+	s := open(t)
+	id, req := newUser(t, s)
+
+	if err := s.User().DeleteUser(ctx, &pb.UserId{Id: id}); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if err := s.User().DeleteUser(ctx, &pb.UserId{Id: id}); err == nil {
+		t.Error("deleted a user twice")
+	}
+	if _, err := s.User().GetUserById(ctx, &pb.UserId{Id: id}); err == nil {
+		t.Error("GetUserById found a deleted user")
+	}
+	if _, err := s.User().GetUserByEmail(ctx, &pb.GetUSerByEmailReq{Email: req.Email}); err == nil {
+		t.Error("GetUserByEmail found a deleted user")
+	}
+	if err := s.User().IsUserExist(ctx, &pb.UserId{Id: id}); err == nil {
+		t.Error("IsUserExist found a deleted user")
+	}
+	if err := s.User().UpdateUser(ctx, &pb.UpdateUserRequest{Id: id, Name: "Ghost"}); err == nil {
+		t.Error("updated a deleted user")
+	}
+	deleted, err := s.User().GetUserByIdWithDeleted(ctx, &pb.UserId{Id: id})
+	if err != nil {
+		t.Fatalf("GetUserByIdWithDeleted: %v", err)
+	}
+	if deleted.DeletedAt == 0 {
+		t.Error("deleted_at is not set")
+	}
+
+	// Logging in within the restore window restores the account
+	if got := login(t, s, req.Email, Password); got != id {
+		t.Errorf("login restored user %s, want %s", got, id)
+	}
+	if _, err := s.User().GetUserById(ctx, &pb.UserId{Id: id}); err != nil {
+		t.Errorf("GetUserById after the restoring login: %v", err)
+	}
+
+	t.Run("Reregister", func(t *testing.T) {
+		if err := s.User().DeleteUser(ctx, &pb.UserId{Id: id}); err != nil {
+			t.Fatalf("DeleteUser: %v", err)
+		}
+		deleted, err := s.User().GetUserByIdWithDeleted(ctx, &pb.UserId{Id: id})
+		if err != nil {
+			t.Fatalf("GetUserByIdWithDeleted: %v", err)
+		}
+
+		// The email and phone number are free again
+		again := registerReq(marker())
+		again.Email, again.Phone = req.Email, req.Phone
+		newId := register(t, s, again)
+		if newId == id {
+			t.Fatal("registration reused the deleted user")
+		}
+		if got := login(t, s, req.Email, Password); got != newId {
+			t.Errorf("login picked user %s, want the active %s", got, newId)
+		}
+		if got, err := s.User().UserIdByLogin(ctx, req.Email); err != nil || got != newId {
+			t.Errorf("UserIdByLogin = %s, %v; want the active %s", got, err, newId)
+		}
+
+		if err := s.User().RestoreUser(ctx, &pb.UserId{Id: id}); err == nil {
+			t.Error("restored a user whose email is taken")
+		}
+		if _, err := s.User().RestoreDeletedUser(ctx, id, deleted.DeletedAt); err == nil {
+			t.Error("RestoreDeletedUser restored a user whose email is taken")
+		}
+
+		if err := s.User().DeleteUser(ctx, &pb.UserId{Id: newId}); err != nil {
+			t.Fatalf("DeleteUser: %v", err)
+		}
+		if _, err := s.User().RestoreDeletedUser(ctx, id, deleted.DeletedAt+1); err == nil {
+			t.Error("RestoreDeletedUser ignored deleted_at")
+		}
+		role, err := s.User().RestoreDeletedUser(ctx, id, deleted.DeletedAt)
+		if err != nil || role != "user" {
+			t.Fatalf("RestoreDeletedUser = %q, %v", role, err)
+		}
+		if err := s.User().RestoreUser(ctx, &pb.UserId{Id: id}); err == nil {
+			t.Error("RestoreUser restored an active user")
+		}
+	})
+}
+
+func testPurge(t *testing.T, open func(t *testing.T) storage.IStorage) {
+	s := open(t)
+
+	t.Run("Anonymize", func(t *testing.T) {
+		id, req := newUser(t, s)
+		if err := s.User().PurgeUser(ctx, id, true); err != nil {
+			t.Fatalf("PurgeUser of an active user: %v", err)
+		}
+		if _, err := s.User().GetUserById(ctx, &pb.UserId{Id: id}); err != nil {
+			t.Fatalf("purging anonymized an active user: %v", err)
+		}
+
+		if _, err := s.SavedSearch().CreateSavedSearch(ctx, &pb.CreateSavedSearchReq{UserId: id, Name: "gone"}); err != nil {
+			t.Fatalf("CreateSavedSearch: %v", err)
+		}
+		archive := readyExport(t, s, id)
+		if err := s.User().DeleteUser(ctx, &pb.UserId{Id: id}); err != nil {
+			t.Fatalf("DeleteUser: %v", err)
+		}
+		if _, exports, err := s.User().PurgeFiles(ctx, id); err != nil || len(exports) != 1 || exports[0] != archive {
+			t.Errorf("PurgeFiles = %v, %v, want the archive %s", exports, err, archive)
+		}
+		candidates, err := s.User().PurgeCandidates(ctx, time.Now().Unix())
+		if err != nil {
+			t.Fatalf("PurgeCandidates: %v", err)
+		}
+		if !containsUser(candidates, id) && len(candidates) < 100 {
+			t.Error("the deleted user is no purge candidate")
+		}
+		if err := s.User().PurgeUser(ctx, id, true); err != nil {
+			t.Fatalf("PurgeUser: %v", err)
+		}
+
+		purged, err := s.User().GetUserByIdWithDeleted(ctx, &pb.UserId{Id: id})
+		if err != nil {
+			t.Fatalf("GetUserByIdWithDeleted: %v", err)
+		}
+		if purged.Email != "" || purged.PhoneNumber != "" || purged.Name != "" || purged.BirthDate != "" {
+			t.Errorf("purged user keeps personal data: %v", purged)
+		}
+		if _, err := s.User().UserIdByLogin(ctx, req.Email); err == nil {
+			t.Error("the purged user's email still finds it")
+		}
+		if _, err := s.User().LoginById(ctx, id); err == nil {
+			t.Error("a purged user logged in")
+		}
+		if err := s.User().RestoreUser(ctx, &pb.UserId{Id: id}); err == nil {
+			t.Error("restored a purged user")
+		}
+		searches, err := s.SavedSearch().ListSavedSearches(ctx, &pb.UserId{Id: id})
+		if err != nil || len(searches.SavedSearches) != 0 {
+			t.Errorf("saved searches after purge = %v, %v", searches, err)
+		}
+		if _, err := s.Export().LatestExport(ctx, &pb.UserId{Id: id}); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("LatestExport after purge = %v, want sql.ErrNoRows", err)
+		}
+		candidates, err = s.User().PurgeCandidates(ctx, time.Now().Unix())
+		if err != nil {
+			t.Fatalf("PurgeCandidates: %v", err)
+		}
+		if containsUser(candidates, id) {
+			t.Error("the purged user is still a candidate")
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		id, _ := newUser(t, s)
+		if err := s.User().PurgeUser(ctx, id, false); err != nil {
+			t.Fatalf("PurgeUser of an active user: %v", err)
+		}
+		if _, err := s.User().GetUserById(ctx, &pb.UserId{Id: id}); err != nil {
+			t.Fatalf("purging deleted an active user: %v", err)
+		}
+		if err := s.User().DeleteUser(ctx, &pb.UserId{Id: id}); err != nil {
+			t.Fatalf("DeleteUser: %v", err)
+		}
+		if err := s.User().PurgeUser(ctx, id, false); err != nil {
+			t.Fatalf("PurgeUser: %v", err)
+		}
+		if _, err := s.User().GetUserByIdWithDeleted(ctx, &pb.UserId{Id: id}); err == nil {
+			t.Error("the purged user is still there")
+		}
+	})
+}
+
+// readyExport makes a ready export of the user and returns its archive.
+func readyExport(t *testing.T, s storage.IStorage, userId string) string {
+	t.Helper()
+	created, err := s.Export().CreateExport(ctx, userId, 0)
+	if err != nil {
+		t.Fatalf("CreateExport: %v", err)
+	}
+	claimedAt, ok := claim(t, s, time.Hour, created.Id)
+	if !ok {
+		t.Fatal("ClaimPendingExport did not return the pending export")
+	}
+	archive := "storagetest/" + created.Id + ".zip"
+	if err := s.Export().CompleteExport(ctx, created.Id, claimedAt, archive, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("CompleteExport: %v", err)
+	}
+	return archive
+}
+
+func containsUser(users []*pb.AdminUser, id string) bool {
+	for _, user := range users {
+		if user.Id == id {
+			return true
+		}
+	}
+	return false
+}
+
+func testAdmin(t *testing.T, open func(t *testing.T) storage.IStorage) {
+	s := open(t)
+	mark := marker()
+	var ids []string
+	for i := 0; i < 3; i++ {
+		ids = append(ids, register(t, s, registerReq(mark)))
+	}
+	if err := s.User().DeleteUser(ctx, &pb.UserId{Id: ids[2]}); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+
+	for _, tc := range []struct {
+		deleted string
+		want    int32
+	}{{"", 2}, {"active", 2}, {"deleted", 1}, {"all", 3}} {
+		list, err := s.User().ListUsers(ctx, &pb.AdminListUsersReq{Email: strings.ToUpper(mark), Deleted: tc.deleted})
+		if err != nil {
+			t.Fatalf("ListUsers(%q): %v", tc.deleted, err)
+		}
+		if list.Total != tc.want || len(list.Users) != int(tc.want) {
+			t.Errorf("ListUsers(%q) found %d of %d users, want %d", tc.deleted, len(list.Users), list.Total, tc.want)
+		}
+	}
+	list, err := s.User().ListUsers(ctx, &pb.AdminListUsersReq{Email: mark, Limit: 1, Page: 2})
+	if err != nil {
+		t.Fatalf("ListUsers: %v", err)
+	}
+	if list.Total != 2 || len(list.Users) != 1 || list.Page != 2 || list.Limit != 1 {
+		t.Errorf("second page = %d users of %d, page %d limit %d", len(list.Users), list.Total, list.Page, list.Limit)
+	}
+	if _, err := s.User().ListUsers(ctx, &pb.AdminListUsersReq{Deleted: "some"}); err == nil {
+		t.Error("ListUsers accepted an unknown deleted filter")
+	}
+
+	if err := s.User().UpdateRole(ctx, &pb.UpdateRoleReq{Id: ids[0], Role: "owner"}); err == nil {
+		t.Error("UpdateRole accepted an unknown role")
+	}
+	if err := s.User().UpdateRole(ctx, &pb.UpdateRoleReq{Id: ids[0], Role: "admin"}); err != nil {
+		t.Fatalf("UpdateRole: %v", err)
+	}
+	list, err = s.User().ListUsers(ctx, &pb.AdminListUsersReq{Email: mark, Role: "admin"})
+	if err != nil || list.Total != 1 || list.Users[0].Id != ids[0] {
+		t.Errorf("ListUsers(role admin) = %v, %v", list, err)
+	}
+
+	if err := s.User().RestoreUser(ctx, &pb.UserId{Id: ids[2]}); err != nil {
+		t.Fatalf("RestoreUser: %v", err)
+	}
+	if err := s.User().HardDeleteUser(ctx, &pb.UserId{Id: ids[1]}); err != nil {
+		t.Fatalf("HardDeleteUser: %v", err)
+	}
+	if err := s.User().HardDeleteUser(ctx, &pb.UserId{Id: ids[1]}); err == nil {
+		t.Error("hard deleted a user twice")
+	}
+	list, err = s.User().ListUsers(ctx, &pb.AdminListUsersReq{Email: mark, Deleted: "all"})
+	if err != nil || list.Total != 2 {
+		t.Errorf("ListUsers after restore and hard delete = %v, %v", list, err)
+	}
+}
+
+func testProfile(t *testing.T, open func(t *testing.T) storage.IStorage) {
+	s := open(t)
+	id, req := newUser(t, s)
+
+	privacy, err := s.User().GetPrivacySettings(ctx, &pb.UserId{Id: id})
+	if err != nil {
+		t.Fatalf("GetPrivacySettings: %v", err)
+	}
+	if privacy.PhoneNumber != model.VisibilityNobody || privacy.Email != model.VisibilityNobody {
+		t.Errorf("default privacy = %v, want nobody", privacy)
+	}
+
+	err = s.User().UpdatePrivacySettings(ctx, &pb.PrivacySettings{UserId: id, PhoneNumber: "friends", Email: model.VisibilityNobody})
+	if err == nil {
+		t.Error("UpdatePrivacySettings accepted an unknown visibility")
+	}
+	err = s.User().UpdatePrivacySettings(ctx, &pb.PrivacySettings{
+		UserId: id, PhoneNumber: model.VisibilityEveryone, Email: model.VisibilityLoggedIn,
+	})
+	if err != nil {
+		t.Fatalf("UpdatePrivacySettings: %v", err)
+	}
+
+	profile, privacy, err := s.User().GetPublicProfile(ctx, id)
+	if err != nil {
+		t.Fatalf("GetPublicProfile: %v", err)
+	}
+	if profile.Id != id || profile.Name != req.Name || profile.PhoneNumber != req.Phone || profile.Email != req.Email {
+		t.Errorf("GetPublicProfile = %v", profile)
+	}
+	if privacy.PhoneNumber != model.VisibilityEveryone || privacy.Email != model.VisibilityLoggedIn {
+		t.Errorf("privacy = %v", privacy)
+	}
+
+	if err := s.User().UpdateUser(ctx, &pb.UpdateUserRequest{Id: id, Photo: "photo.jpg"}); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	if err := s.User().DeleteMediaUser(ctx, &pb.UserId{Id: id}); err != nil {
+		t.Fatalf("DeleteMediaUser: %v", err)
+	}
+	if user, err := s.User().GetUserById(ctx, &pb.UserId{Id: id}); err != nil || user.Photo != "" {
+		t.Errorf("photo after DeleteMediaUser = %v, %v", user, err)
+	}
+
+	if err := s.User().DeleteUser(ctx, &pb.UserId{Id: id}); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if _, _, err := s.User().GetPublicProfile(ctx, id); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetPublicProfile of a deleted user = %v, want sql.ErrNoRows", err)
+	}
+}
@@ -0,0 +1,220 @@
+package worker
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"time"
+	"wegugin/api/email"
+	"wegugin/config"
+	pb "wegugin/genproto/user"
+	"wegugin/storage"
+	minioStorage "wegugin/storage/minio"
+)
+
+// ExportBuilder turns pending data exports into a zip archive holding
+// data.json plus the user's media, uploads it to the private exports bucket
+// and mails the user a presigned link. It also drops archives past their
+// retention.
+type ExportBuilder struct {
+	Storage      storage.IStorage
+	Minio        *minioStorage.Storage
+	Email        *email.Sender
+	Logger       *slog.Logger
+	Interval     time.Duration
+	Bucket       string
+	ImagesBucket string
+	Retention    time.Duration
+	LinkTTL      time.Duration
+	// Lease is how long a build may take before another builder takes the
+	// export over
+	Lease time.Duration
+}
+
+func NewExportBuilder(st storage.IStorage, conf *config.Config, logger *slog.Logger) *ExportBuilder {
+	return &ExportBuilder{
+		Storage:      st,
+		Minio:        minioStorage.New(conf.Minio),
+		Email:        email.NewSender(conf.Email),
+		Logger:       logger,
+		Interval:     conf.Export.EXPORT_INTERVAL,
+		Bucket:       conf.Export.EXPORT_BUCKET,
+		ImagesBucket: conf.Export.EXPORT_IMAGES_BUCKET,
+		Retention:    conf.Export.EXPORT_RETENTION,
+		LinkTTL:      conf.Export.EXPORT_LINK_TTL,
+		Lease:        conf.Export.EXPORT_LEASE,
+	}
+}
+
+// Run builds pending exports every Interval until ctx is cancelled.
+func (b *ExportBuilder) Run(ctx context.Context) {
+	runEvery(ctx, b.Interval, b.RunOnce)
+}
+
+// RunOnce builds the pending exports one by one, and claims no more once
+// stop is done.
+func (b *ExportBuilder) RunOnce(stop, ctx context.Context) {
+	b.expire(ctx)
+
+	for stop.Err() == nil {
+		export, claimedAt, err := b.Storage.Export().ClaimPendingExport(ctx, b.Lease)
+		if err != nil {
+			b.Logger.Error(fmt.Sprintf("export builder: %v", err))
+			return
+		}
+		if export == nil {
+			return
+		}
+
+		err = b.build(ctx, export, claimedAt)
+		if errors.Is(err, storage.ErrExportLeaseLost) {
+			// The builder that took it over finishes it and mails the link
+			b.Logger.Error(fmt.Sprintf("export builder: export %s: %v", export.Id, err))
+			continue
+		}
+		if err != nil {
+			b.Logger.Error(fmt.Sprintf("export builder: export %s: %v", export.Id, err))
+			if err := b.Storage.Export().FailExport(ctx, export.Id, claimedAt, err.Error()); err != nil {
+				b.Logger.Error(fmt.Sprintf("export builder: export %s: %v", export.Id, err))
+			}
+			continue
+		}
+		b.Logger.Info(fmt.Sprintf("export builder: export %s is ready", export.Id))
+	}
+}
+
+func (b *ExportBuilder) expire(ctx context.Context) {
+	objects, err := b.Storage.Export().ExpireExports(ctx)
+	if err != nil {
+		b.Logger.Error(fmt.Sprintf("export builder: %v", err))
+		return
+	}
+	for _, object := range objects {
+		if err := b.Minio.RemoveObject(ctx, b.Bucket, object); err != nil {
+			b.Logger.Error(fmt.Sprintf("export builder: %v", err))
+		}
+	}
+}
+
+func (b *ExportBuilder) build(ctx context.Context, export *pb.DataExport, claimedAt time.Time) error {
+	user, err := b.Storage.User().GetUserByIdWithDeleted(ctx, &pb.UserId{Id: export.UserId})
+	if err != nil {
+		return err
+	}
+	data, err := b.Storage.Export().CollectUserData(ctx, export.UserId)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp("", "export-*.zip")
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if err := b.writeArchive(ctx, file, user, data); err != nil {
+		return err
+	}
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("failed to size archive: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind archive: %w", err)
+	}
+
+	object := fmt.Sprintf("%s/%s.zip", export.UserId, export.Id)
+	if err := b.Minio.PutPrivateObject(ctx, b.Bucket, object, file, size, "application/zip"); err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(b.Retention)
+	if err := b.Storage.Export().CompleteExport(ctx, export.Id, claimedAt, object, expiresAt); err != nil {
+		return err
+	}
+
+	link, err := b.Minio.PresignedURL(ctx, b.Bucket, object, b.LinkTTL)
+	if err != nil {
+		return err
+	}
+	err = b.Email.SendLink(user.Email, email.LinkMessage{
+		Subject: "Your data export is ready",
+		Title:   "Your data export is ready",
+		Text: fmt.Sprintf("The link below works for %s. Until %s you can get a new one from the app.",
+			b.LinkTTL, expiresAt.Format("2006-01-02 15:04")),
+		Link:   link,
+		Button: "Download my data",
+	})
+	if err != nil {
+		// The archive is there already, the user can still fetch it
+		b.Logger.Error(fmt.Sprintf("export builder: export %s: error sending email: %v", export.Id, err))
+	}
+	return nil
+}
+
+func (b *ExportBuilder) writeArchive(ctx context.Context, w io.Writer, user *pb.AdminUser, data map[string]json.RawMessage) error {
+	archive := zip.NewWriter(w)
+
+	entry, err := archive.Create("data.json")
+	if err != nil {
+		return fmt.Errorf("failed to write data.json: %w", err)
+	}
+	encoder := json.NewEncoder(entry)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(data); err != nil {
+		return fmt.Errorf("failed to write data.json: %w", err)
+	}
+
+	if user.Photo != "" {
+		name := path.Base(user.Photo)
+		if err := b.copyObject(ctx, archive, minioStorage.PhotosBucket, name, "media/profile/"+name); err != nil {
+			return err
+		}
+	}
+
+	if b.ImagesBucket != "" {
+		var images []struct {
+			CarId    string `json:"car_id"`
+			Filename string `json:"filename"`
+		}
+		if err := json.Unmarshal(data["images"], &images); err != nil {
+			return fmt.Errorf("failed to read images: %w", err)
+		}
+		for _, image := range images {
+			name := path.Base(image.Filename)
+			target := fmt.Sprintf("media/cars/%s/%s", image.CarId, name)
+			if err := b.copyObject(ctx, archive, b.ImagesBucket, name, target); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("failed to finish archive: %w", err)
+	}
+	return nil
+}
+
+func (b *ExportBuilder) copyObject(ctx context.Context, archive *zip.Writer, bucket, name, target string) error {
+	object, err := b.Minio.GetObject(ctx, bucket, name)
+	if err != nil {
+		return err
+	}
+	defer object.Close()
+
+	entry, err := archive.Create(target)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", target, err)
+	}
+	if _, err := io.Copy(entry, object); err != nil {
+		return fmt.Errorf("failed to copy %s/%s: %w", bucket, name, err)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"context"
+	"sync"
+)
+
+// Worker is one of the background jobs of this package.
+type Worker interface {
+	Run(ctx context.Context)
+}
+
+// Group runs workers until Stop.
+type Group struct {
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Start runs each worker in its own goroutine.
+func Start(workers ...Worker) *Group {
+	ctx, cancel := context.WithCancel(context.Background())
+	g := &Group{cancel: cancel}
+	for _, w := range workers {
+		g.wg.Add(1)
+		go func(w Worker) {
+			defer g.wg.Done()
+			w.Run(ctx)
+		}(w)
+	}
+	return g
+}
+
+// Stop keeps the workers from starting another run and waits for the runs
+// in progress to finish, or ctx to be done.
+func (g *Group) Stop(ctx context.Context) error {
+	g.cancel()
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,96 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"wegugin/config"
+	"wegugin/model"
+	"wegugin/storage"
+)
+
+const priceDropNotification = "price_drop"
+
+// PriceDropNotifier walks the car price changes recorded since its last run
+// and notifies the users who saved a car when its price dropped by at least
+// Threshold percent.
+type PriceDropNotifier struct {
+	Storage   storage.IStorage
+	Logger    *slog.Logger
+	Interval  time.Duration
+	Threshold float64
+}
+
+func NewPriceDropNotifier(st storage.IStorage, conf *config.Config, logger *slog.Logger) *PriceDropNotifier {
+	return &PriceDropNotifier{
+		Storage:   st,
+		Logger:    logger,
+		Interval:  conf.Worker.PRICE_DROP_INTERVAL,
+		Threshold: conf.Worker.PRICE_DROP_THRESHOLD,
+	}
+}
+
+// Run checks for price drops every Interval until ctx is cancelled.
+func (n *PriceDropNotifier) Run(ctx context.Context) {
+	runEvery(ctx, n.Interval, n.RunOnce)
+}
+
+func (n *PriceDropNotifier) RunOnce(stop, ctx context.Context) {
+	changes, err := n.Storage.Car().PendingPriceChanges(ctx)
+	if err != nil {
+		n.Logger.Error(fmt.Sprintf("price drop notifier: %v", err))
+		return
+	}
+
+	var done []int64
+	for _, change := range changes {
+		// The changes notified so far are still marked
+		if stop.Err() != nil {
+			break
+		}
+		if err := n.notify(ctx, change); err != nil {
+			n.Logger.Error(fmt.Sprintf("price drop notifier: car %s: %v", change.CarId, err))
+			continue
+		}
+		done = append(done, change.Id)
+	}
+
+	if err := n.Storage.Car().MarkPriceChangesAlerted(ctx, done); err != nil {
+		n.Logger.Error(fmt.Sprintf("price drop notifier: %v", err))
+	}
+}
+
+func (n *PriceDropNotifier) notify(ctx context.Context, change *model.PriceChange) error {
+	if change.OldPrice <= 0 || change.Price >= change.OldPrice {
+		return nil
+	}
+	drop := (change.OldPrice - change.Price) / change.OldPrice * 100
+	if drop < n.Threshold {
+		return nil
+	}
+
+	users, err := n.Storage.Car().PriceWatchers(ctx, change.CarId)
+	if err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("Price dropped by %.0f%% on the %d %s %s you saved: %.0f -> %.0f",
+		drop, change.Year, change.Make, change.Model, change.OldPrice, change.Price)
+	// The change stays pending while a watcher is left to notify; the ones
+	// notified already are skipped when it is retried
+	var errs []error
+	for _, user := range users {
+		err := n.Storage.Car().NotifyPriceDrop(ctx, change.Id, &model.Notification{
+			UserId:  user,
+			Type:    priceDropNotification,
+			Message: message,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("user %s: %w", user, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
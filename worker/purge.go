@@ -0,0 +1,72 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+	"wegugin/config"
+	"wegugin/storage"
+	minioStorage "wegugin/storage/minio"
+)
+
+// AccountPurger removes users whose restore window has passed: their photo,
+// car images and export archives go from MinIO, then the row is deleted
+// together with everything that references it, or anonymized when Anonymize
+// is set.
+type AccountPurger struct {
+	Storage       storage.IStorage
+	Minio         *minioStorage.Storage
+	Logger        *slog.Logger
+	Interval      time.Duration
+	RestoreWindow time.Duration
+	Anonymize     bool
+	ImagesBucket  string
+	ExportBucket  string
+}
+
+func NewAccountPurger(st storage.IStorage, conf *config.Config, logger *slog.Logger) *AccountPurger {
+	return &AccountPurger{
+		Storage:       st,
+		Minio:         minioStorage.New(conf.Minio),
+		Logger:        logger,
+		Interval:      conf.Account.PURGE_INTERVAL,
+		RestoreWindow: conf.Account.RESTORE_WINDOW,
+		Anonymize:     conf.Account.PURGE_MODE == "anonymize",
+		ImagesBucket:  conf.Export.EXPORT_IMAGES_BUCKET,
+		ExportBucket:  conf.Export.EXPORT_BUCKET,
+	}
+}
+
+// Run purges expired accounts every Interval until ctx is cancelled.
+func (p *AccountPurger) Run(ctx context.Context) {
+	runEvery(ctx, p.Interval, p.RunOnce)
+}
+
+func (p *AccountPurger) RunOnce(stop, ctx context.Context) {
+	before := time.Now().Add(-p.RestoreWindow).Unix()
+	users, err := p.Storage.User().PurgeCandidates(ctx, before)
+	if err != nil {
+		p.Logger.Error(fmt.Sprintf("account purger: %v", err))
+		return
+	}
+
+	for _, user := range users {
+		if stop.Err() != nil {
+			return
+		}
+		images, exports, err := p.Storage.User().PurgeFiles(ctx, user.Id)
+		if err == nil {
+			err = p.Minio.RemoveUserFiles(ctx, user.Photo, images, exports, p.ImagesBucket, p.ExportBucket)
+		}
+		if err != nil {
+			p.Logger.Error(fmt.Sprintf("account purger: user %s: %v", user.Id, err))
+			continue
+		}
+		if err := p.Storage.User().PurgeUser(ctx, user.Id, p.Anonymize); err != nil {
+			p.Logger.Error(fmt.Sprintf("account purger: user %s: %v", user.Id, err))
+			continue
+		}
+		p.Logger.Info(fmt.Sprintf("account purger: purged user %s", user.Id))
+	}
+}
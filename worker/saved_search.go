@@ -0,0 +1,100 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+	"wegugin/config"
+	pb "wegugin/genproto/user"
+	"wegugin/model"
+	"wegugin/storage"
+)
+
+const savedSearchNotification = "saved_search"
+
+// SavedSearchMatcher periodically evaluates new and updated car listings
+// against active saved searches and notifies their owners about matches.
+// Instant searches get one notification per car on every run, daily searches
+// get a single digest once a day.
+type SavedSearchMatcher struct {
+	Storage  storage.IStorage
+	Logger   *slog.Logger
+	Interval time.Duration
+	Dedup    time.Duration
+	Lag      time.Duration
+}
+
+func NewSavedSearchMatcher(st storage.IStorage, conf *config.Config, logger *slog.Logger) *SavedSearchMatcher {
+	return &SavedSearchMatcher{
+		Storage:  st,
+		Logger:   logger,
+		Interval: conf.Worker.SAVED_SEARCH_INTERVAL,
+		Dedup:    conf.Worker.SAVED_SEARCH_DEDUP,
+		Lag:      conf.Worker.SAVED_SEARCH_LAG,
+	}
+}
+
+// Run evaluates saved searches every Interval until ctx is cancelled.
+func (m *SavedSearchMatcher) Run(ctx context.Context) {
+	runEvery(ctx, m.Interval, m.RunOnce)
+}
+
+func (m *SavedSearchMatcher) RunOnce(stop, ctx context.Context) {
+	// Searches are checked by the database's clock, the one updated_at goes by
+	now, err := m.Storage.SavedSearch().CheckCutoff(ctx, m.Lag)
+	if err != nil {
+		m.Logger.Error(fmt.Sprintf("saved search matcher: %v", err))
+		return
+	}
+	searches, err := m.Storage.SavedSearch().DueSavedSearches(ctx, now)
+	if err != nil {
+		m.Logger.Error(fmt.Sprintf("saved search matcher: %v", err))
+		return
+	}
+
+	for _, search := range searches {
+		if stop.Err() != nil {
+			return
+		}
+		if err := m.match(ctx, search, now); err != nil {
+			m.Logger.Error(fmt.Sprintf("saved search matcher: search %s: %v", search.Id, err))
+		}
+	}
+}
+
+func (m *SavedSearchMatcher) match(ctx context.Context, search *pb.SavedSearch, now time.Time) error {
+	cars, err := m.Storage.SavedSearch().MatchCars(ctx, search, now, m.Dedup)
+	if err != nil {
+		return err
+	}
+
+	var messages []string
+	switch {
+	case len(cars) == 0:
+	case search.Frequency == "daily":
+		messages = append(messages, fmt.Sprintf("%d new cars match your search %q", len(cars), search.Name))
+	default:
+		for _, car := range cars {
+			messages = append(messages, fmt.Sprintf("New car for your search %q: %d %s %s, %.0f, %s",
+				search.Name, car.Year, car.Make, car.Model, car.Price, car.Location))
+		}
+	}
+
+	for _, message := range messages {
+		err := m.Storage.Notification().CreateNotification(ctx, &model.Notification{
+			UserId:  search.UserId,
+			Type:    savedSearchNotification,
+			Message: message,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	carIds := make([]string, 0, len(cars))
+	for _, car := range cars {
+		carIds = append(carIds, car.Id)
+	}
+	return m.Storage.SavedSearch().MarkChecked(ctx, search.Id, carIds, now)
+}
@@ -0,0 +1,59 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+	"wegugin/config"
+	"wegugin/storage"
+	"wegugin/storage/redis"
+)
+
+// SuspensionSync keeps derived suspension state in line with the
+// user_suspensions table: it shows listings again once a suspension expired
+// and restores the Redis markers read by the auth middleware.
+type SuspensionSync struct {
+	Storage  storage.IStorage
+	Redis    *redis.Client
+	Logger   *slog.Logger
+	Interval time.Duration
+}
+
+func NewSuspensionSync(st storage.IStorage, rdb *redis.Client, conf *config.Config, logger *slog.Logger) *SuspensionSync {
+	return &SuspensionSync{
+		Storage:  st,
+		Redis:    rdb,
+		Logger:   logger,
+		Interval: conf.Worker.SUSPENSION_SYNC_INTERVAL,
+	}
+}
+
+// Run syncs suspensions every Interval until ctx is cancelled.
+func (s *SuspensionSync) Run(ctx context.Context) {
+	runEvery(ctx, s.Interval, s.RunOnce)
+}
+
+func (s *SuspensionSync) RunOnce(stop, ctx context.Context) {
+	if err := s.Storage.Suspension().SyncHiddenCars(ctx); err != nil {
+		s.Logger.Error(fmt.Sprintf("suspension sync: %v", err))
+	}
+
+	suspensions, err := s.Storage.Suspension().ActiveSuspensions(ctx)
+	if err != nil {
+		s.Logger.Error(fmt.Sprintf("suspension sync: %v", err))
+		return
+	}
+	for _, suspension := range suspensions {
+		if stop.Err() != nil {
+			return
+		}
+		var until time.Time
+		if suspension.ExpiresAt != "" {
+			until, _ = time.Parse(time.RFC3339Nano, suspension.ExpiresAt)
+		}
+		if err := s.Redis.MarkSuspended(ctx, suspension.UserId, until); err != nil {
+			s.Logger.Error(fmt.Sprintf("suspension sync: user %s: %v", suspension.UserId, err))
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package worker
+
+import (
+	"context"
+	"time"
+)
+
+// runEvery calls fn right away and then every interval until ctx is
+// cancelled. fn gets ctx as stop and, to work with, a context that is not
+// cancelled with it: a run checks stop before taking on the next item and
+// returns once it is done, the item in progress finishes so shutdown does
+// not leave half done work behind.
+func runEvery(ctx context.Context, interval time.Duration, fn func(stop, ctx context.Context)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		fn(ctx, context.WithoutCancel(ctx))
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
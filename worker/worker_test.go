@@ -0,0 +1,37 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestRunEveryStops cancels the worker during a run: the run sees stop done
+// but keeps a live context for the work in progress, and no run follows.
+func TestRunEveryStops(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	runs := 0
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runEvery(ctx, time.Millisecond, func(stop, work context.Context) {
+			runs++
+			cancel()
+			if stop.Err() == nil {
+				t.Error("stop is not done after shutdown")
+			}
+			if work.Err() != nil {
+				t.Error("the work in progress was cancelled")
+			}
+		})
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("runEvery did not return after shutdown")
+	}
+	if runs != 1 {
+		t.Fatalf("%d runs, want 1", runs)
+	}
+}